@@ -0,0 +1,349 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package avro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+)
+
+var (
+	// DefaultAvroBufferSize is the default capacity of the
+	// internal Go channel.
+	DefaultAvroBufferSize = dsl.DefaultChanBufferSize
+)
+
+func init() {
+	dsl.TheChanRegistry.Register(dsl.NewCtx(nil), "avro", NewAvroChan)
+}
+
+// Avro is a Chan that wraps another Chan whose payloads are
+// Avro-encoded.
+//
+// Payloads use the Confluent wire format, which is a zero byte, a
+// four-byte (big-endian) schema ID, and the Avro binary data.  The
+// payload of a message published to this channel is the JSON
+// representation of an Avro value, which is encoded with the latest
+// schema for the subject (see Subjects) from the Schema Registry
+// before the underlying channel publishes it.  Each message that the
+// underlying channel receives is decoded into JSON (with the same
+// topic) using the schema with the payload's ID from the registry.
+// A payload that can't be decoded is received as a DecodeError.
+//
+// A union value is given without its type name, and a bytes or fixed
+// value is a string with one character per byte (and a \u escape,
+// like "\u00ff", for each byte above 0x7f).
+//
+// Fetched schemas are cached for the duration of the run.  The
+// options (like the RegistryURL, Username, and Password) are subject
+// to bindings substitution.
+type Avro struct {
+	opts     *AvroOpts
+	inner    dsl.Chan
+	registry *registry
+	c        chan dsl.Msg
+
+	sync.Mutex
+	done chan bool
+
+	// subjects caches the latest schema ID (for publishing) by
+	// subject.
+	subjects map[string]int
+}
+
+func (c *Avro) DocSpec() *dsl.DocSpec {
+	return &dsl.DocSpec{
+		Chan:   &Avro{},
+		Opts:   &AvroOpts{},
+		Output: &DecodeError{},
+	}
+}
+
+// AvroOpts configures an Avro channel.
+type AvroOpts struct {
+	// Chan is the type of the underlying channel (like "mqtt").
+	Chan dsl.ChanKind `json:",omitempty" yaml:",omitempty"`
+
+	// Config is the configuration for the underlying channel.
+	Config interface{} `json:",omitempty" yaml:",omitempty"`
+
+	// RegistryURL is the base URL of the Schema Registry (like
+	// "http://localhost:8081").
+	RegistryURL string `json:",omitempty" yaml:",omitempty"`
+
+	// Username is the optional username for basic
+	// authentication with the registry.
+	Username string `json:",omitempty" yaml:",omitempty"`
+
+	// Password is the optional password for basic
+	// authentication with the registry.
+	Password string `json:",omitempty" yaml:",omitempty"`
+
+	// Subjects optionally maps topics to the registry subjects
+	// for publishing.  The default subject for a topic is
+	// "TOPIC-value".
+	Subjects map[string]string `json:",omitempty" yaml:",omitempty"`
+
+	// SchemaID, when not zero, is the ID of the schema for
+	// publishing (instead of the latest schema for the subject).
+	SchemaID int `json:",omitempty" yaml:",omitempty"`
+
+	// RegistryTimeout is the timeout in milliseconds for each
+	// registry request.  The default is 5000.
+	RegistryTimeout int64 `json:",omitempty" yaml:",omitempty"`
+
+	// BufferSize specifies the capacity of the internal Go
+	// channel.
+	//
+	// The default is DefaultAvroBufferSize.
+	BufferSize int `json:",omitempty" yaml:",omitempty"`
+}
+
+// DecodeError is what a test receives for a payload that couldn't
+// be decoded.
+type DecodeError struct {
+	// Error describes the problem.
+	Error string `json:"error"`
+
+	// SchemaID is the payload's schema ID (if known).
+	SchemaID int `json:"schemaId,omitempty"`
+
+	// Raw is the (base64-encoded) payload.
+	Raw []byte `json:"raw"`
+}
+
+func NewAvroChan(ctx *dsl.Ctx, opts interface{}) (dsl.Chan, error) {
+	o := AvroOpts{}
+
+	js, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(js, &o); err != nil {
+		return nil, fmt.Errorf("NewAvroChan: %w", err)
+	}
+
+	if o.Chan == "" {
+		return nil, dsl.Brokenf("avro channel needs a Chan")
+	}
+	if !strings.HasPrefix(o.RegistryURL, "http://") && !strings.HasPrefix(o.RegistryURL, "https://") {
+		return nil, dsl.Brokenf("avro channel needs an http(s) RegistryURL (not '%s')", o.RegistryURL)
+	}
+
+	maker, have := dsl.TheChanRegistry[o.Chan]
+	if !have {
+		return nil, dsl.Brokenf("unknown Chan kind: '%s'", o.Chan)
+	}
+
+	if o.RegistryTimeout == 0 {
+		o.RegistryTimeout = 5000 // ms
+	}
+
+	bufSize := o.BufferSize
+	if bufSize == 0 {
+		bufSize = DefaultAvroBufferSize
+	}
+
+	c := &Avro{
+		opts:     &o,
+		registry: newRegistry(&o),
+		c:        make(chan dsl.Msg, bufSize),
+		subjects: make(map[string]int),
+	}
+
+	if c.inner, err = maker(ctx, o.Config); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Avro) Kind() dsl.ChanKind {
+	return "avro"
+}
+
+func (c *Avro) Open(ctx *dsl.Ctx) error {
+	if err := c.inner.Open(ctx); err != nil {
+		return err
+	}
+
+	c.Lock()
+	c.done = make(chan bool)
+	done := c.done
+	c.Unlock()
+
+	go c.decode(ctx, c.inner.Recv(ctx), done)
+
+	return nil
+}
+
+// decode forwards the decoded messages from the underlying channel.
+func (c *Avro) decode(ctx *dsl.Ctx, in chan dsl.Msg, done chan bool) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case m := <-in:
+			ctx.Logf("Avro decoding %d bytes from '%s'", len(m.Payload), m.Topic)
+			m.Payload = c.decodePayload(ctx, m.Payload)
+			if err := c.To(ctx, m); err != nil {
+				ctx.Warnf("warning: %s To for %s", err, m.Topic)
+			}
+		}
+	}
+}
+
+// decodePayload returns the JSON for the encoded payload (or for a
+// DecodeError).
+func (c *Avro) decodePayload(ctx *dsl.Ctx, payload string) string {
+	failed := func(err error, id int) string {
+		return dsl.JSON(&DecodeError{
+			Error:    err.Error(),
+			SchemaID: id,
+			Raw:      []byte(payload),
+		})
+	}
+
+	if len(payload) < 5 || payload[0] != 0 {
+		return failed(fmt.Errorf("payload isn't in the Confluent wire format"), 0)
+	}
+	id := int(binary.BigEndian.Uint32([]byte(payload[1:5])))
+
+	s, err := c.registry.byID(ctx, id)
+	if err != nil {
+		return failed(err, id)
+	}
+
+	x, err := s.Decode([]byte(payload[5:]))
+	if err != nil {
+		return failed(fmt.Errorf("bad Avro data for schema %d: %w", id, err), id)
+	}
+
+	return dsl.JSON(x)
+}
+
+func (c *Avro) Close(ctx *dsl.Ctx) error {
+	c.Lock()
+	if c.done != nil {
+		close(c.done)
+		c.done = nil
+	}
+	c.Unlock()
+
+	return c.inner.Close(ctx)
+}
+
+func (c *Avro) Sub(ctx *dsl.Ctx, topic string) error {
+	return c.inner.Sub(ctx, topic)
+}
+
+// schema returns the ID and schema for publishing to the topic.
+func (c *Avro) schema(ctx *dsl.Ctx, topic string) (int, *Schema, error) {
+	if c.opts.SchemaID != 0 {
+		s, err := c.registry.byID(ctx, c.opts.SchemaID)
+		return c.opts.SchemaID, s, err
+	}
+
+	subject, have := c.opts.Subjects[topic]
+	if !have {
+		subject = topic + "-value"
+	}
+
+	c.Lock()
+	id, have := c.subjects[subject]
+	c.Unlock()
+	if have {
+		s, err := c.registry.byID(ctx, id)
+		return id, s, err
+	}
+
+	id, s, err := c.registry.latest(ctx, subject)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	c.Lock()
+	c.subjects[subject] = id
+	c.Unlock()
+
+	return id, s, nil
+}
+
+// Pub encodes the (JSON) payload with the topic's schema and
+// publishes it on the underlying channel.
+func (c *Avro) Pub(ctx *dsl.Ctx, m dsl.Msg) error {
+	id, s, err := c.schema(ctx, m.Topic)
+	if err != nil {
+		return err
+	}
+
+	bs, err := s.Encode([]byte(m.Payload))
+	if err != nil {
+		return fmt.Errorf("bad payload for schema %d: %w", id, err)
+	}
+
+	framed := make([]byte, 5, 5+len(bs))
+	binary.BigEndian.PutUint32(framed[1:], uint32(id))
+	framed = append(framed, bs...)
+
+	ctx.Logf("Avro encoded %d bytes for '%s' with schema %d", len(framed), m.Topic, id)
+	m.Payload = string(framed)
+
+	return c.inner.Pub(ctx, m)
+}
+
+func (c *Avro) Recv(ctx *dsl.Ctx) chan dsl.Msg {
+	return c.c
+}
+
+func (c *Avro) Kill(ctx *dsl.Ctx) error {
+	return c.inner.Kill(ctx)
+}
+
+// Ping checks the registry and pings the underlying channel (if it
+// can).
+func (c *Avro) Ping(ctx *dsl.Ctx) error {
+	if err := c.registry.ping(ctx); err != nil {
+		return err
+	}
+	if p, is := c.inner.(dsl.Pinger); is {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *Avro) To(ctx *dsl.Ctx, m dsl.Msg) error {
+	m.ReceivedAt = time.Now().UTC()
+	select {
+	case <-ctx.Done():
+	case c.c <- m:
+	default:
+		panic("Warning: Avro channel full")
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package avro
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+)
+
+func TestDocs(t *testing.T) {
+	(&Avro{}).DocSpec().Write("avro")
+}
+
+// fakeRegistry serves the order schema with ID 42 for the
+// "orders-value" subject, and it counts its requests.
+func fakeRegistry(t *testing.T, requests *int32) string {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		if user, pass, _ := r.BasicAuth(); user != "homer" || pass != "donuts" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		resp := map[string]interface{}{
+			"id":     42,
+			"schema": orderSchema,
+		}
+		switch r.URL.Path {
+		case "/subjects":
+			json.NewEncoder(w).Encode([]string{"orders-value"})
+			return
+		case "/subjects/orders-value/versions/latest", "/schemas/ids/42":
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_code":40403,"message":"Schema not found"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func open(t *testing.T, ctx *dsl.Ctx, opts *AvroOpts) dsl.Chan {
+	c, err := NewAvroChan(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close(ctx) })
+	return c
+}
+
+func recv(t *testing.T, ctx *dsl.Ctx, c dsl.Chan) dsl.Msg {
+	select {
+	case m := <-c.Recv(ctx):
+		return m
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	return dsl.Msg{}
+}
+
+func TestAvro(t *testing.T) {
+	var (
+		requests int32
+		ctx      = dsl.NewCtx(nil)
+		c        = open(t, ctx, &AvroOpts{
+			Chan:        "mock",
+			RegistryURL: fakeRegistry(t, &requests),
+			Username:    "homer",
+			Password:    "donuts",
+		})
+		inner = c.(*Avro).inner
+	)
+
+	if err := c.(*Avro).Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	order := `{"at":0,"code":"ab","id":7,"items":[{"price":1.5,"sku":"taco"}],"next":null,"note":null,"ok":true,"status":"SHIPPED","tags":{}}`
+
+	t.Run("roundtrip", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if err := c.Pub(ctx, dsl.Msg{
+				Topic:   "orders",
+				Payload: order,
+			}); err != nil {
+				t.Fatal(err)
+			}
+			m := recv(t, ctx, c)
+			if m.Topic != "orders" || m.Payload != order {
+				t.Fatal(m)
+			}
+		}
+		// The ping, the latest version, and nothing else
+		// (thanks to the caches).
+		if n := atomic.LoadInt32(&requests); n != 2 {
+			t.Fatalf("%d requests", n)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		if err := inner.Pub(ctx, dsl.Msg{Payload: "\x00\x00\x00\x00\x07\x00"}); err != nil {
+			t.Fatal(err)
+		}
+		var e DecodeError
+		if err := json.Unmarshal([]byte(recv(t, ctx, c).Payload), &e); err != nil {
+			t.Fatal(err)
+		}
+		if e.SchemaID != 7 || !strings.Contains(e.Error, "404") {
+			t.Fatal(e)
+		}
+	})
+
+	t.Run("unframed", func(t *testing.T) {
+		if err := inner.Pub(ctx, dsl.Msg{Payload: `{"id":7}`}); err != nil {
+			t.Fatal(err)
+		}
+		var e DecodeError
+		if err := json.Unmarshal([]byte(recv(t, ctx, c).Payload), &e); err != nil {
+			t.Fatal(err)
+		}
+		if string(e.Raw) != `{"id":7}` || !strings.Contains(e.Error, "Confluent wire format") {
+			t.Fatal(e)
+		}
+	})
+
+	t.Run("badpub", func(t *testing.T) {
+		err := c.Pub(ctx, dsl.Msg{Topic: "orders", Payload: `{"id":7}`})
+		if err == nil || !strings.Contains(err.Error(), "bad payload for schema 42") {
+			t.Fatal(err)
+		}
+		if err = c.Pub(ctx, dsl.Msg{Topic: "nope", Payload: `{"id":7}`}); err == nil {
+			t.Fatal("no error for an unknown subject")
+		}
+	})
+}
+
+func TestAvroBroken(t *testing.T) {
+	ctx := dsl.NewCtx(nil)
+
+	for name, opts := range map[string]*AvroOpts{
+		"nochan":     {RegistryURL: "http://localhost:8081"},
+		"noregistry": {Chan: "mock"},
+		"badchan":    {Chan: "nope", RegistryURL: "http://localhost:8081"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewAvroChan(ctx, opts)
+			if _, is := dsl.IsBroken(err); !is {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package avro
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// Schema is a (parsed) Avro schema.
+//
+// The schema's codecs are goavro codecs.  Values are given and
+// returned as plain JSON, so union values don't have their type
+// names, and bytes and fixed values are strings (with one character
+// per byte).  A byte above 0x7f must be given as a \u escape.
+type Schema struct {
+	// codec uses plain JSON.
+	codec *goavro.Codec
+
+	// avroJSON uses the Avro JSON encoding, where a union value
+	// has its type name (like {"string":"queso"}).
+	avroJSON *goavro.Codec
+}
+
+// ParseSchema parses the Avro schema (in JSON).
+func ParseSchema(js string) (*Schema, error) {
+	codec, err := goavro.NewCodecForStandardJSONFull(js)
+	if err != nil {
+		return nil, fmt.Errorf("bad Avro schema: %w", err)
+	}
+	avroJSON, err := goavro.NewCodec(js)
+	if err != nil {
+		return nil, fmt.Errorf("bad Avro schema: %w", err)
+	}
+	return &Schema{
+		codec:    codec,
+		avroJSON: avroJSON,
+	}, nil
+}
+
+// Decode decodes the Avro binary data into a value that can be
+// rendered as JSON.
+func (s *Schema) Decode(bs []byte) (interface{}, error) {
+	native, rest, err := s.codec.NativeFromBinary(bs)
+	if err != nil {
+		return nil, err
+	}
+	if 0 < len(rest) {
+		return nil, fmt.Errorf("%d extra bytes", len(rest))
+	}
+
+	js, err := s.codec.TextualFromNative(nil, native)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+	var x interface{}
+	if err := dec.Decode(&x); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// Encode encodes the JSON value as Avro binary data.  A union value
+// can be given without its type name or, if the whole value uses
+// the Avro JSON encoding, with it (like {"string":"queso"}).
+func (s *Schema) Encode(js []byte) ([]byte, error) {
+	native, _, err := s.codec.NativeFromTextual(js)
+	if err != nil {
+		var err2 error
+		if native, _, err2 = s.avroJSON.NativeFromTextual(js); err2 != nil {
+			return nil, err
+		}
+	}
+	return s.codec.BinaryFromNative(nil, native)
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package avro
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Comcast/plax/dsl"
+)
+
+const orderSchema = `{
+  "type": "record",
+  "name": "Order",
+  "namespace": "acme",
+  "fields": [
+    {"name": "id", "type": "long"},
+    {"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["PENDING", "SHIPPED"]}},
+    {"name": "note", "type": ["null", "string"], "default": null},
+    {"name": "items", "type": {"type": "array", "items": {
+      "type": "record", "name": "Item", "fields": [
+        {"name": "sku", "type": "string"},
+        {"name": "price", "type": "double"}
+      ]}}},
+    {"name": "tags", "type": {"type": "map", "values": "int"}},
+    {"name": "code", "type": {"type": "fixed", "name": "Code", "size": 2}},
+    {"name": "next", "type": ["null", "Order"], "default": null},
+    {"name": "ok", "type": "boolean"},
+    {"name": "at", "type": {"type": "long", "logicalType": "timestamp-millis"}}
+  ]
+}`
+
+func TestCodecPrimitives(t *testing.T) {
+	for _, c := range []struct {
+		schema string
+		value  string
+		bs     []byte
+	}{
+		{`"long"`, `64`, []byte{0x80, 0x01}},
+		{`"int"`, `-1`, []byte{0x01}},
+		{`"string"`, `"foo"`, []byte{0x06, 'f', 'o', 'o'}},
+		{`"boolean"`, `true`, []byte{0x01}},
+		{`"null"`, `null`, []byte{}},
+		{`["null","string"]`, `"a"`, []byte{0x02, 0x02, 'a'}},
+		{`["null","string"]`, `{"string":"a"}`, []byte{0x02, 0x02, 'a'}},
+		{`{"type":"array","items":"int"}`, `[1,2]`, []byte{0x04, 0x02, 0x04, 0x00}},
+	} {
+		s, err := ParseSchema(c.schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bs, err := s.Encode([]byte(c.value))
+		if err != nil {
+			t.Fatalf("%s %s: %s", c.schema, c.value, err)
+		}
+		if !bytes.Equal(bs, c.bs) {
+			t.Fatalf("%s %s: got %x (wanted %x)", c.schema, c.value, bs, c.bs)
+		}
+	}
+}
+
+func TestCodecRoundtrip(t *testing.T) {
+	s, err := ParseSchema(orderSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := `{"at":1600000000000,"code":"\u00ff\u0001","id":7,"items":[{"price":1.5,"sku":"taco"}],"next":{"at":0,"code":"ab","id":8,"items":[],"ok":false,"status":"PENDING","tags":{}},"note":"hot","ok":true,"status":"SHIPPED","tags":{"a":1,"b":2}}`
+
+	bs, err := s.Encode([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x, err := s.Decode(bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The defaults fill in the nested note and next.
+	want := strings.Replace(in, `"items":[],"ok"`, `"items":[],"next":null,"note":null,"ok"`, 1)
+	want = strings.Replace(want, `\u00ff`, `ÿ`, 1)
+	if got := dsl.JSON(x); got != want {
+		t.Fatalf("got\n%s\nwanted\n%s", got, want)
+	}
+
+	if _, err := s.Decode(append(bs, 0)); err == nil {
+		t.Fatal("no error for extra bytes")
+	}
+	if _, err := s.Decode(bs[:len(bs)-1]); err == nil {
+		t.Fatal("no error for truncated data")
+	}
+}
+
+func TestCodecErrors(t *testing.T) {
+	for _, src := range []string{`"nope"`, `{"type":"record"}`, `{"type":"fixed","name":"F"}`, `{`} {
+		if _, err := ParseSchema(src); err == nil {
+			t.Fatalf("%s: no error", src)
+		}
+	}
+
+	s, err := ParseSchema(orderSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for value, want := range map[string]string{
+		`{"id":7}`:                             `only found 3 of 9 fields`,
+		`{"id":1.5,"status":"SHIPPED"}`:        `received: '.'`,
+		`{"id":7,"status":"LOST"}`:             `value ought to be member of symbols`,
+		`{"id":7,"status":"SHIPPED","note":3}`: `for key: "note"`,
+		`{"id":7,"status":"SHIPPED","items":[],"tags":{},"code":"a"}`: `datum size ought to equal schema size: 1 != 2`,
+	} {
+		_, err := s.Encode([]byte(value))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: got %v (wanted %s)", value, err, want)
+		}
+	}
+}
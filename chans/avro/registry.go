@@ -0,0 +1,176 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+)
+
+// schemaCache caches the schemas fetched from registries (by
+// registry URL and schema ID) for the duration of the process (run).
+var schemaCache = struct {
+	sync.Mutex
+	schemas map[string]*Schema
+}{
+	schemas: make(map[string]*Schema),
+}
+
+// registry is a Confluent Schema Registry client.
+type registry struct {
+	url      string
+	username string
+	password string
+	client   *http.Client
+}
+
+// registrySchema is a response from the registry.
+type registrySchema struct {
+	ID     int    `json:"id"`
+	Schema string `json:"schema"`
+
+	// SchemaType is "AVRO" when not given.
+	SchemaType string `json:"schemaType"`
+}
+
+// do makes a GET request to the registry and returns the body of
+// a successful response.
+func (r *registry) do(ctx *dsl.Ctx, path string) ([]byte, error) {
+	u := strings.TrimSuffix(r.url, "/") + path
+	ctx.Logf("Avro registry GET %s", u)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json, application/json")
+	if r.username != "" || r.password != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	bs, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("schema registry GET %s: %s: %s", path, resp.Status, strings.TrimSpace(string(bs)))
+	}
+	return bs, nil
+}
+
+// ping checks that the registry answers.
+func (r *registry) ping(ctx *dsl.Ctx) error {
+	_, err := r.do(ctx, "/subjects")
+	return err
+}
+
+// get requests a schema from the registry.
+func (r *registry) get(ctx *dsl.Ctx, path string) (*registrySchema, error) {
+	bs, err := r.do(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rs registrySchema
+	if err := json.Unmarshal(bs, &rs); err != nil {
+		return nil, fmt.Errorf("bad schema registry response for %s: %w", path, err)
+	}
+	if rs.SchemaType != "" && rs.SchemaType != "AVRO" {
+		return nil, fmt.Errorf("schema registry %s schema isn't Avro (%s)", path, rs.SchemaType)
+	}
+	return &rs, nil
+}
+
+// parse parses (and caches) the schema with the given ID.
+func (r *registry) parse(id int, src string) (*Schema, error) {
+	s, err := ParseSchema(src)
+	if err != nil {
+		return nil, fmt.Errorf("schema %d: %w", id, err)
+	}
+
+	schemaCache.Lock()
+	schemaCache.schemas[r.key(id)] = s
+	schemaCache.Unlock()
+
+	return s, nil
+}
+
+func (r *registry) key(id int) string {
+	return fmt.Sprintf("%s#%d", r.url, id)
+}
+
+// cached returns the cached schema (if any) with the given ID.
+func (r *registry) cached(id int) (*Schema, bool) {
+	schemaCache.Lock()
+	defer schemaCache.Unlock()
+	s, have := schemaCache.schemas[r.key(id)]
+	return s, have
+}
+
+// byID returns the (perhaps cached) schema with the given ID.
+func (r *registry) byID(ctx *dsl.Ctx, id int) (*Schema, error) {
+	if s, have := r.cached(id); have {
+		return s, nil
+	}
+
+	rs, err := r.get(ctx, fmt.Sprintf("/schemas/ids/%d", id))
+	if err != nil {
+		return nil, err
+	}
+	return r.parse(id, rs.Schema)
+}
+
+// latest returns the ID and schema of the latest version for the
+// subject.
+func (r *registry) latest(ctx *dsl.Ctx, subject string) (int, *Schema, error) {
+	rs, err := r.get(ctx, "/subjects/"+url.PathEscape(subject)+"/versions/latest")
+	if err != nil {
+		return 0, nil, err
+	}
+	if s, have := r.cached(rs.ID); have {
+		return rs.ID, s, nil
+	}
+	s, err := r.parse(rs.ID, rs.Schema)
+	return rs.ID, s, err
+}
+
+func newRegistry(o *AvroOpts) *registry {
+	return &registry{
+		url:      o.RegistryURL,
+		username: o.Username,
+		password: o.Password,
+		client: &http.Client{
+			Timeout: time.Duration(o.RegistryTimeout) * time.Millisecond,
+		},
+	}
+}
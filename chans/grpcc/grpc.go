@@ -0,0 +1,503 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package grpcc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// DefaultGRPCBufferSize is the default capacity of the
+	// internal Go channel.
+	DefaultGRPCBufferSize = dsl.DefaultChanBufferSize
+)
+
+func init() {
+	dsl.TheChanRegistry.Register(dsl.NewCtx(nil), "grpc", NewGRPCChan)
+}
+
+// GRPC is a gRPC client Chan.
+//
+// This channel type invokes unary and server-streaming RPCs.  The
+// topic of a message published to this channel is the full method
+// name (like "grpc.health.v1.Health/Check"), and the payload is the
+// request message in JSON.  Each response is forwarded (with the
+// method name as its topic) for the test to receive as a
+// GRPCResponse.
+//
+// The service descriptions come from a descriptor set file (see
+// 'protoc --descriptor_set_out --include_imports') or, when no file
+// is given, from the server via gRPC reflection.
+type GRPC struct {
+	opts *GRPCOpts
+	conn *grpc.ClientConn
+	stub grpcdynamic.Stub
+	c    chan dsl.Msg
+
+	// files are the descriptions from DescriptorSetFile (if any).
+	files map[string]*desc.FileDescriptor
+
+	// reflect resolves services when there are no files.
+	reflect *grpcreflect.Client
+}
+
+func (c *GRPC) DocSpec() *dsl.DocSpec {
+	return &dsl.DocSpec{
+		Chan:   &GRPC{},
+		Opts:   &GRPCOpts{},
+		Output: &GRPCResponse{},
+	}
+}
+
+// GRPCOpts configures a GRPC channel.
+type GRPCOpts struct {
+	// Target is the server address (like "localhost:50051").
+	Target string `json:",omitempty" yaml:",omitempty"`
+
+	// DescriptorSetFile is the optional filename of a serialized
+	// FileDescriptorSet for the services.  When empty, the
+	// channel uses the server's reflection service.
+	DescriptorSetFile string `json:",omitempty" yaml:",omitempty"`
+
+	// TLS turns on TLS for the connection, which is otherwise
+	// plaintext.
+	TLS bool `json:",omitempty" yaml:",omitempty"`
+
+	// CertFile is the optional filename for the client's
+	// certificate.
+	CertFile string `json:",omitempty" yaml:",omitempty"`
+
+	// KeyFile is the optional filename for the client's private
+	// key.
+	KeyFile string `json:",omitempty" yaml:",omitempty"`
+
+	// CACertFile is the optional filename for the certificate
+	// authority.
+	CACertFile string `json:",omitempty" yaml:",omitempty"`
+
+	// ServerName is the optional name for verifying the server's
+	// certificate, which otherwise comes from the Target.
+	ServerName string `json:",omitempty" yaml:",omitempty"`
+
+	// Insecure skips verifying the server's certificate
+	// (with TLS).  This should be used only for testing.
+	Insecure bool `json:",omitempty" yaml:",omitempty"`
+
+	// Metadata is sent with every RPC.
+	Metadata map[string]string `json:",omitempty" yaml:",omitempty"`
+
+	// ConnectTimeout is the timeout in milliseconds for
+	// connecting to the server.  The default is 1000.
+	ConnectTimeout int64 `json:",omitempty" yaml:",omitempty"`
+
+	// Timeout is the optional timeout in milliseconds for each
+	// RPC (including all of a stream's responses).
+	Timeout int64 `json:",omitempty" yaml:",omitempty"`
+
+	// BufferSize specifies the capacity of the internal Go
+	// channel.
+	//
+	// The default is DefaultGRPCBufferSize.
+	BufferSize int `json:",omitempty" yaml:",omitempty"`
+}
+
+// GRPCResponse is what a test receives for each response (and, for
+// a server-streaming RPC, for the end of the stream).
+type GRPCResponse struct {
+	// Message is the response message (if any).
+	Message interface{} `json:"message,omitempty"`
+
+	// Code is the status code (like "OK" or "NotFound") of the
+	// RPC, which is given for a unary RPC and at the end of a
+	// stream.
+	Code string `json:"code,omitempty"`
+
+	// Error is the status message of an RPC that failed, or it
+	// describes a channel processing error.
+	Error string `json:"error,omitempty"`
+
+	// Done reports that the stream of a server-streaming RPC
+	// has ended.
+	Done bool `json:"done,omitempty"`
+}
+
+func NewGRPCChan(ctx *dsl.Ctx, opts interface{}) (dsl.Chan, error) {
+	o := GRPCOpts{}
+
+	js, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(js, &o); err != nil {
+		return nil, fmt.Errorf("NewGRPCChan: %w", err)
+	}
+
+	if o.Target == "" {
+		return nil, dsl.Brokenf("grpc channel needs a Target")
+	}
+
+	if o.ConnectTimeout == 0 {
+		o.ConnectTimeout = 1000 // ms
+	}
+
+	bufSize := o.BufferSize
+	if bufSize == 0 {
+		bufSize = DefaultGRPCBufferSize
+	}
+
+	c := &GRPC{
+		opts: &o,
+		c:    make(chan dsl.Msg, bufSize),
+	}
+
+	if o.DescriptorSetFile != "" {
+		if c.files, err = readDescriptorSet(o.DescriptorSetFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// readDescriptorSet reads the serialized FileDescriptorSet.
+func readDescriptorSet(filename string) (map[string]*desc.FileDescriptor, error) {
+	bs, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, dsl.NewBroken(err)
+	}
+	var fds dpb.FileDescriptorSet
+	if err := proto.Unmarshal(bs, &fds); err != nil {
+		return nil, dsl.Brokenf("bad descriptor set '%s': %v", filename, err)
+	}
+	files, err := desc.CreateFileDescriptorsFromSet(&fds)
+	if err != nil {
+		return nil, dsl.Brokenf("bad descriptor set '%s': %v", filename, err)
+	}
+	return files, nil
+}
+
+func (c *GRPC) Kind() dsl.ChanKind {
+	return "grpc"
+}
+
+// dialOpts returns the options for dialing the Target.
+func (o *GRPCOpts) dialOpts() ([]grpc.DialOption, error) {
+	opts := []grpc.DialOption{
+		grpc.WithBlock(),
+	}
+
+	if !o.TLS {
+		return append(opts, grpc.WithInsecure()), nil
+	}
+
+	tlsConf := &tls.Config{
+		InsecureSkipVerify: o.Insecure,
+		ServerName:         o.ServerName,
+	}
+
+	if o.CACertFile != "" {
+		certs, err := ioutil.ReadFile(o.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read '%s': %s", o.CACertFile, err)
+		}
+		rootCAs := x509.NewCertPool()
+		if ok := rootCAs.AppendCertsFromPEM(certs); !ok {
+			return nil, fmt.Errorf("no certs appended from '%s'", o.CACertFile)
+		}
+		tlsConf.RootCAs = rootCAs
+	}
+
+	if o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, dsl.NewBroken(err)
+		}
+		tlsConf.Certificates = []tls.Certificate{cert}
+	}
+
+	return append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConf))), nil
+}
+
+// dial connects to the Target.
+func (c *GRPC) dial(ctx *dsl.Ctx) (*grpc.ClientConn, error) {
+	opts, err := c.opts.dialOpts()
+	if err != nil {
+		return nil, err
+	}
+
+	dctx, cancel := context.WithTimeout(ctx, time.Duration(c.opts.ConnectTimeout)*time.Millisecond)
+	defer cancel()
+
+	conn, err := grpc.DialContext(dctx, c.opts.Target, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", c.opts.Target, err)
+	}
+
+	return conn, nil
+}
+
+func (c *GRPC) Open(ctx *dsl.Ctx) error {
+	if c.conn != nil {
+		c.Close(ctx)
+	}
+
+	ctx.Logf("GRPC opening %s", c.opts.Target)
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.conn = conn
+	c.stub = grpcdynamic.NewStub(conn)
+	if c.files == nil {
+		// Use a background context because the reflection
+		// stream should last as long as the connection.
+		c.reflect = grpcreflect.NewClient(context.Background(), rpb.NewServerReflectionClient(conn))
+	}
+
+	return nil
+}
+
+func (c *GRPC) Close(ctx *dsl.Ctx) error {
+	ctx.Logf("GRPC closing %s", c.opts.Target)
+	if c.reflect != nil {
+		c.reflect.Reset()
+		c.reflect = nil
+	}
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// Ping connects to the server and then disconnects.
+func (c *GRPC) Ping(ctx *dsl.Ctx) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (c *GRPC) Sub(ctx *dsl.Ctx, topic string) error {
+	return fmt.Errorf("%T doesn't support 'sub'", c)
+}
+
+// method finds the description of the given full method name, which
+// has the form "package.Service/Method".
+func (c *GRPC) method(name string) (*desc.MethodDescriptor, error) {
+	name = strings.TrimPrefix(name, "/")
+	i := strings.LastIndex(name, "/")
+	if i < 0 {
+		return nil, fmt.Errorf("bad method '%s' (should be 'package.Service/Method')", name)
+	}
+	service, method := name[:i], name[i+1:]
+
+	var sd *desc.ServiceDescriptor
+	if c.files != nil {
+		for _, fd := range c.files {
+			if sd = fd.FindService(service); sd != nil {
+				break
+			}
+		}
+		if sd == nil {
+			return nil, fmt.Errorf("unknown service '%s'", service)
+		}
+	} else {
+		if c.reflect == nil {
+			return nil, fmt.Errorf("grpc channel to %s isn't open", c.opts.Target)
+		}
+		var err error
+		if sd, err = c.reflect.ResolveService(service); err != nil {
+			return nil, fmt.Errorf("failed to resolve service '%s': %w", service, err)
+		}
+	}
+
+	md := sd.FindMethodByName(method)
+	if md == nil {
+		return nil, fmt.Errorf("service '%s' has no method '%s'", service, method)
+	}
+	if md.IsClientStreaming() {
+		return nil, fmt.Errorf("client-streaming method '%s' isn't supported", name)
+	}
+
+	return md, nil
+}
+
+// Pub invokes the RPC given by the message topic with the request
+// given by (the JSON) payload.
+func (c *GRPC) Pub(ctx *dsl.Ctx, m dsl.Msg) error {
+	ctx.Logf("GRPC Pub %s", m.Topic)
+
+	md, err := c.method(m.Topic)
+	if err != nil {
+		return err
+	}
+
+	js, err := dsl.MaybeSerialize(m.Payload)
+	if err != nil {
+		return err
+	}
+
+	req := dynamic.NewMessage(md.GetInputType())
+	if strings.TrimSpace(js) != "" {
+		if err := jsonpb.UnmarshalString(js, req); err != nil {
+			return fmt.Errorf("bad request for %s: %w", m.Topic, err)
+		}
+	}
+
+	go c.invoke(ctx, m.Topic, md, req)
+
+	return nil
+}
+
+// invoke performs the RPC and forwards the responses.
+func (c *GRPC) invoke(ctx *dsl.Ctx, topic string, md *desc.MethodDescriptor, req proto.Message) {
+	var (
+		rctx   context.Context = ctx
+		cancel                 = func() {}
+	)
+	if 0 < c.opts.Timeout {
+		rctx, cancel = context.WithTimeout(ctx, time.Duration(c.opts.Timeout)*time.Millisecond)
+	}
+	defer cancel()
+
+	if 0 < len(c.opts.Metadata) {
+		rctx = metadata.NewOutgoingContext(rctx, metadata.New(c.opts.Metadata))
+	}
+
+	forward := func(r *GRPCResponse) {
+		if err := c.To(ctx, dsl.Msg{Topic: topic, Payload: dsl.JSON(r)}); err != nil {
+			ctx.Warnf("warning: %s To for %s", err, topic)
+		}
+	}
+
+	if !md.IsServerStreaming() {
+		resp, err := c.stub.InvokeRpc(rctx, md, req)
+		if err != nil {
+			forward(statusResponse(err))
+			return
+		}
+		r := messageResponse(resp)
+		if r.Error == "" {
+			r.Code = "OK"
+		}
+		forward(r)
+		return
+	}
+
+	stream, err := c.stub.InvokeRpcServerStream(rctx, md, req)
+	if err != nil {
+		forward(statusResponse(err))
+		return
+	}
+	for {
+		resp, err := stream.RecvMsg()
+		if err == io.EOF {
+			forward(&GRPCResponse{
+				Code: "OK",
+				Done: true,
+			})
+			return
+		}
+		if err != nil {
+			r := statusResponse(err)
+			r.Done = true
+			forward(r)
+			return
+		}
+		forward(messageResponse(resp))
+	}
+}
+
+// messageResponse makes a GRPCResponse for the response message.
+func messageResponse(m proto.Message) *GRPCResponse {
+	js, err := (&jsonpb.Marshaler{}).MarshalToString(m)
+	if err != nil {
+		return &GRPCResponse{
+			Error: err.Error(),
+		}
+	}
+	var x interface{}
+	if err := json.Unmarshal([]byte(js), &x); err != nil {
+		return &GRPCResponse{
+			Error: err.Error(),
+		}
+	}
+	return &GRPCResponse{
+		Message: x,
+	}
+}
+
+// statusResponse makes a GRPCResponse for the error of an RPC.
+func statusResponse(err error) *GRPCResponse {
+	s := status.Convert(err)
+	return &GRPCResponse{
+		Code:  s.Code().String(),
+		Error: s.Message(),
+	}
+}
+
+func (c *GRPC) Recv(ctx *dsl.Ctx) chan dsl.Msg {
+	return c.c
+}
+
+func (c *GRPC) Kill(ctx *dsl.Ctx) error {
+	return fmt.Errorf("%T doesn't support 'Kill'", c)
+}
+
+func (c *GRPC) To(ctx *dsl.Ctx, m dsl.Msg) error {
+	ctx.Logf("GRPC To %s", m.Topic)
+	ctx.Logdf("     %s", m.Payload)
+	m.ReceivedAt = time.Now().UTC()
+	select {
+	case <-ctx.Done():
+	case c.c <- m:
+	default:
+		panic("Warning: GRPC channel full")
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package grpcc
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/desc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	hpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+func TestDocs(t *testing.T) {
+	(&GRPC{}).DocSpec().Write("grpc")
+}
+
+// serve starts a gRPC server with the health and reflection
+// services.
+func serve(t *testing.T) (string, *health.Server) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	h := health.NewServer()
+	hpb.RegisterHealthServer(s, h)
+	reflection.Register(s)
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+	return l.Addr().String(), h
+}
+
+func recv(t *testing.T, ctx *dsl.Ctx, c dsl.Chan) GRPCResponse {
+	select {
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout")
+	case m := <-c.Recv(ctx):
+		var r GRPCResponse
+		if err := json.Unmarshal([]byte(m.Payload), &r); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	panic("unreachable")
+}
+
+func open(t *testing.T, ctx *dsl.Ctx, opts *GRPCOpts) dsl.Chan {
+	c, err := NewGRPCChan(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		c.Close(ctx)
+	})
+	return c
+}
+
+func TestGRPCReflection(t *testing.T) {
+	var (
+		ctx     = dsl.NewCtx(context.Background())
+		addr, h = serve(t)
+		c       = open(t, ctx, &GRPCOpts{
+			Target: addr,
+		})
+	)
+
+	h.SetServingStatus("queso", hpb.HealthCheckResponse_SERVING)
+
+	if err := c.Pub(ctx, dsl.Msg{
+		Topic:   "grpc.health.v1.Health/Check",
+		Payload: `{"service":"queso"}`,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	r := recv(t, ctx, c)
+	if r.Code != "OK" || dsl.JSON(r.Message) != `{"status":"SERVING"}` {
+		t.Fatal(dsl.JSON(r))
+	}
+
+	if err := c.Pub(ctx, dsl.Msg{
+		Topic:   "grpc.health.v1.Health/Check",
+		Payload: `{"service":"chips"}`,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if r = recv(t, ctx, c); r.Code != "NotFound" {
+		t.Fatal(dsl.JSON(r))
+	}
+
+	// Server streaming
+	if err := c.Pub(ctx, dsl.Msg{
+		Topic:   "grpc.health.v1.Health/Watch",
+		Payload: `{"service":"queso"}`,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if r = recv(t, ctx, c); dsl.JSON(r.Message) != `{"status":"SERVING"}` || r.Done {
+		t.Fatal(dsl.JSON(r))
+	}
+	h.SetServingStatus("queso", hpb.HealthCheckResponse_NOT_SERVING)
+	if r = recv(t, ctx, c); dsl.JSON(r.Message) != `{"status":"NOT_SERVING"}` {
+		t.Fatal(dsl.JSON(r))
+	}
+
+	if err := c.Pub(ctx, dsl.Msg{
+		Topic: "grpc.health.v1.Health/Taco",
+	}); err == nil {
+		t.Fatal("expected an error for an unknown method")
+	}
+}
+
+func TestGRPCDescriptorSet(t *testing.T) {
+	fd, err := desc.LoadFileDescriptor("grpc/health/v1/health.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := proto.Marshal(desc.ToFileDescriptorSet(fd))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "health.protoset")
+	if err := ioutil.WriteFile(filename, bs, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		ctx     = dsl.NewCtx(context.Background())
+		addr, _ = serve(t)
+		c       = open(t, ctx, &GRPCOpts{
+			Target:            addr,
+			DescriptorSetFile: filename,
+			Metadata: map[string]string{
+				"x-test": "plax",
+			},
+		})
+	)
+
+	if err := c.Pub(ctx, dsl.Msg{
+		Topic:   "/grpc.health.v1.Health/Check",
+		Payload: `{}`,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if r := recv(t, ctx, c); r.Code != "OK" || dsl.JSON(r.Message) != `{"status":"SERVING"}` {
+		t.Fatal(dsl.JSON(r))
+	}
+}
+
+func TestGRPCOpenFails(t *testing.T) {
+	ctx := dsl.NewCtx(context.Background())
+	c, err := NewGRPCChan(ctx, &GRPCOpts{
+		Target:         "127.0.0.1:1",
+		ConnectTimeout: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Open(ctx); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -21,11 +21,18 @@ package chans
 import (
 	"bytes"
 	"crypto/tls"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
+	"net/http/cookiejar"
+	"net/textproto"
 	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/Comcast/plax/dsl"
@@ -44,6 +51,9 @@ func init() {
 // This channel type implements HTTP requests.  A test publishes a
 // request that includes a URL.  This channel performs the HTTP
 // request and then forwards the response for the test to receive.
+//
+// A 'recv' can match the response's statuscode, headers, and body
+// separately, and a failure reports which of them didn't match.
 type HTTPClient struct {
 	opts   *HTTPClientOpts
 	client *http.Client
@@ -63,9 +73,13 @@ func (c *HTTPClient) DocSpec() *dsl.DocSpec {
 }
 
 // HTTPClientOpts configures an HTTPClient.
-//
-// Currently this channel doesn't have any configuration.
 type HTTPClientOpts struct {
+	// Cookies if true gives the channel a cookie jar, so cookies
+	// that a response sets are sent with later requests (for the
+	// life of the channel).  For example, a login
+	// request can establish a session for the requests that
+	// follow it.
+	Cookies bool `json:"cookies,omitempty" yaml:"cookies,omitempty"`
 }
 
 func (c *HTTPClient) Kind() dsl.ChanKind {
@@ -74,6 +88,13 @@ func (c *HTTPClient) Kind() dsl.ChanKind {
 
 func (c *HTTPClient) Open(ctx *dsl.Ctx) error {
 	c.client = &http.Client{}
+	if c.opts.Cookies {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return err
+		}
+		c.client.Jar = jar
+	}
 	return nil
 }
 
@@ -121,6 +142,11 @@ type HTTPRequest struct {
 	// values instead of providing an explicit Body.
 	Form url.Values `json:"form,omitempty"`
 
+	// Multipart can specify a multipart/form-data body (with
+	// file uploads) instead of an explicit Body.  The request's
+	// Content-Type header will include the boundary.
+	Multipart *Multipart `json:"multipart,omitempty"`
+
 	// HTTPRequestCtl is optional data for managing polling
 	// requests.
 	HTTPRequestCtl `json:"ctl,omitempty" yaml:"ctl"`
@@ -160,6 +186,125 @@ type HTTPRequestCtl struct {
 	Terminate string `json:"terminate,omitempty"`
 }
 
+// Multipart specifies a multipart/form-data request body.
+type Multipart struct {
+	// Fields maps form field names to values.
+	Fields map[string]string `json:"fields,omitempty"`
+
+	// Files are the file parts, which follow the fields.  Each
+	// has a 'field', an optional 'filename' and 'contentType',
+	// and either a 'path' or inline 'content' (which is
+	// base64-encoded if 'base64' is true).
+	Files []FilePart `json:"files,omitempty"`
+}
+
+// FilePart is a file in a multipart/form-data request body.
+//
+// Exactly one of Path and Content should be given.
+type FilePart struct {
+	// Field is the form field name for the file.
+	Field string `json:"field"`
+
+	// Filename is the filename reported to the server.  Defaults
+	// to the base name of Path.
+	Filename string `json:"filename,omitempty"`
+
+	// ContentType is the part's Content-Type.  Defaults to
+	// application/octet-stream.
+	ContentType string `json:"contentType,omitempty"`
+
+	// Path is the file to upload.  A relative path is relative to
+	// the test's directory.
+	Path string `json:"path,omitempty"`
+
+	// Content is the inline content of the file.
+	Content string `json:"content,omitempty"`
+
+	// Base64 if true means that Content is base64-encoded.
+	Base64 bool `json:"base64,omitempty"`
+}
+
+// content returns the bytes of the file.
+func (f *FilePart) content(ctx *dsl.Ctx) ([]byte, error) {
+	switch {
+	case f.Path != "" && f.Content != "":
+		return nil, fmt.Errorf("file part '%s' can't specify both path and content", f.Field)
+	case f.Path != "":
+		path := f.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(ctx.Dir, path)
+		}
+		return ioutil.ReadFile(path)
+	case f.Base64:
+		bs, err := base64.StdEncoding.DecodeString(f.Content)
+		if err != nil {
+			return nil, fmt.Errorf("file part '%s' content: %w", f.Field, err)
+		}
+		return bs, nil
+	default:
+		return []byte(f.Content), nil
+	}
+}
+
+// encode writes the multipart body and returns it along with its
+// Content-Type.
+func (m *Multipart) encode(ctx *dsl.Ctx) ([]byte, string, error) {
+	var (
+		buf bytes.Buffer
+		w   = multipart.NewWriter(&buf)
+	)
+
+	names := make([]string, 0, len(m.Fields))
+	for name := range m.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := w.WriteField(name, m.Fields[name]); err != nil {
+			return nil, "", err
+		}
+	}
+
+	for i := range m.Files {
+		f := &m.Files[i]
+		if f.Field == "" {
+			return nil, "", fmt.Errorf("file part %d needs a field", i)
+		}
+		bs, err := f.content(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		filename := f.Filename
+		if filename == "" {
+			filename = filepath.Base(f.Path)
+		}
+		contentType := f.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, quote(f.Field), quote(filename)))
+		h.Set("Content-Type", contentType)
+		part, err := w.CreatePart(h)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err = part.Write(bs); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return buf.Bytes(), w.FormDataContentType(), nil
+}
+
+// quote escapes quotes and backslashes in Content-Disposition
+// parameters as mime/multipart does.
+var quote = strings.NewReplacer("\\", "\\\\", `"`, "\\\"").Replace
+
 // extractHTTPRequest attempts to make an http.Request from the
 // (payload of the) given message.
 //
@@ -199,6 +344,9 @@ func extractHTTPRequest(ctx *dsl.Ctx, m dsl.Msg) (*HTTPRequest, error) {
 		Method: req.Method,
 		Header: req.Headers,
 	}
+	if real.Header == nil {
+		real.Header = make(http.Header)
+	}
 
 	if req.Form != nil {
 		if req.Body != nil {
@@ -208,7 +356,19 @@ func extractHTTPRequest(ctx *dsl.Ctx, m dsl.Msg) (*HTTPRequest, error) {
 		req.body = []byte(req.Form.Encode())
 	}
 
-	if req.Body != nil {
+	if req.Multipart != nil {
+		if req.Body != nil || req.Form != nil {
+			return nil, fmt.Errorf("can't specify Multipart with Body or Form")
+		}
+		bs, contentType, err := req.Multipart.encode(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req.body = bs
+		real.Header.Set("Content-Type", contentType)
+	}
+
+	if req.Body != nil || req.Multipart != nil {
 		real.Body = ioutil.NopCloser(bytes.NewReader(req.body))
 		real.ContentLength = int64(len(req.body))
 	}
@@ -296,6 +456,8 @@ type HTTPResponse struct {
 	Error string `json:"error,omitempty"`
 
 	// Headers contains the response headers from the HTTP server.
+	// Names are canonical (e.g., "Content-Type"), and each name
+	// maps to a list of values.
 	Headers map[string][]string `json:"headers"`
 }
 
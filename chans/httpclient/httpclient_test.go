@@ -26,6 +26,7 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -264,3 +265,161 @@ func TestInsecure(t *testing.T) {
 	case <-ch:
 	}
 }
+
+func TestMultipart(t *testing.T) {
+	var (
+		ctx = dsl.NewCtx(context.Background())
+
+		ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			got := map[string]interface{}{
+				"name": r.FormValue("name"),
+			}
+			for _, field := range []string{"menu", "logo"} {
+				f, h, err := r.FormFile(field)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				bs, _ := ioutil.ReadAll(f)
+				got[field] = []string{h.Filename, h.Header.Get("Content-Type"), string(bs)}
+			}
+			json.NewEncoder(w).Encode(got)
+		}))
+	)
+
+	defer ts.Close()
+
+	ctx.Dir = t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(ctx.Dir, "menu.txt"), []byte("tacos"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewHTTPClientChan(ctx, &HTTPClientOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if err := c.Close(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	payload, err := json.Marshal(&HTTPRequest{
+		Method: "POST",
+		URL:    ts.URL,
+		Multipart: &Multipart{
+			Fields: map[string]string{
+				"name": "Tacos Inc.",
+			},
+			Files: []FilePart{
+				{
+					Field:       "menu",
+					Path:        "menu.txt",
+					ContentType: "text/plain",
+				},
+				{
+					Field:    "logo",
+					Filename: "logo.png",
+					Content:  "bG9nbw==",
+					Base64:   true,
+				},
+			},
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Pub(ctx, dsl.Msg{Payload: string(payload)}); err != nil {
+		t.Fatal(err)
+	}
+
+	var resp HTTPResponse
+	if err := json.Unmarshal([]byte((<-c.Recv(ctx)).Payload), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"logo":["logo.png","application/octet-stream","logo"],"menu":["menu.txt","text/plain","tacos"],"name":"Tacos Inc."}`
+	if resp.StatusCode != 200 || dsl.JSON(resp.Body) != want {
+		t.Fatalf("%d %s", resp.StatusCode, dsl.JSON(resp.Body))
+	}
+
+	err = c.Pub(ctx, dsl.Msg{
+		Payload: `{"method":"POST","url":"` + ts.URL + `","body":"x","multipart":{"fields":{"name":"x"}}}`,
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestCookies(t *testing.T) {
+	var (
+		ctx = dsl.NewCtx(context.Background())
+
+		ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/login" {
+				http.SetCookie(w, &http.Cookie{Name: "session", Value: "tacos", Path: "/"})
+				fmt.Fprintln(w, `{}`)
+				return
+			}
+			session, err := r.Cookie("session")
+			if err != nil {
+				fmt.Fprintln(w, `{"session":null}`)
+				return
+			}
+			fmt.Fprintf(w, `{"session":"%s"}`+"\n", session.Value)
+		}))
+	)
+
+	defer ts.Close()
+
+	for _, cookies := range []bool{false, true} {
+		t.Run(fmt.Sprintf("%v", cookies), func(t *testing.T) {
+			c, err := NewHTTPClientChan(ctx, &HTTPClientOpts{Cookies: cookies})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err = c.Open(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			defer func() {
+				if err := c.Close(ctx); err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			var resp HTTPResponse
+			for _, path := range []string{"/login", "/whoami"} {
+				err = c.Pub(ctx, dsl.Msg{
+					Payload: `{"method":"GET","url":"` + ts.URL + path + `"}`,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := json.Unmarshal([]byte((<-c.Recv(ctx)).Payload), &resp); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want := `{"session":null}`
+			if cookies {
+				want = `{"session":"tacos"}`
+			}
+			if got := dsl.JSON(resp.Body); got != want {
+				t.Fatalf("%s rather than %s", got, want)
+			}
+		})
+	}
+}
@@ -22,8 +22,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/Comcast/plax/dsl"
@@ -51,21 +53,42 @@ func init() {
 //
 // Note that you have to do 'pub' each specific response for each
 // client request.
+//
+// When made by Mother, the channel binds its URL (see the Binding
+// option).
 type HTTPServer struct {
 	opts  *HTTPServerOpts
 	reqs  chan dsl.Msg
 	resps chan dsl.Msg
 
 	server *http.Server
+
+	// url is the server's URL once it's listening.
+	url string
 }
 
 // HTTPServerOpts configures an HTTPServer channel.
 type HTTPServerOpts struct {
-	Host      string `json:"host"`
-	Port      int    `json:"port"`
-	ParseJSON bool   `json:"parsejson" yaml:"parsejson"`
+	Host string `json:"host"`
+
+	// Port is the port to listen on.  With the default 0, the
+	// server listens on an ephemeral port, which it keeps if the
+	// channel is reconnected.
+	Port int `json:"port"`
+
+	ParseJSON bool `json:"parsejson" yaml:"parsejson"`
+
+	// Binding is the binding for the server's URL (as in
+	// "http://localhost:40404"), which the test can give to a
+	// service that will call back (say by a webhook).  Defaults to
+	// "?!callbackUrl", so a payload can contain
+	// "{?!callbackUrl}/hook".
+	Binding string `json:"binding,omitempty" yaml:"binding,omitempty"`
 }
 
+// DefaultBinding is the default HTTPServerOpts.Binding.
+const DefaultBinding = "?!callbackUrl"
+
 func (c *HTTPServer) DocSpec() *dsl.DocSpec {
 	return &dsl.DocSpec{
 		Chan:   &HTTPServer{},
@@ -115,7 +138,20 @@ type Response struct {
 }
 
 func (c *HTTPServer) Open(ctx *dsl.Ctx) error {
-	addr := fmt.Sprintf("%s:%d", c.opts.Host, c.opts.Port)
+	l, err := net.Listen("tcp", net.JoinHostPort(c.opts.Host, strconv.Itoa(c.opts.Port)))
+	if err != nil {
+		return fmt.Errorf("httpserver failed to listen: %w", err)
+	}
+
+	// Keep an ephemeral port for reconnects.
+	c.opts.Port = l.Addr().(*net.TCPAddr).Port
+
+	host := c.opts.Host
+	if host == "" {
+		host = "localhost"
+	}
+	c.url = "http://" + net.JoinHostPort(host, strconv.Itoa(c.opts.Port))
+	ctx.Logf("httpserver listening at %s", c.url)
 
 	punt := func(w http.ResponseWriter, err error) {
 		w.WriteHeader(501)
@@ -133,7 +169,7 @@ func (c *HTTPServer) Open(ctx *dsl.Ctx) error {
 	f := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		if err := r.ParseForm(); err != nil {
-			ctx.Logf("httpserver ParseForm error %v on %v", r.URL)
+			ctx.Logf("httpserver ParseForm error %v on %v", err, r.URL)
 		}
 
 		payload := &Request{
@@ -143,7 +179,8 @@ func (c *HTTPServer) Open(ctx *dsl.Ctx) error {
 			Method:  r.Method,
 		}
 
-		if r.Method == http.MethodPost {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
 			bs, err := ioutil.ReadAll(r.Body)
 			if err != nil {
 				punt(w, err)
@@ -194,6 +231,11 @@ func (c *HTTPServer) Open(ctx *dsl.Ctx) error {
 					w.Write([]byte(err.Error() + " on response"))
 					return
 				}
+				for name, vals := range r.Headers {
+					for _, val := range vals {
+						w.Header().Add(name, val)
+					}
+				}
 				w.WriteHeader(r.StatusCode)
 				// ToDo: Check err, bytes written.
 				w.Write([]byte(body))
@@ -202,7 +244,6 @@ func (c *HTTPServer) Open(ctx *dsl.Ctx) error {
 	})
 
 	c.server = &http.Server{
-		Addr:           addr,
 		Handler:        f,
 		ReadTimeout:    10 * time.Second, // ToDo: opt
 		WriteTimeout:   10 * time.Second, // ToDo: opt
@@ -210,15 +251,25 @@ func (c *HTTPServer) Open(ctx *dsl.Ctx) error {
 	}
 
 	go func() {
-		// ToDo: Report failure to listen better.
-		if err := c.server.ListenAndServe(); err != nil {
-			ctx.Logf("httpserver ListenAndServe error: %v", err)
+		if err := c.server.Serve(l); err != nil && err != http.ErrServerClosed {
+			ctx.Logf("httpserver Serve error: %v", err)
 		}
 	}()
 
 	return nil
 }
 
+// Bindings binds the server's URL.
+func (c *HTTPServer) Bindings(ctx *dsl.Ctx) map[string]interface{} {
+	binding := c.opts.Binding
+	if binding == "" {
+		binding = DefaultBinding
+	}
+	return map[string]interface{}{
+		binding: c.url,
+	}
+}
+
 func (c *HTTPServer) Close(ctx *dsl.Ctx) error {
 	return c.server.Close()
 }
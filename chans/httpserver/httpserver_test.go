@@ -18,8 +18,99 @@
 
 package httpserver
 
-import "testing"
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Comcast/plax/dsl"
+)
 
 func TestDocs(t *testing.T) {
 	(&HTTPServer{}).DocSpec().Write("httpserver")
 }
+
+func TestCallback(t *testing.T) {
+	ctx := dsl.NewCtx(context.Background())
+
+	c, err := NewHTTPServerChan(ctx, map[string]interface{}{
+		"parsejson": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close(ctx)
+
+	url, is := c.(dsl.Binder).Bindings(ctx)[DefaultBinding].(string)
+	if !is || !strings.HasPrefix(url, "http://localhost:") || strings.HasSuffix(url, ":0") {
+		t.Fatalf("bad url %#v", url)
+	}
+
+	type result struct {
+		resp *http.Response
+		body string
+		err  error
+	}
+	results := make(chan result, 1)
+	go func() {
+		resp, err := http.Post(url+"/hook", "application/json", strings.NewReader(`{"order":"tacos"}`))
+		if err != nil {
+			results <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		bs, err := ioutil.ReadAll(resp.Body)
+		results <- result{resp, string(bs), err}
+	}()
+
+	m := <-c.Recv(ctx)
+	if m.Topic != "/hook" || !strings.Contains(m.Payload, `"body":{"order":"tacos"}`) {
+		t.Fatal(dsl.JSON(m))
+	}
+
+	err = c.Pub(ctx, dsl.Msg{
+		Payload: `{"statuscode":202,"headers":{"X-Order":["42"]},"body":{"queued":true}}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := <-results
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if r.resp.StatusCode != 202 || r.resp.Header.Get("X-Order") != "42" || r.body != `{"queued":true}` {
+		t.Fatalf("%d %v %s", r.resp.StatusCode, r.resp.Header, r.body)
+	}
+}
+
+func TestListenError(t *testing.T) {
+	ctx := dsl.NewCtx(context.Background())
+
+	c, err := NewHTTPServerChan(ctx, map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close(ctx)
+
+	port := c.(*HTTPServer).opts.Port
+	d, err := NewHTTPServerChan(ctx, map[string]interface{}{
+		"host": "localhost",
+		"port": port,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Open(ctx); err == nil {
+		d.Close(ctx)
+		t.Fatal("expected an error")
+	}
+}
@@ -31,6 +31,7 @@ import (
 
 	"github.com/Comcast/plax/dsl"
 
+	"github.com/eclipse/paho.golang/paho"
 	mq "github.com/eclipse/paho.mqtt.golang"
 )
 
@@ -42,6 +43,10 @@ var (
 
 func init() {
 	dsl.TheChanRegistry.Register(dsl.NewCtx(nil), "mqtt", NewMQTTChan)
+
+	// Each MQTT message has its topic, so tests can share
+	// connections.
+	dsl.PooledChanKinds["mqtt"] = true
 }
 
 // MQTT is an MQTT client Chan.
@@ -56,11 +61,53 @@ func init() {
 // topic for the message.  Similarly, the topic of the message
 // received from the broker becomes the topic of the message the test
 // sees.
+//
+// With Envelope, messages carry their QoS, retained flag, and (with
+// ProtocolVersion 5, which talks MQTT 5) their MQTT 5 properties:
+//
+//	{"payload":{"want":"tacos"},"qos":1,"retained":false,
+//	 "properties":{"contentType":"application/json","responseTopic":"replies",
+//	               "correlationData":"42","userProperties":{"tenant":"tacos"}}}
+//
+// A payload received in an Envelope is parsed as JSON if possible.
 type MQTT struct {
 	opts   *MQTTOpts
 	mopts  *mq.ClientOptions
 	client mq.Client
 	c      chan dsl.Msg
+
+	// conn is the MQTT 5 connection that's used instead of the
+	// client when the ProtocolVersion is 5.
+	conn *conn5
+}
+
+// Message is the payload of a message with the Envelope option.
+type Message struct {
+	Payload interface{} `json:"payload"`
+
+	// QoS is the message's QoS.  The default for publishing is
+	// 1.  A received message has the QoS that the broker
+	// delivered it with (which is at most the SubQoS).
+	QoS *int `json:"qos,omitempty"`
+
+	// Retained is the MQTT retain flag.  A received message is
+	// retained when the broker sent it because it was retained
+	// (rather than because it was just published).
+	Retained bool `json:"retained"`
+
+	// Properties are the message's MQTT 5 properties (if any).
+	Properties *Properties `json:"properties,omitempty"`
+}
+
+// Properties are MQTT 5 PUBLISH properties.
+type Properties struct {
+	ContentType     string `json:"contentType,omitempty"`
+	ResponseTopic   string `json:"responseTopic,omitempty"`
+	CorrelationData string `json:"correlationData,omitempty"`
+
+	// UserProperties maps names to values.  For a name that's
+	// repeated in a received message, the last value wins.
+	UserProperties map[string]string `json:"userProperties,omitempty"`
 }
 
 func (c *MQTT) DocSpec() *dsl.DocSpec {
@@ -230,6 +277,23 @@ type MQTTOpts struct {
 	// messages when connecting but not reconnecting if
 	// CleanSession is false.
 	ResumeSubs bool `json:",omitempty" yaml:",omitempty"`
+
+	// ProtocolVersion is 3 (MQTT 3.1), 4 (MQTT 3.1.1), or 5 (MQTT
+	// 5).  The default tries 4 and then 3.
+	//
+	// With 5, only "tcp", "mqtt", "ssl", "tls", and "mqtts"
+	// BrokerURLs are supported (not WebSockets), and AutoReconnect
+	// and ResumeSubs don't apply.
+	ProtocolVersion int `json:",omitempty" yaml:",omitempty"`
+
+	// Envelope makes each published payload a Message (with
+	// optional Properties) and delivers each received message as
+	// a Message.
+	Envelope bool `json:",omitempty" yaml:",omitempty"`
+
+	// SubQoS is the maximum QoS of the messages that a 'sub'
+	// receives.  The default is 1.
+	SubQoS *int `json:",omitempty" yaml:",omitempty"`
 }
 
 // dur converts a int64 representing milliseconds to a time.Duration.
@@ -253,6 +317,14 @@ func (o *MQTTOpts) Opts(ctx *dsl.Ctx) (*mq.ClientOptions, error) {
 	opts.AutoReconnect = o.AutoReconnect
 	opts.CleanSession = o.CleanSession
 
+	switch o.ProtocolVersion {
+	case 0, 5:
+	case 3, 4:
+		opts.ProtocolVersion = uint(o.ProtocolVersion)
+	default:
+		return nil, dsl.Brokenf("bad MQTT ProtocolVersion %d (want 3, 4, or 5)", o.ProtocolVersion)
+	}
+
 	ctx.Logf("MQTT ClientID: %v", opts.ClientID)
 	ctx.Logf("MQTT CleanSession: %v", opts.CleanSession)
 	ctx.Logf("MQTT AutoReconnect: %v", opts.AutoReconnect)
@@ -345,12 +417,16 @@ func (c *MQTT) Kind() dsl.ChanKind {
 }
 
 func (c *MQTT) Open(ctx *dsl.Ctx) error {
-	if c.client != nil {
+	if c.client != nil || c.conn != nil {
 		c.Close(ctx)
 	}
 
 	ctx.Logf("MQTT %s opening", c.mopts.ClientID)
 
+	if c.opts.ProtocolVersion == 5 {
+		return c.open5(ctx)
+	}
+
 	c.client = mq.NewClient(c.mopts)
 
 	// The c.mopts.ConnectTimeout doesn't work when trying AWS IoT
@@ -386,27 +462,139 @@ func (c *MQTT) Open(ctx *dsl.Ctx) error {
 	}
 }
 
+// open5 opens the MQTT 5 connection.
+func (c *MQTT) open5(ctx *dsl.Ctx) error {
+	var (
+		err  error
+		conn *conn5
+		con  = make(chan struct{})
+	)
+
+	go func() {
+		conn, err = dial5(ctx, c.mopts, func(pub *paho.Publish) {
+			c.receive(ctx, pub.Topic, pub.Payload, pub.QoS, pub.Retain, properties(pub.Properties))
+		}, func(err error) {
+			ctx.Logf("MQTT %s connection lost: %v", c.opts.ClientID, err)
+		})
+		close(con)
+	}()
+
+	select {
+	case <-ctx.Done():
+		go func() {
+			<-con
+			if conn != nil {
+				conn.disconnect()
+			}
+		}()
+		return fmt.Errorf("interrupted")
+	case <-con:
+		c.conn = conn
+		return err
+	}
+}
+
 func (c *MQTT) Close(ctx *dsl.Ctx) error {
 	ctx.Logf("MQTT %s closing", c.opts.ClientID)
+	if c.conn != nil {
+		c.conn.disconnect()
+		c.conn = nil
+		return nil
+	}
 	c.client.Disconnect(1000)
 	return nil
 }
 
+// Ping connects to the broker and then disconnects.
+func (c *MQTT) Ping(ctx *dsl.Ctx) error {
+	if err := c.Open(ctx); err != nil {
+		return err
+	}
+	if c.conn != nil {
+		c.conn.disconnect()
+		c.conn = nil
+		return nil
+	}
+	c.client.Disconnect(0)
+	return nil
+}
+
 func (c *MQTT) Sub(ctx *dsl.Ctx, topic string) error {
-	t := c.client.Subscribe(topic, 1, nil)
+	if c.conn != nil {
+		acked, err := c.conn.subscribe(ctx, topic, c.subQoS(), dur(c.opts.SubTimeout))
+		if !acked && err == nil {
+			ctx.Warnf("Warning: MQTT wait timeout on Sub: %s", topic)
+		}
+		return err
+	}
+
+	t := c.client.Subscribe(topic, c.subQoS(), nil)
 	if ok := t.WaitTimeout(dur(c.opts.SubTimeout)); !ok {
 		ctx.Warnf("Warning: MQTT wait timeout on Sub: %s", topic)
 	}
 	return t.Error()
 }
 
+// subQoS returns the SubQoS (or its default).
+func (c *MQTT) subQoS() byte {
+	if c.opts.SubQoS == nil {
+		return 1
+	}
+	return byte(*c.opts.SubQoS)
+}
+
 func (c *MQTT) Pub(ctx *dsl.Ctx, m dsl.Msg) error {
 	ctx.Logf("MQTT %s Pub %s", c.opts.ClientID, m.Topic)
+
+	var (
+		props    *Properties
+		qos      = byte(1)
+		retained bool
+	)
+	if c.opts.Envelope {
+		var msg Message
+		if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+			return dsl.Brokenf("with Envelope, the MQTT payload should be a JSON Message: %v", err)
+		}
+		if s, is := msg.Payload.(string); is {
+			m.Payload = s
+		} else {
+			m.Payload = dsl.JSON(msg.Payload)
+		}
+		props = msg.Properties
+		retained = msg.Retained
+		if msg.QoS != nil {
+			if *msg.QoS < 0 || 2 < *msg.QoS {
+				return dsl.Brokenf("bad MQTT QoS %d", *msg.QoS)
+			}
+			qos = byte(*msg.QoS)
+		}
+	}
+
 	js, err := dsl.MaybeSerialize(m.Payload)
 	if err != nil {
 		return nil
 	}
-	t := c.client.Publish(m.Topic, 1, false, js)
+
+	if c.conn != nil {
+		acked, err := c.conn.publish(ctx, &paho.Publish{
+			Topic:      m.Topic,
+			QoS:        qos,
+			Retain:     retained,
+			Properties: props.publishProperties(),
+			Payload:    []byte(js),
+		}, dur(c.opts.PubTimeout))
+		if !acked && err == nil {
+			ctx.Warnf("Warning: MQTT wait timeout on Pub: %s", m.Topic)
+		}
+		return err
+	}
+
+	if props != nil {
+		return dsl.Brokenf("MQTT properties need ProtocolVersion 5")
+	}
+
+	t := c.client.Publish(m.Topic, qos, retained, js)
 	t.WaitTimeout(dur(c.opts.PubTimeout))
 
 	return t.Error()
@@ -416,10 +604,16 @@ func (c *MQTT) Recv(ctx *dsl.Ctx) chan dsl.Msg {
 	return c.c
 }
 
-// Kill is not currently supported.  (It should be but the paho client does not support ungraceful termination of the connection.)
-//
-// ToDo: Terminate the subprocess ungracefully.
+// Kill is only supported with ProtocolVersion 5, which closes the
+// connection without a DISCONNECT (so the broker publishes the will).
+// (The paho client does not support ungraceful termination of the
+// connection.)
 func (c *MQTT) Kill(ctx *dsl.Ctx) error {
+	if c.conn != nil {
+		c.conn.kill()
+		c.conn = nil
+		return nil
+	}
 	return fmt.Errorf("MQTT Channel %s: Kill is not yet supported", c.opts.ClientID)
 }
 
@@ -461,6 +655,10 @@ func NewMQTTChan(ctx *dsl.Ctx, opts interface{}) (dsl.Chan, error) {
 		o.ConnectTimeout = 1000 // ms
 	}
 
+	if o.SubQoS != nil && (*o.SubQoS < 0 || 2 < *o.SubQoS) {
+		return nil, dsl.Brokenf("bad MQTT SubQoS %d", *o.SubQoS)
+	}
+
 	mopts, err := o.Opts(ctx)
 	if err != nil {
 		return nil, err
@@ -485,20 +683,38 @@ func NewMQTTChan(ctx *dsl.Ctx, opts interface{}) (dsl.Chan, error) {
 	// reconnected client with a persistent session.)
 
 	mopts.DefaultPublishHandler = func(_ mq.Client, m mq.Message) {
-		ctx.Logf("MQTT %s receiving %s", o.ClientID, m.Topic())
-		ctx.Logdf("     %s", m.Payload())
-
-		msg := dsl.Msg{
-			Topic:   m.Topic(),
-			Payload: string(m.Payload()),
-		}
-		go func() {
-			if err := c.To(ctx, msg); err != nil {
-				ctx.Warnf("warning: %s To for %s from MQTT.Sub handler", err, js)
-			}
-		}()
+		go c.receive(ctx, m.Topic(), m.Payload(), m.Qos(), m.Retained(), nil)
 	}
 
 	return c, nil
 
 }
+
+// receive forwards a message from the broker for the test to receive.
+func (c *MQTT) receive(ctx *dsl.Ctx, topic string, payload []byte, qos byte, retained bool, props *Properties) {
+	ctx.Logf("MQTT %s receiving %s", c.opts.ClientID, topic)
+	ctx.Logdf("     %s", payload)
+
+	msg := dsl.Msg{
+		Topic:   topic,
+		Payload: string(payload),
+	}
+
+	if c.opts.Envelope {
+		var x interface{}
+		if err := json.Unmarshal(payload, &x); err != nil {
+			x = string(payload)
+		}
+		q := int(qos)
+		msg.Payload = dsl.JSON(Message{
+			Payload:    x,
+			QoS:        &q,
+			Retained:   retained,
+			Properties: props,
+		})
+	}
+
+	if err := c.To(ctx, msg); err != nil {
+		ctx.Warnf("warning: %s To for %s from MQTT.Sub handler", err, topic)
+	}
+}
@@ -1,7 +1,266 @@
 package mqtt
 
-import "testing"
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+
+	"github.com/eclipse/paho.golang/paho"
+)
 
 func TestDocs(t *testing.T) {
 	(&MQTT{}).DocSpec().Write("mqtt")
 }
+
+func TestProperties(t *testing.T) {
+	p := &Properties{
+		ContentType:     "application/json",
+		ResponseTopic:   "replies",
+		CorrelationData: "42",
+		UserProperties:  map[string]string{"tenant": "tacos", "region": "west"},
+	}
+
+	pp := p.publishProperties()
+	if pp.User.Get("region") != "west" || pp.User[0].Key != "region" {
+		t.Fatal(pp.User)
+	}
+	if got := properties(pp); !reflect.DeepEqual(got, p) {
+		t.Fatal(dsl.JSON(got))
+	}
+
+	if got := properties(&paho.PublishProperties{}); got != nil {
+		t.Fatal(got)
+	}
+}
+
+func TestMQTTErrors(t *testing.T) {
+	ctx := dsl.NewCtx(context.Background())
+
+	if _, err := NewMQTTChan(ctx, &MQTTOpts{ProtocolVersion: 6}); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	c, err := NewMQTTChan(ctx, &MQTTOpts{
+		BrokerURL:       "wss://localhost:1883",
+		ProtocolVersion: 5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Open(ctx); err == nil || !strings.Contains(err.Error(), "isn't supported") {
+		t.Fatal(err)
+	}
+
+	c, err = NewMQTTChan(ctx, &MQTTOpts{
+		BrokerURL: "tcp://localhost:1883",
+		Envelope:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Pub(ctx, dsl.Msg{Topic: "x", Payload: `{"payload":1,"properties":{"contentType":"text/plain"}}`})
+	if _, is := dsl.IsBroken(err); !is {
+		t.Fatal(err)
+	}
+}
+
+func open(t *testing.T, ctx *dsl.Ctx, opts *MQTTOpts) *MQTT {
+	c, err := NewMQTTChan(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return c.(*MQTT)
+}
+
+func recv(t *testing.T, c *MQTT) dsl.Msg {
+	select {
+	case m := <-c.Recv(nil):
+		return m
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout")
+	}
+	return dsl.Msg{}
+}
+
+// TestMQTTServer runs the tests against an MQTT 5 broker at
+// localhost:1883 (like "docker run -p 1883:1883
+// eclipse-mosquitto:1.6").  If there isn't one, the test is skipped.
+func TestMQTTServer(t *testing.T) {
+	var (
+		ctx    = dsl.NewCtx(context.Background())
+		broker = "tcp://localhost:1883"
+		prefix = fmt.Sprintf("plaxtest/%d/", time.Now().UnixNano())
+	)
+
+	c, err := NewMQTTChan(ctx, &MQTTOpts{
+		BrokerURL:       broker,
+		ProtocolVersion: 5,
+		ConnectTimeout:  200,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.(*MQTT).Ping(ctx); err != nil {
+		t.Skipf("skipping MQTT broker test (%s)", err)
+	}
+
+	t.Run("envelope", func(t *testing.T) {
+		c := open(t, ctx, &MQTTOpts{
+			BrokerURL:       broker,
+			ClientID:        "tacos",
+			CleanSession:    true,
+			ProtocolVersion: 5,
+			Envelope:        true,
+			KeepAlive:       1,
+		})
+		defer c.Close(ctx)
+
+		if err := c.Sub(ctx, prefix+"replies/#"); err != nil {
+			t.Fatal(err)
+		}
+
+		pub := `{"payload":{"want":"tacos"},"properties":{"contentType":"application/json","responseTopic":"replies/1","correlationData":"42","userProperties":{"tenant":"tacos","region":"west"}}}`
+		if err := c.Pub(ctx, dsl.Msg{Topic: prefix + "replies/1", Payload: pub}); err != nil {
+			t.Fatal(err)
+		}
+
+		m := recv(t, c)
+		want := `{"payload":{"want":"tacos"},"qos":1,"retained":false,"properties":{"contentType":"application/json","responseTopic":"replies/1","correlationData":"42","userProperties":{"region":"west","tenant":"tacos"}}}`
+		if m.Topic != prefix+"replies/1" || m.Payload != want {
+			t.Fatal(dsl.JSON(m))
+		}
+
+		if err := c.Pub(ctx, dsl.Msg{Topic: prefix + "replies/2", Payload: `{"payload":"not json"}`}); err != nil {
+			t.Fatal(err)
+		}
+		if m := recv(t, c); m.Payload != `{"payload":"not json","qos":1,"retained":false}` {
+			t.Fatal(dsl.JSON(m))
+		}
+
+		// Survive a keep alive.
+		time.Sleep(1200 * time.Millisecond)
+		if err := c.Pub(ctx, dsl.Msg{Topic: prefix + "replies/3", Payload: `{"payload":3}`}); err != nil {
+			t.Fatal(err)
+		}
+		recv(t, c)
+	})
+
+	t.Run("kill", func(t *testing.T) {
+		opts := func(id string) *MQTTOpts {
+			return &MQTTOpts{
+				BrokerURL:       broker,
+				ClientID:        id,
+				CleanSession:    true,
+				ProtocolVersion: 5,
+			}
+		}
+
+		watcher := open(t, ctx, opts("watcher"))
+		defer watcher.Close(ctx)
+		if err := watcher.Sub(ctx, prefix+"gone"); err != nil {
+			t.Fatal(err)
+		}
+
+		o := opts("doomed")
+		o.WillTopic = prefix + "gone"
+		o.WillPayload = "bye"
+		doomed := open(t, ctx, o)
+		if err := doomed.Kill(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		if m := recv(t, watcher); m.Topic != prefix+"gone" || m.Payload != "bye" {
+			t.Fatal(dsl.JSON(m))
+		}
+	})
+
+	for _, version := range []int{4, 5} {
+		opts := func(id string, subQoS *int) *MQTTOpts {
+			return &MQTTOpts{
+				BrokerURL:       broker,
+				ClientID:        id,
+				CleanSession:    true,
+				ProtocolVersion: version,
+				Envelope:        true,
+				SubQoS:          subQoS,
+			}
+		}
+
+		t.Run("retained-"+strconv.Itoa(version), func(t *testing.T) {
+			topic := prefix + "lights/" + strconv.Itoa(version)
+
+			early := open(t, ctx, opts("early", nil))
+			defer early.Close(ctx)
+			if err := early.Sub(ctx, topic); err != nil {
+				t.Fatal(err)
+			}
+			if err := early.Pub(ctx, dsl.Msg{Topic: topic, Payload: `{"payload":"on","retained":true}`}); err != nil {
+				t.Fatal(err)
+			}
+			// An empty retained message clears the
+			// retained one.
+			defer early.Pub(ctx, dsl.Msg{Topic: topic, Payload: `{"payload":"","retained":true}`})
+			if m := recv(t, early); m.Payload != `{"payload":"on","qos":1,"retained":false}` {
+				t.Fatal(dsl.JSON(m))
+			}
+
+			late := open(t, ctx, opts("late", nil))
+			defer late.Close(ctx)
+			if err := late.Sub(ctx, topic); err != nil {
+				t.Fatal(err)
+			}
+			if m := recv(t, late); m.Topic != topic || m.Payload != `{"payload":"on","qos":1,"retained":true}` {
+				t.Fatal(dsl.JSON(m))
+			}
+		})
+
+		t.Run("qos-"+strconv.Itoa(version), func(t *testing.T) {
+			var (
+				topic   = prefix + "q/" + strconv.Itoa(version)
+				two     = 2
+				c       = open(t, ctx, opts("two", &two))
+				capped  = open(t, ctx, opts("capped", nil))
+				publish = func(qos int) {
+					if err := c.Pub(ctx, dsl.Msg{Topic: topic, Payload: fmt.Sprintf(`{"payload":%d,"qos":%d}`, qos, qos)}); err != nil {
+						t.Fatal(err)
+					}
+				}
+			)
+			defer c.Close(ctx)
+			defer capped.Close(ctx)
+
+			for _, sub := range []*MQTT{c, capped} {
+				if err := sub.Sub(ctx, topic); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, qos := range []int{0, 1, 2} {
+				publish(qos)
+				want := fmt.Sprintf(`{"payload":%d,"qos":%d,"retained":false}`, qos, qos)
+				if m := recv(t, c); m.Payload != want {
+					t.Fatalf("%s rather than %s", m.Payload, want)
+				}
+				if qos == 2 {
+					want = `{"payload":2,"qos":1,"retained":false}`
+				}
+				if m := recv(t, capped); m.Payload != want {
+					t.Fatalf("%s rather than %s", m.Payload, want)
+				}
+			}
+
+			if err := c.Pub(ctx, dsl.Msg{Topic: topic, Payload: `{"payload":3,"qos":3}`}); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
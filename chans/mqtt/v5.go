@@ -0,0 +1,281 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/eclipse/paho.golang/packets"
+	"github.com/eclipse/paho.golang/paho"
+	mq "github.com/eclipse/paho.mqtt.golang"
+)
+
+// conn5 is an MQTT 5 connection to a broker.
+//
+// The paho.mqtt.golang client only speaks MQTT 3.1 and 3.1.1, so
+// this paho.golang client is used instead when the ProtocolVersion
+// is 5.
+type conn5 struct {
+	client *paho.Client
+	nc     net.Conn
+}
+
+// dial5 connects to the broker that the (paho.mqtt.golang) options
+// describe.  The handler gets each PUBLISH, and lost gets the
+// reason that the connection was lost.
+//
+// Only "tcp", "mqtt", "ssl", "tls", "mqtts", and "tcps" broker URLs
+// are supported.
+func dial5(ctx context.Context, mopts *mq.ClientOptions, handler func(*paho.Publish), lost func(error)) (*conn5, error) {
+	if len(mopts.Servers) == 0 {
+		return nil, errors.New("no BrokerURL")
+	}
+	u := mopts.Servers[0]
+
+	var secure bool
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts", "tcps":
+		secure = true
+	default:
+		return nil, fmt.Errorf("BrokerURL scheme %q isn't supported with ProtocolVersion 5", u.Scheme)
+	}
+
+	if 0 < mopts.ConnectTimeout {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, mopts.ConnectTimeout)
+		defer cancel()
+	}
+
+	var (
+		d    = net.Dialer{}
+		addr = hostPort(u, secure)
+		nc   net.Conn
+		err  error
+	)
+	if secure {
+		conf := mopts.TLSConfig
+		if conf == nil {
+			conf = &tls.Config{}
+		}
+		d := tls.Dialer{NetDialer: &d, Config: conf}
+		nc, err = d.DialContext(ctx, "tcp", addr)
+	} else {
+		nc, err = d.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	nc = packets.NewThreadSafeConn(nc)
+
+	c := &conn5{
+		nc: nc,
+		client: paho.NewClient(paho.ClientConfig{
+			ClientID: mopts.ClientID,
+			Conn:     nc,
+			Router:   paho.NewSingleHandlerRouter(handler),
+			PingHandler: &pinger{
+				PingHandler: paho.DefaultPingerWithCustomFailHandler(func(err error) {
+					// Closing the connection stops the
+					// client, which reports the error.
+					nc.Close()
+				}),
+				stop: make(chan struct{}),
+			},
+			OnClientError: lost,
+			OnServerDisconnect: func(d *paho.Disconnect) {
+				lost(fmt.Errorf("disconnected by the broker: %s", (&packets.Disconnect{ReasonCode: d.ReasonCode}).Reason()))
+			},
+		}),
+	}
+
+	cp := &paho.Connect{
+		ClientID:     mopts.ClientID,
+		KeepAlive:    uint16(mopts.KeepAlive),
+		CleanStart:   mopts.CleanSession,
+		Username:     mopts.Username,
+		UsernameFlag: mopts.Username != "",
+		Password:     []byte(mopts.Password),
+		PasswordFlag: mopts.Password != "",
+	}
+	if !mopts.CleanSession {
+		// Like an MQTT 3.1.1 persistent session, the session
+		// doesn't end when the connection does.
+		forever := uint32(0xFFFFFFFF)
+		cp.Properties = &paho.ConnectProperties{
+			SessionExpiryInterval: &forever,
+			RequestProblemInfo:    true,
+		}
+	}
+	if mopts.WillEnabled {
+		cp.WillMessage = &paho.WillMessage{
+			Topic:   mopts.WillTopic,
+			Payload: mopts.WillPayload,
+			QoS:     mopts.WillQos,
+			Retain:  mopts.WillRetained,
+		}
+	}
+
+	ack, err := c.client.Connect(ctx, cp)
+	if err != nil {
+		if ack != nil {
+			return nil, fmt.Errorf("connection refused: %s", (&packets.Connack{ReasonCode: ack.ReasonCode}).Reason())
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// hostPort returns the address of the broker with the default port
+// (if the URL doesn't have one).
+func hostPort(u *url.URL, secure bool) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	if secure {
+		return net.JoinHostPort(u.Hostname(), "8883")
+	}
+	return net.JoinHostPort(u.Hostname(), "1883")
+}
+
+// pinger is the paho.golang default Pinger, which can't handle a
+// connection without a keep alive.
+type pinger struct {
+	*paho.PingHandler
+	stop chan struct{}
+	once sync.Once
+}
+
+func (p *pinger) Start(c net.Conn, keepAlive time.Duration) {
+	if 0 < keepAlive {
+		p.PingHandler.Start(c, keepAlive)
+		return
+	}
+	<-p.stop
+}
+
+func (p *pinger) Stop() {
+	p.once.Do(func() { close(p.stop) })
+	p.PingHandler.Stop()
+}
+
+// publish sends a PUBLISH and, for QoS 1 or 2, waits (at most the
+// timeout) for its acknowledgements.  It reports whether the (last)
+// acknowledgement arrived.
+func (c *conn5) publish(ctx context.Context, pub *paho.Publish, timeout time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resp, err := c.client.Publish(ctx, pub)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return false, nil
+	}
+	if err == nil && resp != nil && 0x80 <= resp.ReasonCode {
+		// A PUBREC with a failure isn't an error for
+		// paho.golang.
+		err = fmt.Errorf("publication refused: %s", (&packets.Pubrec{ReasonCode: resp.ReasonCode}).Reason())
+	}
+	return true, err
+}
+
+// subscribe sends a SUBSCRIBE (with the maximum QoS) and waits (at
+// most the timeout) for its SUBACK.  It reports whether the SUBACK
+// arrived.
+func (c *conn5) subscribe(ctx context.Context, topic string, qos byte, timeout time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ack, err := c.client.Subscribe(ctx, &paho.Subscribe{
+		Subscriptions: []paho.SubscribeOptions{
+			{Topic: topic, QoS: qos},
+		},
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return false, nil
+	}
+	if err != nil && ack != nil && len(ack.Reasons) == 1 {
+		err = fmt.Errorf("subscription refused: %s", (&packets.Suback{Reasons: ack.Reasons}).Reason(0))
+	}
+	return true, err
+}
+
+// disconnect sends DISCONNECT (with a normal reason, so the broker
+// doesn't publish the will) and closes the connection.
+func (c *conn5) disconnect() {
+	c.client.Disconnect(&paho.Disconnect{})
+}
+
+// kill closes the connection without a DISCONNECT, so that the
+// broker publishes the will (if any).
+func (c *conn5) kill() {
+	c.nc.Close()
+}
+
+// publishProperties returns the paho.golang PUBLISH properties.
+func (p *Properties) publishProperties() *paho.PublishProperties {
+	if p == nil {
+		return nil
+	}
+	pp := &paho.PublishProperties{
+		ContentType:   p.ContentType,
+		ResponseTopic: p.ResponseTopic,
+	}
+	if p.CorrelationData != "" {
+		pp.CorrelationData = []byte(p.CorrelationData)
+	}
+	names := make([]string, 0, len(p.UserProperties))
+	for name := range p.UserProperties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pp.User.Add(name, p.UserProperties[name])
+	}
+	return pp
+}
+
+// properties returns the Properties (or nil if there aren't any).
+func properties(pp *paho.PublishProperties) *Properties {
+	if pp == nil {
+		return nil
+	}
+	if pp.ContentType == "" && pp.ResponseTopic == "" && pp.CorrelationData == nil && len(pp.User) == 0 {
+		return nil
+	}
+	p := &Properties{
+		ContentType:     pp.ContentType,
+		ResponseTopic:   pp.ResponseTopic,
+		CorrelationData: string(pp.CorrelationData),
+	}
+	for _, kv := range pp.User {
+		if p.UserProperties == nil {
+			p.UserProperties = make(map[string]string, len(pp.User))
+		}
+		p.UserProperties[kv.Key] = kv.Value
+	}
+	return p
+}
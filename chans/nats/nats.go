@@ -0,0 +1,521 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package nats
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+
+	"github.com/nats-io/nats.go"
+)
+
+var (
+	// DefaultNATSBufferSize is the default capacity of the
+	// internal Go channel.
+	DefaultNATSBufferSize = dsl.DefaultChanBufferSize
+
+	// DefaultNATSPort is the port for a server URL without one.
+	DefaultNATSPort = "4222"
+)
+
+func init() {
+	dsl.TheChanRegistry.Register(dsl.NewCtx(nil), "nats", NewNATSChan)
+}
+
+// NATS is a NATS client Chan.
+//
+// This channel type uses the NATS Go client
+// (https://github.com/nats-io/nats.go) to talk to a server.  The
+// topic of a message published to this channel is the NATS subject.
+// A 'sub' to a subject (which can have wildcards) forwards each
+// message received on that subject for the test to receive, and the
+// topic of that message is the message's subject.
+//
+// When the options give a Queue, subscriptions join that queue
+// group.  When the options give a Stream and a Durable, a 'sub'
+// instead creates (or resumes) a JetStream durable push consumer
+// for the subject, and each message from the consumer is acknowledged
+// after it's forwarded.
+//
+// The client doesn't reconnect, so a connection that's lost (or
+// killed) stays closed until the next 'open'.
+type NATS struct {
+	opts *NATSOpts
+	c    chan dsl.Msg
+
+	// lock protects the fields below.
+	lock sync.Mutex
+	nc   *nats.Conn
+
+	// conn is the network connection under nc, which Kill
+	// closes.
+	conn net.Conn
+}
+
+func (c *NATS) DocSpec() *dsl.DocSpec {
+	return &dsl.DocSpec{
+		Chan: &NATS{},
+		Opts: &NATSOpts{},
+	}
+}
+
+// NATSOpts configures a NATS channel.
+type NATSOpts struct {
+	// Servers are the URLs (like "nats://localhost:4222") of the
+	// servers to try (in order).  A URL can include a user and
+	// password.  The scheme "tls" requires TLS.
+	Servers []string `json:",omitempty" yaml:",omitempty"`
+
+	// Name is the optional client name that the server reports.
+	Name string `json:",omitempty" yaml:",omitempty"`
+
+	// User is the optional user for authentication.
+	User string `json:",omitempty" yaml:",omitempty"`
+
+	// Password is the optional password for authentication.
+	Password string `json:",omitempty" yaml:",omitempty"`
+
+	// Token is the optional token for authentication.
+	Token string `json:",omitempty" yaml:",omitempty"`
+
+	// CredsFile is the optional filename of the user credentials
+	// (a JWT and an NKey seed) for authentication.
+	CredsFile string `json:",omitempty" yaml:",omitempty"`
+
+	// TLS requires TLS for the connection (which the server can
+	// also require).
+	TLS bool `json:",omitempty" yaml:",omitempty"`
+
+	// CertFile is the optional filename for the client's
+	// certificate.
+	CertFile string `json:",omitempty" yaml:",omitempty"`
+
+	// KeyFile is the optional filename for the client's private
+	// key.
+	KeyFile string `json:",omitempty" yaml:",omitempty"`
+
+	// CACertFile is the optional filename for the certificate
+	// authority.
+	CACertFile string `json:",omitempty" yaml:",omitempty"`
+
+	// Insecure skips verifying the server's certificate.  This
+	// should be used only for testing.
+	Insecure bool `json:",omitempty" yaml:",omitempty"`
+
+	// Queue is the optional queue group for subscriptions.
+	Queue string `json:",omitempty" yaml:",omitempty"`
+
+	// Stream is the JetStream stream for durable consumers.
+	//
+	// See Durable.
+	Stream string `json:",omitempty" yaml:",omitempty"`
+
+	// Durable is the name of the JetStream durable consumer that
+	// a 'sub' creates (or resumes).  With a Queue, the consumer
+	// delivers to that queue group.
+	//
+	// See Stream.
+	Durable string `json:",omitempty" yaml:",omitempty"`
+
+	// ConnectTimeout is the timeout in milliseconds for
+	// connecting to a server.  The default is 1000.
+	ConnectTimeout int64 `json:",omitempty" yaml:",omitempty"`
+
+	// RequestTimeout is the timeout in milliseconds for
+	// JetStream API requests.  The default is 2000.
+	RequestTimeout int64 `json:",omitempty" yaml:",omitempty"`
+
+	// BufferSize specifies the capacity of the internal Go
+	// channel.
+	//
+	// The default is DefaultNATSBufferSize.
+	BufferSize int `json:",omitempty" yaml:",omitempty"`
+}
+
+// dur converts a int64 representing milliseconds to a time.Duration.
+func dur(ms int64) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
+
+func NewNATSChan(ctx *dsl.Ctx, opts interface{}) (dsl.Chan, error) {
+	o := NATSOpts{}
+
+	js, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(js, &o); err != nil {
+		return nil, fmt.Errorf("NewNATSChan: %w", err)
+	}
+
+	if len(o.Servers) == 0 {
+		return nil, dsl.Brokenf("nats channel needs Servers")
+	}
+
+	if (o.Stream == "") != (o.Durable == "") {
+		return nil, dsl.Brokenf("nats channel needs both Stream and Durable (or neither)")
+	}
+
+	if o.ConnectTimeout == 0 {
+		o.ConnectTimeout = 1000 // ms
+	}
+
+	if o.RequestTimeout == 0 {
+		o.RequestTimeout = 2000 // ms
+	}
+
+	bufSize := o.BufferSize
+	if bufSize == 0 {
+		bufSize = DefaultNATSBufferSize
+	}
+
+	return &NATS{
+		opts: &o,
+		c:    make(chan dsl.Msg, bufSize),
+	}, nil
+}
+
+func (c *NATS) Kind() dsl.ChanKind {
+	return "nats"
+}
+
+// servers returns the Servers as URLs for the client, which requires
+// a scheme, and whether any of them requires TLS.
+func (c *NATS) servers() ([]string, bool, error) {
+	var (
+		urls   = make([]string, 0, len(c.opts.Servers))
+		secure bool
+	)
+	for _, s := range c.opts.Servers {
+		if !strings.Contains(s, "://") {
+			s = "nats://" + s
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, false, dsl.Brokenf("bad NATS server URL '%s': %v", s, err)
+		}
+		if u.Port() == "" {
+			u.Host = net.JoinHostPort(u.Hostname(), DefaultNATSPort)
+		}
+		if u.Scheme == "tls" {
+			secure = true
+		}
+		urls = append(urls, u.String())
+	}
+	return urls, secure, nil
+}
+
+// dialer remembers the last connection that it dialed.
+type dialer struct {
+	net.Dialer
+
+	lock sync.Mutex
+	conn net.Conn
+}
+
+func (d *dialer) Dial(network, address string) (net.Conn, error) {
+	conn, err := d.Dialer.Dial(network, address)
+	if err == nil {
+		d.lock.Lock()
+		d.conn = conn
+		d.lock.Unlock()
+	}
+	return conn, err
+}
+
+// connect tries each server in order.
+func (c *NATS) connect(ctx *dsl.Ctx) (*nats.Conn, *dialer, error) {
+	urls, secure, err := c.servers()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d := &dialer{
+		Dialer: net.Dialer{
+			Timeout: dur(c.opts.ConnectTimeout),
+		},
+	}
+
+	opts := []nats.Option{
+		nats.Name(c.opts.Name),
+		nats.Timeout(dur(c.opts.ConnectTimeout)),
+		nats.DontRandomize(),
+		nats.NoReconnect(),
+		nats.SetCustomDialer(d),
+		nats.ErrorHandler(func(_ *nats.Conn, sub *nats.Subscription, err error) {
+			if sub != nil {
+				ctx.Warnf("NATS %s: %v", sub.Subject, err)
+				return
+			}
+			ctx.Warnf("NATS: %v", err)
+		}),
+	}
+	if c.opts.User != "" || c.opts.Password != "" {
+		opts = append(opts, nats.UserInfo(c.opts.User, c.opts.Password))
+	}
+	if c.opts.Token != "" {
+		opts = append(opts, nats.Token(c.opts.Token))
+	}
+	if c.opts.CredsFile != "" {
+		opts = append(opts, nats.UserCredentials(c.opts.CredsFile))
+	}
+	conf, err := c.tlsConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	if secure || c.opts.TLS {
+		opts = append(opts, nats.Secure(conf))
+	} else {
+		// For a server that requires TLS.
+		opts = append(opts, func(o *nats.Options) error {
+			o.TLSConfig = conf
+			return nil
+		})
+	}
+
+	nc, err := nats.Connect(strings.Join(urls, ","), opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	return nc, d, nil
+}
+
+func (c *NATS) tlsConfig() (*tls.Config, error) {
+	conf := &tls.Config{
+		InsecureSkipVerify: c.opts.Insecure,
+	}
+
+	if c.opts.CACertFile != "" {
+		certs, err := ioutil.ReadFile(c.opts.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read '%s': %s", c.opts.CACertFile, err)
+		}
+		rootCAs := x509.NewCertPool()
+		if ok := rootCAs.AppendCertsFromPEM(certs); !ok {
+			return nil, fmt.Errorf("no certs appended from '%s'", c.opts.CACertFile)
+		}
+		conf.RootCAs = rootCAs
+	}
+
+	if c.opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.opts.CertFile, c.opts.KeyFile)
+		if err != nil {
+			return nil, dsl.NewBroken(err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	return conf, nil
+}
+
+func (c *NATS) Open(ctx *dsl.Ctx) error {
+	if c.nc != nil {
+		c.Close(ctx)
+	}
+
+	ctx.Logf("NATS opening %s", strings.Join(c.opts.Servers, ","))
+
+	nc, d, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	d.lock.Lock()
+	conn := d.conn
+	d.lock.Unlock()
+
+	c.lock.Lock()
+	c.nc = nc
+	c.conn = conn
+	c.lock.Unlock()
+
+	return nil
+}
+
+// client returns the client when the channel is open.
+func (c *NATS) client() (*nats.Conn, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.nc == nil || c.nc.IsClosed() {
+		return nil, fmt.Errorf("nats channel isn't open")
+	}
+	return c.nc, nil
+}
+
+// handler returns the MsgHandler that forwards messages for the test
+// to receive (and acknowledges them if ack is true).
+func (c *NATS) handler(ctx *dsl.Ctx, ack bool) nats.MsgHandler {
+	return func(msg *nats.Msg) {
+		if err := c.To(ctx, dsl.Msg{Topic: msg.Subject, Payload: string(msg.Data)}); err != nil {
+			ctx.Warnf("warning: %s To for NATS %s", err, msg.Subject)
+		}
+		if ack {
+			if err := msg.Ack(); err != nil {
+				ctx.Warnf("NATS ack for %s: %v", msg.Subject, err)
+			}
+		}
+	}
+}
+
+// durable creates (or resumes) the JetStream durable push consumer
+// for the subject, and it subscribes to it.
+func (c *NATS) durable(ctx *dsl.Ctx, nc *nats.Conn, subject string) error {
+	js, err := nc.JetStream(nats.MaxWait(dur(c.opts.RequestTimeout)))
+	if err != nil {
+		return err
+	}
+
+	opts := []nats.SubOpt{
+		nats.BindStream(c.opts.Stream),
+		nats.Durable(c.opts.Durable),
+		nats.AckExplicit(),
+		nats.ManualAck(),
+	}
+
+	if c.opts.Queue != "" {
+		_, err = js.QueueSubscribe(subject, c.opts.Queue, c.handler(ctx, true), opts...)
+	} else {
+		_, err = js.Subscribe(subject, c.handler(ctx, true), opts...)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create JetStream consumer %s: %w", c.opts.Durable, err)
+	}
+
+	return nil
+}
+
+func (c *NATS) Sub(ctx *dsl.Ctx, topic string) error {
+	ctx.Logf("NATS Sub %s", topic)
+
+	nc, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	if c.opts.Durable != "" {
+		return c.durable(ctx, nc, topic)
+	}
+
+	if c.opts.Queue != "" {
+		_, err = nc.QueueSubscribe(topic, c.opts.Queue, c.handler(ctx, false))
+	} else {
+		_, err = nc.Subscribe(topic, c.handler(ctx, false))
+	}
+	if err != nil {
+		return err
+	}
+
+	// Make sure the server has the subscription before a
+	// subsequent 'pub'.
+	return nc.Flush()
+}
+
+func (c *NATS) Pub(ctx *dsl.Ctx, m dsl.Msg) error {
+	ctx.Logf("NATS Pub %s", m.Topic)
+
+	if m.Topic == "" {
+		return fmt.Errorf("nats pub needs a topic (the subject)")
+	}
+
+	nc, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	js, err := dsl.MaybeSerialize(m.Payload)
+	if err != nil {
+		return err
+	}
+
+	if err := nc.Publish(m.Topic, []byte(js)); err != nil {
+		return err
+	}
+
+	return nc.Flush()
+}
+
+func (c *NATS) Close(ctx *dsl.Ctx) error {
+	ctx.Logf("NATS closing")
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.nc == nil {
+		return nil
+	}
+	if !c.nc.IsClosed() {
+		c.nc.Flush()
+	}
+	c.nc.Close()
+	c.nc = nil
+	c.conn = nil
+	return nil
+}
+
+// Ping connects to a server and then disconnects.
+func (c *NATS) Ping(ctx *dsl.Ctx) error {
+	nc, _, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+	nc.Close()
+	return nil
+}
+
+func (c *NATS) Recv(ctx *dsl.Ctx) chan dsl.Msg {
+	return c.c
+}
+
+// Kill closes the connection without flushing anything.
+func (c *NATS) Kill(ctx *dsl.Ctx) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.nc == nil || c.conn == nil {
+		return fmt.Errorf("nats channel isn't open")
+	}
+	err := c.conn.Close()
+	c.nc.Close()
+	c.nc = nil
+	c.conn = nil
+	return err
+}
+
+func (c *NATS) To(ctx *dsl.Ctx, m dsl.Msg) error {
+	ctx.Logf("NATS To %s", m.Topic)
+	ctx.Logdf("     %s", m.Payload)
+	m.ReceivedAt = time.Now().UTC()
+	select {
+	case <-ctx.Done():
+	case c.c <- m:
+	default:
+		panic("Warning: NATS channel full")
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package nats
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+
+	"github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+)
+
+func TestDocs(t *testing.T) {
+	(&NATS{}).DocSpec().Write("nats")
+}
+
+// runServer starts a nats-server (on a random port) for the test.
+func runServer(t *testing.T, opts *server.Options) *server.Server {
+	opts.Host = "127.0.0.1"
+	opts.Port = -1
+	opts.NoLog = true
+	opts.NoSigs = true
+
+	s, err := server.NewServer(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start()
+	t.Cleanup(s.Shutdown)
+
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats-server isn't ready")
+	}
+
+	return s
+}
+
+func recv(t *testing.T, ctx *dsl.Ctx, c dsl.Chan) dsl.Msg {
+	select {
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout")
+	case m := <-c.Recv(ctx):
+		return m
+	}
+	panic("unreachable")
+}
+
+func open(t *testing.T, ctx *dsl.Ctx, opts *NATSOpts) dsl.Chan {
+	c, err := NewNATSChan(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close(ctx) })
+	return c
+}
+
+func TestNATS(t *testing.T) {
+	var (
+		ctx = dsl.NewCtx(context.Background())
+		s   = runServer(t, &server.Options{
+			Username: "plax",
+			Password: "tacos",
+		})
+		addr = strings.TrimPrefix(s.ClientURL(), "nats://")
+		c    = open(t, ctx, &NATSOpts{
+			Servers: []string{"nats://plax:tacos@" + addr},
+			Name:    "plax",
+			Queue:   "workers",
+		})
+	)
+
+	if err := c.Sub(ctx, "orders.*"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Pub(ctx, dsl.Msg{Topic: "orders.new", Payload: `{"want":"tacos"}`}); err != nil {
+		t.Fatal(err)
+	}
+	if m := recv(t, ctx, c); m.Topic != "orders.new" || m.Payload != `{"want":"tacos"}` {
+		t.Fatal(dsl.JSON(m))
+	}
+
+	connz, err := s.Connz(&server.ConnzOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(connz.Conns) != 1 || connz.Conns[0].Name != "plax" {
+		t.Fatal(dsl.JSON(connz.Conns))
+	}
+
+	subsz, err := s.Subsz(&server.SubszOptions{Subscriptions: true, Test: "orders.new"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subsz.Subs) != 1 || subsz.Subs[0].Queue != "workers" {
+		t.Fatal(dsl.JSON(subsz.Subs))
+	}
+
+	// The wrong password.
+	bad, err := NewNATSChan(ctx, &NATSOpts{
+		Servers: []string{"nats://plax:queso@" + addr},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bad.Open(ctx); err == nil || !strings.Contains(err.Error(), "Authorization Violation") {
+		t.Fatal(err)
+	}
+}
+
+func TestNATSKill(t *testing.T) {
+	var (
+		ctx = dsl.NewCtx(context.Background())
+		s   = runServer(t, &server.Options{})
+		c   = open(t, ctx, &NATSOpts{
+			Servers: []string{s.ClientURL()},
+		})
+	)
+
+	if err := c.Kill(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Pub(ctx, dsl.Msg{Topic: "orders.new", Payload: "chips"}); err == nil {
+		t.Fatal("expected an error after the kill")
+	}
+
+	// The channel opens again.
+	if err := c.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Pub(ctx, dsl.Msg{Topic: "orders.new", Payload: "chips"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNATSJetStream(t *testing.T) {
+	var (
+		ctx = dsl.NewCtx(context.Background())
+		s   = runServer(t, &server.Options{
+			JetStream: true,
+			StoreDir:  t.TempDir(),
+		})
+	)
+
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := js.AddStream(&nats.StreamConfig{Name: "ORDERS", Subjects: []string{"orders.>"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := open(t, ctx, &NATSOpts{
+		Servers: []string{s.ClientURL()},
+		Stream:  "ORDERS",
+		Durable: "plax",
+	})
+
+	if err := c.Sub(ctx, "orders.>"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Pub(ctx, dsl.Msg{Topic: "orders.new", Payload: "chips"}); err != nil {
+		t.Fatal(err)
+	}
+	if m := recv(t, ctx, c); m.Topic != "orders.new" || m.Payload != "chips" {
+		t.Fatal(dsl.JSON(m))
+	}
+
+	// Wait for the ack.
+	for i := 0; i < 100; i++ {
+		info, err := js.ConsumerInfo("ORDERS", "plax")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.AckFloor.Consumer == 1 && info.NumAckPending == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("no ack")
+}
+
+func TestNATSCreds(t *testing.T) {
+	// An operator with an account that has a user.
+	okp, _ := nkeys.CreateOperator()
+	opub, _ := okp.PublicKey()
+	oc := jwt.NewOperatorClaims(opub)
+
+	akp, _ := nkeys.CreateAccount()
+	apub, _ := akp.PublicKey()
+	ajwt, err := jwt.NewAccountClaims(apub).Encode(okp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ukp, _ := nkeys.CreateUser()
+	upub, _ := ukp.PublicKey()
+	uc := jwt.NewUserClaims(upub)
+	uc.IssuerAccount = apub
+	ujwt, err := uc.Encode(akp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	useed, _ := ukp.Seed()
+	creds, err := jwt.FormatUserConfig(ujwt, useed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "plax.creds")
+	if err := ioutil.WriteFile(filename, creds, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := &server.MemAccResolver{}
+	if err := resolver.Store(apub, ajwt); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		ctx = dsl.NewCtx(context.Background())
+		s   = runServer(t, &server.Options{
+			TrustedOperators: []*jwt.OperatorClaims{oc},
+			AccountResolver:  resolver,
+		})
+		c = open(t, ctx, &NATSOpts{
+			Servers:   []string{s.ClientURL()},
+			CredsFile: filename,
+		})
+	)
+
+	if err := c.Sub(ctx, "orders"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Pub(ctx, dsl.Msg{Topic: "orders", Payload: "queso"}); err != nil {
+		t.Fatal(err)
+	}
+	if m := recv(t, ctx, c); m.Payload != "queso" {
+		t.Fatal(dsl.JSON(m))
+	}
+
+	// Without the credentials.
+	anon, err := NewNATSChan(ctx, &NATSOpts{
+		Servers: []string{s.ClientURL()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := anon.Open(ctx); err == nil || !strings.Contains(err.Error(), "Authorization Violation") {
+		t.Fatal(err)
+	}
+}
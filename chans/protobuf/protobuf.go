@@ -0,0 +1,413 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package protobuf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+var (
+	// DefaultProtobufBufferSize is the default capacity of the
+	// internal Go channel.
+	DefaultProtobufBufferSize = dsl.DefaultChanBufferSize
+)
+
+func init() {
+	dsl.TheChanRegistry.Register(dsl.NewCtx(nil), "protobuf", NewProtobufChan)
+}
+
+// Protobuf is a Chan that wraps another Chan whose payloads are
+// protobuf-encoded.
+//
+// The payload of a message published to this channel is the JSON
+// representation of a protobuf message, which is encoded before the
+// underlying channel publishes it.  Each message that the underlying
+// channel receives is decoded into JSON (with the same topic) for
+// the test to receive.  A payload that can't be decoded is received
+// as a DecodeError.
+//
+// The message descriptions come from .proto files, a descriptor set
+// file (see 'protoc --descriptor_set_out --include_imports'), or,
+// when neither is given, the message types registered in the plax
+// executable (like "google.protobuf.Struct").
+type Protobuf struct {
+	opts  *ProtobufOpts
+	inner dsl.Chan
+	c     chan dsl.Msg
+
+	// files are the descriptions from ProtoFiles and
+	// DescriptorSetFile (if any).
+	files []*desc.FileDescriptor
+
+	// types caches message descriptors by name.
+	types map[string]*desc.MessageDescriptor
+
+	sync.Mutex
+	done chan bool
+}
+
+func (c *Protobuf) DocSpec() *dsl.DocSpec {
+	return &dsl.DocSpec{
+		Chan:   &Protobuf{},
+		Opts:   &ProtobufOpts{},
+		Output: &DecodeError{},
+	}
+}
+
+// ProtobufOpts configures a Protobuf channel.
+type ProtobufOpts struct {
+	// Chan is the type of the underlying channel (like "mqtt").
+	Chan dsl.ChanKind `json:",omitempty" yaml:",omitempty"`
+
+	// Config is the configuration for the underlying channel.
+	Config interface{} `json:",omitempty" yaml:",omitempty"`
+
+	// ProtoFiles are the optional filenames of .proto files that
+	// describe the messages.
+	ProtoFiles []string `json:",omitempty" yaml:",omitempty"`
+
+	// ImportPaths are the optional directories for finding
+	// ProtoFiles and their imports.
+	ImportPaths []string `json:",omitempty" yaml:",omitempty"`
+
+	// DescriptorSetFile is the optional filename of a serialized
+	// FileDescriptorSet that describes the messages.
+	DescriptorSetFile string `json:",omitempty" yaml:",omitempty"`
+
+	// MessageType is the full name (like "acme.Order") of the
+	// message type for topics that aren't in Topics.
+	MessageType string `json:",omitempty" yaml:",omitempty"`
+
+	// Topics optionally maps topics to message types, which
+	// override MessageType.
+	Topics map[string]string `json:",omitempty" yaml:",omitempty"`
+
+	// OrigName makes the JSON of received messages use the field
+	// names from the .proto files (rather than lowerCamelCase
+	// names).
+	OrigName bool `json:",omitempty" yaml:",omitempty"`
+
+	// BufferSize specifies the capacity of the internal Go
+	// channel.
+	//
+	// The default is DefaultProtobufBufferSize.
+	BufferSize int `json:",omitempty" yaml:",omitempty"`
+}
+
+// DecodeError is what a test receives for a payload that couldn't
+// be decoded.
+type DecodeError struct {
+	// Error describes the problem.
+	Error string `json:"error"`
+
+	// Type is the message type (if known).
+	Type string `json:"type,omitempty"`
+
+	// Raw is the (base64-encoded) payload.
+	Raw []byte `json:"raw"`
+}
+
+func NewProtobufChan(ctx *dsl.Ctx, opts interface{}) (dsl.Chan, error) {
+	o := ProtobufOpts{}
+
+	js, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(js, &o); err != nil {
+		return nil, fmt.Errorf("NewProtobufChan: %w", err)
+	}
+
+	if o.Chan == "" {
+		return nil, dsl.Brokenf("protobuf channel needs a Chan")
+	}
+	if o.MessageType == "" && len(o.Topics) == 0 {
+		return nil, dsl.Brokenf("protobuf channel needs a MessageType or Topics")
+	}
+
+	maker, have := dsl.TheChanRegistry[o.Chan]
+	if !have {
+		return nil, dsl.Brokenf("unknown Chan kind: '%s'", o.Chan)
+	}
+
+	bufSize := o.BufferSize
+	if bufSize == 0 {
+		bufSize = DefaultProtobufBufferSize
+	}
+
+	c := &Protobuf{
+		opts:  &o,
+		c:     make(chan dsl.Msg, bufSize),
+		types: make(map[string]*desc.MessageDescriptor),
+	}
+
+	if 0 < len(o.ProtoFiles) {
+		p := protoparse.Parser{
+			ImportPaths: o.ImportPaths,
+		}
+		files, err := p.ParseFiles(o.ProtoFiles...)
+		if err != nil {
+			return nil, dsl.Brokenf("bad proto files: %v", err)
+		}
+		c.files = append(c.files, files...)
+	}
+
+	if o.DescriptorSetFile != "" {
+		files, err := readDescriptorSet(o.DescriptorSetFile)
+		if err != nil {
+			return nil, err
+		}
+		c.files = append(c.files, files...)
+	}
+
+	for _, name := range o.messageTypes() {
+		if _, err := c.messageType(name); err != nil {
+			return nil, dsl.NewBroken(err)
+		}
+	}
+
+	if c.inner, err = maker(ctx, o.Config); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// messageTypes returns all of the message type names.
+func (o *ProtobufOpts) messageTypes() []string {
+	acc := make([]string, 0, len(o.Topics)+1)
+	if o.MessageType != "" {
+		acc = append(acc, o.MessageType)
+	}
+	for _, name := range o.Topics {
+		acc = append(acc, name)
+	}
+	return acc
+}
+
+// readDescriptorSet reads the serialized FileDescriptorSet.
+func readDescriptorSet(filename string) ([]*desc.FileDescriptor, error) {
+	bs, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, dsl.NewBroken(err)
+	}
+	var fds dpb.FileDescriptorSet
+	if err := proto.Unmarshal(bs, &fds); err != nil {
+		return nil, dsl.Brokenf("bad descriptor set '%s': %v", filename, err)
+	}
+	files, err := desc.CreateFileDescriptorsFromSet(&fds)
+	if err != nil {
+		return nil, dsl.Brokenf("bad descriptor set '%s': %v", filename, err)
+	}
+	acc := make([]*desc.FileDescriptor, 0, len(files))
+	for _, fd := range files {
+		acc = append(acc, fd)
+	}
+	return acc, nil
+}
+
+// messageType finds the descriptor for the named message type.
+func (c *Protobuf) messageType(name string) (*desc.MessageDescriptor, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	name = strings.TrimPrefix(name, ".")
+	if md, have := c.types[name]; have {
+		return md, nil
+	}
+
+	var md *desc.MessageDescriptor
+	for _, fd := range c.files {
+		if md = fd.FindMessage(name); md != nil {
+			break
+		}
+	}
+	if md == nil && len(c.files) == 0 {
+		md, _ = desc.LoadMessageDescriptor(name)
+	}
+	if md == nil {
+		return nil, fmt.Errorf("unknown message type '%s'", name)
+	}
+
+	c.types[name] = md
+	return md, nil
+}
+
+// topicType returns the descriptor for the topic's message type.
+func (c *Protobuf) topicType(topic string) (*desc.MessageDescriptor, error) {
+	name, have := c.opts.Topics[topic]
+	if !have {
+		name = c.opts.MessageType
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no message type for topic '%s'", topic)
+	}
+	return c.messageType(name)
+}
+
+func (c *Protobuf) Kind() dsl.ChanKind {
+	return "protobuf"
+}
+
+func (c *Protobuf) Open(ctx *dsl.Ctx) error {
+	if err := c.inner.Open(ctx); err != nil {
+		return err
+	}
+
+	c.Lock()
+	c.done = make(chan bool)
+	done := c.done
+	c.Unlock()
+
+	go c.decode(ctx, c.inner.Recv(ctx), done)
+
+	return nil
+}
+
+// decode forwards the decoded messages from the underlying channel.
+func (c *Protobuf) decode(ctx *dsl.Ctx, in chan dsl.Msg, done chan bool) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case m := <-in:
+			ctx.Logf("Protobuf decoding %d bytes from '%s'", len(m.Payload), m.Topic)
+			m.Payload = c.decodePayload(m.Topic, m.Payload)
+			if err := c.To(ctx, m); err != nil {
+				ctx.Warnf("warning: %s To for %s", err, m.Topic)
+			}
+		}
+	}
+}
+
+// decodePayload returns the JSON for the encoded payload (or for a
+// DecodeError).
+func (c *Protobuf) decodePayload(topic string, payload string) string {
+	failed := func(err error, typ string) string {
+		return dsl.JSON(&DecodeError{
+			Error: err.Error(),
+			Type:  typ,
+			Raw:   []byte(payload),
+		})
+	}
+
+	md, err := c.topicType(topic)
+	if err != nil {
+		return failed(err, "")
+	}
+
+	msg := dynamic.NewMessage(md)
+	if err := msg.Unmarshal([]byte(payload)); err != nil {
+		return failed(fmt.Errorf("bad %s: %w", md.GetFullyQualifiedName(), err), md.GetFullyQualifiedName())
+	}
+
+	js, err := msg.MarshalJSONPB(&jsonpb.Marshaler{
+		OrigName: c.opts.OrigName,
+	})
+	if err != nil {
+		return failed(err, md.GetFullyQualifiedName())
+	}
+
+	return string(js)
+}
+
+func (c *Protobuf) Close(ctx *dsl.Ctx) error {
+	c.Lock()
+	if c.done != nil {
+		close(c.done)
+		c.done = nil
+	}
+	c.Unlock()
+
+	return c.inner.Close(ctx)
+}
+
+func (c *Protobuf) Sub(ctx *dsl.Ctx, topic string) error {
+	return c.inner.Sub(ctx, topic)
+}
+
+// Pub encodes the (JSON) payload as a message of the topic's type
+// and publishes it on the underlying channel.
+func (c *Protobuf) Pub(ctx *dsl.Ctx, m dsl.Msg) error {
+	md, err := c.topicType(m.Topic)
+	if err != nil {
+		return dsl.NewBroken(err)
+	}
+
+	msg := dynamic.NewMessage(md)
+	if strings.TrimSpace(m.Payload) != "" {
+		if err := msg.UnmarshalJSONPB(&jsonpb.Unmarshaler{}, []byte(m.Payload)); err != nil {
+			return fmt.Errorf("bad %s for '%s': %w", md.GetFullyQualifiedName(), m.Topic, err)
+		}
+	}
+
+	bs, err := msg.Marshal()
+	if err != nil {
+		return err
+	}
+
+	ctx.Logf("Protobuf encoded %d bytes for '%s'", len(bs), m.Topic)
+	m.Payload = string(bs)
+
+	return c.inner.Pub(ctx, m)
+}
+
+func (c *Protobuf) Recv(ctx *dsl.Ctx) chan dsl.Msg {
+	return c.c
+}
+
+func (c *Protobuf) Kill(ctx *dsl.Ctx) error {
+	return c.inner.Kill(ctx)
+}
+
+// Ping pings the underlying channel (if it can).
+func (c *Protobuf) Ping(ctx *dsl.Ctx) error {
+	if p, is := c.inner.(dsl.Pinger); is {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *Protobuf) To(ctx *dsl.Ctx, m dsl.Msg) error {
+	m.ReceivedAt = time.Now().UTC()
+	select {
+	case <-ctx.Done():
+	case c.c <- m:
+	default:
+		panic("Warning: Protobuf channel full")
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package protobuf
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+)
+
+func TestDocs(t *testing.T) {
+	(&Protobuf{}).DocSpec().Write("protobuf")
+}
+
+const orderProto = `syntax = "proto3";
+
+package acme;
+
+message Order {
+  int32 id = 1;
+  string status = 2;
+  repeated Item items = 3;
+}
+
+message Item {
+  string sku = 1;
+}
+`
+
+func open(t *testing.T, ctx *dsl.Ctx, opts *ProtobufOpts) dsl.Chan {
+	c, err := NewProtobufChan(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close(ctx) })
+	return c
+}
+
+func recv(t *testing.T, ctx *dsl.Ctx, c dsl.Chan) dsl.Msg {
+	select {
+	case m := <-c.Recv(ctx):
+		return m
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	return dsl.Msg{}
+}
+
+func TestProtobuf(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "order.proto"), []byte(orderProto), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := dsl.NewCtx(nil)
+
+	c := open(t, ctx, &ProtobufOpts{
+		Chan:        "mock",
+		ProtoFiles:  []string{"order.proto"},
+		ImportPaths: []string{dir},
+		MessageType: "acme.Order",
+		Topics: map[string]string{
+			"items": "acme.Item",
+		},
+	})
+
+	inner := c.(*Protobuf).inner
+
+	t.Run("roundtrip", func(t *testing.T) {
+		if err := c.Pub(ctx, dsl.Msg{
+			Topic:   "orders",
+			Payload: `{"id":7,"status":"shipped","items":[{"sku":"taco"}]}`,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		m := recv(t, ctx, c)
+		if m.Topic != "orders" || m.Payload != `{"id":7,"status":"shipped","items":[{"sku":"taco"}]}` {
+			t.Fatal(m)
+		}
+	})
+
+	t.Run("topic", func(t *testing.T) {
+		if err := c.Pub(ctx, dsl.Msg{
+			Topic:   "items",
+			Payload: `{"sku":"queso"}`,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if m := recv(t, ctx, c); m.Payload != `{"sku":"queso"}` {
+			t.Fatal(m.Payload)
+		}
+	})
+
+	t.Run("bad", func(t *testing.T) {
+		if err := inner.Pub(ctx, dsl.Msg{
+			Topic:   "orders",
+			Payload: "\xff\xff",
+		}); err != nil {
+			t.Fatal(err)
+		}
+		m := recv(t, ctx, c)
+		var e DecodeError
+		if err := json.Unmarshal([]byte(m.Payload), &e); err != nil {
+			t.Fatal(err)
+		}
+		if e.Type != "acme.Order" || string(e.Raw) != "\xff\xff" || !strings.Contains(e.Error, "bad acme.Order") {
+			t.Fatal(m.Payload)
+		}
+	})
+
+	t.Run("badpub", func(t *testing.T) {
+		err := c.Pub(ctx, dsl.Msg{
+			Topic:   "orders",
+			Payload: `{"nope":1}`,
+		})
+		if err == nil || !strings.Contains(err.Error(), "bad acme.Order for 'orders'") {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestProtobufRegistered(t *testing.T) {
+	ctx := dsl.NewCtx(nil)
+
+	c := open(t, ctx, &ProtobufOpts{
+		Chan:        "mock",
+		MessageType: "google.protobuf.Duration",
+	})
+
+	if err := c.Pub(ctx, dsl.Msg{Payload: `"1.5s"`}); err != nil {
+		t.Fatal(err)
+	}
+	if m := recv(t, ctx, c); m.Payload != `"1.500s"` {
+		t.Fatal(m.Payload)
+	}
+}
+
+func TestProtobufBroken(t *testing.T) {
+	ctx := dsl.NewCtx(nil)
+
+	for name, opts := range map[string]*ProtobufOpts{
+		"nochan":     {MessageType: "acme.Order"},
+		"notype":     {Chan: "mock"},
+		"badchan":    {Chan: "nope", MessageType: "acme.Order"},
+		"unknown":    {Chan: "mock", MessageType: "acme.Nope"},
+		"badproto":   {Chan: "mock", MessageType: "acme.Order", ProtoFiles: []string{"nope.proto"}},
+		"baddescset": {Chan: "mock", MessageType: "acme.Order", DescriptorSetFile: "nope.fds"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewProtobufChan(ctx, opts)
+			if _, is := dsl.IsBroken(err); !is {
+				t.Fatal(err)
+			}
+		})
+	}
+}
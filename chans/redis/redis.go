@@ -0,0 +1,436 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package redis
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	// DefaultRedisBufferSize is the default capacity of the
+	// internal Go channel.
+	DefaultRedisBufferSize = dsl.DefaultChanBufferSize
+)
+
+func init() {
+	dsl.TheChanRegistry.Register(dsl.NewCtx(nil), "redis", NewRedisChan)
+}
+
+// Redis is a Redis pub/sub (or streams) client Chan.
+//
+// This channel type uses the go-redis client
+// (https://github.com/go-redis/redis).  By default, the topic of a
+// message published to this channel is the Redis channel for a
+// PUBLISH, and a 'sub' SUBSCRIBEs to a Redis channel (or PSUBSCRIBEs
+// when the topic has a '*', '?', or '[').  The test receives each
+// message with the Redis channel as its topic.
+//
+// With Streams, the topic is a stream key instead.  A publish XADDs
+// an entry whose fields come from the (JSON object) payload (or a
+// single field "payload" for any other payload), and a 'sub' XREADs
+// the stream.  The test receives each entry with the stream key as
+// its topic and a payload like {"id":"1-0","fields":{"want":"tacos"}}.
+//
+// Close closes all of the connections that the channel opened.
+type Redis struct {
+	opts *RedisOpts
+	c    chan dsl.Msg
+
+	// lock protects the fields below.
+	lock sync.Mutex
+
+	client *redis.Client
+
+	// subs are the (P)SUBSCRIBEs.
+	subs []*redis.PubSub
+
+	// readers is the number of XREADs, which Close waits for.
+	readers sync.WaitGroup
+}
+
+func (c *Redis) DocSpec() *dsl.DocSpec {
+	return &dsl.DocSpec{
+		Chan: &Redis{},
+		Opts: &RedisOpts{},
+	}
+}
+
+// RedisOpts configures a Redis channel.
+type RedisOpts struct {
+	// Addr is the server address (like "localhost:6379").
+	Addr string `json:",omitempty" yaml:",omitempty"`
+
+	// Username is the optional user for AUTH.
+	Username string `json:",omitempty" yaml:",omitempty"`
+
+	// Password is the optional password for AUTH.
+	Password string `json:",omitempty" yaml:",omitempty"`
+
+	// DB is the optional database number to SELECT.
+	DB int `json:",omitempty" yaml:",omitempty"`
+
+	// TLS turns on TLS for the connections.
+	TLS bool `json:",omitempty" yaml:",omitempty"`
+
+	// Insecure skips verifying the server's certificate.  This
+	// should be used only for testing.
+	Insecure bool `json:",omitempty" yaml:",omitempty"`
+
+	// Streams makes topics stream keys (see above).
+	Streams bool `json:",omitempty" yaml:",omitempty"`
+
+	// StreamStart is the ID after which a 'sub' reads a stream.
+	// The default "$" reads only the entries added after the
+	// 'sub', and "0" reads the entire stream.
+	StreamStart string `json:",omitempty" yaml:",omitempty"`
+
+	// ConnectTimeout is the timeout in milliseconds for
+	// connecting to the server.  The default is 1000.
+	ConnectTimeout int64 `json:",omitempty" yaml:",omitempty"`
+
+	// BufferSize specifies the capacity of the internal Go
+	// channel.
+	//
+	// The default is DefaultRedisBufferSize.
+	BufferSize int `json:",omitempty" yaml:",omitempty"`
+}
+
+func NewRedisChan(ctx *dsl.Ctx, opts interface{}) (dsl.Chan, error) {
+	o := RedisOpts{}
+
+	js, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(js, &o); err != nil {
+		return nil, fmt.Errorf("NewRedisChan: %w", err)
+	}
+
+	if o.Addr == "" {
+		return nil, dsl.Brokenf("redis channel needs an Addr")
+	}
+
+	if o.StreamStart == "" {
+		o.StreamStart = "$"
+	}
+
+	if o.ConnectTimeout == 0 {
+		o.ConnectTimeout = 1000 // ms
+	}
+
+	bufSize := o.BufferSize
+	if bufSize == 0 {
+		bufSize = DefaultRedisBufferSize
+	}
+
+	return &Redis{
+		opts: &o,
+		c:    make(chan dsl.Msg, bufSize),
+	}, nil
+}
+
+func (c *Redis) Kind() dsl.ChanKind {
+	return "redis"
+}
+
+// timeout returns the ConnectTimeout.
+func (c *Redis) timeout() time.Duration {
+	return time.Duration(c.opts.ConnectTimeout) * time.Millisecond
+}
+
+// newClient makes a client, which connects (and AUTHs and SELECTs
+// the DB) when it needs to.
+func (c *Redis) newClient() *redis.Client {
+	o := &redis.Options{
+		Addr:        c.opts.Addr,
+		Username:    c.opts.Username,
+		Password:    c.opts.Password,
+		DB:          c.opts.DB,
+		DialTimeout: c.timeout(),
+		MaxRetries:  -1,
+	}
+	if c.opts.TLS {
+		host, _, _ := net.SplitHostPort(c.opts.Addr)
+		o.TLSConfig = &tls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: c.opts.Insecure,
+		}
+	}
+	return redis.NewClient(o)
+}
+
+// Open makes the client and checks that it can connect.
+func (c *Redis) Open(ctx *dsl.Ctx) error {
+	c.Close(ctx)
+
+	ctx.Logf("Redis opening %s", c.opts.Addr)
+
+	client := c.newClient()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return fmt.Errorf("failed to connect to %s: %w", c.opts.Addr, err)
+	}
+
+	c.lock.Lock()
+	c.client = client
+	c.lock.Unlock()
+
+	return nil
+}
+
+// Close closes all of the connections.
+func (c *Redis) Close(ctx *dsl.Ctx) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.client == nil {
+		return nil
+	}
+
+	ctx.Logf("Redis closing %s", c.opts.Addr)
+
+	for _, sub := range c.subs {
+		sub.Close()
+	}
+	c.subs = nil
+
+	// Closing the client ends the XREADs.
+	err := c.client.Close()
+	c.readers.Wait()
+	c.client = nil
+
+	return err
+}
+
+// Ping connects to the server, PINGs, and disconnects.
+func (c *Redis) Ping(ctx *dsl.Ctx) error {
+	client := c.newClient()
+	defer client.Close()
+	return client.Ping(ctx).Err()
+}
+
+// open returns the client when the channel is open.
+func (c *Redis) open() (*redis.Client, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.client == nil {
+		return nil, fmt.Errorf("redis channel to %s isn't open", c.opts.Addr)
+	}
+	return c.client, nil
+}
+
+func (c *Redis) Sub(ctx *dsl.Ctx, topic string) error {
+	ctx.Logf("Redis Sub %s", topic)
+
+	client, err := c.open()
+	if err != nil {
+		return err
+	}
+
+	if c.opts.Streams {
+		return c.xread(ctx, client, topic)
+	}
+
+	var sub *redis.PubSub
+	if strings.ContainsAny(topic, "*?[") {
+		sub = client.PSubscribe(ctx, topic)
+	} else {
+		sub = client.Subscribe(ctx, topic)
+	}
+
+	// Wait for the confirmation so that a subsequent publish
+	// can't beat the subscription.
+	if _, err := sub.ReceiveTimeout(ctx, c.timeout()); err != nil {
+		sub.Close()
+		return fmt.Errorf("no confirmation of the subscription to %s: %w", topic, err)
+	}
+
+	c.lock.Lock()
+	c.subs = append(c.subs, sub)
+	c.lock.Unlock()
+
+	go func() {
+		for m := range sub.Channel() {
+			c.forward(ctx, m.Channel, m.Payload)
+		}
+	}()
+
+	return nil
+}
+
+// streamEntry is the payload for a stream entry.
+type streamEntry struct {
+	ID     string            `json:"id"`
+	Fields map[string]string `json:"fields"`
+}
+
+// lastID returns the ID of the last entry of the stream (or "0-0").
+func lastID(ctx *dsl.Ctx, client *redis.Client, key string) (string, error) {
+	entries, err := client.XRevRangeN(ctx, key, "+", "-", 1).Result()
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "0-0", nil
+	}
+	return entries[0].ID, nil
+}
+
+// xreadBlock is how long an XREAD blocks.  An XREAD that blocks
+// indefinitely can keep its connection open on the server after
+// Close.
+var xreadBlock = 100 * time.Millisecond
+
+// xread starts reading the stream.
+func (c *Redis) xread(ctx *dsl.Ctx, client *redis.Client, key string) error {
+	id := c.opts.StreamStart
+	if id == "$" {
+		// Resolve "$" now so that a subsequent XADD can't
+		// beat the first XREAD.
+		var err error
+		if id, err = lastID(ctx, client, key); err != nil {
+			return err
+		}
+	}
+
+	c.readers.Add(1)
+	go func() {
+		defer c.readers.Done()
+		for {
+			streams, err := client.XRead(ctx, &redis.XReadArgs{
+				Streams: []string{key, id},
+				Block:   xreadBlock,
+			}).Result()
+			if err == redis.Nil {
+				continue
+			}
+			if err == redis.ErrClosed {
+				return
+			}
+			if err != nil {
+				ctx.Logf("Redis %s XREAD %s: %v", c.opts.Addr, key, err)
+				return
+			}
+			for _, s := range streams {
+				for _, e := range s.Messages {
+					entry := streamEntry{
+						ID:     e.ID,
+						Fields: make(map[string]string, len(e.Values)),
+					}
+					for f, v := range e.Values {
+						entry.Fields[f] = fmt.Sprint(v)
+					}
+					id = entry.ID
+					c.forward(ctx, key, dsl.JSON(&entry))
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// fields returns the XADD field-value pairs for the payload.
+func fields(payload string) []string {
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(payload), &m); err != nil || m == nil {
+		return []string{"payload", payload}
+	}
+	fs := make([]string, 0, len(m))
+	for f := range m {
+		fs = append(fs, f)
+	}
+	sort.Strings(fs)
+	fvs := make([]string, 0, 2*len(m))
+	for _, f := range fs {
+		v := m[f]
+		s, is := v.(string)
+		if !is {
+			s = dsl.JSON(v)
+		}
+		fvs = append(fvs, f, s)
+	}
+	return fvs
+}
+
+func (c *Redis) Pub(ctx *dsl.Ctx, m dsl.Msg) error {
+	ctx.Logf("Redis Pub %s", m.Topic)
+
+	if m.Topic == "" {
+		return fmt.Errorf("redis pub needs a topic")
+	}
+
+	client, err := c.open()
+	if err != nil {
+		return err
+	}
+
+	payload, err := dsl.MaybeSerialize(m.Payload)
+	if err != nil {
+		return err
+	}
+
+	if c.opts.Streams {
+		return client.XAdd(ctx, &redis.XAddArgs{
+			Stream: m.Topic,
+			Values: fields(payload),
+		}).Err()
+	}
+
+	return client.Publish(ctx, m.Topic, payload).Err()
+}
+
+func (c *Redis) forward(ctx *dsl.Ctx, topic, payload string) {
+	if err := c.To(ctx, dsl.Msg{Topic: topic, Payload: payload}); err != nil {
+		ctx.Warnf("warning: %s To for Redis %s", err, topic)
+	}
+}
+
+func (c *Redis) Recv(ctx *dsl.Ctx) chan dsl.Msg {
+	return c.c
+}
+
+func (c *Redis) Kill(ctx *dsl.Ctx) error {
+	return fmt.Errorf("%T doesn't support 'Kill'", c)
+}
+
+func (c *Redis) To(ctx *dsl.Ctx, m dsl.Msg) error {
+	ctx.Logf("Redis To %s", m.Topic)
+	ctx.Logdf("     %s", m.Payload)
+	m.ReceivedAt = time.Now().UTC()
+	select {
+	case <-ctx.Done():
+	case c.c <- m:
+	default:
+		panic("Warning: Redis channel full")
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestDocs(t *testing.T) {
+	(&Redis{}).DocSpec().Write("redis")
+}
+
+func recv(t *testing.T, ctx *dsl.Ctx, c dsl.Chan) dsl.Msg {
+	select {
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout")
+	case m := <-c.Recv(ctx):
+		return m
+	}
+	panic("unreachable")
+}
+
+func open(t *testing.T, ctx *dsl.Ctx, opts *RedisOpts) dsl.Chan {
+	c, err := NewRedisChan(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// waitClosed checks that the server eventually has no open
+// connections.
+func waitClosed(t *testing.T, s *miniredis.Miniredis) {
+	for i := 0; i < 100; i++ {
+		if s.CurrentConnectionCount() == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%d connections still open", s.CurrentConnectionCount())
+}
+
+// testPubSub publishes to a pattern subscription.
+func testPubSub(t *testing.T, ctx *dsl.Ctx, opts *RedisOpts, topic string) dsl.Chan {
+	c := open(t, ctx, opts)
+
+	if err := c.Sub(ctx, topic+".*"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Pub(ctx, dsl.Msg{Topic: topic + ".new", Payload: `{"want":"tacos"}`}); err != nil {
+		t.Fatal(err)
+	}
+	if m := recv(t, ctx, c); m.Topic != topic+".new" || m.Payload != `{"want":"tacos"}` {
+		t.Fatal(dsl.JSON(m))
+	}
+
+	if err := c.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+// testStreams adds entries to a stream before and after a 'sub'.
+func testStreams(t *testing.T, ctx *dsl.Ctx, opts *RedisOpts, key string) {
+	opts.Streams = true
+	c := open(t, ctx, opts)
+
+	// An entry before the 'sub' isn't received.
+	if err := c.Pub(ctx, dsl.Msg{Topic: key, Payload: "old"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Sub(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Pub(ctx, dsl.Msg{Topic: key, Payload: `{"want":"tacos","n":2}`}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := recv(t, ctx, c)
+	var e streamEntry
+	if err := json.Unmarshal([]byte(m.Payload), &e); err != nil {
+		t.Fatal(err)
+	}
+	if m.Topic != key || e.ID == "" || e.Fields["want"] != "tacos" || e.Fields["n"] != "2" {
+		t.Fatal(dsl.JSON(m))
+	}
+
+	// Close ends the XREAD.
+	if err := c.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRedisPubSub(t *testing.T) {
+	var (
+		ctx = dsl.NewCtx(context.Background())
+		s   = miniredis.RunT(t)
+	)
+	s.RequireAuth("tacos")
+
+	testPubSub(t, ctx, &RedisOpts{
+		Addr:     s.Addr(),
+		Password: "tacos",
+		DB:       2,
+	}, "orders")
+	waitClosed(t, s)
+
+	// Bad password
+	c, err := NewRedisChan(ctx, &RedisOpts{
+		Addr:     s.Addr(),
+		Password: "chips",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Open(ctx); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Fatal(err)
+	}
+}
+
+func TestRedisStreams(t *testing.T) {
+	var (
+		ctx = dsl.NewCtx(context.Background())
+		s   = miniredis.RunT(t)
+	)
+
+	testStreams(t, ctx, &RedisOpts{
+		Addr: s.Addr(),
+	}, "orders")
+	waitClosed(t, s)
+}
+
+// TestRedisServer runs the tests against a Redis server at
+// localhost:6379 (like "docker run -p 6379:6379 redis").  If there
+// isn't one, the test is skipped.
+func TestRedisServer(t *testing.T) {
+	var (
+		ctx  = dsl.NewCtx(context.Background())
+		opts = RedisOpts{
+			Addr:           "localhost:6379",
+			ConnectTimeout: 200,
+		}
+		key = fmt.Sprintf("plaxtest-%d", time.Now().UnixNano())
+	)
+
+	c, err := NewRedisChan(ctx, &opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.(*Redis).Ping(ctx); err != nil {
+		t.Skipf("skipping Redis server test (%s)", err)
+	}
+
+	t.Run("pubsub", func(t *testing.T) {
+		o := opts
+		testPubSub(t, ctx, &o, key)
+	})
+
+	t.Run("streams", func(t *testing.T) {
+		o := opts
+		testStreams(t, ctx, &o, key)
+	})
+}
@@ -0,0 +1,415 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package shell
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+)
+
+var (
+	// DefaultExecBufferSize is the default capacity of the
+	// internal Go channel.
+	DefaultExecBufferSize = dsl.DefaultChanBufferSize
+
+	// ExecOutputGrace is how long we keep reading a command's
+	// output after the command exits (in case a background
+	// process still holds its stdout or stderr).
+	ExecOutputGrace = 100 * time.Millisecond
+)
+
+func init() {
+	dsl.TheChanRegistry.Register(dsl.NewCtx(nil), "exec", NewExecChan)
+}
+
+// ExecChan is a channel that runs a command for each message
+// published to it.
+//
+// The payload of a published message is either a string, which is
+// run with the Shell, or an ExecRequest like
+//
+//	{"command":"grep","args":["-c","tacos"],"stdin":"tacos\n",
+//	 "dir":"/tmp","env":{"LANG":"C"},"timeout":5000}
+//
+// where the command is run directly when there are args (and with
+// the Shell otherwise), and the other properties are optional.  The
+// dir, env, and timeout override the channel's options.
+//
+// When the command finishes, the test receives a message with the
+// published message's topic and a payload like
+//
+//	{"stdout":"tacos","stderr":"","exitCode":0}
+//
+// where stdout and stderr don't include their trailing newlines.  A
+// command that exceeds its timeout is killed, and then the result
+// has "timedOut":true and an exitCode of -1.  A command that can't
+// start at all has an exitCode of -1 and an "error".
+//
+// Commands run in the background, so several can run at once.
+// Close and Kill kill the commands that are still running.
+type ExecChan struct {
+	opts *ExecOpts
+	c    chan dsl.Msg
+
+	// lock protects the fields below.
+	lock sync.Mutex
+
+	// cancels kill the running commands.
+	cancels map[int]context.CancelFunc
+
+	// next is the key for the next command's cancel.
+	next int
+}
+
+func (c *ExecChan) DocSpec() *dsl.DocSpec {
+	return &dsl.DocSpec{
+		Chan: &ExecChan{},
+		Opts: &ExecOpts{},
+	}
+}
+
+// ExecOpts configures an ExecChan.
+type ExecOpts struct {
+	// Shell is the program that runs a command given as a
+	// string (with "-c").  The default is "sh".
+	Shell string `json:",omitempty" yaml:",omitempty"`
+
+	// Dir is the default working directory for the commands.
+	Dir string `json:",omitempty" yaml:",omitempty"`
+
+	// Env gives additional environment variables for the
+	// commands, which also get plax's environment.
+	Env map[string]string `json:",omitempty" yaml:",omitempty"`
+
+	// Timeout is the default timeout in milliseconds for a
+	// command.  The default is no timeout.
+	Timeout int64 `json:",omitempty" yaml:",omitempty"`
+
+	// BufferSize specifies the capacity of the internal Go
+	// channel.
+	//
+	// The default is DefaultExecBufferSize.
+	BufferSize int `json:",omitempty" yaml:",omitempty"`
+}
+
+// ExecRequest is a command to run.
+type ExecRequest struct {
+	// Command is the command, which is run with the Shell
+	// unless there are Args.
+	Command string `json:"command"`
+
+	// Args are the arguments for running the Command directly
+	// (without the Shell).
+	Args []string `json:"args,omitempty"`
+
+	// Stdin is the optional input for the command.
+	Stdin string `json:"stdin,omitempty"`
+
+	// Dir is the working directory, which overrides the
+	// channel's Dir.
+	Dir string `json:"dir,omitempty"`
+
+	// Env gives more environment variables, which override the
+	// channel's.
+	Env map[string]string `json:"env,omitempty"`
+
+	// Timeout is the timeout in milliseconds, which overrides
+	// the channel's Timeout.
+	Timeout int64 `json:"timeout,omitempty"`
+}
+
+// ExecResult is the payload of the message that reports a
+// command's result.
+type ExecResult struct {
+	// Stdout is the command's standard output.
+	Stdout string `json:"stdout"`
+
+	// Stderr is the command's standard error.
+	Stderr string `json:"stderr"`
+
+	// ExitCode is the command's exit code (or -1).
+	ExitCode int `json:"exitCode"`
+
+	// TimedOut reports that the command was killed because it
+	// exceeded its timeout.
+	TimedOut bool `json:"timedOut,omitempty"`
+
+	// Error reports a failure to run the command at all.
+	Error string `json:"error,omitempty"`
+}
+
+// NewExecChan makes a new ExecChan.
+func NewExecChan(ctx *dsl.Ctx, opts interface{}) (dsl.Chan, error) {
+	o := ExecOpts{}
+
+	js, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(js, &o); err != nil {
+		return nil, fmt.Errorf("NewExecChan: %w", err)
+	}
+
+	if o.Shell == "" {
+		o.Shell = "sh"
+	}
+
+	bufSize := o.BufferSize
+	if bufSize == 0 {
+		bufSize = DefaultExecBufferSize
+	}
+
+	return &ExecChan{
+		opts:    &o,
+		c:       make(chan dsl.Msg, bufSize),
+		cancels: make(map[int]context.CancelFunc),
+	}, nil
+}
+
+func (c *ExecChan) Kind() dsl.ChanKind {
+	return "exec"
+}
+
+func (c *ExecChan) Open(ctx *dsl.Ctx) error {
+	return nil
+}
+
+// Close kills the commands that are still running.
+func (c *ExecChan) Close(ctx *dsl.Ctx) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, cancel := range c.cancels {
+		cancel()
+	}
+	return nil
+}
+
+func (c *ExecChan) Sub(ctx *dsl.Ctx, topic string) error {
+	return fmt.Errorf("%T doesn't support 'sub'", c)
+}
+
+// request parses the payload.
+func request(payload string) *ExecRequest {
+	var req ExecRequest
+	if strings.HasPrefix(strings.TrimSpace(payload), "{") {
+		if err := json.Unmarshal([]byte(payload), &req); err == nil && req.Command != "" {
+			return &req
+		}
+	}
+	return &ExecRequest{
+		Command: payload,
+	}
+}
+
+// Pub starts the command given by the payload.
+func (c *ExecChan) Pub(ctx *dsl.Ctx, m dsl.Msg) error {
+	payload, err := dsl.MaybeSerialize(m.Payload)
+	if err != nil {
+		return err
+	}
+
+	req := request(payload)
+
+	ctx.Logf("ExecChan Pub %s", req.Command)
+
+	timeout := c.opts.Timeout
+	if req.Timeout != 0 {
+		timeout = req.Timeout
+	}
+
+	var (
+		cctx   context.Context
+		cancel context.CancelFunc
+	)
+	if 0 < timeout {
+		cctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Millisecond)
+	} else {
+		cctx, cancel = context.WithCancel(ctx)
+	}
+
+	c.lock.Lock()
+	id := c.next
+	c.next++
+	c.cancels[id] = cancel
+	c.lock.Unlock()
+
+	go func() {
+		result := c.run(cctx, req)
+
+		c.lock.Lock()
+		delete(c.cancels, id)
+		c.lock.Unlock()
+		cancel()
+
+		if err := c.To(ctx, dsl.Msg{Topic: m.Topic, Payload: dsl.JSON(result)}); err != nil {
+			ctx.Warnf("warning: %s To for ExecChan", err)
+		}
+	}()
+
+	return nil
+}
+
+// run runs the command and waits for its result.
+func (c *ExecChan) run(ctx context.Context, req *ExecRequest) *ExecResult {
+	var cmd *exec.Cmd
+	if req.Args != nil {
+		cmd = exec.CommandContext(ctx, req.Command, req.Args...)
+	} else {
+		cmd = exec.CommandContext(ctx, c.opts.Shell, "-c", req.Command)
+	}
+
+	cmd.Dir = c.opts.Dir
+	if req.Dir != "" {
+		cmd.Dir = req.Dir
+	}
+
+	env := make(map[string]string)
+	for k, v := range c.opts.Env {
+		env[k] = v
+	}
+	for k, v := range req.Env {
+		env[k] = v
+	}
+	cmd.Env = os.Environ()
+	names := make([]string, 0, len(env))
+	for k := range env {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		cmd.Env = append(cmd.Env, k+"="+env[k])
+	}
+
+	if req.Stdin != "" {
+		cmd.Stdin = strings.NewReader(req.Stdin)
+	}
+
+	fail := func(err error) *ExecResult {
+		return &ExecResult{
+			ExitCode: -1,
+			Error:    err.Error(),
+		}
+	}
+
+	// We use our own pipes (rather than buffers) so that a
+	// background process that holds stdout or stderr can't
+	// prevent us from reporting the result.
+	stdout, stdoutW, err := os.Pipe()
+	if err != nil {
+		return fail(err)
+	}
+	stderr, stderrW, err := os.Pipe()
+	if err != nil {
+		stdout.Close()
+		stdoutW.Close()
+		return fail(err)
+	}
+	cmd.Stdout = stdoutW
+	cmd.Stderr = stderrW
+
+	var (
+		outs = make([]bytes.Buffer, 2)
+		wg   sync.WaitGroup
+	)
+	for i, r := range []*os.File{stdout, stderr} {
+		wg.Add(1)
+		go func(buf *bytes.Buffer, r *os.File) {
+			defer wg.Done()
+			io.Copy(buf, r)
+		}(&outs[i], r)
+	}
+
+	err = cmd.Start()
+	stdoutW.Close()
+	stderrW.Close()
+	if err == nil {
+		err = cmd.Wait()
+	}
+
+	read := make(chan bool)
+	go func() {
+		wg.Wait()
+		close(read)
+	}()
+	select {
+	case <-read:
+	case <-time.After(ExecOutputGrace):
+		stdout.Close()
+		stderr.Close()
+		<-read
+	}
+	stdout.Close()
+	stderr.Close()
+
+	result := &ExecResult{
+		Stdout: strings.TrimRight(outs[0].String(), "\r\n"),
+		Stderr: strings.TrimRight(outs[1].String(), "\r\n"),
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		result.ExitCode = -1
+		result.TimedOut = true
+	case err == nil:
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	default:
+		result.ExitCode = -1
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+func (c *ExecChan) Recv(ctx *dsl.Ctx) chan dsl.Msg {
+	return c.c
+}
+
+// Kill kills the commands that are still running.
+func (c *ExecChan) Kill(ctx *dsl.Ctx) error {
+	return c.Close(ctx)
+}
+
+func (c *ExecChan) To(ctx *dsl.Ctx, m dsl.Msg) error {
+	ctx.Logf("ExecChan To %s", m.Topic)
+	ctx.Logdf("     %s", m.Payload)
+	m.ReceivedAt = time.Now().UTC()
+	select {
+	case <-ctx.Done():
+	case c.c <- m:
+	default:
+		panic("Warning: ExecChan channel full")
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package shell
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+)
+
+func TestExecDocs(t *testing.T) {
+	(&ExecChan{}).DocSpec().Write("exec")
+}
+
+func execResult(t *testing.T, ctx *dsl.Ctx, c dsl.Chan, topic string) *ExecResult {
+	select {
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	case m := <-c.Recv(ctx):
+		if m.Topic != topic {
+			t.Fatal(dsl.JSON(m))
+		}
+		var r ExecResult
+		if err := json.Unmarshal([]byte(m.Payload), &r); err != nil {
+			t.Fatal(err)
+		}
+		return &r
+	}
+	panic("unreachable")
+}
+
+func TestExec(t *testing.T) {
+	var (
+		ctx = dsl.NewCtx(context.Background())
+		dir = t.TempDir()
+	)
+
+	c, err := NewExecChan(ctx, &ExecOpts{
+		Dir: dir,
+		Env: map[string]string{
+			"WANT": "tacos",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close(ctx)
+
+	pub := func(topic, payload string) {
+		if err := c.Pub(ctx, dsl.Msg{Topic: topic, Payload: payload}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pub("env", `echo "$WANT in $(pwd)"; echo oops >&2; exit 3`)
+	r := execResult(t, ctx, c, "env")
+	if r.Stdout != "tacos in "+dir || r.Stderr != "oops" || r.ExitCode != 3 {
+		t.Fatal(dsl.JSON(r))
+	}
+
+	pub("args", dsl.JSON(&ExecRequest{
+		Command: "cat",
+		Args:    []string{"-"},
+		Stdin:   "chips\n",
+	}))
+	if r = execResult(t, ctx, c, "args"); r.Stdout != "chips" || r.ExitCode != 0 {
+		t.Fatal(dsl.JSON(r))
+	}
+
+	pub("override", dsl.JSON(&ExecRequest{
+		Command: `echo $WANT`,
+		Env:     map[string]string{"WANT": "queso"},
+	}))
+	if r = execResult(t, ctx, c, "override"); r.Stdout != "queso" {
+		t.Fatal(dsl.JSON(r))
+	}
+
+	// The background sleep holds stdout, but we still get a
+	// prompt result.
+	then := time.Now()
+	pub("timeout", dsl.JSON(&ExecRequest{
+		Command: `sleep 10 & echo started; sleep 10`,
+		Timeout: 100,
+	}))
+	if r = execResult(t, ctx, c, "timeout"); !r.TimedOut || r.ExitCode != -1 || r.Stdout != "started" {
+		t.Fatal(dsl.JSON(r))
+	}
+	if elapsed := time.Since(then); 2*time.Second < elapsed {
+		t.Fatal(elapsed)
+	}
+
+	pub("missing", dsl.JSON(&ExecRequest{
+		Command: "/no/such/program",
+		Args:    []string{"-v"},
+	}))
+	if r = execResult(t, ctx, c, "missing"); r.ExitCode != -1 || r.Error == "" {
+		t.Fatal(dsl.JSON(r))
+	}
+}
@@ -0,0 +1,512 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package socket
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+)
+
+var (
+	// DefaultSocketBufferSize is the default capacity of the
+	// internal Go channel.
+	DefaultSocketBufferSize = dsl.DefaultChanBufferSize
+
+	// DefaultMaxFrameSize is the default limit on the size of a
+	// received length-prefixed frame.
+	DefaultMaxFrameSize = 1 << 20
+)
+
+func init() {
+	dsl.TheChanRegistry.Register(dsl.NewCtx(nil), "socket", NewSocketChan)
+}
+
+// Socket is a raw TCP or UDP client Chan.
+//
+// Each message published to this channel is sent as one frame (for
+// TCP) or datagram (for UDP), and each frame or datagram received is
+// forwarded for the test to receive with the topic "frame".  The
+// topic of a published message is ignored.
+//
+// For TCP, Framing says how frames are delimited in the stream:
+// "newline" (the default) ends each frame with the Delimiter,
+// "length" precedes each frame with its length in LengthBytes bytes,
+// "fixed" uses frames of exactly FrameSize bytes, and "none" makes
+// each read a frame.  When the server closes the connection, the
+// test receives a message with the topic "close".
+//
+// Encoding says how payloads represent bytes: "text" (the default),
+// "hex", or "base64".
+type Socket struct {
+	opts *SocketOpts
+	c    chan dsl.Msg
+
+	// lock protects conn.
+	lock sync.Mutex
+	conn net.Conn
+}
+
+func (c *Socket) DocSpec() *dsl.DocSpec {
+	return &dsl.DocSpec{
+		Chan: &Socket{},
+		Opts: &SocketOpts{},
+	}
+}
+
+// SocketOpts configures a Socket channel.
+type SocketOpts struct {
+	// Network is "tcp" (the default) or "udp".
+	Network string `json:",omitempty" yaml:",omitempty"`
+
+	// Addr is the server address (like "localhost:9000").
+	Addr string `json:",omitempty" yaml:",omitempty"`
+
+	// Framing is "newline" (the default), "length", "fixed", or
+	// "none" (see above).
+	Framing string `json:",omitempty" yaml:",omitempty"`
+
+	// Delimiter ends each frame with "newline" Framing.  The
+	// default is "\n".
+	Delimiter string `json:",omitempty" yaml:",omitempty"`
+
+	// LengthBytes is the size (1, 2, or 4) of the length prefix
+	// with "length" Framing.  The default is 4.
+	LengthBytes int `json:",omitempty" yaml:",omitempty"`
+
+	// LittleEndian makes the length prefix little-endian instead
+	// of big-endian.
+	LittleEndian bool `json:",omitempty" yaml:",omitempty"`
+
+	// FrameSize is the size of each frame with "fixed" Framing.
+	FrameSize int `json:",omitempty" yaml:",omitempty"`
+
+	// MaxFrameSize limits the size of received frames.  The
+	// default is DefaultMaxFrameSize.
+	MaxFrameSize int `json:",omitempty" yaml:",omitempty"`
+
+	// Encoding is "text" (the default), "hex", or "base64".
+	Encoding string `json:",omitempty" yaml:",omitempty"`
+
+	// ConnectTimeout is the timeout in milliseconds for
+	// connecting to the server.  The default is 1000.
+	ConnectTimeout int64 `json:",omitempty" yaml:",omitempty"`
+
+	// BufferSize specifies the capacity of the internal Go
+	// channel.
+	//
+	// The default is DefaultSocketBufferSize.
+	BufferSize int `json:",omitempty" yaml:",omitempty"`
+}
+
+func NewSocketChan(ctx *dsl.Ctx, opts interface{}) (dsl.Chan, error) {
+	o := SocketOpts{}
+
+	js, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(js, &o); err != nil {
+		return nil, fmt.Errorf("NewSocketChan: %w", err)
+	}
+
+	if o.Addr == "" {
+		return nil, dsl.Brokenf("socket channel needs an Addr")
+	}
+
+	switch o.Network {
+	case "":
+		o.Network = "tcp"
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6":
+	default:
+		return nil, dsl.Brokenf("unsupported socket Network '%s'", o.Network)
+	}
+
+	switch o.Framing {
+	case "":
+		o.Framing = "newline"
+	case "newline", "none":
+	case "length":
+		switch o.LengthBytes {
+		case 0:
+			o.LengthBytes = 4
+		case 1, 2, 4:
+		default:
+			return nil, dsl.Brokenf("bad socket LengthBytes %d (want 1, 2, or 4)", o.LengthBytes)
+		}
+	case "fixed":
+		if o.FrameSize <= 0 {
+			return nil, dsl.Brokenf("socket channel with fixed Framing needs a FrameSize")
+		}
+	default:
+		return nil, dsl.Brokenf("unknown socket Framing '%s'", o.Framing)
+	}
+
+	switch o.Encoding {
+	case "":
+		o.Encoding = "text"
+	case "text", "hex", "base64":
+	default:
+		return nil, dsl.Brokenf("unknown socket Encoding '%s'", o.Encoding)
+	}
+
+	if o.Delimiter == "" {
+		o.Delimiter = "\n"
+	}
+
+	if o.MaxFrameSize == 0 {
+		o.MaxFrameSize = DefaultMaxFrameSize
+	}
+
+	if o.ConnectTimeout == 0 {
+		o.ConnectTimeout = 1000 // ms
+	}
+
+	bufSize := o.BufferSize
+	if bufSize == 0 {
+		bufSize = DefaultSocketBufferSize
+	}
+
+	return &Socket{
+		opts: &o,
+		c:    make(chan dsl.Msg, bufSize),
+	}, nil
+}
+
+func (c *Socket) Kind() dsl.ChanKind {
+	return "socket"
+}
+
+func (c *Socket) udp() bool {
+	return c.opts.Network[:3] == "udp"
+}
+
+func (c *Socket) dial(ctx *dsl.Ctx) (net.Conn, error) {
+	d := &net.Dialer{
+		Timeout: time.Duration(c.opts.ConnectTimeout) * time.Millisecond,
+	}
+	conn, err := d.DialContext(ctx, c.opts.Network, c.opts.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", c.opts.Addr, err)
+	}
+	return conn, nil
+}
+
+func (c *Socket) Open(ctx *dsl.Ctx) error {
+	c.Close(ctx)
+
+	ctx.Logf("Socket opening %s %s", c.opts.Network, c.opts.Addr)
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	c.conn = conn
+	c.lock.Unlock()
+
+	go c.read(ctx, conn)
+
+	return nil
+}
+
+// read forwards the frames received on the connection until the
+// connection closes.
+func (c *Socket) read(ctx *dsl.Ctx, conn net.Conn) {
+	var (
+		r   = bufio.NewReader(conn)
+		buf = make([]byte, 65536)
+	)
+
+	for {
+		var (
+			frame []byte
+			err   error
+		)
+
+		if c.udp() {
+			var n int
+			if n, err = conn.Read(buf); err == nil {
+				frame = buf[:n]
+			}
+		} else {
+			frame, err = c.frame(r, buf)
+		}
+
+		if err != nil {
+			if err == io.EOF {
+				c.forward(ctx, "close", "")
+			} else {
+				ctx.Logf("Socket %s read: %v", c.opts.Addr, err)
+			}
+			return
+		}
+
+		c.forward(ctx, "frame", c.encode(frame))
+	}
+}
+
+// frame reads the next frame from the stream.
+func (c *Socket) frame(r *bufio.Reader, buf []byte) ([]byte, error) {
+	switch c.opts.Framing {
+	case "newline":
+		var (
+			delim = []byte(c.opts.Delimiter)
+			last  = delim[len(delim)-1]
+			frame []byte
+		)
+		for {
+			bs, err := r.ReadBytes(last)
+			frame = append(frame, bs...)
+			if err != nil {
+				if err == io.EOF && 0 < len(frame) {
+					err = io.ErrUnexpectedEOF
+				}
+				return nil, err
+			}
+			if n := len(frame) - len(delim); 0 <= n && string(frame[n:]) == c.opts.Delimiter {
+				return frame[:n], nil
+			}
+			if c.opts.MaxFrameSize < len(frame) {
+				return nil, fmt.Errorf("frame exceeds %d bytes", c.opts.MaxFrameSize)
+			}
+		}
+	case "length":
+		prefix := make([]byte, c.opts.LengthBytes)
+		if _, err := io.ReadFull(r, prefix); err != nil {
+			return nil, err
+		}
+		n := c.length(prefix)
+		if c.opts.MaxFrameSize < n {
+			return nil, fmt.Errorf("frame length %d exceeds %d", n, c.opts.MaxFrameSize)
+		}
+		frame := make([]byte, n)
+		if _, err := io.ReadFull(r, frame); err != nil {
+			return nil, unexpected(err)
+		}
+		return frame, nil
+	case "fixed":
+		frame := make([]byte, c.opts.FrameSize)
+		if n, err := io.ReadFull(r, frame); err != nil {
+			if n == 0 {
+				return nil, err
+			}
+			return nil, unexpected(err)
+		}
+		return frame, nil
+	default: // "none"
+		n, err := r.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+}
+
+// unexpected turns an EOF in the middle of a frame into an
+// io.ErrUnexpectedEOF.
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (c *Socket) order() binary.ByteOrder {
+	if c.opts.LittleEndian {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+func (c *Socket) length(prefix []byte) int {
+	switch len(prefix) {
+	case 1:
+		return int(prefix[0])
+	case 2:
+		return int(c.order().Uint16(prefix))
+	default:
+		return int(c.order().Uint32(prefix))
+	}
+}
+
+// encode represents the bytes as a payload.
+func (c *Socket) encode(bs []byte) string {
+	switch c.opts.Encoding {
+	case "hex":
+		return hex.EncodeToString(bs)
+	case "base64":
+		return base64.StdEncoding.EncodeToString(bs)
+	default:
+		return string(bs)
+	}
+}
+
+// decode gets the bytes that the payload represents.
+func (c *Socket) decode(payload string) ([]byte, error) {
+	switch c.opts.Encoding {
+	case "hex":
+		return hex.DecodeString(payload)
+	case "base64":
+		return base64.StdEncoding.DecodeString(payload)
+	default:
+		return []byte(payload), nil
+	}
+}
+
+// framed returns the bytes to write for the frame.
+func (c *Socket) framed(frame []byte) ([]byte, error) {
+	if c.udp() {
+		return frame, nil
+	}
+
+	switch c.opts.Framing {
+	case "newline":
+		return append(frame, c.opts.Delimiter...), nil
+	case "length":
+		n := len(frame)
+		prefix := make([]byte, c.opts.LengthBytes)
+		switch c.opts.LengthBytes {
+		case 1:
+			if 0xff < n {
+				return nil, fmt.Errorf("frame length %d doesn't fit in 1 byte", n)
+			}
+			prefix[0] = byte(n)
+		case 2:
+			if 0xffff < n {
+				return nil, fmt.Errorf("frame length %d doesn't fit in 2 bytes", n)
+			}
+			c.order().PutUint16(prefix, uint16(n))
+		default:
+			c.order().PutUint32(prefix, uint32(n))
+		}
+		return append(prefix, frame...), nil
+	case "fixed":
+		if len(frame) != c.opts.FrameSize {
+			return nil, fmt.Errorf("frame has %d bytes (not %d)", len(frame), c.opts.FrameSize)
+		}
+	}
+
+	return frame, nil
+}
+
+func (c *Socket) forward(ctx *dsl.Ctx, topic, payload string) {
+	if err := c.To(ctx, dsl.Msg{Topic: topic, Payload: payload}); err != nil {
+		ctx.Warnf("warning: %s To for Socket %s", err, c.opts.Addr)
+	}
+}
+
+func (c *Socket) Close(ctx *dsl.Ctx) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	ctx.Logf("Socket closing %s", c.opts.Addr)
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// Ping connects and then disconnects.
+func (c *Socket) Ping(ctx *dsl.Ctx) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (c *Socket) Sub(ctx *dsl.Ctx, topic string) error {
+	return fmt.Errorf("%T doesn't support 'sub'", c)
+}
+
+func (c *Socket) Pub(ctx *dsl.Ctx, m dsl.Msg) error {
+	ctx.Logf("Socket Pub %s", c.opts.Addr)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.conn == nil {
+		return fmt.Errorf("socket channel to %s isn't open", c.opts.Addr)
+	}
+
+	payload, err := dsl.MaybeSerialize(m.Payload)
+	if err != nil {
+		return err
+	}
+
+	frame, err := c.decode(payload)
+	if err != nil {
+		return fmt.Errorf("bad %s payload: %w", c.opts.Encoding, err)
+	}
+
+	if frame, err = c.framed(frame); err != nil {
+		return err
+	}
+
+	_, err = c.conn.Write(frame)
+	return err
+}
+
+func (c *Socket) Recv(ctx *dsl.Ctx) chan dsl.Msg {
+	return c.c
+}
+
+// Kill resets a TCP connection (or closes a UDP one) without the
+// usual orderly shutdown.
+func (c *Socket) Kill(ctx *dsl.Ctx) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.conn == nil {
+		return fmt.Errorf("socket channel to %s isn't open", c.opts.Addr)
+	}
+	if tc, is := c.conn.(*net.TCPConn); is {
+		tc.SetLinger(0)
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func (c *Socket) To(ctx *dsl.Ctx, m dsl.Msg) error {
+	ctx.Logf("Socket To %s", m.Topic)
+	ctx.Logdf("     %s", m.Payload)
+	m.ReceivedAt = time.Now().UTC()
+	select {
+	case <-ctx.Done():
+	case c.c <- m:
+	default:
+		panic("Warning: Socket channel full")
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package socket
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+)
+
+func TestDocs(t *testing.T) {
+	(&Socket{}).DocSpec().Write("socket")
+}
+
+// echoTCP echoes the bytes on each connection, and it closes a
+// connection after sending "bye".
+func echoTCP(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 1024)
+				for {
+					n, err := conn.Read(buf)
+					if err != nil {
+						return
+					}
+					conn.Write(buf[:n])
+					if string(buf[:n]) == "bye\n" {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func recv(t *testing.T, ctx *dsl.Ctx, c dsl.Chan) dsl.Msg {
+	select {
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout")
+	case m := <-c.Recv(ctx):
+		return m
+	}
+	panic("unreachable")
+}
+
+func open(t *testing.T, ctx *dsl.Ctx, opts *SocketOpts) dsl.Chan {
+	c, err := NewSocketChan(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close(ctx) })
+	return c
+}
+
+func pub(t *testing.T, ctx *dsl.Ctx, c dsl.Chan, payload string) {
+	if err := c.Pub(ctx, dsl.Msg{Payload: payload}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSocketNewline(t *testing.T) {
+	var (
+		ctx = dsl.NewCtx(context.Background())
+		c   = open(t, ctx, &SocketOpts{
+			Addr: echoTCP(t),
+		})
+	)
+
+	pub(t, ctx, c, "tacos")
+	if m := recv(t, ctx, c); m.Topic != "frame" || m.Payload != "tacos" {
+		t.Fatal(dsl.JSON(m))
+	}
+
+	pub(t, ctx, c, "bye")
+	if m := recv(t, ctx, c); m.Payload != "bye" {
+		t.Fatal(dsl.JSON(m))
+	}
+	if m := recv(t, ctx, c); m.Topic != "close" {
+		t.Fatal(dsl.JSON(m))
+	}
+}
+
+func TestSocketLength(t *testing.T) {
+	var (
+		ctx = dsl.NewCtx(context.Background())
+		c   = open(t, ctx, &SocketOpts{
+			Addr:         echoTCP(t),
+			Framing:      "length",
+			LengthBytes:  2,
+			LittleEndian: true,
+			Encoding:     "hex",
+		})
+	)
+
+	pub(t, ctx, c, "cafe")
+	pub(t, ctx, c, "")
+	pub(t, ctx, c, "00ff00")
+	for _, want := range []string{"cafe", "", "00ff00"} {
+		if m := recv(t, ctx, c); m.Payload != want {
+			t.Fatal(dsl.JSON(m))
+		}
+	}
+
+	if err := c.Pub(ctx, dsl.Msg{Payload: "queso"}); err == nil {
+		t.Fatal("no error for bad hex")
+	}
+}
+
+func TestSocketFixed(t *testing.T) {
+	var (
+		ctx = dsl.NewCtx(context.Background())
+		c   = open(t, ctx, &SocketOpts{
+			Addr:      echoTCP(t),
+			Framing:   "fixed",
+			FrameSize: 3,
+			Encoding:  "base64",
+		})
+	)
+
+	pub(t, ctx, c, "AQID")
+	pub(t, ctx, c, "BAUG")
+	for _, want := range []string{"AQID", "BAUG"} {
+		if m := recv(t, ctx, c); m.Payload != want {
+			t.Fatal(dsl.JSON(m))
+		}
+	}
+
+	if err := c.Pub(ctx, dsl.Msg{Payload: "AQ=="}); err == nil {
+		t.Fatal("no error for short frame")
+	}
+}
+
+func TestSocketUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			pc.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	var (
+		ctx = dsl.NewCtx(context.Background())
+		c   = open(t, ctx, &SocketOpts{
+			Network: "udp",
+			Addr:    pc.LocalAddr().String(),
+		})
+	)
+
+	pub(t, ctx, c, "chips\nand salsa")
+	if m := recv(t, ctx, c); m.Payload != "chips\nand salsa" {
+		t.Fatal(dsl.JSON(m))
+	}
+}
+
+func TestSocketKill(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	got := make(chan error, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			got <- err
+			return
+		}
+		_, err = io.ReadAll(conn)
+		got <- err
+	}()
+
+	ctx := dsl.NewCtx(context.Background())
+	c := open(t, ctx, &SocketOpts{
+		Addr: l.Addr().String(),
+	})
+	if err := c.Kill(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-got:
+		if err == nil {
+			t.Fatal("server didn't see a reset")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestSocketOpts(t *testing.T) {
+	ctx := dsl.NewCtx(context.Background())
+	for _, opts := range []*SocketOpts{
+		{},
+		{Addr: "localhost:9", Network: "sctp"},
+		{Addr: "localhost:9", Framing: "fixed"},
+		{Addr: "localhost:9", Framing: "length", LengthBytes: 3},
+		{Addr: "localhost:9", Encoding: "ebcdic"},
+	} {
+		if _, err := NewSocketChan(ctx, opts); err == nil {
+			t.Fatal(dsl.JSON(opts))
+		} else if _, is := dsl.IsBroken(err); !is {
+			t.Fatal(err)
+		}
+	}
+}
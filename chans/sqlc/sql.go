@@ -181,6 +181,16 @@ func (c *Chan) Close(ctx *dsl.Ctx) error {
 	return c.db.Close()
 }
 
+// Ping connects to the database and then closes the connection.
+func (c *Chan) Ping(ctx *dsl.Ctx) error {
+	db, err := sql.Open(c.opts.DriverName, c.opts.DatasourceName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.PingContext(ctx)
+}
+
 func (c *Chan) Sub(ctx *dsl.Ctx, topic string) error {
 	return dsl.Brokenf("Can't Sub on an SQL channel")
 }
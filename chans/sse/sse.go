@@ -0,0 +1,450 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package sse
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+)
+
+var (
+	// DefaultSSEBufferSize is the default capacity of the
+	// internal Go channel.
+	DefaultSSEBufferSize = dsl.DefaultChanBufferSize
+)
+
+func init() {
+	dsl.TheChanRegistry.Register(dsl.NewCtx(nil), "sse", NewSSEChan)
+}
+
+// SSE is a server-sent events (text/event-stream) client Chan.
+//
+// Open makes the GET request, and each event from the server is
+// forwarded for the test to receive with the event type (by default
+// "message") as its topic and a payload like
+//
+//	{"event":"order","id":"42","data":{"want":"tacos"}}
+//
+// where the data is parsed as JSON if possible.
+//
+// When the stream ends, the channel reconnects (after the server's
+// "retry" delay or ReconnectDelay) with a Last-Event-ID header that
+// gives the ID of the last event received.  A 204 response stops
+// the reconnecting.  Kill drops the current connection, which is
+// handy for testing how a server handles a reconnection.
+type SSE struct {
+	opts   *SSEOpts
+	c      chan dsl.Msg
+	client *http.Client
+
+	// lock protects the fields below.
+	lock sync.Mutex
+
+	// cancel stops the stream (if any).
+	cancel context.CancelFunc
+
+	// body is the body of the current response.
+	body io.Closer
+
+	// lastID is the ID of the last event.
+	lastID string
+
+	// retry is the reconnection delay.
+	retry time.Duration
+}
+
+func (c *SSE) DocSpec() *dsl.DocSpec {
+	return &dsl.DocSpec{
+		Chan: &SSE{},
+		Opts: &SSEOpts{},
+	}
+}
+
+// SSEOpts configures an SSE channel.
+type SSEOpts struct {
+	// URL is the http:// or https:// URL of the event stream.
+	URL string `json:",omitempty" yaml:",omitempty"`
+
+	// Headers are the additional HTTP headers for the requests.
+	Headers map[string][]string `json:",omitempty" yaml:",omitempty"`
+
+	// Insecure skips verifying the server's certificate.  This
+	// should be used only for testing.
+	Insecure bool `json:",omitempty" yaml:",omitempty"`
+
+	// LastEventID is the optional Last-Event-ID for the first
+	// request.
+	LastEventID string `json:",omitempty" yaml:",omitempty"`
+
+	// ReconnectDelay is the delay in milliseconds before
+	// reconnecting (unless the server gives a "retry").  The
+	// default is 1000.
+	ReconnectDelay int64 `json:",omitempty" yaml:",omitempty"`
+
+	// NoReconnect turns off reconnecting when the stream ends.
+	NoReconnect bool `json:",omitempty" yaml:",omitempty"`
+
+	// ConnectTimeout is the timeout in milliseconds for getting
+	// the response headers.  The default is 1000.
+	ConnectTimeout int64 `json:",omitempty" yaml:",omitempty"`
+
+	// BufferSize specifies the capacity of the internal Go
+	// channel.
+	//
+	// The default is DefaultSSEBufferSize.
+	BufferSize int `json:",omitempty" yaml:",omitempty"`
+}
+
+// Event is the payload of a received event.
+type Event struct {
+	// Event is the event type.
+	Event string `json:"event"`
+
+	// ID is the event's ID (if any).
+	ID string `json:"id,omitempty"`
+
+	// Data is the event's data, which is parsed as JSON if
+	// possible.
+	Data interface{} `json:"data"`
+}
+
+func NewSSEChan(ctx *dsl.Ctx, opts interface{}) (dsl.Chan, error) {
+	o := SSEOpts{}
+
+	js, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(js, &o); err != nil {
+		return nil, fmt.Errorf("NewSSEChan: %w", err)
+	}
+
+	if o.URL == "" {
+		return nil, dsl.Brokenf("sse channel needs a URL")
+	}
+
+	if o.ReconnectDelay == 0 {
+		o.ReconnectDelay = 1000 // ms
+	}
+
+	if o.ConnectTimeout == 0 {
+		o.ConnectTimeout = 1000 // ms
+	}
+
+	bufSize := o.BufferSize
+	if bufSize == 0 {
+		bufSize = DefaultSSEBufferSize
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			ResponseHeaderTimeout: time.Duration(o.ConnectTimeout) * time.Millisecond,
+		},
+	}
+	if o.Insecure {
+		client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: true,
+		}
+	}
+
+	return &SSE{
+		opts:   &o,
+		c:      make(chan dsl.Msg, bufSize),
+		client: client,
+	}, nil
+}
+
+func (c *SSE) Kind() dsl.ChanKind {
+	return "sse"
+}
+
+// errNoContent reports a 204 response, which means that we should
+// stop reconnecting.
+var errNoContent = fmt.Errorf("server responded with 204 No Content")
+
+// connect makes the GET request.
+func (c *SSE) connect(ctx context.Context) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.opts.URL, nil)
+	if err != nil {
+		return nil, dsl.Brokenf("bad sse URL %s: %s", c.opts.URL, err)
+	}
+	for h, vs := range c.opts.Headers {
+		for _, v := range vs {
+			req.Header.Add(h, v)
+		}
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+
+	c.lock.Lock()
+	if c.lastID != "" {
+		req.Header.Set("Last-Event-ID", c.lastID)
+	}
+	c.lock.Unlock()
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", c.opts.URL, err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		resp.Body.Close()
+		return nil, errNoContent
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to connect to %s: %s", c.opts.URL, resp.Status)
+	}
+
+	c.lock.Lock()
+	c.body = resp.Body
+	c.lock.Unlock()
+
+	return resp, nil
+}
+
+// Open makes the first request and starts reading the stream.
+func (c *SSE) Open(ctx *dsl.Ctx) error {
+	c.Close(ctx)
+
+	ctx.Logf("SSE opening %s", c.opts.URL)
+
+	c.lock.Lock()
+	c.lastID = c.opts.LastEventID
+	c.retry = time.Duration(c.opts.ReconnectDelay) * time.Millisecond
+	c.lock.Unlock()
+
+	sctx, cancel := context.WithCancel(ctx)
+	resp, err := c.connect(sctx)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	c.lock.Lock()
+	c.cancel = cancel
+	c.lock.Unlock()
+
+	go c.stream(ctx, sctx, resp)
+
+	return nil
+}
+
+// stream reads the responses' events (reconnecting as needed) until
+// the given context is done.
+func (c *SSE) stream(ctx *dsl.Ctx, sctx context.Context, resp *http.Response) {
+	for {
+		if resp != nil {
+			err := c.read(ctx, resp.Body)
+			resp.Body.Close()
+			if sctx.Err() != nil {
+				return
+			}
+			ctx.Logf("SSE %s stream ended: %v", c.opts.URL, err)
+		}
+
+		if c.opts.NoReconnect {
+			return
+		}
+
+		c.lock.Lock()
+		retry := c.retry
+		c.lock.Unlock()
+
+		select {
+		case <-sctx.Done():
+			return
+		case <-time.After(retry):
+		}
+
+		ctx.Logf("SSE reconnecting to %s", c.opts.URL)
+
+		var err error
+		if resp, err = c.connect(sctx); err != nil {
+			if err == errNoContent {
+				ctx.Logf("SSE %s: %v", c.opts.URL, err)
+				return
+			}
+			ctx.Logf("SSE %s reconnect: %v", c.opts.URL, err)
+		}
+	}
+}
+
+// read parses the event stream and forwards its events.
+func (c *SSE) read(ctx *dsl.Ctx, r io.Reader) error {
+	var (
+		br    = bufio.NewReader(r)
+		event string
+		data  strings.Builder
+	)
+
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			// An incomplete event is discarded.
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if line == "" {
+			if 0 < data.Len() {
+				if event == "" {
+					event = "message"
+				}
+				c.lock.Lock()
+				id := c.lastID
+				c.lock.Unlock()
+				c.forward(ctx, event, id, strings.TrimSuffix(data.String(), "\n"))
+			}
+			event = ""
+			data.Reset()
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.Index(line, ":"); 0 <= i {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data.WriteString(value)
+			data.WriteString("\n")
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				c.lock.Lock()
+				c.lastID = value
+				c.lock.Unlock()
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+				c.lock.Lock()
+				c.retry = time.Duration(ms) * time.Millisecond
+				c.lock.Unlock()
+			}
+		}
+	}
+}
+
+func (c *SSE) forward(ctx *dsl.Ctx, event, id, data string) {
+	e := &Event{
+		Event: event,
+		ID:    id,
+		Data:  data,
+	}
+	var x interface{}
+	if err := json.Unmarshal([]byte(data), &x); err == nil {
+		e.Data = x
+	}
+
+	if err := c.To(ctx, dsl.Msg{Topic: event, Payload: dsl.JSON(e)}); err != nil {
+		ctx.Warnf("warning: %s To for SSE %s", err, c.opts.URL)
+	}
+}
+
+// Close stops the stream.
+func (c *SSE) Close(ctx *dsl.Ctx) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.cancel != nil {
+		ctx.Logf("SSE closing %s", c.opts.URL)
+		c.cancel()
+		c.cancel = nil
+	}
+	if c.body != nil {
+		c.body.Close()
+		c.body = nil
+	}
+
+	return nil
+}
+
+// Ping makes a request and then disconnects.
+func (c *SSE) Ping(ctx *dsl.Ctx) error {
+	pctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	resp, err := c.connect(pctx)
+	if err != nil && err != errNoContent {
+		return err
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+	return nil
+}
+
+func (c *SSE) Sub(ctx *dsl.Ctx, topic string) error {
+	return fmt.Errorf("%T doesn't support 'sub'", c)
+}
+
+func (c *SSE) Pub(ctx *dsl.Ctx, m dsl.Msg) error {
+	return fmt.Errorf("%T doesn't support 'pub'", c)
+}
+
+func (c *SSE) Recv(ctx *dsl.Ctx) chan dsl.Msg {
+	return c.c
+}
+
+// Kill drops the current connection, so the channel will reconnect
+// (unless NoReconnect).
+func (c *SSE) Kill(ctx *dsl.Ctx) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.body == nil {
+		return fmt.Errorf("sse channel to %s isn't open", c.opts.URL)
+	}
+	return c.body.Close()
+}
+
+func (c *SSE) To(ctx *dsl.Ctx, m dsl.Msg) error {
+	ctx.Logf("SSE To %s", m.Topic)
+	ctx.Logdf("     %s", m.Payload)
+	m.ReceivedAt = time.Now().UTC()
+	select {
+	case <-ctx.Done():
+	case c.c <- m:
+	default:
+		panic("Warning: SSE channel full")
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+)
+
+func TestDocs(t *testing.T) {
+	(&SSE{}).DocSpec().Write("sse")
+}
+
+func recv(t *testing.T, ctx *dsl.Ctx, c dsl.Chan) (dsl.Msg, *Event) {
+	select {
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout")
+	case m := <-c.Recv(ctx):
+		var e Event
+		if err := json.Unmarshal([]byte(m.Payload), &e); err != nil {
+			t.Fatal(err)
+		}
+		return m, &e
+	}
+	panic("unreachable")
+}
+
+func open(t *testing.T, ctx *dsl.Ctx, opts *SSEOpts) dsl.Chan {
+	c, err := NewSSEChan(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close(ctx) })
+	return c
+}
+
+func TestSSEReconnect(t *testing.T) {
+	var (
+		ctx = dsl.NewCtx(context.Background())
+
+		lock    sync.Mutex
+		lastIDs []string
+	)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "text/event-stream" {
+			http.Error(w, "bad accept", http.StatusBadRequest)
+			return
+		}
+		lock.Lock()
+		lastIDs = append(lastIDs, r.Header.Get("Last-Event-ID"))
+		n := len(lastIDs)
+		lock.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		switch n {
+		case 1:
+			fmt.Fprint(w, ": hello\nretry: 10\n\n")
+			fmt.Fprint(w, "event: order\nid: 1\ndata: {\"want\":\n")
+			fmt.Fprint(w, "data: \"tacos\"}\n\n")
+			fmt.Fprint(w, "id: 2\r\ndata:chips\r\n\r\n")
+			// An incomplete event, which is discarded.
+			fmt.Fprint(w, "data: lost\n")
+		case 2:
+			fmt.Fprint(w, "data: queso\n\n")
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	c := open(t, ctx, &SSEOpts{
+		URL: ts.URL,
+	})
+
+	m, e := recv(t, ctx, c)
+	if m.Topic != "order" || e.ID != "1" || dsl.JSON(e.Data) != `{"want":"tacos"}` {
+		t.Fatal(m.Payload)
+	}
+
+	m, e = recv(t, ctx, c)
+	if m.Topic != "message" || e.ID != "2" || e.Data != "chips" {
+		t.Fatal(m.Payload)
+	}
+
+	// The reconnection (after the server's retry delay).
+	m, e = recv(t, ctx, c)
+	if m.Topic != "message" || e.ID != "2" || e.Data != "queso" {
+		t.Fatal(m.Payload)
+	}
+
+	// The third request gets a 204, which stops the reconnecting.
+	time.Sleep(100 * time.Millisecond)
+	lock.Lock()
+	defer lock.Unlock()
+	if got := strings.Join(lastIDs, ","); got != ",2,2" {
+		t.Fatal(got)
+	}
+}
+
+func TestSSEKill(t *testing.T) {
+	var (
+		ctx = dsl.NewCtx(context.Background())
+
+		lock    sync.Mutex
+		lastIDs []string
+	)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		lastIDs = append(lastIDs, r.Header.Get("Last-Event-ID"))
+		n := len(lastIDs)
+		lock.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "id: %d\ndata: %d\n\n", 10+n, n)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	t.Cleanup(ts.Close)
+
+	c := open(t, ctx, &SSEOpts{
+		URL:            ts.URL,
+		LastEventID:    "7",
+		ReconnectDelay: 10,
+		Headers: map[string][]string{
+			"X-Test": {"queso"},
+		},
+	})
+
+	if _, e := recv(t, ctx, c); e.Data != 1.0 {
+		t.Fatal(dsl.JSON(e))
+	}
+	if err := c.Kill(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, e := recv(t, ctx, c); e.Data != 2.0 || e.ID != "12" {
+		t.Fatal(dsl.JSON(e))
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if got := strings.Join(lastIDs, ","); got != "7,11" {
+		t.Fatal(got)
+	}
+}
+
+func TestSSEErrors(t *testing.T) {
+	ctx := dsl.NewCtx(context.Background())
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusUnauthorized)
+	}))
+	t.Cleanup(ts.Close)
+
+	c, err := NewSSEChan(ctx, &SSEOpts{
+		URL: ts.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Open(ctx); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatal(err)
+	}
+
+	if _, err = NewSSEChan(ctx, &SSEOpts{}); err == nil {
+		t.Fatal("no error")
+	} else if _, is := dsl.IsBroken(err); !is {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,369 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package tail
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+)
+
+var (
+	// DefaultTailBufferSize is the default capacity of the
+	// internal Go channel.
+	DefaultTailBufferSize = dsl.DefaultChanBufferSize
+)
+
+func init() {
+	dsl.TheChanRegistry.Register(dsl.NewCtx(nil), "tail", NewTailChan)
+}
+
+// Tail is a Chan that follows files (like 'tail -F').
+//
+// Each line appended to a file that matches the Path (which can be
+// a glob) is forwarded for the test to receive with the file's path
+// as its topic and the line (without its line ending) as its
+// payload.  A file that is rotated (replaced by a new file with the
+// same name) or truncated is followed from its new beginning, and a
+// matching file that appears later is read from its beginning.
+//
+// A message published to this channel is appended as a line to the
+// file given by its topic (or by the Path when the topic is empty),
+// which is handy for fixtures.
+type Tail struct {
+	opts *TailOpts
+	c    chan dsl.Msg
+
+	// lock protects the fields below.
+	lock sync.Mutex
+
+	// files maps paths to the files that we're following.
+	files map[string]*file
+
+	// stop stops the polling (if any).
+	stop chan bool
+}
+
+// file is a file that we're following.
+type file struct {
+	f    *os.File
+	info os.FileInfo
+
+	// offset is where we'll read next.
+	offset int64
+
+	// partial is an incomplete last line.
+	partial []byte
+}
+
+func (c *Tail) DocSpec() *dsl.DocSpec {
+	return &dsl.DocSpec{
+		Chan: &Tail{},
+		Opts: &TailOpts{},
+	}
+}
+
+// TailOpts configures a Tail channel.
+type TailOpts struct {
+	// Path is the path (or glob) of the files to follow.
+	Path string `json:",omitempty" yaml:",omitempty"`
+
+	// FromBeginning makes the channel read the files that exist
+	// at Open from their beginnings.  By default, only lines
+	// appended after Open are received.
+	FromBeginning bool `json:",omitempty" yaml:",omitempty"`
+
+	// PollInterval is how often in milliseconds to check the
+	// files.  The default is 100.
+	PollInterval int64 `json:",omitempty" yaml:",omitempty"`
+
+	// BufferSize specifies the capacity of the internal Go
+	// channel.
+	//
+	// The default is DefaultTailBufferSize.
+	BufferSize int `json:",omitempty" yaml:",omitempty"`
+}
+
+func NewTailChan(ctx *dsl.Ctx, opts interface{}) (dsl.Chan, error) {
+	o := TailOpts{}
+
+	js, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(js, &o); err != nil {
+		return nil, fmt.Errorf("NewTailChan: %w", err)
+	}
+
+	if o.Path == "" {
+		return nil, dsl.Brokenf("tail channel needs a Path")
+	}
+
+	if _, err := filepath.Match(o.Path, ""); err != nil {
+		return nil, dsl.Brokenf("bad tail Path '%s': %s", o.Path, err)
+	}
+
+	if o.PollInterval == 0 {
+		o.PollInterval = 100 // ms
+	}
+
+	bufSize := o.BufferSize
+	if bufSize == 0 {
+		bufSize = DefaultTailBufferSize
+	}
+
+	return &Tail{
+		opts: &o,
+		c:    make(chan dsl.Msg, bufSize),
+	}, nil
+}
+
+func (c *Tail) Kind() dsl.ChanKind {
+	return "tail"
+}
+
+// Open checks the files (so that lines appended after Open are
+// received) and starts polling.
+func (c *Tail) Open(ctx *dsl.Ctx) error {
+	c.Close(ctx)
+
+	ctx.Logf("Tail opening %s", c.opts.Path)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.files = make(map[string]*file)
+	ms := c.poll(ctx, true)
+
+	stop := make(chan bool)
+	c.stop = stop
+
+	go func() {
+		// The lines are forwarded without the lock so that a
+		// full channel (when replaying a long file, for
+		// example) waits for the test to receive them rather
+		// than blocking Close.
+		if !c.forward(ctx, stop, ms) {
+			return
+		}
+
+		ticker := time.NewTicker(time.Duration(c.opts.PollInterval) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.lock.Lock()
+				ms = nil
+				if c.stop == stop {
+					ms = c.poll(ctx, false)
+				}
+				c.lock.Unlock()
+				if !c.forward(ctx, stop, ms) {
+					return
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// poll checks the files matching the Path and returns their new
+// lines.  The caller must hold the lock.
+func (c *Tail) poll(ctx *dsl.Ctx, initial bool) []dsl.Msg {
+	paths, err := filepath.Glob(c.opts.Path)
+	if err != nil {
+		ctx.Logf("Tail %s: %v", c.opts.Path, err)
+		return nil
+	}
+
+	var ms []dsl.Msg
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+
+		t, have := c.files[path]
+		if have && !os.SameFile(t.info, info) {
+			// Rotated: Finish the old file and start
+			// the new one.
+			ctx.Logf("Tail %s rotated", path)
+			ms = c.drain(ctx, path, t, ms)
+			t.f.Close()
+			have = false
+		}
+
+		if !have {
+			f, err := os.Open(path)
+			if err != nil {
+				ctx.Logf("Tail %s: %v", path, err)
+				continue
+			}
+			t = &file{
+				f:    f,
+				info: info,
+			}
+			if initial && !c.opts.FromBeginning {
+				t.offset = info.Size()
+			}
+			c.files[path] = t
+		} else if info.Size() < t.offset {
+			ctx.Logf("Tail %s truncated", path)
+			t.offset = 0
+			t.partial = nil
+		}
+
+		t.info = info
+		ms = c.drain(ctx, path, t, ms)
+	}
+
+	return ms
+}
+
+// drain appends the complete lines from the file's offset to ms.
+func (c *Tail) drain(ctx *dsl.Ctx, path string, t *file, ms []dsl.Msg) []dsl.Msg {
+	if _, err := t.f.Seek(t.offset, io.SeekStart); err != nil {
+		ctx.Logf("Tail %s: %v", path, err)
+		return ms
+	}
+	bs, err := io.ReadAll(t.f)
+	if err != nil {
+		ctx.Logf("Tail %s: %v", path, err)
+	}
+	t.offset += int64(len(bs))
+
+	bs = append(t.partial, bs...)
+	for {
+		i := bytes.IndexByte(bs, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimSuffix(string(bs[:i]), "\r")
+		ms = append(ms, dsl.Msg{Topic: path, Payload: line})
+		bs = bs[i+1:]
+	}
+	t.partial = append([]byte(nil), bs...)
+
+	return ms
+}
+
+// forward sends the lines to the test, waiting when the channel is
+// full, and reports whether the channel is still open.
+func (c *Tail) forward(ctx *dsl.Ctx, stop chan bool, ms []dsl.Msg) bool {
+	for _, m := range ms {
+		ctx.Logf("Tail To %s", m.Topic)
+		ctx.Logdf("     %s", m.Payload)
+		m.ReceivedAt = time.Now().UTC()
+		select {
+		case <-stop:
+			return false
+		case <-ctx.Done():
+			return false
+		case c.c <- m:
+		}
+	}
+	return true
+}
+
+// Close stops polling and closes the files.
+func (c *Tail) Close(ctx *dsl.Ctx) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.stop != nil {
+		ctx.Logf("Tail closing %s", c.opts.Path)
+		close(c.stop)
+		c.stop = nil
+	}
+	for _, t := range c.files {
+		t.f.Close()
+	}
+	c.files = nil
+
+	return nil
+}
+
+func (c *Tail) Sub(ctx *dsl.Ctx, topic string) error {
+	return fmt.Errorf("%T doesn't support 'sub'", c)
+}
+
+// Pub appends the payload as a line to the file given by the topic
+// (or the Path).
+func (c *Tail) Pub(ctx *dsl.Ctx, m dsl.Msg) error {
+	path := m.Topic
+	if path == "" {
+		path = c.opts.Path
+	}
+
+	ctx.Logf("Tail Pub %s", path)
+
+	payload, err := dsl.MaybeSerialize(m.Payload)
+	if err != nil {
+		return err
+	}
+	if !strings.HasSuffix(payload, "\n") {
+		payload += "\n"
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.WriteString(payload); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (c *Tail) Recv(ctx *dsl.Ctx) chan dsl.Msg {
+	return c.c
+}
+
+func (c *Tail) Kill(ctx *dsl.Ctx) error {
+	return fmt.Errorf("%T doesn't support 'Kill'", c)
+}
+
+func (c *Tail) To(ctx *dsl.Ctx, m dsl.Msg) error {
+	ctx.Logf("Tail To %s", m.Topic)
+	ctx.Logdf("     %s", m.Payload)
+	m.ReceivedAt = time.Now().UTC()
+	select {
+	case <-ctx.Done():
+	case c.c <- m:
+	default:
+		panic("Warning: Tail channel full")
+	}
+	return nil
+}
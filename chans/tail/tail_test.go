@@ -0,0 +1,188 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package tail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+)
+
+func TestDocs(t *testing.T) {
+	(&Tail{}).DocSpec().Write("tail")
+}
+
+func recv(t *testing.T, ctx *dsl.Ctx, c dsl.Chan) dsl.Msg {
+	select {
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout")
+	case m := <-c.Recv(ctx):
+		return m
+	}
+	panic("unreachable")
+}
+
+func open(t *testing.T, ctx *dsl.Ctx, opts *TailOpts) dsl.Chan {
+	c, err := NewTailChan(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close(ctx) })
+	return c
+}
+
+func write(t *testing.T, path, s string) {
+	if err := os.WriteFile(path, []byte(s), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expect(t *testing.T, ctx *dsl.Ctx, c dsl.Chan, topic string, lines ...string) {
+	for _, line := range lines {
+		if m := recv(t, ctx, c); m.Topic != topic || m.Payload != line {
+			t.Fatalf("got %s (wanted %s %q)", dsl.JSON(m), topic, line)
+		}
+	}
+}
+
+func TestTailRotation(t *testing.T) {
+	var (
+		ctx  = dsl.NewCtx(context.Background())
+		dir  = t.TempDir()
+		path = filepath.Join(dir, "app.log")
+	)
+
+	write(t, path, "old\n")
+
+	c := open(t, ctx, &TailOpts{
+		Path:         path,
+		PollInterval: 10,
+	})
+
+	// Only lines after Open.
+	if err := c.Pub(ctx, dsl.Msg{Payload: "started"}); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, ctx, c, path, "started")
+
+	// A partial line waits for its end.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("half")
+	f.Sync()
+	time.Sleep(50 * time.Millisecond)
+	f.WriteString(" and half\r\n")
+	f.Close()
+	expect(t, ctx, c, path, "half and half")
+
+	// Rotation
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	write(t, path, "rotated\n")
+	expect(t, ctx, c, path, "rotated")
+
+	// Truncation
+	write(t, path, "")
+	time.Sleep(50 * time.Millisecond)
+	write(t, path, "truncated\n")
+	expect(t, ctx, c, path, "truncated")
+}
+
+func TestTailGlob(t *testing.T) {
+	var (
+		ctx = dsl.NewCtx(context.Background())
+		dir = t.TempDir()
+		a   = filepath.Join(dir, "a.log")
+		b   = filepath.Join(dir, "b.log")
+	)
+
+	write(t, a, "one\ntwo\n")
+
+	c := open(t, ctx, &TailOpts{
+		Path:          filepath.Join(dir, "*.log"),
+		FromBeginning: true,
+		PollInterval:  10,
+	})
+	expect(t, ctx, c, a, "one", "two")
+
+	// A new file is read from its beginning.
+	write(t, b, "three\n")
+	expect(t, ctx, c, b, "three")
+
+	if err := c.Pub(ctx, dsl.Msg{Topic: a, Payload: "four"}); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, ctx, c, a, "four")
+}
+
+func TestTailFull(t *testing.T) {
+	var (
+		ctx  = dsl.NewCtx(context.Background())
+		path = filepath.Join(t.TempDir(), "big.log")
+		n    = 2 * DefaultTailBufferSize
+		acc  strings.Builder
+	)
+
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&acc, "line %d\n", i)
+	}
+	write(t, path, acc.String())
+
+	c := open(t, ctx, &TailOpts{
+		Path:          path,
+		FromBeginning: true,
+		PollInterval:  10,
+	})
+	for i := 0; i < n; i++ {
+		expect(t, ctx, c, path, fmt.Sprintf("line %d", i))
+	}
+
+	// A full channel doesn't block Close.
+	write(t, path, acc.String()+acc.String())
+	time.Sleep(50 * time.Millisecond)
+	if err := c.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTailOpts(t *testing.T) {
+	ctx := dsl.NewCtx(context.Background())
+	for _, opts := range []*TailOpts{
+		{},
+		{Path: "[oops"},
+	} {
+		if _, err := NewTailChan(ctx, opts); err == nil {
+			t.Fatal(dsl.JSON(opts))
+		} else if _, is := dsl.IsBroken(err); !is {
+			t.Fatal(err)
+		}
+	}
+}
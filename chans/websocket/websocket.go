@@ -0,0 +1,344 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package websocket
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+
+	ws "github.com/gorilla/websocket"
+)
+
+var (
+	// DefaultWebSocketBufferSize is the default capacity of the
+	// internal Go channel.
+	DefaultWebSocketBufferSize = dsl.DefaultChanBufferSize
+)
+
+func init() {
+	dsl.TheChanRegistry.Register(dsl.NewCtx(nil), "websocket", NewWebSocketChan)
+}
+
+// WebSocket is a WebSocket client Chan.
+//
+// This channel type connects to a ws:// or wss:// URL.  The topic of
+// a message published to this channel gives the type of frame to
+// send: "text" (the default), "binary", "ping", or "close".  The
+// payload of a "close" is an optional close code and reason (like
+// "1000 bye").
+//
+// Each frame received from the server is forwarded for the test to
+// receive with a topic of "text", "binary", "ping", or "pong" and
+// the frame's data as the payload.  When the server closes the
+// connection, the test receives a message with the topic "close" and
+// a payload like {"code":1000,"text":"bye"}.  Pings are answered
+// automatically.
+type WebSocket struct {
+	opts *WebSocketOpts
+	conn *ws.Conn
+	c    chan dsl.Msg
+
+	// wlock serializes writes of data frames.
+	wlock sync.Mutex
+}
+
+func (c *WebSocket) DocSpec() *dsl.DocSpec {
+	return &dsl.DocSpec{
+		Chan: &WebSocket{},
+		Opts: &WebSocketOpts{},
+	}
+}
+
+// WebSocketOpts configures a WebSocket channel.
+type WebSocketOpts struct {
+	// URL is the ws:// or wss:// URL for the connection.
+	URL string `json:",omitempty" yaml:",omitempty"`
+
+	// Headers are the additional HTTP headers for the opening
+	// handshake.
+	Headers map[string][]string `json:",omitempty" yaml:",omitempty"`
+
+	// Subprotocols are the requested subprotocols (in order of
+	// preference).
+	Subprotocols []string `json:",omitempty" yaml:",omitempty"`
+
+	// Insecure skips verifying the server's certificate.  This
+	// should be used only for testing.
+	Insecure bool `json:",omitempty" yaml:",omitempty"`
+
+	// ConnectTimeout is the timeout in milliseconds for the
+	// opening handshake.  The default is 1000.
+	ConnectTimeout int64 `json:",omitempty" yaml:",omitempty"`
+
+	// BufferSize specifies the capacity of the internal Go
+	// channel.
+	//
+	// The default is DefaultWebSocketBufferSize.
+	BufferSize int `json:",omitempty" yaml:",omitempty"`
+}
+
+// WebSocketClose is the payload of the message a test receives when
+// the server closes the connection.
+type WebSocketClose struct {
+	// Code is the close code (like 1000 for a normal closure).
+	Code int `json:"code"`
+
+	// Text is the close reason (if any).
+	Text string `json:"text,omitempty"`
+}
+
+func NewWebSocketChan(ctx *dsl.Ctx, opts interface{}) (dsl.Chan, error) {
+	o := WebSocketOpts{}
+
+	js, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(js, &o); err != nil {
+		return nil, fmt.Errorf("NewWebSocketChan: %w", err)
+	}
+
+	if o.URL == "" {
+		return nil, dsl.Brokenf("websocket channel needs a URL")
+	}
+
+	if o.ConnectTimeout == 0 {
+		o.ConnectTimeout = 1000 // ms
+	}
+
+	bufSize := o.BufferSize
+	if bufSize == 0 {
+		bufSize = DefaultWebSocketBufferSize
+	}
+
+	return &WebSocket{
+		opts: &o,
+		c:    make(chan dsl.Msg, bufSize),
+	}, nil
+}
+
+func (c *WebSocket) Kind() dsl.ChanKind {
+	return "websocket"
+}
+
+// dial performs the opening handshake.
+func (c *WebSocket) dial(ctx *dsl.Ctx) (*ws.Conn, error) {
+	d := &ws.Dialer{
+		HandshakeTimeout: time.Duration(c.opts.ConnectTimeout) * time.Millisecond,
+		Subprotocols:     c.opts.Subprotocols,
+		Proxy:            http.ProxyFromEnvironment,
+	}
+	if c.opts.Insecure {
+		d.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: true,
+		}
+	}
+
+	conn, resp, err := d.DialContext(ctx, c.opts.URL, http.Header(c.opts.Headers))
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("failed to connect to %s (%s): %w", c.opts.URL, resp.Status, err)
+		}
+		return nil, fmt.Errorf("failed to connect to %s: %w", c.opts.URL, err)
+	}
+
+	return conn, nil
+}
+
+func (c *WebSocket) Open(ctx *dsl.Ctx) error {
+	if c.conn != nil {
+		c.Close(ctx)
+	}
+
+	ctx.Logf("WebSocket opening %s", c.opts.URL)
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx.Logf("WebSocket subprotocol '%s'", conn.Subprotocol())
+
+	conn.SetPingHandler(func(data string) error {
+		c.forward(ctx, "ping", data)
+		err := conn.WriteControl(ws.PongMessage, []byte(data), time.Now().Add(time.Second))
+		if err == ws.ErrCloseSent {
+			return nil
+		}
+		return err
+	})
+
+	conn.SetPongHandler(func(data string) error {
+		c.forward(ctx, "pong", data)
+		return nil
+	})
+
+	c.conn = conn
+
+	go c.read(ctx, conn)
+
+	return nil
+}
+
+// read forwards the frames received on the connection until the
+// connection closes.
+func (c *WebSocket) read(ctx *dsl.Ctx, conn *ws.Conn) {
+	for {
+		kind, bs, err := conn.ReadMessage()
+		if err != nil {
+			if ce, is := err.(*ws.CloseError); is {
+				c.forward(ctx, "close", dsl.JSON(&WebSocketClose{
+					Code: ce.Code,
+					Text: ce.Text,
+				}))
+			} else {
+				ctx.Logf("WebSocket %s read: %v", c.opts.URL, err)
+			}
+			return
+		}
+
+		topic := "text"
+		if kind == ws.BinaryMessage {
+			topic = "binary"
+		}
+		c.forward(ctx, topic, string(bs))
+	}
+}
+
+func (c *WebSocket) forward(ctx *dsl.Ctx, topic, payload string) {
+	if err := c.To(ctx, dsl.Msg{Topic: topic, Payload: payload}); err != nil {
+		ctx.Warnf("warning: %s To for WebSocket %s", err, c.opts.URL)
+	}
+}
+
+func (c *WebSocket) Close(ctx *dsl.Ctx) error {
+	ctx.Logf("WebSocket closing %s", c.opts.URL)
+	if c.conn == nil {
+		return nil
+	}
+	msg := ws.FormatCloseMessage(ws.CloseNormalClosure, "")
+	if err := c.conn.WriteControl(ws.CloseMessage, msg, time.Now().Add(time.Second)); err != nil && err != ws.ErrCloseSent {
+		ctx.Logf("WebSocket %s close frame: %v", c.opts.URL, err)
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// Ping performs the opening handshake and then disconnects.
+func (c *WebSocket) Ping(ctx *dsl.Ctx) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (c *WebSocket) Sub(ctx *dsl.Ctx, topic string) error {
+	return fmt.Errorf("%T doesn't support 'sub'", c)
+}
+
+// closeMessage parses an optional "CODE REASON" payload.
+func closeMessage(payload string) ([]byte, error) {
+	payload = strings.TrimSpace(payload)
+	if payload == "" {
+		return ws.FormatCloseMessage(ws.CloseNormalClosure, ""), nil
+	}
+	parts := strings.SplitN(payload, " ", 2)
+	code, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("bad close code '%s'", parts[0])
+	}
+	var text string
+	if len(parts) == 2 {
+		text = parts[1]
+	}
+	return ws.FormatCloseMessage(code, text), nil
+}
+
+func (c *WebSocket) Pub(ctx *dsl.Ctx, m dsl.Msg) error {
+	ctx.Logf("WebSocket Pub %s", m.Topic)
+
+	if c.conn == nil {
+		return fmt.Errorf("websocket channel to %s isn't open", c.opts.URL)
+	}
+
+	payload, err := dsl.MaybeSerialize(m.Payload)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(time.Second)
+
+	switch m.Topic {
+	case "", "text":
+		c.wlock.Lock()
+		defer c.wlock.Unlock()
+		return c.conn.WriteMessage(ws.TextMessage, []byte(payload))
+	case "binary":
+		c.wlock.Lock()
+		defer c.wlock.Unlock()
+		return c.conn.WriteMessage(ws.BinaryMessage, []byte(payload))
+	case "ping":
+		return c.conn.WriteControl(ws.PingMessage, []byte(payload), deadline)
+	case "close":
+		msg, err := closeMessage(payload)
+		if err != nil {
+			return err
+		}
+		return c.conn.WriteControl(ws.CloseMessage, msg, deadline)
+	default:
+		return fmt.Errorf("unknown websocket frame type '%s' (want text, binary, ping, or close)", m.Topic)
+	}
+}
+
+func (c *WebSocket) Recv(ctx *dsl.Ctx) chan dsl.Msg {
+	return c.c
+}
+
+// Kill closes the underlying network connection without a close
+// frame.
+func (c *WebSocket) Kill(ctx *dsl.Ctx) error {
+	if c.conn == nil {
+		return fmt.Errorf("websocket channel to %s isn't open", c.opts.URL)
+	}
+	return c.conn.UnderlyingConn().Close()
+}
+
+func (c *WebSocket) To(ctx *dsl.Ctx, m dsl.Msg) error {
+	ctx.Logf("WebSocket To %s", m.Topic)
+	ctx.Logdf("     %s", m.Payload)
+	m.ReceivedAt = time.Now().UTC()
+	select {
+	case <-ctx.Done():
+	case c.c <- m:
+	default:
+		panic("Warning: WebSocket channel full")
+	}
+	return nil
+}
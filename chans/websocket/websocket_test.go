@@ -0,0 +1,154 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+
+	ws "github.com/gorilla/websocket"
+)
+
+func TestDocs(t *testing.T) {
+	(&WebSocket{}).DocSpec().Write("websocket")
+}
+
+// echo is a WebSocket server that echoes data frames, checks for a
+// header, and closes the connection when it receives "bye".
+func echo(t *testing.T) *httptest.Server {
+	u := ws.Upgrader{
+		Subprotocols: []string{"plax"},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Test") != "queso" {
+			http.Error(w, "no queso", http.StatusForbidden)
+			return
+		}
+		conn, err := u.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			kind, bs, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if string(bs) == "bye" {
+				msg := ws.FormatCloseMessage(ws.CloseNormalClosure, "bye")
+				conn.WriteControl(ws.CloseMessage, msg, time.Now().Add(time.Second))
+				return
+			}
+			if err := conn.WriteMessage(kind, bs); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func recv(t *testing.T, ctx *dsl.Ctx, c dsl.Chan) dsl.Msg {
+	select {
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout")
+	case m := <-c.Recv(ctx):
+		return m
+	}
+	panic("unreachable")
+}
+
+func TestWebSocket(t *testing.T) {
+	var (
+		ctx = dsl.NewCtx(context.Background())
+		ts  = echo(t)
+		url = "ws" + strings.TrimPrefix(ts.URL, "http")
+	)
+
+	c, err := NewWebSocketChan(ctx, &WebSocketOpts{
+		URL: url,
+		Headers: map[string][]string{
+			"X-Test": {"queso"},
+		},
+		Subprotocols: []string{"plax"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close(ctx)
+
+	if err = c.Pub(ctx, dsl.Msg{Payload: `{"want":"tacos"}`}); err != nil {
+		t.Fatal(err)
+	}
+	if m := recv(t, ctx, c); m.Topic != "text" || m.Payload != `{"want":"tacos"}` {
+		t.Fatal(dsl.JSON(m))
+	}
+
+	if err = c.Pub(ctx, dsl.Msg{Topic: "binary", Payload: "chips"}); err != nil {
+		t.Fatal(err)
+	}
+	if m := recv(t, ctx, c); m.Topic != "binary" || m.Payload != "chips" {
+		t.Fatal(dsl.JSON(m))
+	}
+
+	if err = c.Pub(ctx, dsl.Msg{Topic: "ping", Payload: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if m := recv(t, ctx, c); m.Topic != "pong" || m.Payload != "hello" {
+		t.Fatal(dsl.JSON(m))
+	}
+
+	if err = c.Pub(ctx, dsl.Msg{Topic: "smoke", Payload: "signal"}); err == nil {
+		t.Fatal("expected an error for an unknown frame type")
+	}
+
+	if err = c.Pub(ctx, dsl.Msg{Payload: "bye"}); err != nil {
+		t.Fatal(err)
+	}
+	if m := recv(t, ctx, c); m.Topic != "close" || m.Payload != `{"code":1000,"text":"bye"}` {
+		t.Fatal(dsl.JSON(m))
+	}
+}
+
+func TestWebSocketHandshakeFails(t *testing.T) {
+	var (
+		ctx = dsl.NewCtx(context.Background())
+		ts  = echo(t)
+		url = "ws" + strings.TrimPrefix(ts.URL, "http")
+	)
+
+	c, err := NewWebSocketChan(ctx, &WebSocketOpts{
+		URL: url,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Open(ctx); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatal(err)
+	}
+}
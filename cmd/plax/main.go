@@ -66,6 +66,7 @@ func main() {
 		// We make then their own type to enable flag.Var to parse multiple values.
 		bindings          = make(dsl.Bindings)
 		includeDirs       = IncludeDirs{"."}
+		chanPlugins       = ChanPlugins{}
 		specFilename      = flag.String("test", "", "Filename for test specification")
 		dir               = flag.String("dir", "", "Directory containing test specs")
 		list              = flag.Bool("list", false, "Show report of known tests; don't run anything.  Assumes -dir.")
@@ -81,6 +82,9 @@ func main() {
 		logLevel          = flag.String("log", "info", "log level (info, debug, none)")
 		retry             = flag.String("retry", "", `Specify retries: number or {"N":N,"Delay":"1s","DelayFactor":1.5}`)
 		redact            = flag.Bool("redact", false, "Use redaction gear")
+		includeTimeout    = flag.Duration("include-timeout", dsl.DefaultIncludeTimeout, "Timeout for fetching each http(s) include")
+		includeHeader     = flag.String("include-header", "", `Header ("Name: value") for fetching http(s) includes`)
+		repl              = flag.Bool("repl", false, "Execute steps interactively (in the test given by -test, if any)")
 
 		testRedactPattern = flag.String("check-redact-regexp", "", "regular expression to use for checking redactions (with no test executed)")
 		testRedactString  = flag.String("check-redact", "", "input string to use for -check-redact-regexp")
@@ -88,6 +92,7 @@ func main() {
 
 	flag.Var(&bindings, "p", "Parameter values: PARAM=VALUE")
 	flag.Var(&includeDirs, "I", "YAML include directories")
+	flag.Var(&chanPlugins, "chan-plugin", "Go plugin (.so) that registers channel types")
 
 	flag.Parse()
 
@@ -106,6 +111,12 @@ func main() {
 		log.Printf("plax version %s %s %s\n", version, commit, date)
 	}
 
+	for _, filename := range chanPlugins {
+		if _, err := dsl.LoadChanPlugin(dsl.NewCtx(context.Background()), filename); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	if *listChanTypes {
 		for name, _ := range dsl.TheChanRegistry {
 			fmt.Printf("%s\n", name)
@@ -113,6 +124,20 @@ func main() {
 		return
 	}
 
+	if *repl {
+		iv := invoke.Invocation{
+			Bindings:    bindings,
+			Filename:    *specFilename,
+			IncludeDirs: includeDirs,
+			LogLevel:    *logLevel,
+			Redact:      *redact,
+		}
+		if err := iv.REPL(context.Background(), os.Stdin, os.Stdout); err != nil {
+			log.Fatalf("REPL broken: %s", err)
+		}
+		return
+	}
+
 	if *specFilename == "" && *dir == "" {
 		fmt.Fprintf(os.Stderr, "To run a test, use \"-test FILENAME\" or \"-dir DIR\"\n\n")
 		flag.Usage()
@@ -134,6 +159,8 @@ func main() {
 		ComplainOnAnyError: *nonzeroOnAnyError,
 		Retry:              *retry,
 		Redact:             *redact,
+		IncludeTimeout:     *includeTimeout,
+		IncludeHeader:      *includeHeader,
 	}
 
 	ts, err := iv.Exec(context.Background())
@@ -173,6 +200,21 @@ func (ps *IncludeDirs) Set(value string) error {
 	return nil
 }
 
+// ChanPlugins are Go plugins that register channel types.
+//
+// We make an explicit type to enable flag.Var to parse multiple
+// parameters.
+type ChanPlugins []string
+
+func (ps *ChanPlugins) String() string {
+	return "FILENAME"
+}
+
+func (ps *ChanPlugins) Set(value string) error {
+	*ps = append(*ps, value)
+	return nil
+}
+
 type JSONTestSuite struct {
 	Type   string
 	Time   time.Time
@@ -20,17 +20,25 @@ package async
 
 import (
 	"context"
-	"fmt"
 	"sync"
 	"time"
 )
 
 // Parallel calls the set of tasks in parallel using the given context
-func Parallel(ctx context.Context, tfs ...*TaskFunc) ([]TaskResult, error) {
+//
+// At most maxConcurrency tasks execute at the same time.  A
+// maxConcurrency less than 1 executes all of the tasks at once.  The
+// TaskResults are in the order of the given tasks regardless of the
+// order in which the tasks complete.
+//
+// Once the context is done, no more tasks start and Parallel returns
+// without waiting for the running tasks, which can't be interrupted.
+// They finish in the background, and their results are discarded.
+// Each task without a result gets the context's error, which
+// Parallel also returns.
+func Parallel(ctx context.Context, maxConcurrency int, tfs ...*TaskFunc) (TaskResults, error) {
 	tasks := make([]*Task, len(tfs))
 	taskResults := make([]TaskResult, len(tfs))
-	resch := make(chan TaskResult)
-	count := 0
 
 	for index, tf := range tfs {
 		task, err := NewTask(index, tf)
@@ -41,30 +49,95 @@ func Parallel(ctx context.Context, tfs ...*TaskFunc) ([]TaskResult, error) {
 		tasks[index] = task
 	}
 
+	if maxConcurrency < 1 || len(tasks) < maxConcurrency {
+		maxConcurrency = len(tasks)
+	}
+
+	// Queue every task up front so that the workers never block
+	// on the queue, and buffer the results so that the workers
+	// never block on the results either.  That way a worker can
+	// always exit once the context is done, even after Parallel
+	// has returned.
+	work := make(chan *Task, len(tasks))
 	for _, task := range tasks {
-		if task != nil {
-			count++
-			task.invoke(ctx, resch)
-		}
+		work <- task
 	}
+	close(work)
+
+	resch := make(chan TaskResult, len(tasks))
+
+	// exited is closed when all of the workers have exited (which
+	// is before all of the tasks are done only when the context
+	// is done).
+	var (
+		workers sync.WaitGroup
+		exited  = make(chan struct{})
+	)
+	workers.Add(maxConcurrency)
+
+	for w := 0; w < maxConcurrency; w++ {
+		go func() {
+			defer workers.Done()
+			for task := range work {
+				if ctx.Err() != nil {
+					return
+				}
+				resch <- task.call(ctx)
+			}
+		}()
+	}
+
+	go func() {
+		workers.Wait()
+		close(exited)
+	}()
 
-	for c := 0; c < count; c++ {
+	// When all of the tasks are done, the workers are about to
+	// exit, so wait for them (so that none of them outlives the
+	// call).
+collecting:
+	for c := 0; ; c++ {
+		if c == len(tasks) {
+			<-exited
+			break
+		}
 		select {
+		case res := <-resch:
+			taskResults[res.index] = res
 		case <-ctx.Done():
-			err := fmt.Errorf("context canceled")
-			// Set error on incomplete tasks
-			//taskResults[res.index] = err
-			return taskResults, err
+			break collecting
+		case <-exited:
+			break collecting
+		}
+	}
+
+	// Take the results that are already in.
+	for more := true; more; {
+		select {
 		case res := <-resch:
 			taskResults[res.index] = res
+		default:
+			more = false
+		}
+	}
+
+	var err error
+	for i, task := range tasks {
+		if !taskResults[i].Done {
+			err = ctx.Err()
+			taskResults[i] = TaskResult{
+				index: i,
+				Name:  task.name,
+				Error: err,
+			}
 		}
 	}
 
-	return taskResults, nil
+	return taskResults, err
 }
 
 // ParallelWithTimeout calls the set of tasks in parallel using the given context with timeout
-func ParallelWithTimeout(ctx context.Context, timeout time.Duration, tfs ...*TaskFunc) (TaskResults, error) {
+func ParallelWithTimeout(ctx context.Context, timeout time.Duration, maxConcurrency int, tfs ...*TaskFunc) (TaskResults, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
@@ -77,7 +150,7 @@ func ParallelWithTimeout(ctx context.Context, timeout time.Duration, tfs ...*Tas
 	wg.Add(1)
 
 	go func() {
-		res, err = Parallel(ctx, tfs...)
+		res, err = Parallel(ctx, maxConcurrency, tfs...)
 
 		wg.Done()
 	}()
@@ -21,6 +21,7 @@ package async
 import (
 	"context"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 )
@@ -35,7 +36,7 @@ func TestParallel(t *testing.T) {
 	// 	},
 	// 	func() (string, error) { return sleepAndSayWithError("I like tacos!", nil) },
 	// 	func() { sleep() })
-	results, err := Parallel(context.Background(), testFuncTasks...)
+	results, err := Parallel(context.Background(), 0, testFuncTasks...)
 
 	if err != nil {
 		t.Error(err)
@@ -49,7 +50,7 @@ func TestParallelWithBadFuncNumberReturnValues(t *testing.T) {
 	testFuncs := append(testFuncTasks, &badTaskFunc1)
 
 	// Output will be array of results or an error
-	_, err := Parallel(context.Background(), testFuncs...)
+	_, err := Parallel(context.Background(), 0, testFuncs...)
 
 	if err == nil {
 		t.Errorf("expected bad function")
@@ -61,7 +62,7 @@ func TestParallelWithBadFuncLastReturnValueNotError(t *testing.T) {
 	testFuncs := append(testFuncTasks, &badTaskFunc2)
 
 	// Output will be array of results or an error
-	_, err := Parallel(context.Background(), testFuncs...)
+	_, err := Parallel(context.Background(), 0, testFuncs...)
 
 	if err == nil {
 		t.Errorf("expected bad function")
@@ -73,7 +74,7 @@ func TestParallelWithNilTaskFunc(t *testing.T) {
 	testFuncs := append(testFuncTasks, nil)
 
 	// Output will be array of results or an error
-	_, err := Parallel(context.Background(), testFuncs...)
+	_, err := Parallel(context.Background(), 0, testFuncs...)
 
 	if err == nil {
 		t.Errorf("expected bad function")
@@ -85,7 +86,7 @@ func TestParallelWithNilFunc(t *testing.T) {
 	testFuncs := append(testFuncTasks, &badTaskFunc3)
 
 	// Output will be array of results or an error
-	_, err := Parallel(context.Background(), testFuncs...)
+	_, err := Parallel(context.Background(), 0, testFuncs...)
 
 	if err == nil {
 		t.Errorf("expected bad function")
@@ -93,7 +94,7 @@ func TestParallelWithNilFunc(t *testing.T) {
 }
 
 func TestParallelWithTimeout(t *testing.T) {
-	results, err := ParallelWithTimeout(context.Background(), 3*time.Second, testFuncTasks...)
+	results, err := ParallelWithTimeout(context.Background(), 3*time.Second, 0, testFuncTasks...)
 
 	if err != nil {
 		t.Logf("Error: %v\n", err)
@@ -103,3 +104,167 @@ func TestParallelWithTimeout(t *testing.T) {
 		t.Error(fmt.Errorf("Error in results: %v", results))
 	}
 }
+
+func TestParallelWithMaxConcurrency(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		running int
+		most    int
+		tfs     = make([]*TaskFunc, 0, 8)
+	)
+
+	for i := 0; i < 8; i++ {
+		i := i
+		tfs = append(tfs, &TaskFunc{
+			Name: fmt.Sprintf("task-%d", i),
+			Func: func() int {
+				mu.Lock()
+				running++
+				if most < running {
+					most = running
+				}
+				mu.Unlock()
+
+				// Later tasks finish first.
+				time.Sleep(time.Duration(8-i) * 10 * time.Millisecond)
+
+				mu.Lock()
+				running--
+				mu.Unlock()
+
+				return i
+			},
+		})
+	}
+
+	results, err := Parallel(context.Background(), 3, tfs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if 3 < most {
+		t.Errorf("expected at most 3 concurrent tasks, got %d", most)
+	}
+
+	for i, result := range results {
+		if result.Result != i {
+			t.Errorf("expected result %d at index %d, got %v", i, i, result.Result)
+		}
+	}
+}
+
+func TestParallelWithPanic(t *testing.T) {
+	tfs := []*TaskFunc{
+		{
+			Name: "panics",
+			Func: func() error {
+				panic("tacos are gone")
+			},
+		},
+		{
+			Name: "fine",
+			Func: func() string {
+				return "I like tacos!"
+			},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	results, err := Parallel(ctx, 1, tfs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if results[0].Error == nil {
+		t.Errorf("expected an error from the panicking task")
+	}
+
+	if results[1].Error != nil || results[1].Result != "I like tacos!" {
+		t.Errorf("unexpected result after a panic: %v", results[1])
+	}
+}
+
+func TestParallelWithCanceledContext(t *testing.T) {
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		finished    = make(chan bool)
+		tfs         = []*TaskFunc{
+			{
+				Name: "cancels",
+				Func: func() string {
+					cancel()
+					time.Sleep(50 * time.Millisecond)
+					close(finished)
+					return "I like tacos!"
+				},
+			},
+			{
+				Name: "skipped",
+				Func: func() string {
+					return "I like chips!"
+				},
+			},
+		}
+	)
+	defer cancel()
+
+	results, err := Parallel(ctx, 1, tfs...)
+	if err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+
+	select {
+	case <-finished:
+		t.Errorf("waited for the running task")
+	default:
+	}
+
+	for i, name := range []string{"cancels", "skipped"} {
+		if results[i].Done || results[i].Error != context.Canceled || results[i].Name != name {
+			t.Errorf("unexpected result for %s: %v", name, results[i])
+		}
+	}
+
+	// The running task still finishes in the background.
+	<-finished
+}
+
+func TestParallelWithTimeoutHung(t *testing.T) {
+	var (
+		release = make(chan bool)
+		tfs     = []*TaskFunc{
+			{
+				Name: "fine",
+				Func: func() string {
+					return "I like tacos!"
+				},
+			},
+			{
+				Name: "hangs",
+				Func: func() string {
+					<-release
+					return "I like chips!"
+				},
+			},
+		}
+	)
+	defer close(release)
+
+	then := time.Now()
+	results, err := ParallelWithTimeout(context.Background(), 50*time.Millisecond, 2, tfs...)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(then); time.Second < elapsed {
+		t.Errorf("returned after %v", elapsed)
+	}
+
+	if !results[0].Done || results[0].Result != "I like tacos!" {
+		t.Errorf("unexpected result for the finished task: %v", results[0])
+	}
+	if results[1].Done || results[1].Error != context.DeadlineExceeded {
+		t.Errorf("unexpected result for the hung task: %v", results[1])
+	}
+}
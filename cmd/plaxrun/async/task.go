@@ -128,58 +128,25 @@ func NewTask(index int, tf *TaskFunc) (*Task, error) {
 	}, nil
 }
 
-// invoke calls the function of the task and sends its
-// result to the res channel.  This work is performed in a new goroutine.
-func (t *Task) invoke(ctx context.Context, resch chan TaskResult) {
-	result := &TaskResult{
+// call calls the function of the task and returns its result.  This
+// work is performed syncronously.
+//
+// A panic in the function is recovered and reported as the Error of
+// the TaskResult.
+func (t *Task) call(ctx context.Context) (result TaskResult) {
+	result = TaskResult{
 		index: t.index,
 		Name:  t.name,
 		Done:  true,
 	}
 	params := []reflect.Value{}
 
-	go func() {
-		res := t.valueOf.Call(params)
-		switch t.returnType {
-		case TaskReturnErrorOnly:
-			if v := res[0].Interface(); v != nil {
-				err, ok := v.(error)
-				if !ok {
-					result.Error = fmt.Errorf("value is not an error")
-				}
-				result.Error = err
-			}
-		case TaskReturnResultOnly:
-			result.Result = res[0].Interface()
-		case TaskReturnResultAndError:
-			result.Result = res[0].Interface()
-			if v := res[1].Interface(); v != nil {
-				err, ok := v.(error)
-				if !ok {
-					result.Error = fmt.Errorf("value is not an error")
-				}
-				result.Error = err
-			}
-		default:
-			result.Error = fmt.Errorf("invalid function return type (%d)", t.returnType)
-		}
-
-		select {
-		case <-ctx.Done():
-		case resch <- *result:
+	defer func() {
+		if r := recover(); r != nil {
+			result.Result = nil
+			result.Error = fmt.Errorf("task %s panicked: %v", t.name, r)
 		}
 	}()
-}
-
-// call calls the function of the task and sends its
-// result to the res channel.  This work is performed syncronously
-func (t *Task) call(ctx context.Context) TaskResult {
-	result := TaskResult{
-		index: t.index,
-		Name:  t.name,
-		Done:  true,
-	}
-	params := []reflect.Value{}
 
 	res := t.valueOf.Call(params)
 	switch t.returnType {
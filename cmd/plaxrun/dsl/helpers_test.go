@@ -0,0 +1,99 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/Comcast/plax/junit"
+)
+
+// fakePlugin passes the tests named by its PluginDef.
+type fakePlugin struct {
+	name string
+}
+
+func (p *fakePlugin) Invoke(ctx context.Context) (*junit.TestSuite, error) {
+	ts := junit.NewTestSuite(p.name)
+	tc := junit.NewTestCase(p.name, "")
+	tc.Finish(junit.Passed)
+	ts.Add(*tc)
+	ts.Finish()
+	return ts, nil
+}
+
+// failingPlugin always fails.
+type failingPlugin struct {
+	name string
+}
+
+func (p *failingPlugin) Invoke(ctx context.Context) (*junit.TestSuite, error) {
+	ts := junit.NewTestSuite(p.name)
+	tc := junit.NewTestCase(p.name, "")
+	tc.Finish(junit.Failed, "no tacos")
+	ts.Add(*tc)
+	ts.Finish()
+	return ts, nil
+}
+
+func init() {
+	ThePluginRegistry.Register("fake", func(def PluginDef) (Plugin, error) {
+		name, err := def.GetPluginDefName()
+		if err != nil {
+			return nil, err
+		}
+		return &fakePlugin{name: name}, nil
+	})
+
+	ThePluginRegistry.Register("failing", func(def PluginDef) (Plugin, error) {
+		name, _ := def.GetPluginDefName()
+		return &failingPlugin{name: name}, nil
+	})
+}
+
+// writeRunSpec writes the test run specification as run.yaml (and an
+// empty pass.yaml for its tests) to a new temporary directory and
+// returns the filename of run.yaml.
+func writeRunSpec(t *testing.T, spec string) string {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "run.yaml")
+
+	if err := ioutil.WriteFile(filename, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "pass.yaml"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return filename
+}
+
+// runOptions returns quiet RunOptions for the test run specification
+// in the file.
+func runOptions(filename string) RunOptions {
+	opts := DefaultRunOptions()
+	opts.Filename = filename
+	opts.Dir = filepath.Dir(filename)
+	opts.LogLevel = "none"
+	opts.Verbose = false
+	return opts
+}
@@ -59,6 +59,16 @@ const (
 	PluginDefRetryKey = "Retry"
 	// PluginDefIncludeDirsKey of the PluginDef map
 	PluginDefIncludeDirsKey = "IncludeDirs"
+	// PluginDefCaptureLogsKey of the PluginDef map
+	PluginDefCaptureLogsKey = "CaptureLogs"
+	// PluginDefCaptureBindingsKey of the PluginDef map
+	PluginDefCaptureBindingsKey = "CaptureBindings"
+	// PluginDefMsgHistoryKey of the PluginDef map
+	PluginDefMsgHistoryKey = "MsgHistory"
+	// PluginDefConnectBackoffKey of the PluginDef map
+	PluginDefConnectBackoffKey = "ConnectBackoff"
+	// PluginDefChanPoolKey of the PluginDef map
+	PluginDefChanPoolKey = "ChanPool"
 )
 
 var (
@@ -281,6 +291,87 @@ func (pd PluginDef) GetPluginDefNonzeroOnAnyErrorKey() (bool, error) {
 	return ret, nil
 }
 
+// GetPluginDefCaptureLogs returns the CaptureLogs flag
+func (pd PluginDef) GetPluginDefCaptureLogs() (bool, error) {
+	value, ok := pd[PluginDefCaptureLogsKey]
+	if !ok || value == nil {
+		return false, nil
+	}
+
+	ret, ok := value.(*bool)
+	if !ok {
+		return false, fmt.Errorf("%s is not a bool", PluginDefCaptureLogsKey)
+	}
+
+	return ret != nil && *ret, nil
+}
+
+// GetPluginDefCaptureBindings returns the CaptureBindings flag
+func (pd PluginDef) GetPluginDefCaptureBindings() (bool, error) {
+	value, ok := pd[PluginDefCaptureBindingsKey]
+	if !ok || value == nil {
+		return false, nil
+	}
+
+	ret, ok := value.(*bool)
+	if !ok {
+		return false, fmt.Errorf("%s is not a bool", PluginDefCaptureBindingsKey)
+	}
+
+	return ret != nil && *ret, nil
+}
+
+// GetPluginDefMsgHistory returns the MsgHistory size (or zero)
+func (pd PluginDef) GetPluginDefMsgHistory() (int, error) {
+	value, ok := pd[PluginDefMsgHistoryKey]
+	if !ok || value == nil {
+		return 0, nil
+	}
+
+	ret, ok := value.(*int)
+	if !ok {
+		return 0, fmt.Errorf("%s is not an int", PluginDefMsgHistoryKey)
+	}
+
+	if ret == nil {
+		return 0, nil
+	}
+
+	return *ret, nil
+}
+
+// GetPluginDefConnectBackoff returns the default Backoff for opening
+// channels (or nil)
+func (pd PluginDef) GetPluginDefConnectBackoff() (*dsl.Backoff, error) {
+	value, ok := pd[PluginDefConnectBackoffKey]
+	if !ok || value == nil {
+		return nil, nil
+	}
+
+	ret, ok := value.(*dsl.Backoff)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a *dsl.Backoff", PluginDefConnectBackoffKey)
+	}
+
+	return ret, nil
+}
+
+// GetPluginDefChanPool returns the ChanPool shared by the tests of
+// a group (or nil)
+func (pd PluginDef) GetPluginDefChanPool() (*dsl.ChanPool, error) {
+	value, ok := pd[PluginDefChanPoolKey]
+	if !ok || value == nil {
+		return nil, nil
+	}
+
+	ret, ok := value.(*dsl.ChanPool)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a *dsl.ChanPool", PluginDefChanPoolKey)
+	}
+
+	return ret, nil
+}
+
 // GetPluginDefIncludeDirsKey returns the Includes list
 func (pd PluginDef) GetPluginDefIncludeDirsKey() ([]string, error) {
 	value, ok := pd[PluginDefIncludeDirsKey]
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Comcast/plax/junit"
+	"github.com/google/uuid"
+)
+
+// allureResult is an Allure test result (a "<uuid>-result.json"
+// file).
+type allureResult struct {
+	UUID          string               `json:"uuid"`
+	HistoryID     string               `json:"historyId"`
+	Name          string               `json:"name"`
+	FullName      string               `json:"fullName"`
+	Status        string               `json:"status"`
+	StatusDetails *allureStatusDetails `json:"statusDetails,omitempty"`
+	Stage         string               `json:"stage"`
+	Start         int64                `json:"start,omitempty"`
+	Stop          int64                `json:"stop,omitempty"`
+	Labels        []allureLabel        `json:"labels"`
+	Attachments   []allureAttachment   `json:"attachments,omitempty"`
+}
+
+type allureStatusDetails struct {
+	Message string `json:"message"`
+}
+
+type allureLabel struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type allureAttachment struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Type   string `json:"type"`
+}
+
+// allureStatus maps a TestCaseStatus to an Allure status.  Allure
+// calls errors "broken".
+func allureStatus(status junit.TestCaseStatus) string {
+	switch status {
+	case junit.Passed:
+		return "passed"
+	case junit.Failed:
+		return "failed"
+	case junit.Error:
+		return "broken"
+	case junit.Skipped:
+		return "skipped"
+	default:
+		return "unknown"
+	}
+}
+
+// WriteAllure writes an Allure result file for each TestCase of each
+// TestSuite of the Report to the directory, which can then be given
+// to "allure generate".
+//
+// The captured logs of a TestCase are attachments.  Missing parent
+// directories are created.
+func (tr *TestRun) WriteAllure(dir string) error {
+	if tr.Report == nil {
+		return fmt.Errorf("test run %s has no report", tr.Name)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to make directory for Allure results: %w", err)
+	}
+
+	write := func(filename string, bs []byte) error {
+		if err := ioutil.WriteFile(filepath.Join(dir, filename), bs, 0644); err != nil {
+			return fmt.Errorf("failed to write Allure results: %w", err)
+		}
+		return nil
+	}
+
+	for _, ts := range tr.Report.TestSuite {
+		if ts == nil {
+			continue
+		}
+		for _, tc := range ts.TestCase {
+			fullName := timingName(ts, tc)
+
+			r := allureResult{
+				UUID:      uuid.New().String(),
+				HistoryID: fmt.Sprintf("%x", md5.Sum([]byte(fullName))),
+				Name:      tc.Name,
+				FullName:  fullName,
+				Status:    allureStatus(tc.Status),
+				Stage:     "finished",
+				Labels: []allureLabel{
+					{Name: "suite", Value: ts.Name},
+					{Name: "framework", Value: "plax"},
+				},
+			}
+
+			if tc.Message != "" {
+				r.StatusDetails = &allureStatusDetails{
+					Message: tc.Message,
+				}
+			}
+
+			if tc.Started != nil {
+				r.Start = tc.Started.UnixNano() / 1e6
+				r.Stop = r.Start
+				if tc.Time != nil {
+					r.Stop = tc.Started.Add(*tc.Time).UnixNano() / 1e6
+				}
+			}
+
+			for _, log := range []struct {
+				name, text string
+			}{
+				{"system-out", tc.SystemOut},
+				{"system-err", tc.SystemErr},
+			} {
+				if log.text == "" {
+					continue
+				}
+				a := allureAttachment{
+					Name:   log.name,
+					Source: uuid.New().String() + "-attachment.txt",
+					Type:   "text/plain",
+				}
+				if err := write(a.Source, []byte(log.text)); err != nil {
+					return err
+				}
+				r.Attachments = append(r.Attachments, a)
+			}
+
+			bs, err := json.MarshalIndent(r, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal Allure result: %w", err)
+			}
+			if err = write(r.UUID+"-result.json", bs); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
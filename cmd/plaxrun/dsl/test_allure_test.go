@@ -0,0 +1,92 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Comcast/plax/cmd/plaxrun/plugins/report"
+	"github.com/Comcast/plax/junit"
+)
+
+func TestWriteAllure(t *testing.T) {
+	var (
+		elapsed = 1500 * time.Millisecond
+		started = time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	)
+
+	ts := junit.NewTestSuite("run:group:test")
+	ts.Add(junit.TestCase{Name: "fails", Status: junit.Failed, Started: &started, Time: &elapsed, Message: "no tacos"})
+	ts.Add(junit.TestCase{Name: "breaks", Status: junit.Error, Started: &started, Time: &elapsed, SystemOut: "tacos\n"})
+
+	tr := &TestRun{
+		Report: report.NewTestReport(),
+	}
+	tr.Report.TestSuite = append(tr.Report.TestSuite, ts)
+
+	dir := filepath.Join(t.TempDir(), "allure")
+	if err := tr.WriteAllure(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	filenames, err := filepath.Glob(filepath.Join(dir, "*-result.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filenames) != 2 {
+		t.Fatalf("unexpected result files %v", filenames)
+	}
+
+	results := make(map[string]allureResult)
+	for _, filename := range filenames {
+		bs, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r allureResult
+		if err = json.Unmarshal(bs, &r); err != nil {
+			t.Fatal(err)
+		}
+		results[r.Name] = r
+	}
+
+	fails := results["fails"]
+	if fails.Status != "failed" || fails.StatusDetails == nil || fails.StatusDetails.Message != "no tacos" {
+		t.Fatalf("unexpected result %#v", fails)
+	}
+	if fails.Start != started.UnixNano()/1e6 || fails.Stop-fails.Start != 1500 {
+		t.Fatalf("unexpected start %d and stop %d", fails.Start, fails.Stop)
+	}
+
+	breaks := results["breaks"]
+	if breaks.Status != "broken" || len(breaks.Attachments) != 1 {
+		t.Fatalf("unexpected result %#v", breaks)
+	}
+	bs, err := ioutil.ReadFile(filepath.Join(dir, breaks.Attachments[0].Source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != "tacos\n" {
+		t.Fatalf("unexpected attachment %q", bs)
+	}
+}
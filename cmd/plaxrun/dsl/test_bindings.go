@@ -0,0 +1,102 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	plaxDsl "github.com/Comcast/plax/dsl"
+)
+
+// readBindingsFile reads a YAML (or JSON) map of bindings.
+//
+// The file is found like an include, and it can itself have
+// includes.
+func readBindingsFile(ctx *plaxDsl.Ctx, filename string) (map[string]interface{}, error) {
+	bs, err := plaxDsl.FindInclude(ctx, filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bindings file: %w", err)
+	}
+
+	var x interface{}
+	if err := yaml.Unmarshal(bs, &x); err != nil {
+		return nil, fmt.Errorf("bindings file %s parse error: %w", filename, err)
+	}
+
+	if x == nil {
+		return map[string]interface{}{}, nil
+	}
+
+	if x, err = plaxDsl.Include(ctx, x, []string{}); err != nil {
+		return nil, fmt.Errorf("failed to process includes in bindings file %s: %w", filename, err)
+	}
+
+	m, is := x.(map[string]interface{})
+	if !is {
+		return nil, fmt.Errorf("bindings file %s should be a map, not a %T", filename, x)
+	}
+
+	return m, nil
+}
+
+// envBindings returns the bindings for the environment variables
+// (given as "NAME=value") with the prefix.  The prefix is removed and
+// the rest of the name is lowercased.
+//
+// Values are JSON-deserialized if possible.
+func envBindings(environ []string, prefix string) map[string]interface{} {
+	m := make(map[string]interface{})
+	if prefix == "" {
+		return m
+	}
+
+	for _, kv := range environ {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], prefix) {
+			continue
+		}
+
+		k := strings.ToLower(strings.TrimPrefix(parts[0], prefix))
+		if k == "" {
+			continue
+		}
+
+		var v interface{}
+		if err := json.Unmarshal([]byte(parts[1]), &v); err != nil {
+			v = parts[1]
+		}
+
+		m[k] = v
+	}
+
+	return m
+}
+
+// defaultBindings adds the bindings that aren't already bound.
+func defaultBindings(bs plaxDsl.Bindings, m map[string]interface{}) {
+	for k, v := range m {
+		if _, have := bs[k]; !have {
+			bs.SetKeyValue(k, v)
+		}
+	}
+}
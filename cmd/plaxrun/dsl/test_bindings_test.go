@@ -0,0 +1,92 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	plaxDsl "github.com/Comcast/plax/dsl"
+)
+
+func TestReadBindingsFile(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"bindings.yaml": "WAIT: 600\nMARGIN: 200\ninclude: more.json\n",
+		"more.json":     `{"WORLD": "universe", "LIST": [1, 2]}`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := plaxDsl.NewCtx(context.Background())
+	ctx.LogLevel = "none"
+	ctx.IncludeDirs = []string{dir}
+
+	m, err := readBindingsFile(ctx, "bindings.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bs := plaxDsl.Bindings{
+		"MARGIN": 100,
+	}
+	defaultBindings(bs, m)
+
+	if len(bs) != 4 || bs["WAIT"] != 600 || bs["MARGIN"] != 100 || bs["WORLD"] != "universe" {
+		t.Fatalf("unexpected bindings %v", bs)
+	}
+
+	if _, err := readBindingsFile(ctx, "missing.yaml"); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "list.yaml"), []byte("- WAIT\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := readBindingsFile(ctx, "list.yaml"); err == nil {
+		t.Fatal("expected an error for a list")
+	}
+}
+
+func TestEnvBindings(t *testing.T) {
+	environ := []string{
+		"PLAX_WAIT=600",
+		"PLAX_World=universe",
+		"PLAX_=nothing",
+		"HOME=/root",
+		`PLAX_LIST=[1, 2]`,
+	}
+
+	m := envBindings(environ, "PLAX_")
+
+	if len(m) != 3 || m["wait"] != float64(600) || m["world"] != "universe" || m["list"] == nil {
+		t.Fatalf("unexpected bindings %v", m)
+	}
+
+	if m := envBindings(environ, ""); len(m) != 0 {
+		t.Fatalf("unexpected bindings without a prefix %v", m)
+	}
+}
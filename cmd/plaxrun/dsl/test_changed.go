@@ -0,0 +1,203 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	plaxDsl "github.com/Comcast/plax/dsl"
+)
+
+// changedTests are the files that changed since the
+// TestRunParams.ChangedSince git ref, and they select the tests whose
+// files (or includes) changed.
+type changedTests struct {
+	// files are the absolute paths of the changed files.
+	files map[string]bool
+
+	// paths caches whether each test path changed.
+	paths map[string]bool
+}
+
+// newChangedTests finds the files that changed in the git repository
+// containing dir since the merge base of the ref and HEAD, including
+// the changes that aren't committed and the untracked files.
+func newChangedTests(dir, ref string) (*changedTests, error) {
+	root, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	root = strings.TrimSpace(root)
+
+	base, err := git(dir, "merge-base", ref, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	modified, err := git(dir, "diff", "--name-only", "-z", strings.TrimSpace(base))
+	if err != nil {
+		return nil, err
+	}
+
+	untracked, err := git(dir, "ls-files", "--others", "--exclude-standard", "--full-name", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	ct := &changedTests{
+		files: make(map[string]bool),
+		paths: make(map[string]bool),
+	}
+	for _, name := range strings.Split(modified+untracked, "\x00") {
+		if name != "" {
+			ct.files[filepath.Join(root, filepath.FromSlash(name))] = true
+		}
+	}
+
+	return ct, nil
+}
+
+// git executes the git command in the directory and returns its
+// standard output.
+func git(dir string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to execute git %s: %w (%s)", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// has reports whether the file changed.
+//
+// The paths from git have their symbolic links resolved, so the file's
+// are too.
+func (ct *changedTests) has(filename string) bool {
+	if path, err := filepath.EvalSymlinks(filename); err == nil {
+		filename = path
+	}
+	return ct.files[filename]
+}
+
+// changed reports whether the test at the path (a file or a directory
+// of tests) changed, which is the case when the file, a file in the
+// directory, or any of their (local) includes changed.
+//
+// A test whose includes can't be read is considered changed.
+func (ct *changedTests) changed(ctx *plaxDsl.Ctx, path string) bool {
+	if changed, have := ct.paths[path]; have {
+		return changed
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		// The error is reported when the test is processed.
+		ct.paths[path] = true
+		return true
+	}
+
+	filenames := []string{path}
+	if fi.IsDir() {
+		filenames = filenames[:0]
+		filepath.Walk(path, func(filename string, fi os.FileInfo, err error) error {
+			if err == nil && !fi.IsDir() {
+				switch filepath.Ext(filename) {
+				case ".yaml", ".yml":
+					filenames = append(filenames, filename)
+				}
+			}
+			return nil
+		})
+	}
+
+	changed := false
+	for _, filename := range filenames {
+		if ct.has(filename) {
+			changed = true
+			break
+		}
+		includes, err := includesOf(ctx, filename)
+		if err != nil {
+			ctx.Logdf("changed-since considers %s changed: %s", filename, err)
+			changed = true
+			break
+		}
+		for _, include := range includes {
+			if ct.has(include) {
+				changed = true
+				break
+			}
+		}
+		if changed {
+			break
+		}
+	}
+
+	ct.paths[path] = changed
+
+	return changed
+}
+
+// includesOf returns the absolute paths of the files that the YAML
+// file includes (directly or not).
+func includesOf(ctx *plaxDsl.Ctx, filename string) ([]string, error) {
+	bs, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	ictx := plaxDsl.NewCtx(ctx)
+	ictx.IncludeCache = plaxDsl.NewIncludeCache()
+	ictx.Dir = ctx.Dir
+	ictx.LogLevel = "none"
+	ictx.IncludeDirs = append([]string{filepath.Dir(filename)}, ctx.IncludeDirs...)
+
+	if _, err = plaxDsl.IncludeYAML(ictx, bs); err != nil {
+		return nil, err
+	}
+
+	return ictx.IncludeCache.Paths(), nil
+}
+
+// changedSince reports whether -changed-since selects the named test.
+func (tr TestRun) changedSince(ctx *plaxDsl.Ctx, name string, td TestDef) bool {
+	if tr.changed == nil {
+		return true
+	}
+
+	path := td.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(ctx.Dir, path)
+	}
+	if path, err := filepath.Abs(path); err == nil && tr.changed.changed(ctx, path) {
+		return true
+	}
+
+	ctx.Logdf("changed-since excluded %s test", name)
+
+	return false
+}
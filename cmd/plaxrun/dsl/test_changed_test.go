@@ -0,0 +1,109 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestChangedSince(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("no git")
+	}
+
+	dir := t.TempDir()
+
+	write := func(name, content string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run := func(args ...string) {
+		if _, err := git(dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("run.yaml", `name: run
+version: 0.0.1
+tests:
+  tacos: {path: tacos.yaml, version: fake}
+  queso: {path: queso.yaml, version: fake}
+  chips: {path: chips.yaml, version: fake}
+groups:
+  all:
+    tests:
+      - name: tacos
+      - name: queso
+      - name: chips
+`)
+	write("tacos.yaml", "spec:\n  include: salsa.yaml\n")
+	write("salsa.yaml", "phases: {}\n")
+	write("queso.yaml", "spec: {}\n")
+
+	run("init", "-q")
+	run("add", ".")
+	run("-c", "user.name=plax", "-c", "user.email=plax@example.com", "commit", "-q", "-m", "menu")
+
+	// The shared include changed, and chips is new.
+	write("salsa.yaml", "phases: {phase1: {steps: []}}\n")
+	write("chips.yaml", "spec: {}\n")
+
+	opts := runOptions(filepath.Join(dir, "run.yaml"))
+	opts.Groups = []string{"all"}
+	opts.ChangedSince = "HEAD"
+
+	tr, err := RunTests(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := tr.Report
+	if r.Total != 2 || r.TestSuite[0].Name != "run-0.0.1:all:chips" || r.TestSuite[1].Name != "run-0.0.1:all:tacos" {
+		t.Fatalf("unexpected report %#v", r)
+	}
+
+	// A change to the test run specification selects every test.
+	write("run.yaml", `name: run
+version: 0.0.1
+tests:
+  tacos: {path: tacos.yaml, version: fake}
+  queso: {path: queso.yaml, version: fake}
+groups:
+  all:
+    tests:
+      - name: tacos
+      - name: queso
+`)
+	if tr, err = RunTests(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+	if tr.Report.Total != 2 {
+		t.Fatalf("unexpected report %#v", tr.Report)
+	}
+
+	opts.ChangedSince = "no-such-ref"
+	if _, err = RunTests(context.Background(), opts); err == nil {
+		t.Fatal("expected an error for an unknown ref")
+	}
+}
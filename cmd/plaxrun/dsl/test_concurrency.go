@@ -0,0 +1,77 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"sync"
+
+	"github.com/Comcast/plax/junit"
+)
+
+// concurrencyGroups serializes the tests that share a concurrency
+// group (see TestDef.ConcurrencyGroup), which then never execute at
+// the same time (even with TestRunParams.MaxConcurrency).  Tests in
+// different concurrency groups (or in none) still execute in
+// parallel.
+type concurrencyGroups struct {
+	sync.Mutex
+
+	locks map[string]*sync.Mutex
+}
+
+func newConcurrencyGroups() *concurrencyGroups {
+	return &concurrencyGroups{
+		locks: make(map[string]*sync.Mutex),
+	}
+}
+
+// wrap makes a task func that holds the lock of the concurrency group
+// (if any) while it executes.
+//
+// The lock is only held during the execution of the test itself (and
+// not while waiting for setups or dependencies), so a test never
+// holds it while waiting for another test.
+func (cg *concurrencyGroups) wrap(group string, f func() (*junit.TestSuite, error)) func() (*junit.TestSuite, error) {
+	if cg == nil || group == "" {
+		return f
+	}
+
+	cg.Lock()
+	lock, have := cg.locks[group]
+	if !have {
+		lock = &sync.Mutex{}
+		cg.locks[group] = lock
+	}
+	cg.Unlock()
+
+	return func() (*junit.TestSuite, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return f()
+	}
+}
+
+// concurrencyGroupOf returns the concurrency group of the test, which
+// defaults to the concurrency group of its innermost group.
+func (tr TestRun) concurrencyGroupOf(td TestDef) string {
+	if td.ConcurrencyGroup != "" {
+		return td.ConcurrencyGroup
+	}
+	return tr.concurrencyGroup
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Comcast/plax/junit"
+)
+
+// concurrentPlugin takes a while and records how many of the tests
+// (by kind) execute at the same time.
+type concurrentPlugin struct {
+	name string
+	c    *concurrency
+}
+
+type concurrency struct {
+	sync.Mutex
+	active, most map[string]int
+}
+
+func (p *concurrentPlugin) Invoke(ctx context.Context) (*junit.TestSuite, error) {
+	kinds := []string{"any"}
+	if strings.Contains(p.name, "locked") || strings.Contains(p.name, "shared") {
+		kinds = append(kinds, "topic")
+	}
+
+	p.c.Lock()
+	for _, kind := range kinds {
+		if p.c.active[kind]++; p.c.most[kind] < p.c.active[kind] {
+			p.c.most[kind] = p.c.active[kind]
+		}
+	}
+	p.c.Unlock()
+
+	time.Sleep(100 * time.Millisecond)
+
+	p.c.Lock()
+	for _, kind := range kinds {
+		p.c.active[kind]--
+	}
+	p.c.Unlock()
+
+	ts := junit.NewTestSuite(p.name)
+	tc := junit.NewTestCase(p.name, "")
+	tc.Finish(junit.Passed)
+	ts.Add(*tc)
+	ts.Finish()
+	return ts, nil
+}
+
+func TestConcurrencyGroups(t *testing.T) {
+	c := &concurrency{
+		active: make(map[string]int),
+		most:   make(map[string]int),
+	}
+	ThePluginRegistry.Register("concurrent", func(def PluginDef) (Plugin, error) {
+		name, _ := def.GetPluginDefName()
+		return &concurrentPlugin{name: name, c: c}, nil
+	})
+
+	spec := `name: run
+version: 0.0.1
+tests:
+  locked: {path: pass.yaml, version: concurrent, concurrencyGroup: topic}
+  free: {path: pass.yaml, version: concurrent}
+groups:
+  shared:
+    concurrencyGroup: topic
+    tests:
+      - name: free
+  all:
+    tests:
+      - name: locked
+      - name: locked
+      - name: free
+    groups:
+      - name: shared
+`
+	filename := writeRunSpec(t, spec)
+
+	opts := runOptions(filename)
+	opts.Groups = []string{"all"}
+	opts.MaxConcurrency = 4
+
+	tr, err := RunTests(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tr.Report.Total != 4 || tr.Report.Passed != 4 {
+		t.Fatalf("unexpected report %#v", tr.Report)
+	}
+
+	if c.most["topic"] != 1 {
+		t.Fatalf("%d tests in the same concurrency group executed at the same time", c.most["topic"])
+	}
+	if c.most["any"] < 2 {
+		t.Fatalf("only %d tests executed at the same time", c.most["any"])
+	}
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Comcast/plax/junit"
+
+	plaxDsl "github.com/Comcast/plax/dsl"
+)
+
+// ErrRunTimeout is (wrapped by) the error returned by Exec when the
+// RunTimeout cut the test run short.
+var ErrRunTimeout = errors.New("test run timed out")
+
+// runDeadline aborts the remaining tests of a test run after its
+// RunTimeout.
+type runDeadline struct {
+	timeout time.Duration
+
+	// ctx is set by Exec and is done at the deadline.
+	ctx context.Context
+
+	// aborted counts the tests that the deadline cut short.
+	aborted int32
+}
+
+// start sets the deadline to the timeout from now.
+func (rd *runDeadline) start(ctx context.Context) func() {
+	if rd == nil {
+		return func() {}
+	}
+	var cancel func()
+	rd.ctx, cancel = context.WithTimeout(ctx, rd.timeout)
+	return cancel
+}
+
+// expired reports whether the deadline has passed.
+func (rd *runDeadline) expired() bool {
+	return rd != nil && rd.ctx != nil && rd.ctx.Err() != nil
+}
+
+// err returns an ErrRunTimeout when the deadline cut any test short.
+func (rd *runDeadline) err() error {
+	if rd == nil || atomic.LoadInt32(&rd.aborted) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w after %s", ErrRunTimeout, rd.timeout)
+}
+
+// bind returns a Ctx that is also canceled at the deadline.
+func (rd *runDeadline) bind(ctx *plaxDsl.Ctx) (*plaxDsl.Ctx, func()) {
+	if rd == nil || rd.ctx == nil {
+		return ctx, func() {}
+	}
+
+	bctx, cancel := ctx.WithCancel()
+	go func() {
+		select {
+		case <-rd.ctx.Done():
+			cancel()
+		case <-bctx.Done():
+		}
+	}()
+
+	return bctx, cancel
+}
+
+// wrap makes a task func that skips the named test after the
+// deadline and that gives up on it (with an error) at the deadline.
+func (rd *runDeadline) wrap(name string, f func() (*junit.TestSuite, error)) func() (*junit.TestSuite, error) {
+	if rd == nil {
+		return f
+	}
+
+	return func() (*junit.TestSuite, error) {
+		msg := fmt.Sprintf("test run timed out after %s", rd.timeout)
+
+		if rd.expired() {
+			atomic.AddInt32(&rd.aborted, 1)
+			return skippedSuite(name, msg), nil
+		}
+
+		started := time.Now().UTC()
+
+		type result struct {
+			ts  *junit.TestSuite
+			err error
+		}
+
+		// Buffered so that an abandoned invocation can still
+		// finish.
+		resch := make(chan result, 1)
+
+		go func() {
+			ts, err := f()
+			resch <- result{ts, err}
+		}()
+
+		select {
+		case res := <-resch:
+			return res.ts, res.err
+		case <-rd.ctx.Done():
+			atomic.AddInt32(&rd.aborted, 1)
+			ts := junit.NewTestSuite(name)
+			tc := junit.NewTestCase(name, "")
+			tc.Started = &started
+			tc.Finish(junit.Error, msg)
+			ts.Add(*tc)
+			ts.Finish(msg)
+			return ts, fmt.Errorf("%s: %s", name, msg)
+		}
+	}
+}
+
+// groupDeadlines has the deadlines of the test groups with a
+// timeout.
+type groupDeadlines struct {
+	sync.Mutex
+
+	all []*groupDeadline
+}
+
+// groupDeadline is the deadline of the tests executed by a test
+// group, which is the group's timeout after the first of them
+// starts.
+type groupDeadline struct {
+	sync.Mutex
+
+	timeout time.Duration
+
+	// parent is the deadline of the enclosing group (if any),
+	// which also applies.
+	parent *groupDeadline
+
+	// at is the deadline, which is zero until a test starts.
+	at time.Time
+}
+
+// add makes a new groupDeadline.
+func (gds *groupDeadlines) add(timeout time.Duration, parent *groupDeadline) *groupDeadline {
+	gd := &groupDeadline{
+		timeout: timeout,
+		parent:  parent,
+	}
+	if gds == nil {
+		return gd
+	}
+
+	gds.Lock()
+	defer gds.Unlock()
+
+	gds.all = append(gds.all, gd)
+	return gd
+}
+
+// reset prepares for an execution of the tests.
+func (gds *groupDeadlines) reset() {
+	if gds == nil {
+		return
+	}
+
+	gds.Lock()
+	defer gds.Unlock()
+
+	for _, gd := range gds.all {
+		gd.Lock()
+		gd.at = time.Time{}
+		gd.Unlock()
+	}
+}
+
+// start notes that a test of the group is starting and returns the
+// earliest deadline (of this group and its enclosing groups) and
+// its timeout.
+func (gd *groupDeadline) start() (time.Time, time.Duration) {
+	gd.Lock()
+	if gd.at.IsZero() {
+		gd.at = time.Now().Add(gd.timeout)
+	}
+	at, timeout := gd.at, gd.timeout
+	gd.Unlock()
+
+	if gd.parent != nil {
+		if pat, ptimeout := gd.parent.start(); pat.Before(at) {
+			return pat, ptimeout
+		}
+	}
+	return at, timeout
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Comcast/plax/junit"
+)
+
+// blockingPlugin fails when its context is done.
+type blockingPlugin struct {
+	canceled chan struct{}
+}
+
+func (p *blockingPlugin) Invoke(ctx context.Context) (*junit.TestSuite, error) {
+	<-ctx.Done()
+	close(p.canceled)
+	ts := junit.NewTestSuite("blocking")
+	tc := junit.NewTestCase("blocking", "")
+	tc.Finish(junit.Failed, "canceled")
+	ts.Add(*tc)
+	ts.Finish()
+	return ts, nil
+}
+
+func TestRunTimeout(t *testing.T) {
+	canceled := make(chan struct{})
+
+	ThePluginRegistry.Register("blocking", func(def PluginDef) (Plugin, error) {
+		return &blockingPlugin{canceled: canceled}, nil
+	})
+
+	spec := `name: run
+version: 0.0.1
+tests:
+  first: {path: pass.yaml, version: fake}
+  hang: {path: pass.yaml, version: blocking}
+  later: {path: pass.yaml, version: fake}
+groups:
+  all:
+    tests:
+      - name: first
+      - name: hang
+      - name: later
+`
+	filename := writeRunSpec(t, spec)
+
+	opts := runOptions(filename)
+	opts.Groups = []string{"all"}
+	opts.RunTimeout = 100 * time.Millisecond
+
+	tr, err := RunTests(context.Background(), opts)
+	if !errors.Is(err, ErrRunTimeout) {
+		t.Fatalf("expected a run timeout rather than %v", err)
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("executing test wasn't canceled")
+	}
+
+	r := tr.Report
+	if r.Total != 3 || r.Passed != 1 || r.Errors != 1 || r.Skipped != 1 {
+		t.Fatalf("unexpected report %#v", r)
+	}
+
+	if got := r.TestSuite[2].TestCase[0].Message; got != "test run timed out after 100ms" {
+		t.Fatalf("unexpected skipped message %q", got)
+	}
+
+	if code := tr.ExitCode(err); code != 124 {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+}
+
+// sleepyPlugin passes after a while (unless its context is done
+// first).
+type sleepyPlugin struct {
+	name string
+}
+
+func (p *sleepyPlugin) Invoke(ctx context.Context) (*junit.TestSuite, error) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(100 * time.Millisecond):
+	}
+	ts := junit.NewTestSuite(p.name)
+	tc := junit.NewTestCase(p.name, "")
+	tc.Finish(junit.Passed)
+	ts.Add(*tc)
+	ts.Finish()
+	return ts, nil
+}
+
+func TestGroupTimeout(t *testing.T) {
+	ThePluginRegistry.Register("sleepy", func(def PluginDef) (Plugin, error) {
+		name, _ := def.GetPluginDefName()
+		return &sleepyPlugin{name: name}, nil
+	})
+
+	spec := `name: run
+version: 0.0.1
+tests:
+  nap: {path: pass.yaml, version: sleepy}
+groups:
+  naps:
+    timeout: 150ms
+    tests:
+      - name: nap
+      - name: nap
+      - name: nap
+  outer:
+    timeout: 150ms
+    groups:
+      - name: inner
+  inner:
+    timeout: 1s
+    tests:
+      - name: nap
+      - name: nap
+`
+	filename := writeRunSpec(t, spec)
+
+	// The timeout is for all of the tests of the group (and its
+	// nested groups) rather than for each of them.
+	for group, want := range map[string]string{
+		"naps":  "passed error skipped",
+		"outer": "passed error",
+	} {
+		opts := runOptions(filename)
+		opts.Groups = []string{group}
+
+		// Twice to check that the deadline restarts.
+		opts.Repeat = 2
+
+		tr, _ := RunTests(context.Background(), opts)
+
+		for _, it := range []int{0, 1} {
+			var got []string
+			suites := tr.Report.TestSuite
+			n := len(suites) / 2
+			for _, ts := range suites[it*n : (it+1)*n] {
+				got = append(got, string(ts.TestCase[0].Status))
+			}
+			if strings.Join(got, " ") != want {
+				t.Fatalf("%s: got %s (wanted %s)", group, strings.Join(got, " "), want)
+			}
+		}
+
+		if msg := tr.Report.TestSuite[1].TestCase[0].Message; msg != "group timed out after 150ms" {
+			t.Fatalf("%s: unexpected message %q", group, msg)
+		}
+	}
+}
@@ -19,10 +19,13 @@
 package dsl
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Comcast/plax/cmd/plaxrun/async"
 	plaxDsl "github.com/Comcast/plax/dsl"
@@ -34,6 +37,45 @@ type TestDef struct {
 	Path   string                  `yaml:"path"`
 	Module PluginModule            `yaml:"version"`
 	Params TestParamDependencyList `yaml:"params"`
+
+	// Retries, when given, is the number of times a failing test
+	// is executed again before it's considered failed.
+	//
+	// Defaults to TestRunParams.DefaultRetries.
+	Retries *int `yaml:"retries,omitempty"`
+
+	// RetryDelay is the time to wait before each retry.
+	RetryDelay time.Duration `yaml:"retryDelay,omitempty"`
+
+	// Labels are used by -labels to select tests.
+	Labels []string `yaml:"labels,omitempty"`
+
+	// Priority is used by -priority to select tests.  Priority 0
+	// is the highest priority.
+	//
+	// Defaults to TestRunParams.DefaultPriority.
+	Priority *int `yaml:"priority,omitempty"`
+
+	// DependsOn are the tests that are executed before this test,
+	// which is skipped when any of them fails.
+	DependsOn []string `yaml:"dependsOn,omitempty"`
+
+	// ConcurrencyGroup, when not empty, names a resource (like a
+	// shared topic) that the test shares with the other tests in
+	// the same concurrency group, which never execute at the same
+	// time.
+	//
+	// Defaults to the ConcurrencyGroup of the test's group.
+	ConcurrencyGroup string `yaml:"concurrencyGroup,omitempty"`
+
+	// Skip, when not empty, is the reason the test is reported as
+	// skipped without being executed (unless -run-skipped).
+	Skip string `yaml:"skip,omitempty"`
+
+	// ExpectFail, when true, marks a test of a known bug, which is
+	// reported as passed when it fails and as failed when it
+	// passes (see expectFail).
+	ExpectFail bool `yaml:"expectFail,omitempty"`
 }
 
 // TestDefMap is a map of TestDefs
@@ -64,6 +106,10 @@ func (tdrl TestDefRefList) getTaskFuncs(ctx *plaxDsl.Ctx, tr TestRun, name strin
 			return nil, err
 		}
 
+		if !tr.hook && (!tr.wanted(ctx, n, tr.Tests[tdr.Name]) || !tr.inShard(ctx, n) || !tr.reruns(ctx, n) || !tr.changedSince(ctx, n, tr.Tests[tdr.Name])) {
+			continue
+		}
+
 		err = tdr.Params.bind(ctx, cbs)
 		if err != nil {
 			return nil, fmt.Errorf("failed to substitute test ref parameters: %w", err)
@@ -96,10 +142,38 @@ func (tdr TestDefRef) getTaskFunc(ctx *plaxDsl.Ctx, tr TestRun, name string, bs
 		return nil, fmt.Errorf("failed to find test def %s", tdr.Name)
 	}
 
-	fmt.Fprintf(os.Stderr, "\nProcessing parameters for %s\n\n", name)
+	if td.Skip != "" && !tr.runSkipped() {
+		ctx.Logf("Skipping %s test: %s", name, td.Skip)
+		msg := "skip: " + td.Skip
+		tf := &async.TaskFunc{
+			Name: name,
+			Func: tr.nameCases(name, tr.progress.wrap(name, tr.deps.wrap(tdr.Name, td.DependsOn, name, func() (*junit.TestSuite, error) {
+				return skippedSuite(name, msg), nil
+			}))),
+		}
+		if tr.infos != nil {
+			tr.infos[tf] = &taskInfo{
+				test: tdr.Name,
+				skip: td.Skip,
+			}
+		}
+		return tf, nil
+	}
+
+	if _, is := ctx.Logger.(*plaxDsl.JSONLogger); is {
+		ctx.Logf("Processing parameters for %s", name)
+	} else {
+		fmt.Fprintf(os.Stderr, "\nProcessing parameters for %s\n\n", name)
+	}
 
 	for _, tpd := range td.Params {
 		err := tpd.process(ctx, tr.Params, bs)
+		var missing *missingParamError
+		if errors.As(err, &missing) && tr.missing != nil {
+			// Report all of the missing params later.
+			tr.missing[missing.name] = true
+			continue
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to process test params: %w", err)
 		}
@@ -114,11 +188,7 @@ func (tdr TestDefRef) getTaskFunc(ctx *plaxDsl.Ctx, tr TestRun, name string, bs
 	labelArr := make([]string, 0)
 
 	// Add labels from the command line
-	if tr.trps != nil && tr.trps.Labels != nil {
-		// Labels from test definition
-		arr := strings.Split(*tr.trps.Labels, ",")
-		labelArr = append(labelArr, arr...)
-	}
+	labelArr = append(labelArr, tr.plaxLabels(td)...)
 
 	// Add labels from the test definition
 	if tdr.Labels != nil {
@@ -131,21 +201,35 @@ func (tdr TestDefRef) getTaskFunc(ctx *plaxDsl.Ctx, tr TestRun, name string, bs
 	labels := strings.Join(labelArr, ",")
 
 	def := PluginDef{
-		PluginDefNameKey:        name,
-		PluginDefParamsKey:      bs,
-		PluginDefSeedKey:        tdr.Seed,
-		PluginDefPriorityKey:    priority,
-		PluginDefLabelsKey:      labels,
-		PluginDefTestsKey:       tdr.tests,
-		PluginDefRetryKey:       strconv.Itoa(tdr.Retry),
-		PluginDefVerboseKey:     tr.trps.Verbose,
-		PluginDefLogLevelKey:    tr.trps.LogLevel,
-		PluginDefEmitJSONKey:    tr.trps.EmitJSON,
-		PluginDefIncludeDirsKey: tr.trps.IncludeDirs,
-		PluginDefRedactKey:      tr.trps.Redact,
+		PluginDefNameKey:            name,
+		PluginDefParamsKey:          bs,
+		PluginDefSeedKey:            tdr.Seed,
+		PluginDefPriorityKey:        priority,
+		PluginDefLabelsKey:          labels,
+		PluginDefTestsKey:           tdr.tests,
+		PluginDefRetryKey:           strconv.Itoa(tdr.Retry),
+		PluginDefVerboseKey:         tr.trps.Verbose,
+		PluginDefLogLevelKey:        tr.trps.LogLevel,
+		PluginDefEmitJSONKey:        tr.trps.EmitJSON,
+		PluginDefIncludeDirsKey:     tr.trps.IncludeDirs,
+		PluginDefRedactKey:          tr.trps.Redact,
+		PluginDefCaptureLogsKey:     tr.trps.CaptureLogs,
+		PluginDefMsgHistoryKey:      tr.trps.MsgHistory,
+		PluginDefCaptureBindingsKey: tr.trps.CaptureBindings,
+	}
+
+	if b := tr.trps.connectBackoff(); b != nil {
+		def[PluginDefConnectBackoffKey] = b
+	}
+
+	if tr.chanPool != nil {
+		def[PluginDefChanPoolKey] = tr.chanPool
 	}
 
 	path := td.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(ctx.Dir, path)
+	}
 	fi, err := os.Stat(path)
 	if err != nil {
 		return nil, err
@@ -167,12 +251,240 @@ func (tdr TestDefRef) getTaskFunc(ctx *plaxDsl.Ctx, tr TestRun, name string, bs
 		return nil, err
 	}
 
-	return &async.TaskFunc{
+	gd := tr.groupDeadline
+
+	// Tag the JSON logs of the test with its name.
+	tctx := ctx
+	if jl, is := ctx.Logger.(*plaxDsl.JSONLogger); is {
+		tctx = plaxDsl.NewCtx(ctx)
+		tctx.Logger = jl.WithTest(name)
+		tctx.LogLevel = ctx.LogLevel
+		tctx.IncludeDirs = ctx.IncludeDirs
+		tctx.Dir = ctx.Dir
+	}
+
+	invoke := expectFail(name, td, func() (*junit.TestSuite, error) {
+		// The test is canceled at the run's deadline.
+		ictx, cancel := tr.deadline.bind(tctx)
+		defer cancel()
+
+		var (
+			started = time.Now().UTC()
+			ts      *junit.TestSuite
+			err     error
+		)
+		if gd == nil {
+			ts, err = plugin.Invoke(ictx)
+		} else {
+			ts, err = invokeBefore(ictx, name, plugin, gd)
+		}
+		recordElapsed(ts, started)
+		return ts, err
+	})
+
+	retries := 0
+	if td.Retries != nil {
+		retries = *td.Retries
+	} else if tr.trps.DefaultRetries != nil {
+		retries = *tr.trps.DefaultRetries
+	}
+
+	// Teardowns always execute.
+	ff := tr.failFast
+	if tr.hook {
+		ff = nil
+	}
+
+	tf := &async.TaskFunc{
 		Name: name,
-		Func: func() (*junit.TestSuite, error) {
-			return plugin.Invoke(ctx)
-		},
-	}, nil
+		Func: tr.nameCases(name, tr.progress.wrap(name, tr.deps.wrap(tdr.Name, td.DependsOn, name, ff.wrap(name, tr.deadline.wrap(name, tr.serial.wrap(tr.concurrencyGroupOf(td), func() (*junit.TestSuite, error) {
+			if retries <= 0 {
+				return invoke()
+			}
+			return invokeWithRetries(ctx, name, retries, td.RetryDelay, invoke)
+		})))))),
+	}
+
+	if tr.infos != nil {
+		tr.infos[tf] = &taskInfo{
+			test:   tdr.Name,
+			bs:     bs,
+			plugin: plugin,
+		}
+	}
+
+	return tf, nil
+}
+
+// runSkipped reports whether -run-skipped executes the tests with a
+// TestDef.Skip.
+func (tr TestRun) runSkipped() bool {
+	return tr.trps != nil && tr.trps.RunSkipped != nil && *tr.trps.RunSkipped
+}
+
+// wanted reports whether -labels and -priority select the test.
+func (tr TestRun) wanted(ctx *plaxDsl.Ctx, name string, td TestDef) bool {
+	if !tr.selects(td) {
+		ctx.Logdf("labels excluded %s test", name)
+		return false
+	}
+
+	if !tr.prioritized(td) {
+		ctx.Logdf("priority excluded %s test", name)
+		if tr.excluded != nil {
+			*tr.excluded++
+		}
+		return false
+	}
+
+	return true
+}
+
+// prioritized reports whether the test's priority is no lower than
+// TestRunParams.Priority.
+func (tr TestRun) prioritized(td TestDef) bool {
+	if tr.trps == nil || tr.trps.Priority == nil || *tr.trps.Priority < 0 {
+		return true
+	}
+
+	priority := 0
+	if td.Priority != nil {
+		priority = *td.Priority
+	} else if tr.trps.DefaultPriority != nil {
+		priority = *tr.trps.DefaultPriority
+	}
+
+	return priority <= *tr.trps.Priority
+}
+
+// invokeWithRetries calls invoke again after an error, up to the
+// given number of retries.
+//
+// Only the TestSuite of the last attempt is returned.  Each of its
+// TestCases gets an "attempts" property, and a passing TestCase that
+// didn't pass in an earlier attempt also gets a "flaky" property.
+func invokeWithRetries(ctx *plaxDsl.Ctx, name string, retries int, delay time.Duration, invoke func() (*junit.TestSuite, error)) (*junit.TestSuite, error) {
+	var (
+		ts      *junit.TestSuite
+		err     error
+		failed  = make(map[string]bool)
+		attempt = 1
+	)
+
+	for ; ; attempt++ {
+		ts, err = invoke()
+		if err == nil || retries < attempt {
+			break
+		}
+
+		if ts != nil {
+			for _, tc := range ts.TestCase {
+				if tc.Status == junit.Failed || tc.Status == junit.Error {
+					failed[tc.Name] = true
+				}
+			}
+		}
+
+		ctx.Logf("Retrying %s (attempt %d of %d) after error: %s", name, attempt+1, retries+1, err)
+
+		select {
+		case <-ctx.Done():
+			return ts, err
+		case <-time.After(delay):
+		}
+	}
+
+	if ts != nil {
+		for i := range ts.TestCase {
+			tc := &ts.TestCase[i]
+			tc.AddProperty("attempts", strconv.Itoa(attempt))
+			if tc.Status == junit.Passed && failed[tc.Name] {
+				tc.AddProperty("flaky", "true")
+			}
+		}
+	}
+
+	return ts, err
+}
+
+// recordElapsed gives the time since started to each TestCase that
+// wasn't skipped but doesn't have a time, which isn't recorded by
+// every plugin.
+func recordElapsed(ts *junit.TestSuite, started time.Time) {
+	if ts == nil {
+		return
+	}
+
+	elapsed := time.Since(started)
+	for i := range ts.TestCase {
+		tc := &ts.TestCase[i]
+		if tc.Time != nil || tc.Status == junit.Skipped {
+			continue
+		}
+		d := elapsed
+		tc.Time = &d
+		if tc.Started == nil {
+			tc.Started = &started
+		}
+	}
+}
+
+// invokeBefore invokes the plugin but gives up at the group's
+// deadline.
+//
+// When the deadline is reached, the returned TestSuite has a single
+// TestCase with an error status.  A test that would start after the
+// deadline is skipped.
+func invokeBefore(ctx *plaxDsl.Ctx, name string, plugin Plugin, gd *groupDeadline) (*junit.TestSuite, error) {
+	var (
+		started     = time.Now().UTC()
+		at, timeout = gd.start()
+		msg         = fmt.Sprintf("group timed out after %s", timeout)
+	)
+
+	if !started.Before(at) {
+		return skippedSuite(name, msg), nil
+	}
+
+	tctx, cancel := ctx.WithTimeout(at.Sub(started))
+	defer cancel()
+
+	type result struct {
+		ts  *junit.TestSuite
+		err error
+	}
+
+	// Buffered so that an abandoned invocation can still finish.
+	resch := make(chan result, 1)
+
+	go func() {
+		ts, err := plugin.Invoke(tctx)
+		resch <- result{ts, err}
+	}()
+
+	select {
+	case res := <-resch:
+		return res.ts, res.err
+	case <-tctx.Done():
+		ts := junit.NewTestSuite(name)
+		tc := junit.NewTestCase(name, "")
+		tc.Started = &started
+		tc.Finish(junit.Error, msg)
+		ts.Add(*tc)
+		ts.Finish(msg)
+
+		return ts, fmt.Errorf("%s: %s", name, msg)
+	}
+}
+
+// skippedSuite reports the named test as skipped with the message.
+func skippedSuite(name, msg string) *junit.TestSuite {
+	ts := junit.NewTestSuite(name)
+	tc := junit.NewTestCase(name, "")
+	tc.Finish(junit.Skipped, msg)
+	ts.Add(*tc)
+	ts.Finish(msg)
+	return ts
 }
 
 // TestList are the individual tests to execute
@@ -196,18 +508,53 @@ func (tl *TestList) Set(value string) error {
 func (tl *TestList) getTaskFuncs(ctx *plaxDsl.Ctx, tr TestRun) ([]*async.TaskFunc, error) {
 	tfs := make([]*async.TaskFunc, 0)
 
-	for _, n := range *tl {
+	names := make([]string, 0, len(tr.Tests))
+	for n := range tr.Tests {
+		names = append(names, n)
+	}
+
+	selected, err := selectNames(*tl, names, "test")
+	if err != nil {
+		return nil, err
+	}
+
+	// Report all of the typos before doing any work.
+	unknown := make([]string, 0)
+	for _, n := range selected {
+		if _, ok := tr.Tests[n]; !ok {
+			unknown = append(unknown, n)
+		}
+	}
+
+	if 0 < len(unknown) {
+		return nil, fmt.Errorf("no such test: %s", strings.Join(unknown, ", "))
+	}
+
+	empty := make([]string, 0)
+
+	for _, n := range selected {
 		bs, err := (&tr.trps.Bindings).Copy()
 		if err != nil {
 			return nil, fmt.Errorf("failed to copy bindings for test %s: %w", n, err)
 		}
 
-		name := fmt.Sprintf("%s-%s", tr.Name, tr.Version)
+		if !tr.wanted(ctx, n, tr.Tests[n]) {
+			empty = append(empty, n)
+			continue
+		}
+
+		// Each test gets its own suite, whose name -rerun-failed
+		// selects.
+		name := fmt.Sprintf("%s-%s:%s", tr.Name, tr.Version, n)
+
+		// Another shard has the test, it didn't fail, or it didn't change.
+		if !tr.inShard(ctx, name) || !tr.reruns(ctx, name) || !tr.changedSince(ctx, name, tr.Tests[n]) {
+			continue
+		}
 
 		tdr := TestDefRef{
 			TestConstraints: TestConstraints{
 				Priority: tr.trps.Priority,
-				Labels:   tr.trps.Labels,
 			},
 			Name: n,
 		}
@@ -220,5 +567,11 @@ func (tl *TestList) getTaskFuncs(ctx *plaxDsl.Ctx, tr TestRun) ([]*async.TaskFun
 		tfs = append(tfs, tf)
 	}
 
+	// Only an empty selection is an error, so that -labels and
+	// -priority can leave out some of the selected tests.
+	if len(tfs) == 0 && 0 < len(empty) && len(empty) == len(selected) {
+		return nil, fmt.Errorf("test exists but was excluded by filtering: %s", strings.Join(empty, ", "))
+	}
+
 	return tfs, nil
 }
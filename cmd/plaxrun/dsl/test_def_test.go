@@ -0,0 +1,64 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"testing"
+
+	plaxDsl "github.com/Comcast/plax/dsl"
+)
+
+func TestWantedPriority(t *testing.T) {
+	var (
+		ctx      = plaxDsl.NewCtx(context.Background())
+		priority = 1
+		two      = 2
+		zero     = 0
+	)
+
+	tr := TestRun{
+		trps: &TestRunParams{
+			Priority: &priority,
+		},
+		excluded: new(int),
+	}
+
+	if !tr.wanted(ctx, "default", TestDef{}) {
+		t.Fatal("a test without a priority should default to 0")
+	}
+
+	if tr.wanted(ctx, "low", TestDef{Priority: &two}) {
+		t.Fatal("shouldn't have wanted a lower priority test")
+	}
+
+	tr.trps.DefaultPriority = &two
+
+	if tr.wanted(ctx, "default", TestDef{}) {
+		t.Fatal("should have used the default priority")
+	}
+
+	if !tr.wanted(ctx, "high", TestDef{Priority: &zero}) {
+		t.Fatal("should have wanted a higher priority test")
+	}
+
+	if *tr.excluded != 2 {
+		t.Fatalf("excluded %d tests, want 2", *tr.excluded)
+	}
+}
@@ -0,0 +1,216 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Comcast/plax/cmd/plaxrun/async"
+	"github.com/Comcast/plax/junit"
+)
+
+// testDeps executes each test after the tests that it depends on
+// (see TestDef.DependsOn), and it skips a test when one of those
+// failed.
+//
+// A dependency that isn't part of the test run is ignored.
+type testDeps struct {
+	sync.Mutex
+
+	// tasks counts the task funcs of each test.
+	tasks map[string]int
+
+	// pending counts the task funcs of each test that haven't
+	// finished in the current execution, which is done when that
+	// count reaches zero.
+	pending map[string]int
+	done    map[string]chan struct{}
+
+	// failed are the tests with a task func that failed (or that
+	// was skipped because of a failed dependency).
+	failed map[string]bool
+}
+
+func newTestDeps() *testDeps {
+	return &testDeps{
+		tasks: make(map[string]int),
+	}
+}
+
+// wrap makes a task func for the named execution of the test that
+// waits for the test's dependencies (if any) and that skips the test
+// when one of them failed.
+func (td *testDeps) wrap(test string, deps []string, name string, f func() (*junit.TestSuite, error)) func() (*junit.TestSuite, error) {
+	if td == nil {
+		return f
+	}
+
+	td.Lock()
+	td.tasks[test]++
+	td.Unlock()
+
+	return func() (*junit.TestSuite, error) {
+		for _, dep := range deps {
+			if td.wait(dep) {
+				td.finish(test, true)
+				return skippedSuite(name, fmt.Sprintf("dependsOn: %s failed", dep)), nil
+			}
+		}
+
+		ts, err := f()
+		td.finish(test, err != nil || (ts != nil && (0 < ts.Failures || 0 < ts.Errors)))
+
+		return ts, err
+	}
+}
+
+// reset prepares for an execution of the tests.
+func (td *testDeps) reset() {
+	if td == nil {
+		return
+	}
+
+	td.Lock()
+	defer td.Unlock()
+
+	td.pending = make(map[string]int, len(td.tasks))
+	td.done = make(map[string]chan struct{}, len(td.tasks))
+	td.failed = make(map[string]bool)
+	for test, n := range td.tasks {
+		td.pending[test] = n
+		td.done[test] = make(chan struct{})
+	}
+}
+
+// wait for the test's task funcs to finish and report whether the
+// test failed.
+func (td *testDeps) wait(test string) bool {
+	td.Lock()
+	done := td.done[test]
+	td.Unlock()
+
+	if done == nil {
+		return false
+	}
+	<-done
+
+	td.Lock()
+	defer td.Unlock()
+	return td.failed[test]
+}
+
+// finish notes that one of the test's task funcs finished.
+func (td *testDeps) finish(test string, failed bool) {
+	td.Lock()
+	defer td.Unlock()
+
+	if failed {
+		td.failed[test] = true
+	}
+	if td.pending[test]--; td.pending[test] == 0 {
+		if done := td.done[test]; done != nil {
+			close(done)
+		}
+	}
+}
+
+// orderByDependencies moves the task funcs of each test after the
+// task funcs of the tests that it depends on (and the task funcs of
+// each group's body after its setup and before its teardown) but
+// otherwise keeps their order.
+//
+// Parallel starts the tasks in order, so a task never waits for a
+// dependency that hasn't started.
+func (tr *TestRun) orderByDependencies() error {
+	var (
+		remaining = make(map[string]int)
+		testOf    = make(map[*async.TaskFunc]string)
+		needed    bool
+	)
+	for _, tf := range tr.tfs {
+		if info, have := tr.infos[tf]; have && info.test != "" {
+			testOf[tf] = info.test
+			remaining[info.test]++
+			if 0 < len(tr.Tests[info.test].DependsOn) {
+				needed = true
+			}
+		}
+	}
+	var preds map[*async.TaskFunc][]*async.TaskFunc
+	if tr.hooks != nil && 0 < len(tr.hooks.preds) {
+		preds = tr.hooks.preds
+		needed = true
+	}
+	if !needed {
+		return nil
+	}
+
+	emitted := make(map[*async.TaskFunc]bool, len(tr.tfs))
+	ready := func(tf *async.TaskFunc) bool {
+		for _, pred := range preds[tf] {
+			if !emitted[pred] {
+				return false
+			}
+		}
+		test, have := testOf[tf]
+		if !have {
+			return true
+		}
+		for _, dep := range tr.Tests[test].DependsOn {
+			if 0 < remaining[dep] {
+				return false
+			}
+		}
+		return true
+	}
+
+	var (
+		ordered = make([]*async.TaskFunc, 0, len(tr.tfs))
+		todo    = tr.tfs
+	)
+	for 0 < len(todo) {
+		i := 0
+		for ; i < len(todo); i++ {
+			if ready(todo[i]) {
+				break
+			}
+		}
+		if i == len(todo) {
+			names := make([]string, len(todo))
+			for j, tf := range todo {
+				names[j] = tf.Name
+			}
+			return fmt.Errorf("test dependency cycle among %s", strings.Join(names, ", "))
+		}
+
+		tf := todo[i]
+		ordered = append(ordered, tf)
+		emitted[tf] = true
+		todo = append(todo[:i:i], todo[i+1:]...)
+		if test, have := testOf[tf]; have {
+			remaining[test]--
+		}
+	}
+
+	tr.tfs = ordered
+
+	return nil
+}
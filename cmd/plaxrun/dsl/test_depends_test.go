@@ -0,0 +1,102 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestDependsOn(t *testing.T) {
+	spec := `name: run
+version: 0.0.1
+tests:
+  create: {path: pass.yaml, version: failing}
+  verify: {path: pass.yaml, version: fake, dependsOn: [create]}
+  after: {path: pass.yaml, version: fake, dependsOn: [verify]}
+  alone: {path: pass.yaml, version: fake}
+  setup: {path: pass.yaml, version: fake}
+  use: {path: pass.yaml, version: fake, dependsOn: [setup, missing]}
+groups:
+  all:
+    tests:
+      - name: after
+      - name: verify
+      - name: create
+      - name: alone
+      - name: use
+      - name: setup
+`
+	filename := writeRunSpec(t, spec)
+
+	opts := runOptions(filename)
+	opts.Groups = []string{"all"}
+
+	// "missing" isn't a test.
+	if _, err := RunTests(context.Background(), opts); err == nil || !strings.Contains(err.Error(), `no such test "missing"`) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	spec = strings.Replace(spec, ", missing]", "]", 1)
+	if err := ioutil.WriteFile(filename, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, concurrency := range []int{1, 4} {
+		opts.MaxConcurrency = concurrency
+		opts.Repeat = 2
+
+		tr, err := RunTests(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, ts := range tr.Report.TestSuite[:6] {
+			tc := ts.TestCase[0]
+			got = append(got, tc.Name[strings.LastIndex(tc.Name, ":")+1:]+"="+string(tc.Status)+" "+tc.Message)
+		}
+
+		want := []string{
+			"alone=passed ",
+			"create=failed no tacos",
+			"setup=passed ",
+			"use=passed ",
+			"verify=skipped dependsOn: create failed",
+			"after=skipped dependsOn: verify failed",
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Fatalf("concurrency %d: got\n%s", concurrency, strings.Join(got, "\n"))
+		}
+
+		if r := tr.Report; r.Total != 12 || r.Skipped != 4 || r.Failures != 2 {
+			t.Fatalf("unexpected report %#v", r)
+		}
+	}
+
+	spec = strings.Replace(spec, "dependsOn: [create]", "dependsOn: [after]", 1)
+	if err := ioutil.WriteFile(filename, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunTests(context.Background(), opts); err == nil || !strings.Contains(err.Error(), "test dependency cycle: after -> verify -> after") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"github.com/Comcast/plax/cmd/plaxrun/plugins/report"
+)
+
+// ExitCodePolicy maps the results of a TestRun to a process exit
+// code.
+type ExitCodePolicy struct {
+	// SkippedIsSuccess, when true, makes a test run with only
+	// skipped tests a success.  Otherwise such a run exits with
+	// the FailureCode.
+	SkippedIsSuccess bool
+
+	// FailureCode is the exit code when a test failed.
+	FailureCode int
+
+	// ErrorCode is the exit code when a test had an error, which
+	// takes precedence over the FailureCode.
+	ErrorCode int
+
+	// TimeoutCode, when not zero, is the exit code when the
+	// RunTimeout cut the test run short, which takes precedence
+	// over the other codes.
+	TimeoutCode int
+}
+
+// DefaultExitCodePolicy returns the ExitCodePolicy of the plaxrun
+// command: skipped-only runs succeed, both failures and errors exit
+// with 1, and a run timeout exits with 124.
+func DefaultExitCodePolicy() ExitCodePolicy {
+	return ExitCodePolicy{
+		SkippedIsSuccess: true,
+		FailureCode:      1,
+		ErrorCode:        1,
+		TimeoutCode:      124,
+	}
+}
+
+// Code returns the exit code for the TestReport.
+func (p ExitCodePolicy) Code(r *report.TestReport) int {
+	switch {
+	case r == nil:
+		return 0
+	case 0 < r.Errors:
+		return p.ErrorCode
+	case 0 < r.Failures:
+		return p.FailureCode
+	case 0 < r.Total && r.Skipped == r.Total && !p.SkippedIsSuccess:
+		return p.FailureCode
+	}
+	return 0
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Comcast/plax/cmd/plaxrun/plugins/report"
+)
+
+func TestExitCodePolicy(t *testing.T) {
+	policy := ExitCodePolicy{
+		FailureCode: 2,
+		ErrorCode:   3,
+	}
+
+	tests := []struct {
+		name   string
+		policy ExitCodePolicy
+		report *report.TestReport
+		want   int
+	}{
+		{"no report", policy, nil, 0},
+		{"passed", policy, &report.TestReport{Total: 2, Passed: 2}, 0},
+		{"failed", policy, &report.TestReport{Total: 2, Passed: 1, Failures: 1}, 2},
+		{"error", policy, &report.TestReport{Total: 2, Failures: 1, Errors: 1}, 3},
+		{"skipped", policy, &report.TestReport{Total: 2, Skipped: 2}, 2},
+		{"skipped ok", DefaultExitCodePolicy(), &report.TestReport{Total: 2, Skipped: 2}, 0},
+		{"some skipped", policy, &report.TestReport{Total: 2, Passed: 1, Skipped: 1}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Code(tt.report); got != tt.want {
+				t.Errorf("Code() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	tr := &TestRun{trps: &TestRunParams{ExitCodePolicy: &policy}}
+	if got := tr.ExitCode(fmt.Errorf("broken")); got != 1 {
+		t.Errorf("ExitCode() = %d, want 1 for an error without a report", got)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	plaxDsl "github.com/Comcast/plax/dsl"
+	"github.com/Comcast/plax/subst"
+)
+
+// expandEnv replaces each ${NAME} in the given test run YAML with the
+// value from lookup.  "$$" is a literal "$", and other "$"s are left
+// alone (so that "$include" still works).
+//
+// An undefined NAME expands to nothing unless strict, in which case
+// the undefined NAMEs are an error.
+func expandEnv(bs []byte, lookup func(string) (string, bool), strict bool) ([]byte, error) {
+	var (
+		s         = string(bs)
+		sb        strings.Builder
+		undefined = make(map[string]bool)
+	)
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+
+		switch s[i+1] {
+		case '$':
+			sb.WriteByte('$')
+			i++
+			continue
+		case '{':
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 || !isExpandName(s[i+2:i+2+end]) {
+				break
+			}
+			name := s[i+2 : i+2+end]
+			if v, have := lookup(name); have {
+				sb.WriteString(v)
+			} else {
+				undefined[name] = true
+			}
+			i += 2 + end
+			continue
+		}
+
+		sb.WriteByte(s[i])
+	}
+
+	if strict && 0 < len(undefined) {
+		names := make([]string, 0, len(undefined))
+		for name := range undefined {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("undefined variables in test run: %s", strings.Join(names, ", "))
+	}
+
+	return []byte(sb.String()), nil
+}
+
+// isExpandName reports whether s is a variable name for expandEnv.
+func isExpandName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && 0 < i:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// bindingsLookup looks up names in the bindings and then with the
+// given lookup (usually os.LookupEnv).
+//
+// Binding values that aren't strings are JSON-serialized.
+func bindingsLookup(bs plaxDsl.Bindings, lookup func(string) (string, bool)) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, have := bs[name]
+		if !have {
+			return lookup(name)
+		}
+		if s, is := v.(string); is {
+			return s, true
+		}
+		js, err := subst.JSONMarshal(&v)
+		if err != nil {
+			return fmt.Sprintf("%v", v), true
+		}
+		return string(js), true
+	}
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"testing"
+
+	plaxDsl "github.com/Comcast/plax/dsl"
+)
+
+func TestExpandEnv(t *testing.T) {
+	env := map[string]string{
+		"HOME":     "/home/tacos",
+		"BUILD_ID": "42",
+	}
+	lookup := bindingsLookup(plaxDsl.Bindings{"BUILD_ID": 43, "ENV": "staging"}, func(name string) (string, bool) {
+		v, have := env[name]
+		return v, have
+	})
+
+	tests := []struct {
+		name   string
+		in     string
+		strict bool
+		want   string
+		err    bool
+	}{
+		{"env", "dir: ${HOME}/plax", false, "dir: /home/tacos/plax", false},
+		{"bindings first", "id: ${BUILD_ID}-${ENV}", false, "id: 43-staging", false},
+		{"escaped", "price: $$5 $${HOME}", false, "price: $5 ${HOME}", false},
+		{"other dollars", "- $include<x.yaml>\n- $ {HOME} ${not a name} ${", false, "- $include<x.yaml>\n- $ {HOME} ${not a name} ${", false},
+		{"undefined", "x: '${NOPE}'", false, "x: ''", false},
+		{"strict", "x: '${NOPE}${NADA}'", true, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandEnv([]byte(tt.in), lookup, tt.strict)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", got)
+				}
+				if err.Error() != "undefined variables in test run: NADA, NOPE" {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
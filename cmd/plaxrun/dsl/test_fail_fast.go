@@ -0,0 +1,83 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/Comcast/plax/junit"
+)
+
+// failFast skips the remaining tests of a test run after the first
+// test that fails or errors (or, with a max, after that many failed
+// or errored test cases).
+type failFast struct {
+	// max is the number of failed or errored test cases (if
+	// positive) after which the remaining tests are skipped.
+	// Zero means one failing test.
+	max int32
+
+	// failed counts the failed or errored test cases.
+	failed int32
+}
+
+// reason explains why a test is skipped.
+func (ff *failFast) reason() string {
+	if ff.max <= 0 {
+		return "fail-fast: an earlier test failed"
+	}
+	return fmt.Sprintf("max-failures reached: %d tests failed", atomic.LoadInt32(&ff.failed))
+}
+
+// stop reports whether the remaining tests should be skipped.
+func (ff *failFast) stop() bool {
+	failed := atomic.LoadInt32(&ff.failed)
+	if ff.max <= 0 {
+		return 0 < failed
+	}
+	return ff.max <= failed
+}
+
+// wrap makes a task func that skips the named test after enough
+// failures and that otherwise counts the test's failures.
+//
+// Tests that are already executing (with MaxConcurrency) are not
+// interrupted.
+func (ff *failFast) wrap(name string, f func() (*junit.TestSuite, error)) func() (*junit.TestSuite, error) {
+	if ff == nil {
+		return f
+	}
+
+	return func() (*junit.TestSuite, error) {
+		if ff.stop() {
+			return skippedSuite(name, ff.reason()), nil
+		}
+
+		ts, err := f()
+		switch {
+		case ts != nil && (0 < ts.Failures || 0 < ts.Errors):
+			atomic.AddInt32(&ff.failed, int32(ts.Failures+ts.Errors))
+		case err != nil:
+			atomic.AddInt32(&ff.failed, 1)
+		}
+
+		return ts, err
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestFailFast(t *testing.T) {
+	spec := `name: run
+version: 0.0.1
+tests:
+  begin: {path: pass.yaml, version: fake}
+  fail: {path: pass.yaml, version: failing}
+  then: {path: pass.yaml, version: fake}
+groups:
+  all:
+    tests:
+      - name: begin
+      - name: fail
+      - name: then
+`
+	filename := writeRunSpec(t, spec)
+
+	for _, failFast := range []bool{false, true} {
+		opts := runOptions(filename)
+		opts.Groups = []string{"all"}
+		opts.FailFast = failFast
+
+		tr, err := RunTests(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := tr.Report
+		if failFast {
+			if r.Total != 3 || r.Passed != 1 || r.Failures != 1 || r.Skipped != 1 {
+				t.Fatalf("unexpected fail-fast report %#v", r)
+			}
+			if got := r.TestSuite[2].TestCase[0].Message; got != "fail-fast: an earlier test failed" {
+				t.Fatalf("unexpected skipped message %q", got)
+			}
+		} else if r.Total != 3 || r.Passed != 2 || r.Failures != 1 {
+			t.Fatalf("unexpected report %#v", r)
+		}
+	}
+}
+
+func TestMaxFailures(t *testing.T) {
+	spec := `name: run
+version: 0.0.1
+tests:
+  t1: {path: pass.yaml, version: failing}
+  t2: {path: pass.yaml, version: fake}
+  t3: {path: pass.yaml, version: failing}
+  t4: {path: pass.yaml, version: failing}
+  t5: {path: pass.yaml, version: fake}
+groups:
+  all:
+    tests:
+      - name: t1
+      - name: t2
+      - name: t3
+      - name: t4
+      - name: t5
+`
+	filename := writeRunSpec(t, spec)
+
+	for _, c := range []struct {
+		max                       int
+		passed, failures, skipped int
+	}{
+		{0, 2, 3, 0},
+		{2, 1, 2, 2},
+		{3, 1, 3, 1},
+		{4, 2, 3, 0},
+	} {
+		opts := runOptions(filename)
+		opts.Groups = []string{"all"}
+		opts.MaxFailures = c.max
+
+		tr, err := RunTests(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := tr.Report
+		if r.Total != 5 || r.Passed != c.passed || r.Failures != c.failures || r.Skipped != c.skipped {
+			t.Fatalf("unexpected report with -max-failures %d: %#v", c.max, r)
+		}
+		if 0 < c.skipped {
+			if got, want := r.TestSuite[4].TestCase[0].Message, fmt.Sprintf("max-failures reached: %d tests failed", c.max); got != want {
+				t.Fatalf("unexpected skipped message %q", got)
+			}
+		}
+	}
+}
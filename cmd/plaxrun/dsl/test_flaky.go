@@ -0,0 +1,241 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Comcast/plax/junit"
+)
+
+// FlakyClass classifies a test that was executed several times.
+type FlakyClass string
+
+const (
+	// StablePass is the class of a test that (almost) always
+	// passed.
+	StablePass FlakyClass = "stable-pass"
+
+	// StableFail is the class of a test that (almost) never
+	// passed.
+	StableFail FlakyClass = "stable-fail"
+
+	// Flaky is the class of a test with mixed results.
+	Flaky FlakyClass = "flaky"
+)
+
+// DefaultFlakyPassRatio and DefaultFlakyFailRatio are the defaults
+// for TestRunParams.FlakyPassRatio and FlakyFailRatio, which only
+// consider a test stable when all of its executions agree.
+const (
+	DefaultFlakyPassRatio = 1.0
+	DefaultFlakyFailRatio = 0.0
+)
+
+// FlakyTest is the classification of a test.
+type FlakyTest struct {
+	Name      string     `json:"name"`
+	Runs      int        `json:"runs"`
+	Passed    int        `json:"passed"`
+	PassRatio float64    `json:"passRatio"`
+	Class     FlakyClass `json:"class"`
+}
+
+// FlakyReport classifies the tests executed by a TestRun with
+// TestRunParams.Flaky.
+type FlakyReport struct {
+	Iterations int         `json:"iterations"`
+	PassRatio  float64     `json:"stablePassRatio"`
+	FailRatio  float64     `json:"stableFailRatio"`
+	StablePass int         `json:"stablePass"`
+	StableFail int         `json:"stableFail"`
+	Flaky      int         `json:"flaky"`
+	Tests      []FlakyTest `json:"tests"`
+}
+
+// flakyRatios returns the -flaky-pass-ratio and -flaky-fail-ratio.
+func (trps *TestRunParams) flakyRatios() (float64, float64) {
+	pass, fail := DefaultFlakyPassRatio, DefaultFlakyFailRatio
+	if trps.FlakyPassRatio != nil {
+		pass = *trps.FlakyPassRatio
+	}
+	if trps.FlakyFailRatio != nil {
+		fail = *trps.FlakyFailRatio
+	}
+	return pass, fail
+}
+
+// flaky returns the -flaky number of executions of each test, which
+// is zero without flaky detection.
+func (tr TestRun) flaky() int {
+	if tr.trps == nil || tr.trps.Flaky == nil {
+		return 0
+	}
+	return *tr.trps.Flaky
+}
+
+// checkFlaky validates the -flaky and its ratios.
+func checkFlaky(trps *TestRunParams) error {
+	if trps.Flaky == nil || *trps.Flaky == 0 {
+		return nil
+	}
+	if *trps.Flaky < 2 {
+		return fmt.Errorf("flaky %d must be at least 2", *trps.Flaky)
+	}
+	if trps.Repeat != nil && 1 < *trps.Repeat {
+		return fmt.Errorf("flaky can't be combined with repeat")
+	}
+	pass, fail := trps.flakyRatios()
+	if fail < 0 || pass <= fail || 1 < pass {
+		return fmt.Errorf("flaky ratios must have 0 <= fail (%v) < pass (%v) <= 1", fail, pass)
+	}
+	return nil
+}
+
+// classify returns the FlakyClass of a pass ratio.
+func classify(ratio, pass, fail float64) FlakyClass {
+	switch {
+	case pass <= ratio:
+		return StablePass
+	case ratio <= fail:
+		return StableFail
+	default:
+		return Flaky
+	}
+}
+
+// Flakiness classifies each test (case) of the Report by the ratio
+// of its executions that passed.  Skipped executions don't count,
+// and a test that was always skipped isn't classified.
+func (tr *TestRun) Flakiness() *FlakyReport {
+	pass, fail := DefaultFlakyPassRatio, DefaultFlakyFailRatio
+	if tr.trps != nil {
+		pass, fail = tr.trps.flakyRatios()
+	}
+
+	fr := &FlakyReport{
+		Iterations: len(tr.Iterations),
+		PassRatio:  pass,
+		FailRatio:  fail,
+		Tests:      make([]FlakyTest, 0, 8),
+	}
+	if tr.Report == nil {
+		return fr
+	}
+
+	var (
+		tests = make(map[string]*FlakyTest)
+		names []string
+	)
+	for _, ts := range tr.Report.TestSuite {
+		for _, tc := range ts.TestCase {
+			if tc.Status == junit.Skipped {
+				continue
+			}
+			name := timingName(ts, tc)
+			ft, have := tests[name]
+			if !have {
+				ft = &FlakyTest{Name: name}
+				tests[name] = ft
+				names = append(names, name)
+			}
+			ft.Runs++
+			if tc.Status == junit.Passed {
+				ft.Passed++
+			}
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ft := tests[name]
+		ft.PassRatio = float64(ft.Passed) / float64(ft.Runs)
+		ft.Class = classify(ft.PassRatio, pass, fail)
+		switch ft.Class {
+		case StablePass:
+			fr.StablePass++
+		case StableFail:
+			fr.StableFail++
+		case Flaky:
+			fr.Flaky++
+		}
+		fr.Tests = append(fr.Tests, *ft)
+	}
+
+	return fr
+}
+
+// Write the FlakyReport as "JSON" or as "text", which lists the
+// flaky tests.
+func (fr *FlakyReport) Write(w io.Writer, format string) error {
+	switch format {
+	case "JSON":
+		js, err := json.MarshalIndent(fr, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to serialize flaky report: %w", err)
+		}
+		_, err = fmt.Fprintf(w, "%s\n", js)
+		return err
+	case "text":
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "Flaky=%d StablePass=%d StableFail=%d Iterations=%d\n",
+			fr.Flaky, fr.StablePass, fr.StableFail, fr.Iterations)
+		for _, ft := range fr.Tests {
+			if ft.Class == Flaky {
+				fmt.Fprintf(&sb, "  %s passed %d of %d (%.1f%%)\n",
+					ft.Name, ft.Passed, ft.Runs, 100*ft.PassRatio)
+			}
+		}
+		_, err := io.WriteString(w, sb.String())
+		return err
+	default:
+		return fmt.Errorf("unknown flaky report format '%s'", format)
+	}
+}
+
+// WriteFlakyFile writes the JSON FlakyReport to the named file.
+//
+// Missing parent directories are created.
+func (tr *TestRun) WriteFlakyFile(filename string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("failed to make directory for flaky report: %w", err)
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create flaky report file: %w", err)
+	}
+
+	if err = tr.Flakiness().Write(f, "JSON"); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("failed to write flaky report: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Comcast/plax/cmd/plaxrun/plugins/report"
+	"github.com/Comcast/plax/junit"
+)
+
+func TestFlakiness(t *testing.T) {
+	r := report.NewTestReport()
+	for _, statuses := range [][]junit.TestCaseStatus{
+		{junit.Passed, junit.Failed, junit.Passed},
+		{junit.Passed, junit.Failed, junit.Failed},
+		{junit.Passed, junit.Failed, junit.Skipped},
+		{junit.Passed, junit.Error, junit.Passed},
+	} {
+		for j, status := range statuses {
+			ts := junit.NewTestSuite([]string{"run:g:good", "run:g:bad", "run:g:flaky"}[j])
+			ts.Add(junit.TestCase{Name: "x", Status: status})
+			r.TestSuite = append(r.TestSuite, ts)
+		}
+	}
+
+	pass, fail := DefaultFlakyPassRatio, DefaultFlakyFailRatio
+	tr := &TestRun{
+		Report:     r,
+		Iterations: make([]Iteration, 4),
+		trps: &TestRunParams{
+			FlakyPassRatio: &pass,
+			FlakyFailRatio: &fail,
+		},
+	}
+
+	fr := tr.Flakiness()
+	if fr.Iterations != 4 || fr.StablePass != 1 || fr.StableFail != 1 || fr.Flaky != 1 {
+		t.Fatalf("unexpected report %#v", fr)
+	}
+
+	want := FlakyTest{Name: "run:g:flaky", Runs: 3, Passed: 2, PassRatio: 2.0 / 3, Class: Flaky}
+	if fr.Tests[1] != want {
+		t.Fatalf("got %#v, want %#v", fr.Tests[1], want)
+	}
+
+	var sb strings.Builder
+	if err := fr.Write(&sb, "text"); err != nil {
+		t.Fatal(err)
+	}
+	if got := sb.String(); got != "Flaky=1 StablePass=1 StableFail=1 Iterations=4\n  run:g:flaky passed 2 of 3 (66.7%)\n" {
+		t.Fatalf("unexpected text %q", got)
+	}
+
+	sb.Reset()
+	if err := fr.Write(&sb, "JSON"); err != nil {
+		t.Fatal(err)
+	}
+	var got FlakyReport
+	if err := json.Unmarshal([]byte(sb.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Tests) != 3 || got.Tests[0].Class != StableFail {
+		t.Fatalf("unexpected JSON %s", sb.String())
+	}
+
+	// A lower pass ratio makes the flaky test stable.
+	pass = 0.6
+	if fr := tr.Flakiness(); fr.Flaky != 0 || fr.StablePass != 2 {
+		t.Fatalf("unexpected report %#v", fr)
+	}
+}
+
+func TestCheckFlaky(t *testing.T) {
+	var (
+		two, three = 2, 3
+		one        = 1
+		half, zero = 0.5, 0.0
+	)
+
+	for _, trps := range []*TestRunParams{
+		{Flaky: &one},
+		{Flaky: &two, Repeat: &three},
+		{Flaky: &two, FlakyPassRatio: &zero},
+		{Flaky: &two, FlakyPassRatio: &half, FlakyFailRatio: &half},
+	} {
+		if err := checkFlaky(trps); err == nil {
+			t.Fatalf("expected an error for %#v", trps)
+		}
+	}
+
+	if err := checkFlaky(&TestRunParams{Flaky: &two, FlakyPassRatio: &half}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// FlatTestCase is a TestCase of a TestSuite of the Report as a flat
+// record, which maps onto a table without recursive flattening.
+type FlatTestCase struct {
+	Suite  string `json:"suite"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+
+	// Duration is in seconds (and zero for skipped test cases).
+	Duration float64 `json:"duration"`
+
+	Message   string `json:"message"`
+	SystemOut string `json:"systemOut"`
+}
+
+// FlatTestCases returns a FlatTestCase for each TestCase of each
+// TestSuite of the Report, in the order of execution.
+func (tr *TestRun) FlatTestCases() []FlatTestCase {
+	ftcs := make([]FlatTestCase, 0, 32)
+	if tr.Report == nil {
+		return ftcs
+	}
+
+	for _, ts := range tr.Report.TestSuite {
+		if ts == nil {
+			continue
+		}
+		for _, tc := range ts.TestCase {
+			var secs float64
+			if tc.Time != nil {
+				secs = tc.Time.Seconds()
+			}
+			ftcs = append(ftcs, FlatTestCase{
+				Suite:     ts.Name,
+				Name:      tc.Name,
+				Status:    string(tc.Status),
+				Duration:  secs,
+				Message:   tc.Message,
+				SystemOut: tc.SystemOut,
+			})
+		}
+	}
+
+	return ftcs
+}
+
+// WriteJSONFlat writes the FlatTestCases as a JSON array or, when
+// lines is true, as newline-delimited JSON (one object per line).
+func (tr *TestRun) WriteJSONFlat(w io.Writer, lines bool) error {
+	if tr.Report == nil {
+		return fmt.Errorf("test run %s has no report", tr.Name)
+	}
+
+	ftcs := tr.FlatTestCases()
+
+	if !lines {
+		bs, err := json.MarshalIndent(ftcs, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal results: %w", err)
+		}
+		if _, err = fmt.Fprintf(w, "%s\n", bs); err != nil {
+			return fmt.Errorf("failed to write results: %w", err)
+		}
+		return nil
+	}
+
+	enc := json.NewEncoder(w)
+	for _, ftc := range ftcs {
+		if err := enc.Encode(ftc); err != nil {
+			return fmt.Errorf("failed to write results: %w", err)
+		}
+	}
+
+	return nil
+}
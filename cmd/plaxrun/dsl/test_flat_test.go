@@ -0,0 +1,72 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Comcast/plax/cmd/plaxrun/plugins/report"
+	"github.com/Comcast/plax/junit"
+)
+
+func TestWriteJSONFlat(t *testing.T) {
+	elapsed := 1500 * time.Millisecond
+
+	ts := junit.NewTestSuite("run:group:test")
+	ts.Add(junit.TestCase{Name: "a", Status: junit.Passed, Time: &elapsed, SystemOut: "tacos\n"})
+	ts.Add(junit.TestCase{Name: "b", Status: junit.Skipped, Message: "priority"})
+
+	tr := &TestRun{
+		Report: report.NewTestReport(),
+	}
+	tr.Report.TestSuite = append(tr.Report.TestSuite, ts)
+
+	var sb strings.Builder
+	if err := tr.Write(&sb, "JSON-FLAT"); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []FlatTestCase
+	if err := json.Unmarshal([]byte(sb.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []FlatTestCase{
+		{Suite: "run:group:test", Name: "a", Status: "passed", Duration: 1.5, SystemOut: "tacos\n"},
+		{Suite: "run:group:test", Name: "b", Status: "skipped", Message: "priority"},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+
+	sb.Reset()
+	if err := tr.Write(&sb, "JSON-LINES"); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(sb.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines in %q", sb.String())
+	}
+	if lines[1] != `{"suite":"run:group:test","name":"b","status":"skipped","duration":0,"message":"priority","systemOut":""}` {
+		t.Fatal(lines[1])
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Comcast/plax/junit"
+)
+
+// WriteGitHub writes a GitHub Actions workflow command for each
+// failed, errored, or skipped TestCase of the Report, so that GitHub
+// shows them as annotations: "::error" for a failed or errored
+// TestCase and "::warning" for a skipped one.
+//
+// Each command is one line (see ghaData).
+func (tr *TestRun) WriteGitHub(w io.Writer) error {
+	if tr.Report == nil {
+		return fmt.Errorf("test run %s has no report", tr.Name)
+	}
+
+	var sb strings.Builder
+
+	for _, ts := range tr.Report.TestSuite {
+		if ts == nil {
+			continue
+		}
+		for _, tc := range ts.TestCase {
+			var cmd, what string
+			switch tc.Status {
+			case junit.Failed:
+				cmd, what = "error", "failed"
+			case junit.Error:
+				cmd, what = "error", "errored"
+			case junit.Skipped:
+				cmd, what = "warning", "skipped"
+			default:
+				continue
+			}
+
+			title := fmt.Sprintf("%s %s", timingName(ts, tc), what)
+			msg := tc.Message
+			if msg == "" {
+				msg = title
+			}
+
+			props := ""
+			if file := ghaFile(tc.File); file != "" {
+				props = "file=" + ghaProperty(file) + ","
+			}
+			props += "title=" + ghaProperty(title)
+
+			sb.WriteString(fmt.Sprintf("::%s %s::%s\n", cmd, props, ghaData(msg)))
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+
+	return err
+}
+
+// ghaFile returns the filename relative to the working directory
+// (which is usually the root of the repository in GitHub Actions),
+// or "" if that's not possible.
+func ghaFile(filename string) string {
+	if filename == "" || !filepath.IsAbs(filename) {
+		return filename
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	rel, err := filepath.Rel(wd, filename)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// ghaData encodes the message of a workflow command, which then
+// stays on one line.
+func ghaData(s string) string {
+	return strings.NewReplacer(
+		"%", "%25",
+		"\r", "%0D",
+		"\n", "%0A",
+	).Replace(s)
+}
+
+// ghaProperty encodes a property value of a workflow command.
+func ghaProperty(s string) string {
+	return strings.NewReplacer(
+		"%", "%25",
+		"\r", "%0D",
+		"\n", "%0A",
+		":", "%3A",
+		",", "%2C",
+	).Replace(s)
+}
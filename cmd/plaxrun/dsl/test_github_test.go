@@ -0,0 +1,79 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Comcast/plax/cmd/plaxrun/plugins/report"
+	"github.com/Comcast/plax/junit"
+)
+
+func TestWriteGitHub(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := junit.NewTestSuite("suite")
+
+	for _, c := range []struct {
+		name    string
+		file    string
+		status  junit.TestCaseStatus
+		message string
+	}{
+		{"passes", "passes.yaml", junit.Passed, ""},
+		{"fails", filepath.Join(wd, "tests", "fails.yaml"), junit.Failed, "expected\r\ntacos: 100%"},
+		{"breaks", "/elsewhere/breaks.yaml", junit.Error, ""},
+		{"skips, sadly", "", junit.Skipped, "priority"},
+	} {
+		tc := junit.NewTestCase(c.name, c.file)
+		tc.Finish(c.status, c.message)
+		ts.Add(*tc)
+	}
+
+	tr := &TestRun{
+		Name: "github",
+		Report: &report.TestReport{
+			TestSuite: []*junit.TestSuite{ts},
+			Total:     ts.Total,
+		},
+	}
+
+	var sb strings.Builder
+	if err := tr.WriteGitHub(&sb); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `::error file=tests/fails.yaml,title=suite/fails failed::expected%0D%0Atacos: 100%25
+::error title=suite/breaks errored::suite/breaks errored
+::warning title=suite/skips%2C sadly skipped::priority
+`
+	if got := sb.String(); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+
+	if err := (&TestRun{}).WriteGitHub(&sb); err == nil {
+		t.Fatal("expected an error without a report")
+	}
+}
@@ -20,9 +20,12 @@ package dsl
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/Comcast/plax/cmd/plaxrun/async"
 	plaxDsl "github.com/Comcast/plax/dsl"
+	"github.com/Comcast/plax/junit"
 )
 
 // TestGroupRef references a group by name
@@ -42,6 +45,7 @@ func (tgr TestGroupRef) getTaskFuncs(ctx *plaxDsl.Ctx, tr TestRun, name string,
 	}
 
 	name = fmt.Sprintf("%s:%s", name, tgr.Name)
+	tr.group = tgr.Name
 
 	err := tgr.Params.bind(ctx, bs)
 	if err != nil {
@@ -92,9 +96,37 @@ func (tgrl TestGroupRefList) getTaskFuncs(ctx *plaxDsl.Ctx, tr TestRun, name str
 // TestGroup is a set of grouped tests or nested groups
 type TestGroup struct {
 	Iterate *TestIterate     `yaml:"iterate,omitempty"`
+	ForEach *TestForEach     `yaml:"forEach,omitempty"`
 	Params  TestParamMap     `yaml:"params"`
 	Tests   TestDefRefList   `yaml:"tests"`
 	Groups  TestGroupRefList `yaml:"groups"`
+
+	// Timeout, when not zero, is the maximum duration of the
+	// tests executed by this group (including the tests of its
+	// nested groups), starting when the first of them starts.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+
+	// Labels are used by -labels to select tests.  The tests of
+	// this group and its nested groups have these labels.
+	Labels []string `yaml:"labels,omitempty"`
+
+	// ConcurrencyGroup is the default TestDef.ConcurrencyGroup of
+	// the tests of this group (and of its nested groups that don't
+	// have their own ConcurrencyGroup).
+	ConcurrencyGroup string `yaml:"concurrencyGroup,omitempty"`
+
+	// When, if not empty, is a Javascript expression (with the
+	// bindings as bs) that must be true for this group to
+	// execute.  Otherwise the group is reported as skipped.
+	When string `yaml:"when,omitempty"`
+
+	// Setup tests execute before the other tests of this group,
+	// which are skipped if a setup test fails.
+	Setup TestDefRefList `yaml:"setup,omitempty"`
+
+	// Teardown tests execute after the other tests of this group
+	// (even if they failed).
+	Teardown TestDefRefList `yaml:"teardown,omitempty"`
 }
 
 func (tg TestGroup) getTaskFuncs(ctx *plaxDsl.Ctx, tr TestRun, name string, bs *plaxDsl.Bindings) ([]*async.TaskFunc, error) {
@@ -103,8 +135,39 @@ func (tg TestGroup) getTaskFuncs(ctx *plaxDsl.Ctx, tr TestRun, name string, bs *
 		err   error
 	)
 
+	if 0 < tg.Timeout {
+		tr.groupDeadline = tr.groupDeadlines.add(tg.Timeout, tr.groupDeadline)
+	}
+
+	if tg.ConcurrencyGroup != "" {
+		tr.concurrencyGroup = tg.ConcurrencyGroup
+	}
+
+	// Don't let the nested groups share the backing array.
+	tr.groupLabels = append(tr.groupLabels[:len(tr.groupLabels):len(tr.groupLabels)], tg.Labels...)
+
 	tg.Params.bind(ctx, bs)
 
+	if tg.When != "" {
+		guard := &TestGuard{
+			Source: fmt.Sprintf("return (%s);", tg.When),
+		}
+		run, err := guard.Satisfied(ctx, tr, bs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate %s group when: %w", name, err)
+		}
+		if !run {
+			ctx.Logf("Skipping %s test group because %s is false", name, tg.When)
+			msg := "when: " + tg.When
+			return []*async.TaskFunc{{
+				Name: name,
+				Func: tr.nameCases(name, tr.progress.wrap(name, func() (*junit.TestSuite, error) {
+					return skippedSuite(name, msg), nil
+				})),
+			}}, nil
+		}
+	}
+
 	if tg.Iterate != nil {
 		tibsl, err = tg.Iterate.getBindings(ctx, tr, bs)
 		if err != nil {
@@ -116,6 +179,24 @@ func (tg TestGroup) getTaskFuncs(ctx *plaxDsl.Ctx, tr TestRun, name string, bs *
 		})
 	}
 
+	if tg.ForEach != nil {
+		// Each iteration fans out over the elements.
+		var fibsl TestIterateBindingsList
+		for _, tibs := range tibsl {
+			ebsl, err := tg.ForEach.getBindings(ctx, tr, tibs.bs)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get forEach bindings: %w", err)
+			}
+			for _, ebs := range ebsl {
+				if 0 < len(tibs.name) {
+					ebs.name = tibs.name + ":" + ebs.name
+				}
+				fibsl = append(fibsl, ebs)
+			}
+		}
+		tibsl = fibsl
+	}
+
 	tl := make([]*async.TaskFunc, 0)
 
 	for _, tibs := range tibsl {
@@ -139,7 +220,7 @@ func (tg TestGroup) getTaskFuncs(ctx *plaxDsl.Ctx, tr TestRun, name string, bs *
 		tl = append(tl, tfs...)
 	}
 
-	return tl, nil
+	return tr.withHooks(ctx, name, bs, tg.Setup, tg.Teardown, tl)
 }
 
 // TestGroupMap is a map of TestGroups
@@ -178,10 +259,38 @@ func (tgl *TestGroupList) Set(value string) error {
 func (tgl *TestGroupList) getTaskFuncs(ctx *plaxDsl.Ctx, tr TestRun) ([]*async.TaskFunc, error) {
 	tfs := make([]*async.TaskFunc, 0)
 
-	for _, n := range *tgl {
-		tg, ok := tr.Groups[n]
-		if !ok {
-			return nil, fmt.Errorf("failed to find test group %s", n)
+	names := make([]string, 0, len(tr.Groups))
+	for n := range tr.Groups {
+		names = append(names, n)
+	}
+
+	selected, err := selectNames(*tgl, names, "test group")
+	if err != nil {
+		return nil, err
+	}
+
+	// Report all of the typos before doing any work.
+	unknown := make([]string, 0)
+	for _, n := range selected {
+		if _, ok := tr.Groups[n]; !ok {
+			unknown = append(unknown, n)
+		}
+	}
+
+	if 0 < len(unknown) {
+		return nil, fmt.Errorf("no such test group: %s", strings.Join(unknown, ", "))
+	}
+
+	empty := make([]string, 0)
+
+	for _, n := range selected {
+		tg := tr.Groups[n]
+
+		// The default timeout is for the groups without their
+		// own.
+		tr.groupDeadline = nil
+		if tr.trps.GroupTimeout != nil && 0 < *tr.trps.GroupTimeout && tg.Timeout == 0 {
+			tr.groupDeadline = tr.groupDeadlines.add(*tr.trps.GroupTimeout, nil)
 		}
 
 		bs, err := (&tr.trps.Bindings).Copy()
@@ -195,13 +304,37 @@ func (tgl *TestGroupList) getTaskFuncs(ctx *plaxDsl.Ctx, tr TestRun) ([]*async.T
 			Params: tg.Params,
 		}
 
+		sharded := tr.shardedCount()
+
+		if tr.reusing() {
+			tr.chanPool = plaxDsl.NewChanPool(ctx)
+		}
+
 		gtfs, err := tgr.getTaskFuncs(ctx, tr, name, bs)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get tasks for test group %s: %w", n, err)
 		}
 
+		if tr.chanPool != nil && 0 < len(gtfs) {
+			tr.pools.add(ctx, tr.chanPool, gtfs)
+		}
+
+		tr.tracer.group(name+":"+n, gtfs)
+
+		// A group's tests can all be in other shards (or have
+		// passed, or not have changed).
+		if len(gtfs) == 0 && tr.shardedCount() == sharded && !tr.rerunning() && tr.changed == nil {
+			empty = append(empty, n)
+		}
+
 		tfs = append(tfs, gtfs...)
 	}
 
+	// Only an empty selection is an error, so that -labels,
+	// -priority, and guards can empty some of the selected groups.
+	if len(tfs) == 0 && 0 < len(empty) && len(empty) == len(selected) {
+		return nil, fmt.Errorf("test group exists but has no tests after filtering: %s", strings.Join(empty, ", "))
+	}
+
 	return tfs, nil
 }
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Comcast/plax/cmd/plaxrun/plugins/report"
+	plaxDsl "github.com/Comcast/plax/dsl"
+)
+
+func TestGroupListUnknownAndEmpty(t *testing.T) {
+	var (
+		ctx    = plaxDsl.NewCtx(context.Background())
+		labels = "smoke"
+	)
+
+	selector, err := parseLabels(labels)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.Dir = t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(ctx.Dir, "smoke.yaml"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := TestRun{
+		Tests: TestDefMap{
+			"slow": TestDef{
+				Path:   "slow.yaml",
+				Labels: []string{"slow"},
+			},
+			"smoke": TestDef{
+				Path:   "smoke.yaml",
+				Module: "fake",
+				Labels: []string{"smoke"},
+			},
+		},
+		Groups: TestGroupMap{
+			"slow": TestGroup{
+				Tests: TestDefRefList{{Name: "slow"}},
+			},
+			"smoke": TestGroup{
+				Tests: TestDefRefList{{Name: "smoke"}},
+			},
+		},
+		trps: &TestRunParams{
+			Bindings: make(plaxDsl.Bindings),
+			Labels:   &labels,
+		},
+		selector: selector,
+	}
+
+	_, err = (&TestGroupList{"slwo", "slow", "fats"}).getTaskFuncs(ctx, tr)
+	if err == nil || !strings.Contains(err.Error(), "no such test group: slwo, fats") {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	_, err = (&TestGroupList{"slow"}).getTaskFuncs(ctx, tr)
+	if err == nil || !strings.Contains(err.Error(), "has no tests after filtering: slow") {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	_, err = (&TestList{"slwo"}).getTaskFuncs(ctx, tr)
+	if err == nil || !strings.Contains(err.Error(), "no such test: slwo") {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	_, err = (&TestList{"slow"}).getTaskFuncs(ctx, tr)
+	if err == nil || !strings.Contains(err.Error(), "excluded by filtering: slow") {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// When only some of the selection is filtered out, the rest
+	// are selected.
+	tfs, err := (&TestGroupList{"*"}).getTaskFuncs(ctx, tr)
+	if err != nil || len(tfs) != 1 {
+		t.Fatalf("unexpected %d tasks and error %v", len(tfs), err)
+	}
+
+	tfs, err = (&TestList{"*"}).getTaskFuncs(ctx, tr)
+	if err != nil || len(tfs) != 1 {
+		t.Fatalf("unexpected %d tasks and error %v", len(tfs), err)
+	}
+}
+
+func TestGroupWhen(t *testing.T) {
+	spec := `name: run
+version: 0.0.1
+tests:
+  pass: {path: pass.yaml, version: fake}
+groups:
+  cleanup:
+    when: bs["cleanup"] == "true"
+    tests:
+      - name: pass
+`
+	filename := writeRunSpec(t, spec)
+
+	run := func(cleanup string) *report.TestReport {
+		opts := runOptions(filename)
+		opts.Groups = []string{"cleanup"}
+		opts.Bindings = plaxDsl.Bindings{"cleanup": cleanup}
+
+		tr, err := RunTests(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tr.Report
+	}
+
+	if r := run("true"); r.Total != 1 || r.Passed != 1 {
+		t.Fatalf("unexpected report %#v", r)
+	}
+
+	r := run("false")
+	if r.Total != 1 || r.Skipped != 1 {
+		t.Fatalf("unexpected report %#v", r)
+	}
+	if got := r.TestSuite[0].TestCase[0].Message; got != `when: bs["cleanup"] == "true"` {
+		t.Fatalf("unexpected message %q", got)
+	}
+}
+
+func TestGroupForEach(t *testing.T) {
+	spec := `name: run
+version: 0.0.1
+tests:
+  smoke: {path: pass.yaml, version: fake, labels: [smoke]}
+  slow: {path: pass.yaml, version: fake, labels: [slow]}
+groups:
+  all:
+    forEach:
+      in: endpoints
+      as: endpoint
+    tests:
+      - name: smoke
+      - name: slow
+`
+	filename := writeRunSpec(t, spec)
+
+	opts := runOptions(filename)
+	opts.Groups = []string{"all"}
+	opts.Labels = "smoke"
+	opts.Bindings = plaxDsl.Bindings{"endpoints": `["a","b"]`}
+
+	tr, err := RunTests(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for i, ts := range tr.Report.TestSuite {
+		names = append(names, ts.Name)
+		want := []string{"a", "b"}[i]
+		if got := (*tr.infos[tr.tfs[i]].bs)["endpoint"]; got != want {
+			t.Fatalf("%s has endpoint %v", ts.Name, got)
+		}
+	}
+
+	if got := strings.Join(names, " "); got != "run-0.0.1:all:endpoint=a:smoke run-0.0.1:all:endpoint=b:smoke" {
+		t.Fatalf("unexpected suites %s", got)
+	}
+}
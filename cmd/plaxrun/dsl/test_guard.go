@@ -41,7 +41,7 @@ func getLibrary(ctx *plaxDsl.Ctx, filename string) (string, error) {
 		fn := path.Join(dir, filename)
 		js, err := ioutil.ReadFile(fn)
 		if err != nil {
-			ctx.Logdf("error reading library '%s': %w", fn, err)
+			ctx.Logdf("error reading library '%s': %v", fn, err)
 			continue
 		}
 		return string(js), nil
@@ -0,0 +1,234 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/Comcast/plax/cmd/plaxrun/async"
+	plaxDsl "github.com/Comcast/plax/dsl"
+	"github.com/Comcast/plax/junit"
+)
+
+// testHooks has the setup and teardown phases of the test run and
+// its groups.
+type testHooks struct {
+	sync.Mutex
+
+	phases []*hookPhase
+
+	// preds are the task funcs that must start before a task func
+	// (see TestRun.orderByDependencies).
+	preds map[*async.TaskFunc][]*async.TaskFunc
+}
+
+func newTestHooks() *testHooks {
+	return &testHooks{
+		preds: make(map[*async.TaskFunc][]*async.TaskFunc),
+	}
+}
+
+// hookPhase is a set of task funcs (the setup or the body of a group,
+// for example) that other task funcs wait for.
+type hookPhase struct {
+	sync.Mutex
+
+	tasks   int
+	pending int
+	failed  bool
+	done    chan struct{}
+}
+
+// phase makes a new hookPhase.
+func (th *testHooks) phase() *hookPhase {
+	th.Lock()
+	defer th.Unlock()
+
+	ph := &hookPhase{}
+	th.phases = append(th.phases, ph)
+	return ph
+}
+
+// reset prepares for an execution of the tests.  A phase without
+// task funcs is already done.
+func (th *testHooks) reset() {
+	if th == nil {
+		return
+	}
+
+	th.Lock()
+	defer th.Unlock()
+
+	for _, ph := range th.phases {
+		ph.Lock()
+		ph.pending = ph.tasks
+		ph.failed = false
+		ph.done = make(chan struct{})
+		if ph.tasks == 0 {
+			close(ph.done)
+		}
+		ph.Unlock()
+	}
+}
+
+// wait for the phase to finish and report whether any of its task
+// funcs failed.
+func (ph *hookPhase) wait() bool {
+	ph.Lock()
+	done := ph.done
+	ph.Unlock()
+
+	if done == nil {
+		return false
+	}
+	<-done
+
+	ph.Lock()
+	defer ph.Unlock()
+	return ph.failed
+}
+
+// finish notes that one of the phase's task funcs finished.
+func (ph *hookPhase) finish(failed bool) {
+	ph.Lock()
+	defer ph.Unlock()
+
+	if failed {
+		ph.failed = true
+	}
+	if ph.pending--; ph.pending == 0 && ph.done != nil {
+		close(ph.done)
+	}
+}
+
+// testFunc is the Func of a task func for a test.
+type testFunc = func() (*junit.TestSuite, error)
+
+// taskTestFunc returns the Func of the task func for a test.
+func taskTestFunc(tf *async.TaskFunc) (testFunc, error) {
+	f, is := tf.Func.(testFunc)
+	if !is {
+		return nil, fmt.Errorf("task %s has an unexpected %T", tf.Name, tf.Func)
+	}
+	return f, nil
+}
+
+// member makes the task func part of the phase.
+func (ph *hookPhase) member(tf *async.TaskFunc) error {
+	f, err := taskTestFunc(tf)
+	if err != nil {
+		return err
+	}
+
+	ph.Lock()
+	ph.tasks++
+	ph.Unlock()
+
+	tf.Func = func() (*junit.TestSuite, error) {
+		ts, err := f()
+		ph.finish(err != nil || (ts != nil && (0 < ts.Failures || 0 < ts.Errors)))
+		return ts, err
+	}
+	return nil
+}
+
+// after makes the task func wait for the phase.  When skip is
+// given, the test is skipped with that message if the phase failed.
+func (ph *hookPhase) after(tf *async.TaskFunc, skip string) error {
+	f, err := taskTestFunc(tf)
+	if err != nil {
+		return err
+	}
+
+	tf.Func = func() (*junit.TestSuite, error) {
+		if ph.wait() && skip != "" {
+			return skippedSuite(tf.Name, skip), nil
+		}
+		return f()
+	}
+	return nil
+}
+
+// withHooks surrounds the body task funcs with the task funcs of the
+// setup and teardown tests.
+//
+// The body waits for the setup and is skipped if the setup fails.
+// The teardown waits for the body and is always executed.  The setup
+// and teardown tests aren't subject to -labels, -priority, sharding,
+// or -fail-fast, and they're omitted when the body is empty.
+func (tr TestRun) withHooks(ctx *plaxDsl.Ctx, name string, bs *plaxDsl.Bindings, setup, teardown TestDefRefList, body []*async.TaskFunc) ([]*async.TaskFunc, error) {
+	if len(body) == 0 || (len(setup) == 0 && len(teardown) == 0) || tr.hooks == nil {
+		return body, nil
+	}
+
+	tr.hook = true
+
+	stfs, err := setup.getTaskFuncs(ctx, tr, name+":setup", bs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s setup tasks: %w", name, err)
+	}
+
+	ttfs, err := teardown.getTaskFuncs(ctx, tr, name+":teardown", bs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s teardown tasks: %w", name, err)
+	}
+
+	var (
+		th      = tr.hooks
+		setupPh = th.phase()
+		bodyPh  = th.phase()
+		skip    = fmt.Sprintf("setup: %s:setup failed", name)
+	)
+
+	for _, tf := range stfs {
+		if err := setupPh.member(tf); err != nil {
+			return nil, err
+		}
+	}
+
+	th.Lock()
+	defer th.Unlock()
+
+	for _, tf := range body {
+		if 0 < len(stfs) {
+			if err := setupPh.after(tf, skip); err != nil {
+				return nil, err
+			}
+		}
+		if err := bodyPh.member(tf); err != nil {
+			return nil, err
+		}
+		th.preds[tf] = append(th.preds[tf], stfs...)
+	}
+
+	for _, tf := range ttfs {
+		if err := bodyPh.after(tf, ""); err != nil {
+			return nil, err
+		}
+		th.preds[tf] = append(append(th.preds[tf], stfs...), body...)
+	}
+
+	tfs := make([]*async.TaskFunc, 0, len(stfs)+len(body)+len(ttfs))
+	tfs = append(tfs, stfs...)
+	tfs = append(tfs, body...)
+	tfs = append(tfs, ttfs...)
+
+	return tfs, nil
+}
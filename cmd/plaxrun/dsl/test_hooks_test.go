@@ -0,0 +1,155 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetupTeardown(t *testing.T) {
+	spec := `name: run
+version: 0.0.1
+tests:
+  provision: {path: pass.yaml, version: fake}
+  cleanup: {path: pass.yaml, version: fake}
+  broken: {path: pass.yaml, version: failing}
+  check: {path: pass.yaml, version: fake, labels: [smoke]}
+  flop: {path: pass.yaml, version: failing, labels: [smoke]}
+setup:
+  - name: provision
+teardown:
+  - name: cleanup
+groups:
+  ok:
+    setup: [{name: provision}]
+    teardown: [{name: cleanup}]
+    tests:
+      - name: check
+      - name: flop
+  bad:
+    setup: [{name: broken}]
+    teardown: [{name: cleanup}]
+    tests:
+      - name: check
+`
+	filename := writeRunSpec(t, spec)
+
+	opts := runOptions(filename)
+	opts.Groups = []string{"ok", "bad"}
+	opts.Labels = "smoke"
+	opts.FailFast = true
+	opts.Shuffle = true
+
+	for _, concurrency := range []int{1, 4} {
+		opts.MaxConcurrency = concurrency
+
+		tr, err := RunTests(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, ts := range tr.Report.TestSuite {
+			tc := ts.TestCase[0]
+			got = append(got, strings.TrimPrefix(tc.Name, "run-0.0.1:")+"="+string(tc.Status)+" "+tc.Message)
+		}
+
+		// The teardowns execute despite -fail-fast, which may
+		// skip check (depending on the shuffle).
+		want := map[string]bool{
+			"setup:provision=passed ":                             true,
+			"ok:setup:provision=passed ":                          true,
+			"ok:check=skipped fail-fast: an earlier test failed":  true,
+			"ok:check=passed ":                                    true,
+			"ok:flop=failed no tacos":                             true,
+			"ok:teardown:cleanup=passed ":                         true,
+			"bad:setup:broken=failed no tacos":                    true,
+			"bad:check=skipped setup: run-0.0.1:bad:setup failed": true,
+			"bad:teardown:cleanup=passed ":                        true,
+			"teardown:cleanup=passed ":                            true,
+		}
+		if len(got) != len(want)-1 || got[0] != "setup:provision=passed " || got[len(got)-1] != "teardown:cleanup=passed " {
+			t.Fatalf("concurrency %d: got\n%s", concurrency, strings.Join(got, "\n"))
+		}
+		for _, s := range got {
+			if !want[s] {
+				t.Fatalf("concurrency %d: unexpected %q in\n%s", concurrency, s, strings.Join(got, "\n"))
+			}
+		}
+	}
+
+	spec = strings.Replace(spec, "[{name: broken}]", "[{name: missing}]", 1)
+	if err := ioutil.WriteFile(filename, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunTests(context.Background(), opts); err == nil || !strings.Contains(err.Error(), `groups.bad.setup.0.name: no such test "missing"`) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestTeardownOnly(t *testing.T) {
+	spec := `name: run
+version: 0.0.1
+tests:
+  cleanup: {path: pass.yaml, version: fake}
+  check: {path: pass.yaml, version: fake}
+teardown:
+  - name: cleanup
+groups:
+  ok:
+    teardown: [{name: cleanup}]
+    tests:
+      - name: check
+`
+	opts := runOptions(writeRunSpec(t, spec))
+	opts.Groups = []string{"ok"}
+
+	type result struct {
+		tr  *TestRun
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		tr, err := RunTests(context.Background(), opts)
+		done <- result{tr, err}
+	}()
+
+	var r result
+	select {
+	case r = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("hung")
+	}
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+
+	var got []string
+	for _, ts := range r.tr.Report.TestSuite {
+		tc := ts.TestCase[0]
+		got = append(got, strings.TrimPrefix(tc.Name, "run-0.0.1:")+"="+string(tc.Status))
+	}
+	if want := "ok:check=passed ok:teardown:cleanup=passed teardown:cleanup=passed"; strings.Join(got, " ") != want {
+		t.Fatalf("got %s (wanted %s)", strings.Join(got, " "), want)
+	}
+}
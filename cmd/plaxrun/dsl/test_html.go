@@ -0,0 +1,102 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"time"
+)
+
+// htmlReport is the self-contained page written by WriteHTML.
+//
+// The page has no timestamps (only durations), so the results of two
+// runs can be diffed.
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"seconds": htmlSeconds,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}} {{.Version}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+th { background: #eee; }
+td.duration { text-align: right; white-space: nowrap; }
+pre { margin: 0.3em 0; white-space: pre-wrap; }
+.passed { background: #dfd; }
+.failed { background: #fdd; }
+.error { background: #fcb; }
+.skipped { background: #eee; color: #666; }
+</style>
+</head>
+<body>
+<h1>{{.Name}} {{.Version}}</h1>
+<p>{{.Total}} tests: {{.Passed}} passed, {{.Failures}} failed, {{.Errors}} errors, {{.Skipped}} skipped</p>
+{{range .TestSuite}}{{if .}}<h2>{{.Name}}</h2>
+<p>{{.Total}} tests: {{.Passed}} passed, {{.Failures}} failed, {{.Errors}} errors, {{.Skipped}} skipped ({{seconds .Time}}s)</p>
+{{if .Message}}<pre>{{.Message}}</pre>
+{{end}}<table>
+<tr><th>Test</th><th>Status</th><th>Duration (s)</th><th>Details</th></tr>
+{{range .TestCase}}<tr class="{{.Status}}">
+<td>{{.Name}}</td>
+<td>{{.Status}}</td>
+<td class="duration">{{if .Time}}{{seconds .Time}}{{end}}</td>
+<td>{{if .Message}}<pre>{{.Message}}</pre>{{end}}{{if .SystemOut}}<details><summary>system-out</summary><pre>{{.SystemOut}}</pre></details>{{end}}{{if .SystemErr}}<details><summary>system-err</summary><pre>{{.SystemErr}}</pre></details>{{end}}</td>
+</tr>
+{{end}}</table>
+{{end}}{{end}}</body>
+</html>
+`))
+
+// htmlSeconds formats a duration (or a pointer to one) in seconds.
+func htmlSeconds(d interface{}) string {
+	var secs float64
+	switch v := d.(type) {
+	case time.Duration:
+		secs = v.Seconds()
+	case *time.Duration:
+		if v != nil {
+			secs = v.Seconds()
+		}
+	}
+	return strconv.FormatFloat(secs, 'f', 3, 64)
+}
+
+// WriteHTML writes the Report as a self-contained HTML page (with
+// inline CSS and no external assets) that summarizes each TestSuite
+// and its TestCases.
+//
+// The captured logs are in collapsible sections.  Since the Report is
+// redacted by Exec, so is the page.
+func (tr *TestRun) WriteHTML(w io.Writer) error {
+	if tr.Report == nil {
+		return fmt.Errorf("test run %s has no report", tr.Name)
+	}
+
+	if err := htmlReport.Execute(w, tr.Report); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+
+	return nil
+}
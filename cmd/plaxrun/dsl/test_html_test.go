@@ -0,0 +1,74 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Comcast/plax/cmd/plaxrun/plugins/report"
+	"github.com/Comcast/plax/junit"
+)
+
+func TestWriteHTML(t *testing.T) {
+	elapsed := 1500 * time.Millisecond
+
+	ts := junit.NewTestSuite("run:group:test")
+	ts.Add(junit.TestCase{Name: "a", Status: junit.Passed, Time: &elapsed, SystemOut: "<tacos>\n"})
+	ts.Add(junit.TestCase{Name: "b", Status: junit.Failed, Time: &elapsed, Message: "expected chips & salsa"})
+	ts.Add(junit.TestCase{Name: "c", Status: junit.Skipped, Message: "priority"})
+
+	tr := &TestRun{
+		Name:   "run",
+		Report: report.NewTestReport(),
+	}
+	tr.Report.TestSuite = append(tr.Report.TestSuite, ts)
+
+	var sb strings.Builder
+	if err := tr.Write(&sb, "HTML"); err != nil {
+		t.Fatal(err)
+	}
+	page := sb.String()
+
+	for _, want := range []string{
+		`<tr class="passed">`,
+		`<tr class="failed">`,
+		`<tr class="skipped">`,
+		`<td class="duration">1.500</td>`,
+		`<details><summary>system-out</summary><pre>&lt;tacos&gt;`,
+		`expected chips &amp; salsa`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Fatalf("missing %q in\n%s", want, page)
+		}
+	}
+
+	if strings.Contains(page, "http") {
+		t.Fatalf("unexpected external reference in\n%s", page)
+	}
+
+	sb.Reset()
+	if err := tr.WriteHTML(&sb); err != nil {
+		t.Fatal(err)
+	}
+	if sb.String() != page {
+		t.Fatal("page isn't stable")
+	}
+}
@@ -111,3 +111,76 @@ func (ti TestIterate) getBindings(ctx *plaxDsl.Ctx, tr TestRun, bs *plaxDsl.Bind
 
 	return tibl, nil
 }
+
+// TestForEach fans out a test group over the elements of a
+// list-valued binding
+type TestForEach struct {
+	// In is the name of the binding (or param) with the list.
+	In string `yaml:"in"`
+
+	// As is the name of the binding for each element.
+	As string `yaml:"as"`
+}
+
+// elements returns the list bound to In, which can be a JSON array.
+func (fe TestForEach) elements(ctx *plaxDsl.Ctx, tr TestRun, bs *plaxDsl.Bindings) ([]interface{}, error) {
+	x, have := (*bs)[fe.In]
+	if !have {
+		if err := TestParamDependency(fe.In).process(ctx, tr.Params, bs); err != nil {
+			return nil, err
+		}
+		x = (*bs)[fe.In]
+	}
+
+	if s, is := x.(string); is {
+		var xs []interface{}
+		if err := json.Unmarshal([]byte(trimQuotes(s)), &xs); err != nil {
+			return nil, fmt.Errorf("forEach binding %s isn't a list: %s", fe.In, s)
+		}
+		return xs, nil
+	}
+
+	xs, is := x.([]interface{})
+	if !is {
+		return nil, fmt.Errorf("forEach binding %s is a %T and not a list", fe.In, x)
+	}
+	return xs, nil
+}
+
+// getBindings returns the bindings for each element.  The name of an
+// iteration is As=element (or As-index for non-scalar elements).
+func (fe TestForEach) getBindings(ctx *plaxDsl.Ctx, tr TestRun, bs *plaxDsl.Bindings) (TestIterateBindingsList, error) {
+	if fe.In == "" || fe.As == "" {
+		return nil, fmt.Errorf("forEach needs both in and as")
+	}
+
+	xs, err := fe.elements(ctx, tr, bs)
+	if err != nil {
+		return nil, err
+	}
+
+	tibl := make(TestIterateBindingsList, 0, len(xs))
+
+	for i, x := range xs {
+		fbs, err := bs.Copy()
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy bindings: %w", err)
+		}
+		fbs.SetKeyValue(fe.As, x)
+
+		var name string
+		switch x.(type) {
+		case string, float64, bool:
+			name = fmt.Sprintf("%s=%v", fe.As, x)
+		default:
+			name = fmt.Sprintf("%s-%d", fe.As, i)
+		}
+
+		tibl = append(tibl, TestIterateBindings{
+			name: name,
+			bs:   fbs,
+		})
+	}
+
+	return tibl, nil
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Comcast/plax/junit"
+)
+
+// JUnitPlaceholders describes the placeholders of the
+// TestRunParams.JUnitClassname and JUnitName templates.
+var JUnitPlaceholders = map[string]string{
+	"suite":   "the name of the TestSuite (as in 'RUN-VERSION:GROUP:TEST')",
+	"run":     "the name of the test run",
+	"version": "the version of the test run",
+	"group":   "the name of the innermost group (if any)",
+	"test":    "the (original) name of the TestCase",
+	"shard":   "the -shard-index (if sharding)",
+}
+
+var junitPlaceholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// checkJUnitTemplates validates the placeholders of the
+// TestRunParams.JUnitClassname and JUnitName.
+func checkJUnitTemplates(trps *TestRunParams) error {
+	for flag, tmpl := range map[string]*string{
+		"junit-classname-template": trps.JUnitClassname,
+		"junit-name-template":      trps.JUnitName,
+	} {
+		if tmpl == nil {
+			continue
+		}
+		for _, m := range junitPlaceholder.FindAllStringSubmatch(*tmpl, -1) {
+			if _, have := JUnitPlaceholders[m[1]]; !have {
+				return fmt.Errorf("unknown placeholder %s in -%s", m[0], flag)
+			}
+		}
+	}
+	return nil
+}
+
+// nameCases wraps the function to set the classname and name of each
+// TestCase of the TestSuite from the TestRunParams.JUnitClassname
+// and JUnitName templates (if any).
+func (tr TestRun) nameCases(name string, f func() (*junit.TestSuite, error)) func() (*junit.TestSuite, error) {
+	var classname, caseName string
+	if tr.trps.JUnitClassname != nil {
+		classname = *tr.trps.JUnitClassname
+	}
+	if tr.trps.JUnitName != nil {
+		caseName = *tr.trps.JUnitName
+	}
+	if classname == "" && caseName == "" {
+		return f
+	}
+
+	var shard string
+	if tr.sharding() {
+		shard = strconv.Itoa(tr.shardIndex())
+	}
+
+	return func() (*junit.TestSuite, error) {
+		ts, err := f()
+		if ts == nil {
+			return ts, err
+		}
+		for i := range ts.TestCase {
+			tc := &ts.TestCase[i]
+			r := strings.NewReplacer(
+				"{suite}", ts.Name,
+				"{run}", tr.Name,
+				"{version}", tr.Version,
+				"{group}", tr.group,
+				"{test}", tc.Name,
+				"{shard}", shard,
+			)
+			if classname != "" {
+				tc.Classname = r.Replace(classname)
+			}
+			if caseName != "" {
+				tc.Name = r.Replace(caseName)
+			}
+		}
+		return ts, err
+	}
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"testing"
+)
+
+func TestJUnitNames(t *testing.T) {
+	spec := `name: run
+version: 0.0.1
+tests:
+  pass: {path: pass.yaml, version: fake}
+groups:
+  lunch:
+    tests:
+      - name: pass
+`
+	filename := writeRunSpec(t, spec)
+
+	opts := runOptions(filename)
+	opts.Groups = []string{"lunch"}
+	opts.ShardIndex = 0
+	opts.ShardTotal = 1
+	opts.JUnitClassname = "plax.{run}.{version}.{group}"
+	opts.JUnitName = "{test}[{shard}]"
+
+	tr, err := RunTests(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tc := tr.Report.TestSuite[0].TestCase[0]
+	if tc.Classname != "plax.run.0.0.1.lunch" {
+		t.Fatalf("unexpected classname %q", tc.Classname)
+	}
+	// The fake plugin names its TestCase after the TestSuite.
+	if tc.Name != "run-0.0.1:lunch:pass[0]" {
+		t.Fatalf("unexpected name %q", tc.Name)
+	}
+
+	opts.JUnitName = "{tset}"
+	if _, err = RunTests(context.Background(), opts); err == nil {
+		t.Fatal("expected an error for an unknown placeholder")
+	}
+}
@@ -0,0 +1,282 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// labelExpr is a parsed -labels expression.
+//
+// Labels are combined with "&&" (or ","), "||", "!", and parentheses.
+// "&&" binds more tightly than "||", so "smoke && !slow || nightly"
+// means "(smoke && !slow) || nightly".
+type labelExpr interface {
+	// match reports whether the expression holds for the labels.
+	match(labels map[string]bool) bool
+
+	// list returns the labels of an expression that only requires
+	// labels to be present.
+	list() ([]string, bool)
+}
+
+type labelTerm string
+
+func (e labelTerm) match(labels map[string]bool) bool {
+	return labels[string(e)]
+}
+
+func (e labelTerm) list() ([]string, bool) {
+	return []string{string(e)}, true
+}
+
+type labelNot struct {
+	e labelExpr
+}
+
+func (e labelNot) match(labels map[string]bool) bool {
+	return !e.e.match(labels)
+}
+
+func (e labelNot) list() ([]string, bool) {
+	return nil, false
+}
+
+type labelAnd []labelExpr
+
+func (e labelAnd) match(labels map[string]bool) bool {
+	for _, x := range e {
+		if !x.match(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e labelAnd) list() ([]string, bool) {
+	acc := make([]string, 0, len(e))
+	for _, x := range e {
+		l, ok := x.list()
+		if !ok {
+			return nil, false
+		}
+		acc = append(acc, l...)
+	}
+	return acc, true
+}
+
+type labelOr []labelExpr
+
+func (e labelOr) match(labels map[string]bool) bool {
+	for _, x := range e {
+		if x.match(labels) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e labelOr) list() ([]string, bool) {
+	return nil, false
+}
+
+// parseLabels parses a -labels expression.
+//
+// An empty expression gives a nil labelExpr.
+func parseLabels(s string) (labelExpr, error) {
+	p := &labelParser{
+		toks: tokenizeLabels(s),
+	}
+
+	if len(p.toks) == 0 {
+		return nil, nil
+	}
+
+	e, err := p.or()
+	if err != nil {
+		return nil, fmt.Errorf("bad labels %q: %w", s, err)
+	}
+
+	if p.i < len(p.toks) {
+		return nil, fmt.Errorf("bad labels %q: unexpected %q", s, p.toks[p.i])
+	}
+
+	return e, nil
+}
+
+// tokenizeLabels splits s into labels and operators.
+func tokenizeLabels(s string) []string {
+	var (
+		toks []string
+		rs   = []rune(s)
+	)
+
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '!' || r == '(' || r == ')' || r == ',':
+			toks = append(toks, string(r))
+			i++
+		case (r == '&' || r == '|') && i+1 < len(rs) && rs[i+1] == r:
+			toks = append(toks, string(rs[i:i+2]))
+			i += 2
+		default:
+			j := i
+			for j < len(rs) && !unicode.IsSpace(rs[j]) && !strings.ContainsRune("!(),&|", rs[j]) {
+				j++
+			}
+			if j == i {
+				// A lone '&' or '|'.
+				j++
+			}
+			toks = append(toks, string(rs[i:j]))
+			i = j
+		}
+	}
+
+	return toks
+}
+
+type labelParser struct {
+	toks []string
+	i    int
+}
+
+func (p *labelParser) peek() string {
+	if p.i < len(p.toks) {
+		return p.toks[p.i]
+	}
+	return ""
+}
+
+func (p *labelParser) or() (labelExpr, error) {
+	e, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	es := labelOr{e}
+	for p.peek() == "||" {
+		p.i++
+		if e, err = p.and(); err != nil {
+			return nil, err
+		}
+		es = append(es, e)
+	}
+
+	if len(es) == 1 {
+		return es[0], nil
+	}
+	return es, nil
+}
+
+func (p *labelParser) and() (labelExpr, error) {
+	e, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	es := labelAnd{e}
+	for p.peek() == "&&" || p.peek() == "," {
+		p.i++
+		if e, err = p.not(); err != nil {
+			return nil, err
+		}
+		es = append(es, e)
+	}
+
+	if len(es) == 1 {
+		return es[0], nil
+	}
+	return es, nil
+}
+
+func (p *labelParser) not() (labelExpr, error) {
+	switch tok := p.peek(); tok {
+	case "":
+		return nil, fmt.Errorf("unexpected end")
+	case "!":
+		p.i++
+		e, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return labelNot{e}, nil
+	case "(":
+		p.i++
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.i++
+		return e, nil
+	case ")", "&&", "||", ",", "&", "|":
+		return nil, fmt.Errorf("unexpected %q", tok)
+	default:
+		p.i++
+		return labelTerm(tok), nil
+	}
+}
+
+// selects reports whether the -labels expression selects the test.
+//
+// The labels of a test are its own labels along with the labels of
+// the groups that contain it.  A test without any labels is left to
+// plax to filter when the expression is just a list of labels, which
+// is what -labels has always been.
+func (tr TestRun) selects(td TestDef) bool {
+	if tr.selector == nil {
+		return true
+	}
+
+	labels := make(map[string]bool)
+	for _, l := range tr.groupLabels {
+		labels[l] = true
+	}
+	for _, l := range td.Labels {
+		labels[l] = true
+	}
+
+	if len(labels) == 0 {
+		if _, ok := tr.selector.list(); ok {
+			return true
+		}
+	}
+
+	return tr.selector.match(labels)
+}
+
+// plaxLabels returns the -labels that plax should use to filter the
+// tests in the test's spec.
+func (tr TestRun) plaxLabels(td TestDef) []string {
+	if tr.selector == nil || 0 < len(tr.groupLabels) || 0 < len(td.Labels) {
+		return nil
+	}
+
+	labels, _ := tr.selector.list()
+
+	return labels
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		expr   string
+		labels []string
+		want   bool
+	}{
+		{"smoke", []string{"smoke"}, true},
+		{"smoke", []string{"slow"}, false},
+		{"smoke,regression", []string{"smoke"}, false},
+		{"smoke,regression", []string{"regression", "smoke"}, true},
+		{"smoke && !slow", []string{"smoke"}, true},
+		{"smoke && !slow", []string{"smoke", "slow"}, false},
+		{"smoke && !slow || nightly", []string{"slow", "nightly"}, true},
+		{"smoke && (!slow || nightly)", []string{"slow", "nightly"}, false},
+		{"!!smoke", []string{"smoke"}, true},
+	}
+
+	for _, test := range tests {
+		e, err := parseLabels(test.expr)
+		if err != nil {
+			t.Fatalf("%s: %s", test.expr, err)
+		}
+
+		labels := make(map[string]bool)
+		for _, l := range test.labels {
+			labels[l] = true
+		}
+
+		if got := e.match(labels); got != test.want {
+			t.Errorf("%s with %v: got %v, want %v", test.expr, test.labels, got, test.want)
+		}
+	}
+}
+
+func TestParseLabelsErrors(t *testing.T) {
+	for _, expr := range []string{"smoke &&", "(smoke", "smoke)", "smoke & slow", "|| smoke", "!"} {
+		if _, err := parseLabels(expr); err == nil {
+			t.Errorf("%s: expected an error", expr)
+		}
+	}
+
+	if e, err := parseLabels(" "); err != nil || e != nil {
+		t.Errorf("expected nothing for an empty expression: %v, %v", e, err)
+	}
+}
+
+func TestSelects(t *testing.T) {
+	e, err := parseLabels("smoke,fast")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr := TestRun{
+		selector: e,
+	}
+
+	if !tr.selects(TestDef{}) {
+		t.Fatal("a test without labels should be left to plax")
+	}
+
+	if got, want := tr.plaxLabels(TestDef{}), []string{"smoke", "fast"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	if tr.selects(TestDef{Labels: []string{"smoke"}}) {
+		t.Fatal("shouldn't have selected a test without fast")
+	}
+
+	tr.groupLabels = []string{"fast"}
+
+	if !tr.selects(TestDef{Labels: []string{"smoke"}}) {
+		t.Fatal("should have selected a test in a fast group")
+	}
+
+	if got := tr.plaxLabels(TestDef{}); got != nil {
+		t.Fatalf("labelled test shouldn't be filtered by plax: %v", got)
+	}
+
+	if tr.selector, err = parseLabels("!slow"); err != nil {
+		t.Fatal(err)
+	}
+	tr.groupLabels = nil
+
+	if !tr.selects(TestDef{}) || tr.plaxLabels(TestDef{}) != nil {
+		t.Fatal("a test without labels should be matched by plaxrun")
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"runtime"
+	"time"
+)
+
+var (
+	// DefaultLeakThreshold is the number of goroutines that a
+	// test run can leave running before LeakCheck warns.
+	DefaultLeakThreshold = 5
+
+	// LeakSettle is how long LeakCheck waits for goroutines to
+	// finish after a test run.
+	LeakSettle = time.Second
+)
+
+// leakThreshold is the LeakThreshold param or the DefaultLeakThreshold.
+func (tr *TestRun) leakThreshold() int {
+	if tr.trps.LeakThreshold != nil && 0 < *tr.trps.LeakThreshold {
+		return *tr.trps.LeakThreshold
+	}
+	return DefaultLeakThreshold
+}
+
+// checkLeaks warns, with a goroutine dump, when more than the
+// leakThreshold goroutines than before are still running after
+// LeakSettle.  Returns the number of additional goroutines.
+func (tr *TestRun) checkLeaks(ctx *Ctx, before int) int {
+	var (
+		threshold = tr.leakThreshold()
+		deadline  = time.Now().Add(LeakSettle)
+		leaked    int
+	)
+
+	for {
+		if leaked = runtime.NumGoroutine() - before; leaked <= threshold {
+			return leaked
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx.Warnf("%d goroutines (more than %d) are still running after the test run; a channel might not have been closed:\n%s",
+		leaked, threshold, goroutineDump())
+
+	return leaked
+}
+
+// goroutineDump returns the stacks of all goroutines.
+func goroutineDump() []byte {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
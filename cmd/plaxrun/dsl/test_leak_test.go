@@ -0,0 +1,92 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Comcast/plax/junit"
+)
+
+// leakyPlugin passes after starting goroutines that wait for its
+// stop channel.
+type leakyPlugin struct {
+	stop chan struct{}
+}
+
+func (p *leakyPlugin) Invoke(ctx context.Context) (*junit.TestSuite, error) {
+	for i := 0; i < 10; i++ {
+		go func() { <-p.stop }()
+	}
+	ts := junit.NewTestSuite("leaky")
+	tc := junit.NewTestCase("leaky", "")
+	tc.Finish(junit.Passed)
+	ts.Add(*tc)
+	ts.Finish()
+	return ts, nil
+}
+
+func TestLeakCheck(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+
+	ThePluginRegistry.Register("leaky", func(def PluginDef) (Plugin, error) {
+		return &leakyPlugin{stop: stop}, nil
+	})
+
+	defer func(d time.Duration) { LeakSettle = d }(LeakSettle)
+	LeakSettle = 50 * time.Millisecond
+
+	spec := `name: run
+version: 0.0.1
+tests:
+  leaky: {path: pass.yaml, version: leaky}
+  pass: {path: pass.yaml, version: fake}
+groups:
+  leaky:
+    tests:
+      - name: leaky
+  pass:
+    tests:
+      - name: pass
+`
+	filename := writeRunSpec(t, spec)
+
+	run := func(group string) *TestRun {
+		opts := runOptions(filename)
+		opts.Groups = []string{group}
+		opts.LeakCheck = true
+
+		tr, err := RunTests(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tr
+	}
+
+	if tr := run("pass"); DefaultLeakThreshold < tr.Leaked {
+		t.Fatalf("%d goroutines leaked without a leak", tr.Leaked)
+	}
+
+	if tr := run("leaky"); tr.Leaked < 10 {
+		t.Fatalf("only %d goroutines leaked", tr.Leaked)
+	}
+}
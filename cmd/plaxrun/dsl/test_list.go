@@ -0,0 +1,176 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Catalog describes the tests, groups, and params that a TestRun
+// offers.
+type Catalog struct {
+	Tests  []CatalogTest  `json:"tests"`
+	Groups []CatalogGroup `json:"groups"`
+	Params []CatalogParam `json:"params"`
+}
+
+// CatalogTest describes a test.
+type CatalogTest struct {
+	Name   string   `json:"name"`
+	Path   string   `json:"path"`
+	Labels []string `json:"labels,omitempty"`
+	Params []string `json:"params,omitempty"`
+	Skip   string   `json:"skip,omitempty"`
+}
+
+// CatalogGroup describes a test group and the tests and groups that
+// it references.
+type CatalogGroup struct {
+	Name   string   `json:"name"`
+	Tests  []string `json:"tests,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+// CatalogParam describes a param.
+//
+// The Default is the DEFAULT (or else VALUE) environment variable of
+// the param's command (if any), which is how the included commands
+// get default values.
+type CatalogParam struct {
+	Name      string      `json:"name"`
+	Required  bool        `json:"required,omitempty"`
+	Default   interface{} `json:"default,omitempty"`
+	DependsOn []string    `json:"dependsOn,omitempty"`
+}
+
+// Catalog returns the Catalog of the TestRun with everything sorted
+// by name.
+func (tr *TestRun) Catalog() Catalog {
+	c := Catalog{
+		Tests:  make([]CatalogTest, 0, len(tr.Tests)),
+		Groups: make([]CatalogGroup, 0, len(tr.Groups)),
+		Params: make([]CatalogParam, 0, len(tr.Params)),
+	}
+
+	for name, td := range tr.Tests {
+		ct := CatalogTest{
+			Name:   name,
+			Path:   td.Path,
+			Labels: td.Labels,
+			Skip:   td.Skip,
+		}
+		for _, p := range td.Params {
+			ct.Params = append(ct.Params, string(p))
+		}
+		c.Tests = append(c.Tests, ct)
+	}
+	sort.Slice(c.Tests, func(i, j int) bool { return c.Tests[i].Name < c.Tests[j].Name })
+
+	for name, tg := range tr.Groups {
+		cg := CatalogGroup{
+			Name:   name,
+			Labels: tg.Labels,
+		}
+		for _, tdr := range tg.Tests {
+			cg.Tests = append(cg.Tests, tdr.Name)
+		}
+		for _, tgr := range tg.Groups {
+			cg.Groups = append(cg.Groups, tgr.Name)
+		}
+		c.Groups = append(c.Groups, cg)
+	}
+	sort.Slice(c.Groups, func(i, j int) bool { return c.Groups[i].Name < c.Groups[j].Name })
+
+	for name, tpb := range tr.Params {
+		cp := CatalogParam{
+			Name:     name,
+			Required: tpb.Required,
+			Default:  tpb.Envs["DEFAULT"],
+		}
+		if cp.Default == nil {
+			cp.Default = tpb.Envs["VALUE"]
+		}
+		for _, d := range tpb.dependencies(name, tr.Params) {
+			cp.DependsOn = append(cp.DependsOn, string(d))
+		}
+		c.Params = append(c.Params, cp)
+	}
+	sort.Slice(c.Params, func(i, j int) bool { return c.Params[i].Name < c.Params[j].Name })
+
+	return c
+}
+
+// WriteList writes the Catalog in the given format ("text" or
+// "JSON").
+func (tr *TestRun) WriteList(w io.Writer, format string) error {
+	c := tr.Catalog()
+
+	var sb strings.Builder
+
+	switch format {
+	case "JSON":
+		js, err := json.MarshalIndent(c, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal list: %w", err)
+		}
+		sb.Write(js)
+		sb.WriteString("\n")
+	case "text":
+		sb.WriteString("tests:\n")
+		for _, ct := range c.Tests {
+			fmt.Fprintf(&sb, "  %s (%s)\n", ct.Name, ct.Path)
+		}
+		sb.WriteString("groups:\n")
+		for _, cg := range c.Groups {
+			fmt.Fprintf(&sb, "  %s\n", cg.Name)
+			if 0 < len(cg.Tests) {
+				fmt.Fprintf(&sb, "    tests: %s\n", strings.Join(cg.Tests, ", "))
+			}
+			if 0 < len(cg.Groups) {
+				fmt.Fprintf(&sb, "    groups: %s\n", strings.Join(cg.Groups, ", "))
+			}
+		}
+		sb.WriteString("params:\n")
+		for _, cp := range c.Params {
+			sb.WriteString("  " + cp.Name)
+			if cp.Required {
+				sb.WriteString(" (required)")
+			}
+			if cp.Default != nil {
+				// Multi-line defaults fit on the line.
+				def := strings.Join(strings.Fields(fmt.Sprint(cp.Default)), " ")
+				fmt.Fprintf(&sb, " (default: %s)", def)
+			}
+			sb.WriteString("\n")
+		}
+	default:
+		return fmt.Errorf("unknown list format %s", format)
+	}
+
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return fmt.Errorf("failed to write list: %w", err)
+	}
+
+	return nil
+}
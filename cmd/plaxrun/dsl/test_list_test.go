@@ -0,0 +1,87 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestWriteList(t *testing.T) {
+	spec := `name: run
+version: 0.0.1
+tests:
+  pass:
+    path: pass.yaml
+    version: fake
+    params:
+      - HOST
+groups:
+  passes:
+    tests:
+      - name: pass
+  all:
+    groups:
+      - name: passes
+params:
+  HOST:
+    required: true
+  PORT:
+    cmd: echo
+    envs:
+      DEFAULT: 8080
+`
+	opts := runOptions(writeRunSpec(t, spec))
+	opts.List = true
+
+	// No groups or tests, and the required HOST isn't bound.
+	tr, err := RunTests(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+	if err := tr.WriteList(&sb, "text"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `tests:
+  pass (pass.yaml)
+groups:
+  all
+    groups: passes
+  passes
+    tests: pass
+params:
+  HOST (required)
+  PORT (default: 8080)
+`
+	if got := sb.String(); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	sb.Reset()
+	if err := tr.WriteList(&sb, "JSON"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sb.String(), `"required": true`) {
+		t.Fatalf("unexpected JSON %s", sb.String())
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/Comcast/plax/cmd/plaxrun/plugins/report"
+	"github.com/Comcast/plax/junit"
+)
+
+// MergeJUnit reads the JUnit XML results written by plaxrun (a
+// <testreport>) or plax (a <testsuite>) and writes one <testreport>
+// with all of their test suites.
+//
+// Test suites with the same name (from different shards, for example)
+// are all kept.  The totals are recomputed, the time is the sum of
+// the times of the inputs, and the start is the earliest start.
+func MergeJUnit(inputs []string, out io.Writer) error {
+	merged := report.NewTestReport()
+	started := false
+
+	for _, filename := range inputs {
+		r, err := readJUnit(filename)
+		if err != nil {
+			return err
+		}
+
+		if merged.Name == "" {
+			merged.Name = r.Name
+			merged.Version = r.Version
+		}
+
+		if !started || r.Started.Before(merged.Started) {
+			merged.Started = r.Started
+			started = true
+		}
+
+		for _, ts := range r.TestSuite {
+			merged.TestSuite = append(merged.TestSuite, ts)
+			merged.Total += ts.Total
+			merged.Passed += ts.Passed
+			merged.Skipped += ts.Skipped
+			merged.Failures += ts.Failures
+			merged.Errors += ts.Errors
+		}
+
+		merged.Excluded += r.Excluded
+		merged.Time += r.Time
+	}
+
+	bs, err := xml.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal merged results: %w", err)
+	}
+
+	if _, err = fmt.Fprintf(out, "%s\n", bs); err != nil {
+		return fmt.Errorf("failed to write merged results: %w", err)
+	}
+
+	return nil
+}
+
+// readJUnit reads a <testreport> or a <testsuite> as a TestReport.
+func readJUnit(filename string) (*report.TestReport, error) {
+	bs, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results: %w", err)
+	}
+
+	var root struct {
+		XMLName xml.Name
+	}
+	if err = xml.Unmarshal(bs, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse results %s: %w", filename, err)
+	}
+
+	// plax writes a <TestSuite>.
+	switch strings.ToLower(root.XMLName.Local) {
+	case "testreport":
+		var r report.TestReport
+		if err = xml.Unmarshal(bs, &r); err != nil {
+			return nil, fmt.Errorf("failed to parse results %s: %w", filename, err)
+		}
+		return &r, nil
+	case "testsuite":
+		var ts junit.TestSuite
+		if err = xml.Unmarshal(bs, &ts); err != nil {
+			return nil, fmt.Errorf("failed to parse results %s: %w", filename, err)
+		}
+		return &report.TestReport{
+			TestSuite: []*junit.TestSuite{&ts},
+			Started:   ts.Started,
+			Time:      ts.Time,
+		}, nil
+	default:
+		return nil, fmt.Errorf("results %s have a <%s> rather than a <testreport> or <testsuite>", filename, root.XMLName.Local)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Comcast/plax/cmd/plaxrun/plugins/report"
+	"github.com/Comcast/plax/junit"
+)
+
+func TestMergeJUnit(t *testing.T) {
+	dir := t.TempDir()
+
+	shard := func(filename string, status junit.TestCaseStatus, started time.Time) string {
+		ts := junit.NewTestSuite("run-0.0.1:group:test")
+		ts.Add(junit.TestCase{Name: "test", Status: status})
+		ts.Time = time.Second
+
+		tr := &TestRun{
+			Name:   "run",
+			Report: report.NewTestReport(),
+		}
+		tr.Report.Name = "run"
+		tr.Report.Started = started
+		tr.Report.Time = time.Second
+		tr.Report.TestSuite = append(tr.Report.TestSuite, ts)
+
+		filename = filepath.Join(dir, filename)
+		if err := tr.WriteFile(filename, "XML"); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	plax := filepath.Join(dir, "plax.xml")
+	ts := junit.NewTestSuite("plax")
+	ts.Add(junit.TestCase{Name: "skipped", Status: junit.Skipped})
+	bs, err := xml.Marshal(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(plax, bs, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := []string{
+		shard("0.xml", junit.Passed, now),
+		shard("1.xml", junit.Failed, now.Add(-time.Minute)),
+		plax,
+	}
+
+	var sb strings.Builder
+	if err := MergeJUnit(inputs, &sb); err != nil {
+		t.Fatal(err)
+	}
+
+	var got report.TestReport
+	if err := xml.Unmarshal([]byte(sb.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Name != "run" || got.Total != 3 || got.Passed != 1 || got.Failures != 1 || got.Skipped != 1 {
+		t.Fatalf("unexpected totals in %s", sb.String())
+	}
+
+	if len(got.TestSuite) != 3 || got.TestSuite[0].Name != got.TestSuite[1].Name {
+		t.Fatalf("unexpected test suites in %s", sb.String())
+	}
+
+	if got.Time != 2*time.Second || !got.Started.Equal(now.Add(-time.Minute)) {
+		t.Fatalf("unexpected time %s or start %s", got.Time, got.Started)
+	}
+
+	bad := filepath.Join(dir, "bad.xml")
+	if err = ioutil.WriteFile(bad, []byte("<tacos/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := MergeJUnit([]string{bad}, &sb); err == nil {
+		t.Fatal("expected an error")
+	}
+}
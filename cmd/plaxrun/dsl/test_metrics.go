@@ -0,0 +1,127 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Comcast/plax/junit"
+)
+
+// WriteMetrics writes the Report as metrics in the Prometheus text
+// exposition format, which can be pushed to a Pushgateway:
+//
+//	plax_tests_total{suite}: the number of TestCases in each TestSuite
+//	plax_tests_failed{suite}: the number of those that failed or errored
+//	plax_test_duration_seconds{suite,test}: the duration of each TestCase
+//
+// Skipped TestCases have a zero duration.
+func (tr *TestRun) WriteMetrics(w io.Writer) error {
+	if tr.Report == nil {
+		return fmt.Errorf("test run %s has no report", tr.Name)
+	}
+
+	var (
+		sb     strings.Builder
+		family = func(name, help string) {
+			sb.WriteString(fmt.Sprintf("# HELP %s %s\n# TYPE %s gauge\n", name, help, name))
+		}
+	)
+
+	family("plax_tests_total", "Number of tests in the suite.")
+	for _, ts := range tr.Report.TestSuite {
+		if ts == nil {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("plax_tests_total{suite=%s} %d\n", metricLabel(ts.Name), len(ts.TestCase)))
+	}
+
+	family("plax_tests_failed", "Number of tests in the suite that failed or errored.")
+	for _, ts := range tr.Report.TestSuite {
+		if ts == nil {
+			continue
+		}
+		failed := 0
+		for _, tc := range ts.TestCase {
+			switch tc.Status {
+			case junit.Failed, junit.Error:
+				failed++
+			}
+		}
+		sb.WriteString(fmt.Sprintf("plax_tests_failed{suite=%s} %d\n", metricLabel(ts.Name), failed))
+	}
+
+	family("plax_test_duration_seconds", "Duration of the test in seconds.")
+	for _, ts := range tr.Report.TestSuite {
+		if ts == nil {
+			continue
+		}
+		for _, tc := range ts.TestCase {
+			var secs float64
+			if tc.Time != nil {
+				secs = tc.Time.Seconds()
+			}
+			sb.WriteString(fmt.Sprintf("plax_test_duration_seconds{suite=%s,test=%s} %s\n",
+				metricLabel(ts.Name), metricLabel(tc.Name), strconv.FormatFloat(secs, 'f', -1, 64)))
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+
+	return err
+}
+
+// WriteMetricsFile writes the WriteMetrics metrics to the named file.
+//
+// Missing parent directories are created.
+func (tr *TestRun) WriteMetricsFile(filename string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("failed to make directory for metrics: %w", err)
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create metrics file: %w", err)
+	}
+
+	if err = tr.WriteMetrics(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+
+	return nil
+}
+
+// metricLabel quotes a label value for the text exposition format,
+// which only escapes backslashes, double quotes, and line feeds.
+func metricLabel(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Comcast/plax/cmd/plaxrun/plugins/report"
+	"github.com/Comcast/plax/junit"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	elapsed := 1500 * time.Millisecond
+
+	suite := junit.NewTestSuite(`run:group:"suite"`)
+	suite.Add(junit.TestCase{Name: "a", Status: junit.Passed, Time: &elapsed})
+	suite.Add(junit.TestCase{Name: "b", Status: junit.Error, Time: &elapsed})
+	suite.Add(junit.TestCase{Name: "c", Status: junit.Skipped})
+
+	tr := &TestRun{
+		Report: report.NewTestReport(),
+	}
+	tr.Report.TestSuite = append(tr.Report.TestSuite, suite)
+
+	var sb strings.Builder
+	if err := tr.WriteMetrics(&sb); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"# TYPE plax_tests_total gauge\n",
+		`plax_tests_total{suite="run:group:\"suite\""} 3` + "\n",
+		`plax_tests_failed{suite="run:group:\"suite\""} 1` + "\n",
+		`plax_test_duration_seconds{suite="run:group:\"suite\"",test="a"} 1.5` + "\n",
+		`plax_test_duration_seconds{suite="run:group:\"suite\"",test="c"} 0` + "\n",
+	} {
+		if !strings.Contains(sb.String(), want) {
+			t.Fatalf("missing %q in\n%s", want, sb.String())
+		}
+	}
+}
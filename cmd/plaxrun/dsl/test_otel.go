@@ -0,0 +1,314 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Comcast/plax/cmd/plaxrun/async"
+	"github.com/Comcast/plax/cmd/plaxrun/plugins/report"
+	"github.com/Comcast/plax/junit"
+)
+
+var (
+	// DefaultOTelTimeout is the timeout for exporting the spans
+	// of a test run.
+	DefaultOTelTimeout = 10 * time.Second
+)
+
+// otelConfigured reports whether the environment gives an OTLP
+// endpoint for traces.
+func otelConfigured() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// tracer records the spans of the test run, its groups, and its
+// tests with the OpenTelemetry SDK, whose OTLP/HTTP exporter exports
+// them.  The exporter's OTEL_EXPORTER_OTLP_* environment variables
+// (like OTEL_EXPORTER_OTLP_HEADERS) and the resource's
+// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES apply.
+//
+// The group spans last from the start of their first test to the
+// end of their last.
+type tracer struct {
+	sync.Mutex
+
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+
+	// groupOf is the group (if any) of each task func.
+	groupOf map[*async.TaskFunc]string
+
+	// ctx has the span of the test run.
+	ctx    context.Context
+	root   trace.Span
+	groups map[string]*groupSpan
+}
+
+// groupSpan is the span of a group, which is ended (at the end of
+// its last test) when the run finishes.
+type groupSpan struct {
+	ctx    context.Context
+	span   trace.Span
+	end    time.Time
+	failed bool
+}
+
+// newTracer makes a tracer for the endpoint (e.g.,
+// "http://localhost:4318"), to which "/v1/traces" is added unless
+// it's already there.  Without an endpoint, the exporter's
+// environment variables give it.
+func newTracer(endpoint string) (*tracer, error) {
+	var opts []otlptracehttp.Option
+	if endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("bad OTLP endpoint '%s'", endpoint)
+		}
+		path := strings.TrimSuffix(u.Path, "/")
+		if !strings.HasSuffix(path, "/v1/traces") {
+			path += "/v1/traces"
+		}
+		opts = append(opts, otlptracehttp.WithEndpoint(u.Host), otlptracehttp.WithURLPath(path))
+		if u.Scheme == "http" {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+	}
+
+	ctx := context.Background()
+
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make the OTLP exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", "plaxrun")),
+		resource.WithFromEnv())
+	if err != nil {
+		return nil, fmt.Errorf("failed to make the OpenTelemetry resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res))
+
+	return &tracer{
+		provider: provider,
+		tracer:   provider.Tracer("github.com/Comcast/plax/cmd/plaxrun"),
+		groupOf:  make(map[*async.TaskFunc]string),
+	}, nil
+}
+
+// group notes the group of the task funcs.
+func (t *tracer) group(name string, tfs []*async.TaskFunc) {
+	if t == nil {
+		return
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	for _, tf := range tfs {
+		if _, have := t.groupOf[tf]; !have {
+			t.groupOf[tf] = name
+		}
+	}
+}
+
+// start starts the span of the test run.
+func (t *tracer) start(name string) {
+	if t == nil {
+		return
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	t.ctx, t.root = t.tracer.Start(context.Background(), name)
+	t.groups = make(map[string]*groupSpan)
+}
+
+// wrap makes the task func record its span, which is a child of the
+// span of its group (or of the run).
+func (t *tracer) wrap(tf *async.TaskFunc, redactor *Redactor) {
+	if t == nil {
+		return
+	}
+
+	f := tf.Func.(testFunc)
+	tf.Func = func() (*junit.TestSuite, error) {
+		_, s := t.tracer.Start(t.parent(tf), tf.Name,
+			trace.WithAttributes(attribute.String("plax.test", tf.Name)))
+		ts, err := f()
+		t.record(tf, s, ts, err, redactor)
+		return ts, err
+	}
+}
+
+// parent returns the context with the span of the test's group
+// (which starts with its first test) or of the run.
+func (t *tracer) parent(tf *async.TaskFunc) context.Context {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.root == nil {
+		return context.Background()
+	}
+
+	name, have := t.groupOf[tf]
+	if !have {
+		return t.ctx
+	}
+
+	g := t.groups[name]
+	if g == nil {
+		g = &groupSpan{}
+		g.ctx, g.span = t.tracer.Start(t.ctx, name,
+			trace.WithAttributes(attribute.String("plax.group", name)))
+		t.groups[name] = g
+	}
+	return g.ctx
+}
+
+// record ends the span of a test.
+func (t *tracer) record(tf *async.TaskFunc, s trace.Span, ts *junit.TestSuite, err error, redactor *Redactor) {
+	var failed bool
+
+	switch {
+	case err != nil:
+		failed = true
+		s.SetStatus(codes.Error, redactor.String(err.Error()))
+		s.SetAttributes(attribute.String("plax.status", string(junit.Error)))
+	case ts == nil:
+	default:
+		status := suiteStatus(ts)
+		s.SetAttributes(
+			attribute.Int("plax.tests", ts.Total),
+			attribute.Int("plax.failures", ts.Failures),
+			attribute.Int("plax.errors", ts.Errors),
+			attribute.Int("plax.skipped", ts.Skipped),
+			attribute.String("plax.status", string(status)))
+		switch status {
+		case junit.Failed, junit.Error:
+			failed = true
+			var msg string
+			for _, tc := range ts.TestCase {
+				if tc.Status == junit.Failed || tc.Status == junit.Error {
+					msg = redactor.String(tc.Message)
+					break
+				}
+			}
+			s.SetStatus(codes.Error, msg)
+		case junit.Passed:
+			s.SetStatus(codes.Ok, "")
+		}
+	}
+
+	s.End()
+	ended := time.Now()
+
+	t.Lock()
+	defer t.Unlock()
+
+	if g := t.groups[t.groupOf[tf]]; g != nil {
+		if g.end.Before(ended) {
+			g.end = ended
+		}
+		g.failed = g.failed || failed
+	}
+}
+
+// suiteStatus summarizes the status of the test suite.
+func suiteStatus(ts *junit.TestSuite) junit.TestCaseStatus {
+	switch {
+	case 0 < ts.Errors:
+		return junit.Error
+	case 0 < ts.Failures:
+		return junit.Failed
+	case 0 < ts.Total && ts.Skipped == ts.Total:
+		return junit.Skipped
+	}
+	return junit.Passed
+}
+
+// finish ends the spans of the groups and of the test run and
+// exports any spans that haven't been exported yet.
+func (t *tracer) finish(ctx *Ctx, r *report.TestReport) error {
+	if t == nil {
+		return nil
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	if t.root == nil {
+		return nil
+	}
+
+	for _, g := range t.groups {
+		if g.failed {
+			g.span.SetStatus(codes.Error, "")
+		} else {
+			g.span.SetStatus(codes.Ok, "")
+		}
+		g.span.End(trace.WithTimestamp(g.end))
+	}
+
+	root := t.root
+	root.SetAttributes(
+		attribute.String("plax.run", r.Name),
+		attribute.String("plax.version", r.Version),
+		attribute.Int("plax.tests", r.Total),
+		attribute.Int("plax.passed", r.Passed),
+		attribute.Int("plax.failures", r.Failures),
+		attribute.Int("plax.errors", r.Errors),
+		attribute.Int("plax.skipped", r.Skipped))
+	if 0 < r.Failures || 0 < r.Errors {
+		root.SetStatus(codes.Error, fmt.Sprintf("%d failures and %d errors", r.Failures, r.Errors))
+	} else {
+		root.SetStatus(codes.Ok, "")
+	}
+	root.End()
+	t.root = nil
+
+	ctx.Logf("Exporting the spans of trace %s", root.SpanContext().TraceID())
+
+	c, cancel := context.WithTimeout(ctx, DefaultOTelTimeout)
+	defer cancel()
+
+	if err := t.provider.ForceFlush(c); err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+
+	return t.provider.Shutdown(c)
+}
@@ -0,0 +1,175 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOTel(t *testing.T) {
+	var (
+		path  string
+		spans []*tracepb.Span
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		bs, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		var req coltracepb.ExportTraceServiceRequest
+		if err := proto.Unmarshal(bs, &req); err != nil {
+			t.Error(err)
+		}
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}))
+	defer server.Close()
+
+	spec := `name: run
+version: 0.0.1
+tests:
+  pass: {path: pass.yaml, version: fake}
+  fail: {path: pass.yaml, version: failing}
+groups:
+  all:
+    tests:
+      - name: pass
+      - name: fail
+`
+	filename := writeRunSpec(t, spec)
+
+	opts := runOptions(filename)
+	opts.Groups = []string{"all"}
+	opts.OTelEndpoint = server.URL
+
+	if _, err := RunTests(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if path != "/v1/traces" {
+		t.Fatalf("exported to %q", path)
+	}
+
+	if len(spans) != 4 {
+		t.Fatalf("got %d spans", len(spans))
+	}
+
+	ids := make(map[string]string)
+	for _, s := range spans {
+		ids[s.Name] = hex.EncodeToString(s.SpanId)
+		if !bytes.Equal(s.TraceId, spans[0].TraceId) || s.StartTimeUnixNano == 0 {
+			t.Fatalf("unexpected span %v", s)
+		}
+	}
+
+	for _, s := range spans {
+		var parent string
+		code := tracepb.Status_STATUS_CODE_ERROR
+		switch s.Name {
+		case "run-0.0.1":
+		case "run-0.0.1:all":
+			parent = ids["run-0.0.1"]
+		case "run-0.0.1:all:pass":
+			parent = ids["run-0.0.1:all"]
+			code = tracepb.Status_STATUS_CODE_OK
+		case "run-0.0.1:all:fail":
+			parent = ids["run-0.0.1:all"]
+			if s.Status.Message != "no tacos" {
+				t.Fatalf("unexpected status %v", s.Status)
+			}
+		default:
+			t.Fatalf("unexpected span %s", s.Name)
+		}
+		if hex.EncodeToString(s.ParentSpanId) != parent || s.Status.Code != code {
+			t.Fatalf("unexpected span %v", s)
+		}
+	}
+}
+
+func TestOTelEnv(t *testing.T) {
+	var (
+		path    string
+		service string
+		spans   int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		bs, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		var req coltracepb.ExportTraceServiceRequest
+		if err := proto.Unmarshal(bs, &req); err != nil {
+			t.Error(err)
+		}
+		for _, rs := range req.ResourceSpans {
+			for _, a := range rs.Resource.Attributes {
+				if a.Key == "service.name" {
+					service = a.Value.GetStringValue()
+				}
+			}
+			for _, ss := range rs.ScopeSpans {
+				spans += len(ss.Spans)
+			}
+		}
+	}))
+	defer server.Close()
+
+	// Without -otel-endpoint, the OTEL_* environment variables
+	// configure the export.
+	for k, v := range map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT": server.URL,
+		"OTEL_SERVICE_NAME":           "tacos",
+	} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	filename := writeRunSpec(t, `name: run
+version: 0.0.1
+tests:
+  pass: {path: pass.yaml, version: fake}
+`)
+
+	opts := runOptions(filename)
+	opts.Tests = []string{"pass"}
+
+	if _, err := RunTests(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if path != "/v1/traces" || service != "tacos" || spans != 2 {
+		t.Fatalf("exported %d spans for %q to %q", spans, service, path)
+	}
+}
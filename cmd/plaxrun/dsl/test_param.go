@@ -25,6 +25,7 @@ import (
 	"os"
 	"os/exec"
 	"regexp"
+	"strings"
 
 	plaxDsl "github.com/Comcast/plax/dsl"
 )
@@ -32,6 +33,12 @@ import (
 var (
 	multiLinePropertyValueRegexp = regexp.MustCompile(`(\w+)=(.*(?:\\n   .*|\n[^=\n]+$)*)`)
 	quotedRegexp                 = regexp.MustCompile(`^"(.*)"$`)
+
+	// computeRefRegexp matches the identifiers and string literals
+	// in a Compute expression, which might name other params.
+	computeRefRegexp = regexp.MustCompile(`[A-Za-z_$][A-Za-z0-9_$]*|"[^"]*"|'[^']*'`)
+
+	jsIdentRegexp = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
 )
 
 // TestParamEnvMap type
@@ -77,21 +84,34 @@ func (tpm TestParamMap) bind(ctx *plaxDsl.Ctx, bs *plaxDsl.Bindings) error {
 
 // process the TestParamDependency
 func (tpd TestParamDependency) process(ctx *plaxDsl.Ctx, tpbm TestParamBindingMap, bs *plaxDsl.Bindings) error {
+	return tpd.resolve(ctx, tpbm, bs, nil)
+}
+
+// resolve processes the TestParamDependency after its dependencies.
+// The path is the chain of params that led to this one, which is how
+// a dependency cycle is detected.
+func (tpd TestParamDependency) resolve(ctx *plaxDsl.Ctx, tpbm TestParamBindingMap, bs *plaxDsl.Bindings, path []string) error {
 	tpk := string(tpd)
+	for i, p := range path {
+		if p == tpk {
+			return fmt.Errorf("param dependency cycle: %s", strings.Join(append(path[i:], tpk), " -> "))
+		}
+	}
 	pbm, ok := tpbm[string(tpk)]
 	if !ok {
-		return fmt.Errorf("failed to find test param %s", tpk)
+		return fmt.Errorf("failed to find test param %s: it isn't bound by -p, -env-prefix environment variables, or -bindings-file (in that order of precedence), and it isn't defined in params", tpk)
 	}
 
-	for _, tpd := range pbm.DependsOn {
-		if err := tpd.process(ctx, tpbm, bs); err != nil {
-			return fmt.Errorf("failed to process dependent param for %s: %v", tpk, err)
+	path = append(path[:len(path):len(path)], tpk)
+	for _, tpd := range pbm.dependencies(tpk, tpbm) {
+		if err := tpd.resolve(ctx, tpbm, bs, path); err != nil {
+			return fmt.Errorf("failed to process dependent param for %s: %w", tpk, err)
 		}
 	}
 
 	err := pbm.process(ctx, tpk, bs)
 	if err != nil {
-		return fmt.Errorf("failed to process param %s: %v", tpk, err)
+		return fmt.Errorf("failed to process param %s: %w", tpk, err)
 	}
 
 	return nil
@@ -123,6 +143,129 @@ type TestParamBinding struct {
 
 	// Redact the parameter binding flag
 	Redact bool `json:"redact" yaml:"redact"`
+
+	// Secret values (however they are bound) are always redacted
+	// (as whole tokens) from logs and test results.
+	Secret bool `json:"secret" yaml:"secret"`
+
+	// Required parameters must be bound (by -p, -env-prefix, or
+	// -bindings-file, for example) rather than by running Cmd.
+	Required bool `json:"required" yaml:"required"`
+
+	// Compute is a Javascript expression that computes the value
+	// (instead of running Cmd).  The bindings are available as
+	// 'bs' and (when their names are identifiers) as variables.
+	//
+	// The params that the expression mentions (by identifier or in
+	// a string literal) are processed first, as if they were in
+	// DependsOn.
+	Compute string `json:"compute" yaml:"compute"`
+}
+
+// computeRefs returns the params that the Compute expression src
+// mentions (other than self).
+func computeRefs(src string, self string, isParam func(string) bool) []string {
+	var (
+		acc  []string
+		seen = map[string]bool{self: true}
+	)
+	for _, ref := range computeRefRegexp.FindAllString(src, -1) {
+		if ref[0] == '"' || ref[0] == '\'' {
+			ref = ref[1 : len(ref)-1]
+		}
+		if !seen[ref] && isParam(ref) {
+			seen[ref] = true
+			acc = append(acc, ref)
+		}
+	}
+	return acc
+}
+
+// dependencies returns the DependsOn params along with the params
+// that the Compute expression mentions.
+func (tpb *TestParamBinding) dependencies(pk string, tpbm TestParamBindingMap) TestParamDependencyList {
+	if tpb.Compute == "" {
+		return tpb.DependsOn
+	}
+	acc := append(TestParamDependencyList{}, tpb.DependsOn...)
+	isParam := func(name string) bool {
+		if _, have := tpbm[name]; !have {
+			return false
+		}
+		for _, d := range tpb.DependsOn {
+			if string(d) == name {
+				return false
+			}
+		}
+		return true
+	}
+	for _, ref := range computeRefs(tpb.Compute, pk, isParam) {
+		acc = append(acc, TestParamDependency(ref))
+	}
+	return acc
+}
+
+// compute binds the param to the value of the Compute expression.
+func (tpb *TestParamBinding) compute(ctx *plaxDsl.Ctx, pk string, bs *plaxDsl.Bindings) error {
+	env := map[string]interface{}{
+		"bs": map[string]interface{}(*bs),
+	}
+	for k, v := range *bs {
+		if jsIdentRegexp.MatchString(k) && k != "bs" {
+			env[k] = v
+		}
+	}
+
+	x, err := plaxDsl.JSExec(ctx, tpb.Compute, env)
+	if err != nil {
+		return fmt.Errorf("failed to compute %s: %w", pk, err)
+	}
+
+	if tpb.Redact {
+		s, is := x.(string)
+		if !is {
+			s = plaxDsl.JSON(x)
+		}
+		ctx.AddRedaction(regexp.QuoteMeta(s))
+	}
+	if tpb.Secret {
+		if err := addSecret(ctx, pk, x); err != nil {
+			return err
+		}
+	}
+
+	bs.SetKeyValue(pk, x)
+	ctx.BindingsRedactions(*bs)
+
+	ctx.Logdf("Binding %s=%s", pk, plaxDsl.JSON(x))
+
+	return nil
+}
+
+// addSecret adds the (JSON of a non-string) value of the param as a
+// secret.
+func addSecret(ctx *plaxDsl.Ctx, pk string, v interface{}) error {
+	s, is := v.(string)
+	if !is {
+		js, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to serialize secret %s: %w", pk, err)
+		}
+		s = string(js)
+	}
+	if err := ctx.AddSecret(s); err != nil {
+		return fmt.Errorf("failed to add secret for %s: %w", pk, err)
+	}
+	return nil
+}
+
+// missingParamError reports a required parameter that isn't bound.
+type missingParamError struct {
+	name string
+}
+
+func (e *missingParamError) Error() string {
+	return fmt.Sprintf("required param %s is not bound", e.name)
 }
 
 // environment set the environment fo the script execution
@@ -163,6 +306,7 @@ func (tpb *TestParamBinding) substitute(ctx *plaxDsl.Ctx, bs *plaxDsl.Bindings)
 		Envs:      tpem,
 		ec:        tpb.ec,
 		Redact:    tpb.Redact,
+		Secret:    tpb.Secret,
 	}, nil
 }
 
@@ -184,6 +328,9 @@ func (tpb *TestParamBinding) run(ctx *plaxDsl.Ctx, key string, bs *plaxDsl.Bindi
 		return err
 	}
 
+	// Commands run in the directory containing the test files.
+	tpb.ec.Dir = ctx.Dir
+
 	tpb.ec.Stderr = os.Stderr
 	tpb.ec.Stdin = os.Stdin
 	var stdout bytes.Buffer
@@ -211,6 +358,11 @@ func (tpb *TestParamBinding) run(ctx *plaxDsl.Ctx, key string, bs *plaxDsl.Bindi
 		if tpb.Redact {
 			ctx.AddRedaction(v)
 		}
+		if tpb.Secret {
+			if err := ctx.AddSecret(v); err != nil {
+				return fmt.Errorf("failed to add secret for %s: %w", k, err)
+			}
+		}
 
 		// We might need to JSON-deserialize the value.
 		bs.Set(fmt.Sprintf("%s=%s", k, v))
@@ -225,10 +377,21 @@ func (tpb *TestParamBinding) run(ctx *plaxDsl.Ctx, key string, bs *plaxDsl.Bindi
 // Process the test param binding
 func (tpb *TestParamBinding) process(ctx *plaxDsl.Ctx, pk string, bs *plaxDsl.Bindings) error {
 	// If paramater binding already exists just return
-	if _, ok := (*bs)[pk]; ok {
+	if v, ok := (*bs)[pk]; ok {
+		if tpb.Secret {
+			return addSecret(ctx, pk, v)
+		}
 		return nil
 	}
 
+	if tpb.Required {
+		return &missingParamError{name: pk}
+	}
+
+	if tpb.Compute != "" {
+		return tpb.compute(ctx, pk, bs)
+	}
+
 	// Process the parameter binding run command
 	if err := tpb.run(ctx, pk, bs); err != nil {
 		return err
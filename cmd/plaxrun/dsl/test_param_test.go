@@ -0,0 +1,68 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	plaxDsl "github.com/Comcast/plax/dsl"
+)
+
+func TestParamCompute(t *testing.T) {
+	ctx := plaxDsl.NewCtx(context.Background())
+	ctx.LogLevel = "none"
+
+	tpbm := TestParamBindingMap{
+		"TOPIC":   {Compute: `ENV + "/orders"`},
+		"URL":     {Compute: `"https://" + bs["HOST"] + "/" + TOPIC`},
+		"HOST":    {Compute: `ENV + ".example.com"`},
+		"ENV":     {Required: true},
+		"PORTS":   {Compute: `[8080, 8081]`},
+		"CYCLE_A": {Compute: `CYCLE_B + 1`},
+		"CYCLE_B": {DependsOn: TestParamDependencyList{"CYCLE_A"}},
+	}
+
+	bs := plaxDsl.Bindings{"ENV": "staging"}
+
+	if err := TestParamDependency("URL").process(ctx, tpbm, &bs); err != nil {
+		t.Fatal(err)
+	}
+	if got := bs["URL"]; got != "https://staging.example.com/staging/orders" {
+		t.Fatalf("unexpected URL %v", got)
+	}
+
+	if err := TestParamDependency("PORTS").process(ctx, tpbm, &bs); err != nil {
+		t.Fatal(err)
+	}
+	if got := plaxDsl.JSON(bs["PORTS"]); got != "[8080,8081]" {
+		t.Fatalf("unexpected PORTS %v", got)
+	}
+
+	err := TestParamDependency("CYCLE_A").process(ctx, tpbm, &bs)
+	if err == nil || !strings.Contains(err.Error(), "param dependency cycle: CYCLE_A -> CYCLE_B -> CYCLE_A") {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	deps := tpbm["URL"]
+	if got := deps.dependencies("URL", tpbm); len(got) != 2 || got[0] != "HOST" || got[1] != "TOPIC" {
+		t.Fatalf("unexpected dependencies %v", got)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	plaxDsl "github.com/Comcast/plax/dsl"
+)
+
+// Preflighter is implemented by a Plugin that can check the
+// channels of its tests without executing them.
+type Preflighter interface {
+	// Preflight returns the channel checks keyed by test name.
+	Preflight(ctx context.Context, timeout time.Duration) (map[string][]plaxDsl.ChanCheck, error)
+}
+
+// Preflight checks that the endpoints of the channels of the tests
+// are reachable without executing any steps.  Each channel is
+// written as reachable (with its latency), unreachable (with the
+// reason), or unchecked.  Returns an error when a channel is
+// unreachable.
+func (tr *TestRun) Preflight(ctx *Ctx, w io.Writer) error {
+	var timeout time.Duration
+	if tr.trps.PreflightTimeout != nil {
+		timeout = *tr.trps.PreflightTimeout
+	}
+
+	unreachable := 0
+
+	for _, tf := range tr.tfs {
+		info, have := tr.infos[tf]
+		if !have {
+			continue
+		}
+		p, is := info.plugin.(Preflighter)
+		if !is {
+			fmt.Fprintf(w, "%s: no channel checks\n", tf.Name)
+			continue
+		}
+
+		checks, err := p.Preflight(ctx, timeout)
+		if err != nil {
+			return fmt.Errorf("failed to preflight %s: %w", tf.Name, err)
+		}
+
+		tests := make([]string, 0, len(checks))
+		for test := range checks {
+			tests = append(tests, test)
+		}
+		sort.Strings(tests)
+
+		for _, test := range tests {
+			for _, c := range checks[test] {
+				var status string
+				switch {
+				case c.Err != nil:
+					unreachable++
+					status = fmt.Sprintf("unreachable: %s", c.Err)
+				case !c.Checked:
+					status = "unchecked"
+				default:
+					status = fmt.Sprintf("reachable (%s)", c.Latency.Round(time.Millisecond))
+				}
+				fmt.Fprintf(w, "%s %s %s (%s): %s\n", tf.Name, test, c.Name, c.Kind, status)
+			}
+		}
+	}
+
+	if 0 < unreachable {
+		return fmt.Errorf("%d channels are unreachable", unreachable)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	plaxDsl "github.com/Comcast/plax/dsl"
+	"github.com/Comcast/plax/junit"
+)
+
+// preflightPlugin has a reachable and an unreachable channel.
+type preflightPlugin struct{}
+
+func (p *preflightPlugin) Invoke(ctx context.Context) (*junit.TestSuite, error) {
+	return nil, fmt.Errorf("preflight shouldn't execute tests")
+}
+
+func (p *preflightPlugin) Preflight(ctx context.Context, timeout time.Duration) (map[string][]plaxDsl.ChanCheck, error) {
+	return map[string][]plaxDsl.ChanCheck{
+		"connect": {
+			{Name: "broker", Kind: "mqtt", Checked: true, Latency: 12 * time.Millisecond},
+			{Name: "db", Kind: "sql", Checked: true, Err: fmt.Errorf("connection refused")},
+		},
+	}, nil
+}
+
+func TestPreflight(t *testing.T) {
+	ThePluginRegistry.Register("preflight", func(def PluginDef) (Plugin, error) {
+		return &preflightPlugin{}, nil
+	})
+
+	spec := `name: run
+version: 0.0.1
+tests:
+  connect: {path: connect.yaml, version: preflight}
+groups:
+  all:
+    tests:
+      - name: connect
+`
+	opts := runOptions(writeRunSpec(t, spec))
+	if err := ioutil.WriteFile(filepath.Join(opts.Dir, "connect.yaml"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts.Groups = []string{"all"}
+	opts.Preflight = true
+
+	c := NewCtx(context.Background())
+	tr, err := NewTestRun(c, opts.params())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	err = tr.Preflight(c, &out)
+	if err == nil || !strings.Contains(err.Error(), "1 channels are unreachable") {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := `run-0.0.1:all:connect connect broker (mqtt): reachable (12ms)
+run-0.0.1:all:connect connect db (sql): unreachable: connection refused
+`
+	if got := out.String(); got != want {
+		t.Fatalf("unexpected output\n%s", got)
+	}
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TestProfileMap maps a profile name (like "staging") to the bindings
+// that the profile supplies.
+type TestProfileMap map[string]map[string]interface{}
+
+// Names returns the (sorted) profile names.
+func (pm TestProfileMap) Names() []string {
+	names := make([]string, 0, len(pm))
+	for name := range pm {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyProfile adds the bindings of the named profile that aren't
+// already bound.
+//
+// Bindings given on the command line (including -env-prefix and
+// -bindings-file bindings) therefore take precedence over the
+// profile's bindings, which take precedence over the params defaults.
+func (tr *TestRun) applyProfile(ctx *Ctx, name string) error {
+	bs, have := tr.Profiles[name]
+	if !have {
+		available := "none"
+		if 0 < len(tr.Profiles) {
+			available = strings.Join(tr.Profiles.Names(), ", ")
+		}
+		return fmt.Errorf("no such profile: %s (available profiles: %s)", name, available)
+	}
+
+	ctx.Logf("Using profile %s", name)
+	defaultBindings(tr.trps.Bindings, bs)
+
+	return nil
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestProfiles(t *testing.T) {
+	var (
+		lock   sync.Mutex
+		params map[string]interface{}
+	)
+	ThePluginRegistry.Register("profiled", func(def PluginDef) (Plugin, error) {
+		name, _ := def.GetPluginDefName()
+		ps, err := def.GetPluginDefParams()
+		if err != nil {
+			return nil, err
+		}
+		lock.Lock()
+		params = ps
+		lock.Unlock()
+		return &fakePlugin{name: name}, nil
+	})
+
+	spec := `name: run
+version: 0.0.1
+tests:
+  pass:
+    path: pass.yaml
+    version: profiled
+    params:
+      - HOST
+      - PORT
+      - USER
+groups:
+  passes:
+    tests:
+      - name: pass
+params:
+  HOST:
+    required: true
+  PORT:
+    required: true
+  USER:
+    compute: '"nobody"'
+profiles:
+  dev:
+    HOST: dev.example.com
+    PORT: 1883
+  staging:
+    HOST: staging.example.com
+    PORT: 8883
+    USER: queso
+`
+	filename := writeRunSpec(t, spec)
+
+	run := func(profile string, bindings map[string]interface{}) (map[string]interface{}, error) {
+		opts := runOptions(filename)
+		opts.Groups = []string{"passes"}
+		opts.Profile = profile
+		for k, v := range bindings {
+			opts.Bindings[k] = v
+		}
+		if _, err := RunTests(context.Background(), opts); err != nil {
+			return nil, err
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		return params, nil
+	}
+
+	ps, err := run("staging", map[string]interface{}{"HOST": "localhost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps["HOST"] != "localhost" || ps["PORT"] != 8883 || ps["USER"] != "queso" {
+		t.Fatalf("unexpected params %v", ps)
+	}
+
+	ps, err = run("dev", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps["HOST"] != "dev.example.com" || ps["PORT"] != 1883 || ps["USER"] != "nobody" {
+		t.Fatalf("unexpected params %v", ps)
+	}
+
+	_, err = run("prod", nil)
+	if err == nil || err.Error() != "no such profile: prod (available profiles: dev, staging)" {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if _, err = run("", nil); err == nil || !strings.Contains(err.Error(), "required params are not bound: HOST, PORT") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
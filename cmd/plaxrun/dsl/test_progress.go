@@ -0,0 +1,89 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Comcast/plax/junit"
+)
+
+// ProgressEvent is written (as one line of JSON) to the
+// TestRunParams.ProgressOut as each test starts and finishes.
+type ProgressEvent struct {
+	// Event is "start", "pass", or "fail".
+	Event string `json:"event"`
+
+	// Name is the name of the test in the test run.
+	Name string `json:"name"`
+
+	// Elapsed is the duration of the test in seconds (when it
+	// finished).
+	Elapsed float64 `json:"elapsed,omitempty"`
+}
+
+// progress writes ProgressEvents as NDJSON.
+type progress struct {
+	sync.Mutex
+	w io.Writer
+}
+
+func (p *progress) emit(ev ProgressEvent) {
+	js, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	p.w.Write(append(js, '\n'))
+}
+
+// wrap makes a task func that emits the ProgressEvents for the named
+// test around the given func.
+func (p *progress) wrap(name string, f func() (*junit.TestSuite, error)) func() (*junit.TestSuite, error) {
+	if p == nil {
+		return f
+	}
+
+	return func() (*junit.TestSuite, error) {
+		p.emit(ProgressEvent{
+			Event: "start",
+			Name:  name,
+		})
+
+		started := time.Now()
+		ts, err := f()
+
+		event := "pass"
+		if err != nil || (ts != nil && (0 < ts.Failures || 0 < ts.Errors)) {
+			event = "fail"
+		}
+		p.emit(ProgressEvent{
+			Event:   event,
+			Name:    name,
+			Elapsed: time.Since(started).Seconds(),
+		})
+
+		return ts, err
+	}
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Comcast/plax/cmd/plaxrun/plugins/report"
+	plaxDsl "github.com/Comcast/plax/dsl"
+	"github.com/Comcast/plax/junit"
+)
+
+// Redacted replaces the secret values in the results.
+const Redacted = "REDACTED"
+
+// Redactor replaces secret values (such as credentials that are
+// echoed into a test name, a property, or captured logs) with
+// Redacted in the results of a test run.
+//
+// Values are replaced as whole tokens (see plaxDsl.TokenPattern).
+type Redactor struct {
+	sync.RWMutex
+
+	values map[string]bool
+
+	// pattern matches the values (longest first) and is compiled
+	// again after an Add.
+	pattern *regexp.Regexp
+
+	// secrets, when not nil, has more secret values (such as the
+	// values of secret params) and the patterns to mask.
+	secrets *plaxDsl.Redactions
+}
+
+// NewRedactor makes a Redactor without any secret values.
+func NewRedactor() *Redactor {
+	return &Redactor{
+		values: make(map[string]bool),
+	}
+}
+
+// Add registers a secret value.  Blank values are ignored, since
+// they would redact everything.
+func (r *Redactor) Add(value string) {
+	if strings.TrimSpace(value) == "" {
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if !r.values[value] {
+		r.values[value] = true
+		r.pattern = nil
+	}
+}
+
+// String returns s with the secret values redacted.
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+
+	if r.secrets != nil {
+		s = r.secrets.MaskAll(r.secrets.RedactSecrets(s, Redacted))
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	if len(r.values) == 0 {
+		return s
+	}
+
+	if r.pattern == nil {
+		values := make([]string, 0, len(r.values))
+		for v := range r.values {
+			values = append(values, v)
+		}
+		// A value that contains another value is replaced
+		// first.
+		sort.Slice(values, func(i, j int) bool {
+			if len(values[i]) != len(values[j]) {
+				return len(values[j]) < len(values[i])
+			}
+			return values[i] < values[j]
+		})
+
+		pats := make([]string, 0, len(values))
+		for _, v := range values {
+			pats = append(pats, plaxDsl.TokenPattern(v))
+		}
+		r.pattern = regexp.MustCompile(strings.Join(pats, "|"))
+	}
+
+	return r.pattern.ReplaceAllLiteralString(s, Redacted)
+}
+
+// Report redacts the names, messages, properties, and captured logs
+// in the TestReport.
+func (r *Redactor) Report(tr *report.TestReport) {
+	if r == nil || tr == nil {
+		return
+	}
+
+	tr.Name = r.String(tr.Name)
+
+	for _, ts := range tr.TestSuite {
+		if ts == nil {
+			continue
+		}
+		ts.Name = r.String(ts.Name)
+		ts.Message = r.String(ts.Message)
+		r.properties(ts.Properties)
+
+		for i := range ts.TestCase {
+			tc := &ts.TestCase[i]
+			tc.Name = r.String(tc.Name)
+			tc.File = r.String(tc.File)
+			tc.Message = r.String(tc.Message)
+			tc.SystemOut = r.String(tc.SystemOut)
+			tc.SystemErr = r.String(tc.SystemErr)
+			r.properties(tc.Properties)
+		}
+	}
+}
+
+func (r *Redactor) properties(ps []junit.Property) {
+	for i := range ps {
+		ps[i].Name = r.String(ps[i].Name)
+		ps[i].Value = r.String(ps[i].Value)
+	}
+}
+
+// RedactValueList are the secret values to redact from the results
+//
+// We make an explicit type to enable flag.Var to parse multiple
+// parameters.
+type RedactValueList []string
+
+// String representation
+func (rvl *RedactValueList) String() string {
+	return "Secret value"
+}
+
+// Set adds the value
+func (rvl *RedactValueList) Set(value string) error {
+	*rvl = append(*rvl, value)
+	return nil
+}
+
+// RedactPatternList are the regular expressions whose matches are
+// masked in the logs and the results
+//
+// We make an explicit type to enable flag.Var to parse multiple
+// parameters.
+type RedactPatternList []string
+
+// String representation
+func (rpl *RedactPatternList) String() string {
+	return "Regular expression"
+}
+
+// Set adds the pattern
+func (rpl *RedactPatternList) Set(value string) error {
+	*rpl = append(*rpl, value)
+	return nil
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Comcast/plax/cmd/plaxrun/plugins/report"
+	plaxDsl "github.com/Comcast/plax/dsl"
+	"github.com/Comcast/plax/junit"
+)
+
+func TestRedactor(t *testing.T) {
+	r := NewRedactor()
+	r.Add("hunter2")
+	r.Add("hunter")
+	r.Add(" ")
+
+	if got := r.String("pw=hunter2 user=hunter hunters"); got != "pw=REDACTED user=REDACTED hunters" {
+		t.Fatalf("unexpected redaction %q", got)
+	}
+
+	ts := junit.NewTestSuite("suite-hunter2")
+	ts.AddProperty("token", "Bearer hunter2")
+	tc := junit.NewTestCase("case", "")
+	tc.SystemOut = "logged hunter2"
+	tc.Finish(junit.Failed, "expected hunter2")
+	ts.Add(*tc)
+
+	tr := report.NewTestReport()
+	tr.TestSuite = append(tr.TestSuite, ts)
+
+	r.Report(tr)
+
+	got := tr.TestSuite[0]
+	if got.Name != "suite-REDACTED" || got.Properties[0].Value != "Bearer REDACTED" {
+		t.Fatalf("unexpected suite %#v", got)
+	}
+	if c := got.TestCase[0]; c.SystemOut != "logged REDACTED" || c.Message != "expected REDACTED" {
+		t.Fatalf("unexpected case %#v", c)
+	}
+
+	var nr *Redactor
+	if got := nr.String("hunter2"); got != "hunter2" {
+		t.Fatalf("nil Redactor redacted %q", got)
+	}
+}
+
+func TestRunTestsRedactValues(t *testing.T) {
+	spec := `name: run
+version: 0.0.1
+tests:
+  tenant-acme: {path: pass.yaml, version: fake}
+groups:
+  all:
+    tests:
+      - name: tenant-acme
+`
+	filename := writeRunSpec(t, spec)
+
+	opts := runOptions(filename)
+	opts.Groups = []string{"all"}
+	opts.RedactValues = []string{"acme"}
+
+	tr, err := RunTests(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := tr.Report.TestSuite[0].Name; got != "run-0.0.1:all:tenant-REDACTED" {
+		t.Fatalf("unexpected suite name %q", got)
+	}
+}
+
+func TestSecretParams(t *testing.T) {
+	spec := `name: run
+version: 0.0.1
+tests:
+  pass: {path: pass.yaml, version: fake, params: [token]}
+groups:
+  all:
+    tests:
+      - name: pass
+params:
+  token:
+    required: true
+    secret: true
+`
+	filename := writeRunSpec(t, spec)
+
+	opts := runOptions(filename)
+	opts.Groups = []string{"all"}
+	opts.Bindings = plaxDsl.Bindings{"token": "7"}
+
+	c := NewCtx(context.Background())
+	tr, err := NewTestRun(c, opts.params())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.Redactions.Redactf("token=7 count=17"); got != "token=<redacted> count=17" {
+		t.Fatalf("unexpected log redaction %q", got)
+	}
+	if got := tr.redactor.String("token=7 count=17"); got != "token=REDACTED count=17" {
+		t.Fatalf("unexpected result redaction %q", got)
+	}
+}
+
+func TestRedactPatterns(t *testing.T) {
+	spec := `name: run
+version: 0.0.1
+tests:
+  pass: {path: pass.yaml, version: fake}
+groups:
+  all:
+    tests:
+      - name: pass
+`
+	filename := writeRunSpec(t, spec)
+
+	opts := runOptions(filename)
+	opts.Groups = []string{"all"}
+
+	opts.RedactPatterns = []string{`Bearer (?P<redact>\S+)`, `(unbalanced`}
+	if _, err := NewTestRun(NewCtx(context.Background()), opts.params()); err == nil {
+		t.Fatal("expected an error for a bad pattern")
+	}
+
+	opts.RedactPatterns = opts.RedactPatterns[:1]
+	c := NewCtx(context.Background())
+	tr, err := NewTestRun(c, opts.params())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.Redactions.Redactf("auth=Bearer chips&salsa"); got != "auth=Bearer c*********a" {
+		t.Fatalf("unexpected log redaction %q", got)
+	}
+	if got := tr.redactor.String("auth=Bearer chips&salsa"); got != "auth=Bearer c*********a" {
+		t.Fatalf("unexpected result redaction %q", got)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Comcast/plax/junit"
+)
+
+// Iteration summarizes the results of one execution of the tests of
+// a TestRun with TestRunParams.Repeat.
+type Iteration struct {
+	// Number is the number (from 1) of the iteration.
+	Number int
+
+	Total    int
+	Passed   int
+	Skipped  int
+	Failures int
+	Errors   int
+}
+
+// add the counts of the TestSuite.
+func (it *Iteration) add(ts *junit.TestSuite) {
+	it.Total += ts.Total
+	it.Passed += ts.Passed
+	it.Skipped += ts.Skipped
+	it.Failures += ts.Failures
+	it.Errors += ts.Errors
+}
+
+// PassRate is the fraction of the executed (not skipped) tests that
+// passed, which is 0 if none was executed.
+func (it Iteration) PassRate() float64 {
+	return passRate(it.Passed, it.Total-it.Skipped)
+}
+
+func passRate(passed, executed int) float64 {
+	if executed <= 0 {
+		return 0
+	}
+	return float64(passed) / float64(executed)
+}
+
+// repeat returns the number of times to execute the tests, which is
+// the -flaky number (if any) or else the -repeat.
+func (tr TestRun) repeat() int {
+	if n := tr.flaky(); 0 < n {
+		return n
+	}
+	if tr.trps == nil || tr.trps.Repeat == nil || *tr.trps.Repeat < 1 {
+		return 1
+	}
+	return *tr.trps.Repeat
+}
+
+// checkRepeat validates the -repeat.
+func checkRepeat(trps *TestRunParams) error {
+	if trps.Repeat != nil && *trps.Repeat < 0 {
+		return fmt.Errorf("repeat %d is negative", *trps.Repeat)
+	}
+	return nil
+}
+
+// writeIterations writes a line for each of the Iterations (if there
+// were several) and a line with the overall pass rate.
+func (tr *TestRun) writeIterations(w io.Writer) error {
+	if len(tr.Iterations) < 2 {
+		return nil
+	}
+
+	var passed, executed int
+	for _, it := range tr.Iterations {
+		_, err := fmt.Fprintf(w, "Iteration=%d Total=%d Passed=%d Failed=%d Errors=%d Skipped=%d PassRate=%.1f%%\n",
+			it.Number, it.Total, it.Passed, it.Failures, it.Errors, it.Skipped, 100*it.PassRate())
+		if err != nil {
+			return fmt.Errorf("failed to write summary: %w", err)
+		}
+		passed += it.Passed
+		executed += it.Total - it.Skipped
+	}
+
+	_, err := fmt.Fprintf(w, "Iterations=%d PassRate=%.1f%%\n", len(tr.Iterations), 100*passRate(passed, executed))
+	if err != nil {
+		return fmt.Errorf("failed to write summary: %w", err)
+	}
+
+	return nil
+}
@@ -80,24 +80,20 @@ func (trp *TestReportPlugin) Generate(ctx *dsl.Ctx, tpbm TestParamBindingMap, bs
 }
 
 // Generate the test reports from the TestReportPluginMap for the TestRun
-func (trpm TestReportPluginMap) Generate(ctx *dsl.Ctx, tpbm TestParamBindingMap, bs plaxDsl.Bindings, tr *report.TestReport, emitJSON bool) error {
-	if _, ok := trpm[stdoutPlugin]; !ok {
+//
+// Unless the map has a stdout report, a stdout report of the given
+// stdoutType ("XML" or "JSON") is generated.  An empty stdoutType
+// disables that default report.
+func (trpm TestReportPluginMap) Generate(ctx *dsl.Ctx, tpbm TestParamBindingMap, bs plaxDsl.Bindings, tr *report.TestReport, stdoutType string) error {
+	if _, ok := trpm[stdoutPlugin]; !ok && stdoutType != "" {
 		if trpm == nil {
 			trpm = make(TestReportPluginMap)
 		}
 
-		if emitJSON {
-			trpm[stdoutPlugin] = TestReportPlugin{
-				Config: map[string]string{
-					"Type": "JSON",
-				},
-			}
-		} else {
-			trpm[stdoutPlugin] = TestReportPlugin{
-				Config: map[string]string{
-					"Type": "XML",
-				},
-			}
+		trpm[stdoutPlugin] = TestReportPlugin{
+			Config: map[string]string{
+				"Type": stdoutType,
+			},
 		}
 	}
 
@@ -105,7 +101,7 @@ func (trpm TestReportPluginMap) Generate(ctx *dsl.Ctx, tpbm TestParamBindingMap,
 		trp.name = key
 		err := trp.Generate(ctx, tpbm, bs, tr)
 		if err != nil {
-			ctx.Logf(err.Error())
+			ctx.Logf("%s", err)
 		}
 	}
 
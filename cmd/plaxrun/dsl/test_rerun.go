@@ -0,0 +1,62 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	plaxDsl "github.com/Comcast/plax/dsl"
+)
+
+// readFailed returns the names of the test suites that failed or
+// had errors in the JUnit XML results in the file.
+//
+// plaxrun names each test suite after the task func that produced it
+// (e.g. "run-0.0.1:group:test"), so these names select the same
+// tests again.
+func readFailed(filename string) (map[string]bool, error) {
+	r, err := readJUnit(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	failed := make(map[string]bool)
+	for _, ts := range r.TestSuite {
+		if 0 < ts.Failures || 0 < ts.Errors {
+			failed[ts.Name] = true
+		}
+	}
+
+	return failed, nil
+}
+
+// rerunning reports whether the tests are limited to the ones that
+// failed in the TestRunParams.RerunFailed results.
+func (tr TestRun) rerunning() bool {
+	return tr.rerun != nil
+}
+
+// reruns reports whether -rerun-failed selects the named test.
+func (tr TestRun) reruns(ctx *plaxDsl.Ctx, name string) bool {
+	if !tr.rerunning() || tr.rerun[name] {
+		return true
+	}
+
+	ctx.Logdf("rerun-failed excluded %s test", name)
+
+	return false
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestRerunFailed(t *testing.T) {
+	spec := `name: run
+version: 0.0.1
+tests:
+  pass: {path: pass.yaml, version: fake}
+  fail: {path: pass.yaml, version: failing}
+groups:
+  all:
+    tests:
+      - name: pass
+      - name: fail
+  passes:
+    tests:
+      - name: pass
+`
+	filename := writeRunSpec(t, spec)
+	dir := filepath.Dir(filename)
+
+	opts := runOptions(filename)
+	opts.Groups = []string{"all", "passes"}
+
+	tr, err := RunTests(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := xml.Marshal(tr.Report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := filepath.Join(dir, "results.xml")
+	if err := ioutil.WriteFile(results, bs, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts.RerunFailed = results
+	if tr, err = RunTests(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	// The "passes" group doesn't have any failed tests, which
+	// isn't an error.
+	if r := tr.Report; r.Total != 1 || r.Failures != 1 || r.TestSuite[0].Name != "run-0.0.1:all:fail" {
+		t.Fatalf("unexpected report %#v", r)
+	}
+
+	// Each -t test has its own suite, so only the failed one
+	// executes again.
+	opts.Groups = nil
+	opts.Tests = []string{"pass", "fail"}
+	opts.RerunFailed = ""
+	if tr, err = RunTests(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+	if bs, err = xml.Marshal(tr.Report); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(results, bs, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts.RerunFailed = results
+	if tr, err = RunTests(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+	if r := tr.Report; r.Total != 1 || r.Failures != 1 || r.TestSuite[0].Name != "run-0.0.1:fail" {
+		t.Fatalf("unexpected report %#v", r)
+	}
+
+	opts.RerunFailed = filepath.Join(dir, "missing.xml")
+	if _, err = RunTests(context.Background(), opts); err == nil {
+		t.Fatal("expected an error for missing results")
+	}
+}
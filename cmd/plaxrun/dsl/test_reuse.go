@@ -0,0 +1,127 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"sync"
+
+	"github.com/Comcast/plax/cmd/plaxrun/async"
+	plaxDsl "github.com/Comcast/plax/dsl"
+	"github.com/Comcast/plax/junit"
+)
+
+// chanPools are the ChanPools of the groups when the
+// TestRunParams.ReuseConnections.
+type chanPools struct {
+	sync.Mutex
+
+	pools []*groupChanPool
+}
+
+// groupChanPool is the ChanPool shared by the tests of a group,
+// which is closed when they have all finished.
+type groupChanPool struct {
+	sync.Mutex
+
+	ctx     *plaxDsl.Ctx
+	pool    *plaxDsl.ChanPool
+	tasks   int
+	pending int
+}
+
+// reusing reports whether the tests of each group share their
+// connections.
+func (tr TestRun) reusing() bool {
+	return tr.trps != nil && tr.trps.ReuseConnections != nil && *tr.trps.ReuseConnections
+}
+
+// add makes the task funcs of a group close the pool after the last
+// of them has finished.
+func (cp *chanPools) add(ctx *plaxDsl.Ctx, pool *plaxDsl.ChanPool, tfs []*async.TaskFunc) {
+	gp := &groupChanPool{
+		ctx:   ctx,
+		pool:  pool,
+		tasks: len(tfs),
+	}
+
+	for _, tf := range tfs {
+		f := tf.Func.(testFunc)
+		tf.Func = func() (*junit.TestSuite, error) {
+			defer gp.release()
+			return f()
+		}
+	}
+
+	cp.Lock()
+	defer cp.Unlock()
+
+	cp.pools = append(cp.pools, gp)
+}
+
+// release notes that one of the group's tests finished.
+func (gp *groupChanPool) release() {
+	gp.Lock()
+	defer gp.Unlock()
+
+	if gp.pending--; gp.pending == 0 {
+		gp.close()
+	}
+}
+
+func (gp *groupChanPool) close() {
+	if err := gp.pool.Close(); err != nil {
+		gp.ctx.Warnf("failed to close shared connections: %s", err)
+	}
+}
+
+// reset prepares for an execution of the tests.
+func (cp *chanPools) reset() {
+	if cp == nil {
+		return
+	}
+
+	cp.Lock()
+	defer cp.Unlock()
+
+	for _, gp := range cp.pools {
+		gp.Lock()
+		gp.pending = gp.tasks
+		gp.Unlock()
+	}
+}
+
+// close closes the connections of the groups whose tests didn't all
+// finish (because the execution was canceled, for example).
+func (cp *chanPools) close() {
+	if cp == nil {
+		return
+	}
+
+	cp.Lock()
+	defer cp.Unlock()
+
+	for _, gp := range cp.pools {
+		gp.Lock()
+		if 0 < gp.pending {
+			gp.pending = 0
+			gp.close()
+		}
+		gp.Unlock()
+	}
+}
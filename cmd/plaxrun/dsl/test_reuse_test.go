@@ -0,0 +1,135 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	plaxDsl "github.com/Comcast/plax/dsl"
+	"github.com/Comcast/plax/junit"
+)
+
+// poolingPlugin opens a mock channel with the ChanPool of its
+// PluginDef and remembers the pool and its size.
+type poolingPlugin struct {
+	name string
+	pool *plaxDsl.ChanPool
+
+	mu    *sync.Mutex
+	seen  map[string]*plaxDsl.ChanPool
+	sizes map[string]int
+}
+
+func (p *poolingPlugin) Invoke(ctx context.Context) (*junit.TestSuite, error) {
+	ts := junit.NewTestSuite(p.name)
+	tc := junit.NewTestCase(p.name, "")
+
+	size := 0
+	if p.pool != nil {
+		dctx := plaxDsl.NewCtx(ctx)
+		c, err := p.pool.Chan(dctx, "mock", map[string]interface{}{"broker": "here"}, plaxDsl.NewMockChan)
+		if err != nil {
+			return nil, err
+		}
+		if err = c.Open(dctx); err != nil {
+			return nil, err
+		}
+		defer c.Close(dctx)
+		size = p.pool.Len()
+	}
+
+	p.mu.Lock()
+	p.seen[p.name] = p.pool
+	p.sizes[p.name] = size
+	p.mu.Unlock()
+
+	tc.Finish(junit.Passed)
+	ts.Add(*tc)
+	ts.Finish()
+	return ts, nil
+}
+
+func TestReuseConnections(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		seen  = make(map[string]*plaxDsl.ChanPool)
+		sizes = make(map[string]int)
+	)
+
+	ThePluginRegistry.Register("pooling", func(def PluginDef) (Plugin, error) {
+		name, _ := def.GetPluginDefName()
+		pool, err := def.GetPluginDefChanPool()
+		if err != nil {
+			return nil, err
+		}
+		return &poolingPlugin{name: name, pool: pool, mu: &mu, seen: seen, sizes: sizes}, nil
+	})
+
+	spec := `name: run
+version: 0.0.1
+tests:
+  a: {path: pass.yaml, version: pooling}
+  b: {path: pass.yaml, version: pooling}
+groups:
+  one:
+    tests:
+      - name: a
+      - name: b
+  two:
+    tests:
+      - name: a
+`
+	filename := writeRunSpec(t, spec)
+
+	opts := runOptions(filename)
+	opts.Groups = []string{"one", "two"}
+
+	if _, err := RunTests(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+	if seen["run-0.0.1:one:a"] != nil {
+		t.Fatal("connections shared without ReuseConnections")
+	}
+
+	opts.ReuseConnections = true
+	opts.MaxConcurrency = 2
+	if _, err := RunTests(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		a   = seen["run-0.0.1:one:a"]
+		b   = seen["run-0.0.1:one:b"]
+		two = seen["run-0.0.1:two:a"]
+	)
+	if a == nil || a != b || a == two {
+		t.Fatalf("unexpected pools %p %p %p", a, b, two)
+	}
+
+	// The tests of a group share one connection, which is
+	// closed after the group's last test.
+	if sizes["run-0.0.1:one:a"] != 1 || sizes["run-0.0.1:one:b"] != 1 {
+		t.Fatalf("unexpected pool sizes %v", sizes)
+	}
+	if a.Len() != 0 || two.Len() != 0 {
+		t.Fatalf("connections still open")
+	}
+}
@@ -20,10 +20,21 @@ package dsl
 
 import (
 	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
@@ -33,8 +44,13 @@ import (
 	"github.com/Comcast/plax/junit"
 
 	plaxDsl "github.com/Comcast/plax/dsl"
+	"github.com/Comcast/plax/subst"
 )
 
+// StdinFilename is the TestRunParams.Filename that reads the test run
+// specification from standard input.
+const StdinFilename = "-"
+
 // Ctx is the context type
 type Ctx struct {
 	*plaxDsl.Ctx
@@ -57,23 +73,251 @@ type TestRun struct {
 	Groups  TestGroupMap        `yaml:"groups" json:"-"`
 	Params  TestParamBindingMap `yaml:"params" json:"-"`
 	Reports TestReportPluginMap `yaml:"reports" json:"-"`
-	trps    *TestRunParams      `json:"-"`
-	tfs     []*async.TaskFunc   `json:"-"`
+
+	// Profiles are the bindings of each environment (selected
+	// with TestRunParams.Profile).
+	Profiles TestProfileMap `yaml:"profiles,omitempty" json:"-"`
+
+	// Setup tests execute before all of the other tests, which
+	// are skipped if a setup test fails.
+	Setup TestDefRefList `yaml:"setup,omitempty" json:"-"`
+
+	// Teardown tests execute after all of the other tests (even
+	// if they failed).
+	Teardown TestDefRefList `yaml:"teardown,omitempty" json:"-"`
+
+	// Report is the TestReport produced by Exec.
+	Report *report.TestReport `yaml:"-" json:"-"`
+
+	// Leaked is the number of additional goroutines still
+	// running after Exec with the LeakCheck param.
+	Leaked int `yaml:"-" json:"-"`
+
+	// Iterations summarize each execution of the tests by Exec
+	// (which executes them TestRunParams.Repeat times).
+	Iterations []Iteration `yaml:"-" json:"-"`
+
+	trps *TestRunParams    `json:"-"`
+	tfs  []*async.TaskFunc `json:"-"`
+
+	// infos describes the test behind each of the tfs.
+	infos map[*async.TaskFunc]*taskInfo
+
+	// selector is the parsed TestRunParams.Labels.
+	selector labelExpr
+
+	// groupLabels are the labels of the groups containing the
+	// tests being processed.
+	groupLabels []string
+
+	// concurrencyGroup is the ConcurrencyGroup of the innermost
+	// group (with one) containing the tests being processed.
+	concurrencyGroup string
+
+	// group is the name of the innermost group containing the
+	// tests being processed.
+	group string
+
+	// serial serializes the tests in the same concurrency group.
+	serial *concurrencyGroups
+
+	// excluded counts the tests excluded by -priority.
+	excluded *int
+
+	// sharded counts the tests that aren't in the -shard-index
+	// shard.
+	sharded *int
+
+	// missing are the required params that aren't bound.
+	missing map[string]bool
+
+	// progress, when not nil, writes the TestRunParams.ProgressOut.
+	progress *progress
+
+	// deadline, when not nil, aborts the tests after the
+	// TestRunParams.RunTimeout.
+	deadline *runDeadline
+
+	// failFast, when not nil, skips the tests after the first
+	// failing test (or after TestRunParams.MaxFailures).
+	failFast *failFast
+
+	// redactor redacts secret values from the results.
+	redactor *Redactor
+
+	// deps executes tests after their TestDef.DependsOn.
+	deps *testDeps
+
+	// hooks executes the setup and teardown tests.
+	hooks *testHooks
+
+	// hook is true while getting the task funcs of setup and
+	// teardown tests.
+	hook bool
+
+	// rerun, when not nil, are the names of the tests that
+	// failed in the TestRunParams.RerunFailed results.
+	rerun map[string]bool
+
+	// changed, when not nil, are the files that changed since the
+	// TestRunParams.ChangedSince git ref.
+	changed *changedTests
+
+	// chanPool, when not nil, is the ChanPool of the group being
+	// processed, and pools are the ChanPools of all the groups.
+	chanPool *plaxDsl.ChanPool
+	pools    *chanPools
+
+	// tracer, when not nil, exports the spans of the run to the
+	// TestRunParams.OTelEndpoint.
+	tracer *tracer
+
+	// seed is the seed used to shuffle the tfs (if they were).
+	seed int64
+
+	// groupDeadline is the deadline (if any) of the test group
+	// being processed.
+	//
+	// A TestRun is passed by value while getting task funcs, so
+	// a group's deadline only applies to the tests and groups it
+	// references.
+	groupDeadline *groupDeadline
+
+	// groupDeadlines has all of the groupDeadlines (so that they
+	// can be reset for each execution).
+	groupDeadlines *groupDeadlines
+}
+
+// taskInfo describes the test executed by a TaskFunc.
+type taskInfo struct {
+	// test is the name of the TestDef.
+	test string
+
+	// bs are the bindings given to the test.
+	bs *plaxDsl.Bindings
+
+	// plugin executes the test.
+	plugin Plugin
+
+	// skip is the TestDef.Skip of a test that isn't executed.
+	skip string
 }
 
 // NewTestRun makes a new TestRun with the given TestRunParams
 func NewTestRun(ctx *Ctx, trps *TestRunParams) (*TestRun, error) {
-	tr := TestRun{}
+	tr := TestRun{
+		infos:    make(map[*async.TaskFunc]*taskInfo),
+		excluded: new(int),
+		sharded:  new(int),
+		missing:  make(map[string]bool),
+		deps:     newTestDeps(),
+		hooks:    newTestHooks(),
+		pools:    &chanPools{},
+		serial:   newConcurrencyGroups(),
+
+		groupDeadlines: &groupDeadlines{},
+	}
 
 	if trps.Dir == nil {
 		return nil, fmt.Errorf("TestRunParams.Dir is nil")
 	}
 
-	ctx.Dir = *trps.Dir
+	if err := checkShard(trps); err != nil {
+		return nil, err
+	}
+
+	if err := checkRepeat(trps); err != nil {
+		return nil, err
+	}
+
+	if err := checkFlaky(trps); err != nil {
+		return nil, err
+	}
+
+	if err := checkJUnitTemplates(trps); err != nil {
+		return nil, err
+	}
+
+	if trps.ProgressOut != nil {
+		tr.progress = &progress{w: trps.ProgressOut}
+	}
+
+	if trps.RunTimeout != nil && 0 < *trps.RunTimeout {
+		tr.deadline = &runDeadline{timeout: *trps.RunTimeout}
+	}
+
+	if trps.FailFast != nil && *trps.FailFast {
+		tr.failFast = &failFast{}
+	} else if trps.MaxFailures != nil && 0 < *trps.MaxFailures {
+		tr.failFast = &failFast{max: int32(*trps.MaxFailures)}
+	}
+
+	var endpoint string
+	if trps.OTelEndpoint != nil {
+		endpoint = *trps.OTelEndpoint
+	}
+	if endpoint != "" || otelConfigured() {
+		var err error
+		if tr.tracer, err = newTracer(endpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	tr.redactor = NewRedactor()
+	tr.redactor.secrets = ctx.Redactions
+	for _, v := range trps.RedactValues {
+		tr.redactor.Add(v)
+	}
+	if err := ctx.RedactPatterns(trps.RedactPatterns); err != nil {
+		return nil, err
+	}
+
+	// Files are resolved against Dir rather than changing the
+	// working directory, which is shared by the whole process.
+	testDir, err := filepath.Abs(*trps.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find path to test files: %w", err)
+	}
+
+	ctx.Dir = testDir
 	ctx.LogLevel = *trps.LogLevel
-	ctx.IncludeDirs = trps.IncludeDirs
+
+	if trps.LogFormat != nil {
+		switch *trps.LogFormat {
+		case "", "text":
+		case "json":
+			if _, is := ctx.Logger.(*plaxDsl.JSONLogger); !is {
+				ctx.Logger = plaxDsl.NewJSONLogger(os.Stderr)
+			}
+		default:
+			return nil, fmt.Errorf("log format '%s' isn't 'text' or 'json'", *trps.LogFormat)
+		}
+	}
+	ctx.IncludeDirs = append([]string{}, trps.IncludeDirs...)
 	ctx.Redact = *trps.Redact
 
+	// Fragments included by many files are only read and parsed
+	// once.
+	if ctx.IncludeCache == nil {
+		ctx.IncludeCache = plaxDsl.NewIncludeCache()
+	}
+
+	if trps.IncludeTimeout != nil || trps.IncludeHeader != nil {
+		var (
+			timeout = plaxDsl.DefaultIncludeTimeout
+			header  string
+		)
+		if trps.IncludeTimeout != nil {
+			timeout = *trps.IncludeTimeout
+		}
+		if trps.IncludeHeader != nil {
+			header = *trps.IncludeHeader
+		}
+		// Shared by the tests, so remote includes are fetched
+		// once per run.
+		ctx.Fetcher = plaxDsl.NewIncludeFetcher(timeout, header)
+	}
+
 	reportPluginDir, err := filepath.Abs(*trps.ReportPluginDir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find path to report plugins: %w", err)
@@ -86,39 +330,135 @@ func NewTestRun(ctx *Ctx, trps *TestRunParams) (*TestRun, error) {
 		filename = *trps.Filename
 	}
 
-	// Add the test run directory to the end of the includeDirs.
-	dir, err := filepath.Abs(filepath.Dir(filename))
-	if err != nil {
-		return nil, fmt.Errorf("failed to find path to test run file: %w", err)
-	}
-	ctx.IncludeDirs = append(ctx.IncludeDirs, dir)
+	var bs []byte
+	if filename == StdinFilename {
+		// There's no test run file to resolve includes
+		// against, so Dir (below) serves.
+		in := trps.Stdin
+		if in == nil {
+			in = os.Stdin
+		}
+		if bs, err = ioutil.ReadAll(in); err != nil {
+			return nil, fmt.Errorf("failed to read test runner configuration from standard input: %w", err)
+		}
+	} else {
+		// Add the test run directory to the end of the includeDirs.
+		dir, err := filepath.Abs(filepath.Dir(filename))
+		if err != nil {
+			return nil, fmt.Errorf("failed to find path to test run file: %w", err)
+		}
+		ctx.IncludeDirs = append(ctx.IncludeDirs, dir)
 
-	bs, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read test runner configuration file: %w", err)
+		if bs, err = ioutil.ReadFile(filename); err != nil {
+			return nil, fmt.Errorf("failed to read test runner configuration file: %w", err)
+		}
 	}
 
-	ctx.Redactf("Test Bindings: %v\n", trps.Bindings)
+	ctx.IncludeDirs = append(ctx.IncludeDirs, testDir)
 
-	err = os.Chdir(*trps.Dir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to change directory: %w", err)
+	if trps.Bindings == nil {
+		trps.Bindings = make(plaxDsl.Bindings)
+	}
+
+	// Command-line bindings take precedence over environment
+	// variables, which take precedence over the bindings file.
+	if trps.EnvPrefix != nil && *trps.EnvPrefix != "" {
+		defaultBindings(trps.Bindings, envBindings(os.Environ(), *trps.EnvPrefix))
 	}
 
-	ctx.IncludeDirs = append(ctx.IncludeDirs, *trps.Dir)
+	if trps.BindingsFile != nil && *trps.BindingsFile != "" {
+		m, err := readBindingsFile(ctx.Ctx, *trps.BindingsFile)
+		if err != nil {
+			return nil, err
+		}
+		defaultBindings(trps.Bindings, m)
+	}
 
 	bs, err = plaxDsl.IncludeYAML(ctx.Ctx, bs)
 	if err != nil {
+		if filename == StdinFilename {
+			return nil, fmt.Errorf("failed to process include YAML (from standard input, so relative to -dir %s or -I): %w", testDir, err)
+		}
 		return nil, fmt.Errorf("failed to process include YAML: %w", err)
 	}
 
+	if trps.ExpandEnv != nil && *trps.ExpandEnv {
+		strict := trps.ExpandEnvStrict != nil && *trps.ExpandEnvStrict
+		bs, err = expandEnv(bs, bindingsLookup(trps.Bindings, os.LookupEnv), strict)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ves, err := ValidateTestRun(bs)
+	if err != nil {
+		return nil, err
+	}
+
+	if 0 < len(ves) {
+		return nil, ValidationErrors(ves)
+	}
+
 	if err := yaml.Unmarshal(bs, &tr); err != nil {
 		return nil, fmt.Errorf("test runner configuration parse error: %w", err)
 	}
 
+	tr.trps = trps
+
+	if trps.Profile != nil && *trps.Profile != "" {
+		if err := tr.applyProfile(ctx, *trps.Profile); err != nil {
+			return nil, err
+		}
+	}
+
+	// Secret params that are already bound are redacted from
+	// everything that follows.
+	for pk, tpb := range tr.Params {
+		if v, have := trps.Bindings[pk]; have && tpb.Secret {
+			if err := addSecret(ctx.Ctx, pk, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	ctx.Redactf("Test Bindings: %v\n", trps.Bindings)
+
 	ctx.Logdf("TestRun: %v\n", tr)
 
-	tr.trps = trps
+	if trps.ValidateOnly != nil && *trps.ValidateOnly {
+		return &tr, nil
+	}
+
+	// Listing doesn't need any bindings.
+	if trps.List != nil && *trps.List {
+		return &tr, nil
+	}
+
+	if trps.Labels != nil {
+		if tr.selector, err = parseLabels(*trps.Labels); err != nil {
+			return nil, err
+		}
+	}
+
+	if trps.RerunFailed != nil && *trps.RerunFailed != "" {
+		if tr.rerun, err = readFailed(*trps.RerunFailed); err != nil {
+			return nil, err
+		}
+		ctx.Logf("Rerunning the %d failed tests in %s", len(tr.rerun), *trps.RerunFailed)
+	}
+
+	if trps.ChangedSince != nil && *trps.ChangedSince != "" {
+		if tr.changed, err = newChangedTests(ctx.Dir, *trps.ChangedSince); err != nil {
+			return nil, err
+		}
+		if spec, err := filepath.Abs(filename); err == nil && tr.changed.has(spec) {
+			// The change could affect any test.
+			ctx.Logf("The test run specification changed since %s, so every test is selected", *trps.ChangedSince)
+			tr.changed = nil
+		} else {
+			ctx.Logf("Selecting the tests that changed since %s (%d changed files)", *trps.ChangedSince, len(tr.changed.files))
+		}
+	}
 
 	tfs, err := trps.Groups.getTaskFuncs(ctx.Ctx, tr)
 	if err != nil {
@@ -147,43 +487,559 @@ func NewTestRun(ctx *Ctx, trps *TestRunParams) (*TestRun, error) {
 		tr.tfs = append(tr.tfs, tfs...)
 	}
 
+	hbs, err := (&trps.Bindings).Copy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy bindings for setup and teardown: %w", err)
+	}
+
+	tr.tfs, err = tr.withHooks(ctx.Ctx, fmt.Sprintf("%s-%s", tr.Name, tr.Version), hbs, tr.Setup, tr.Teardown, tr.tfs)
+	if err != nil {
+		return nil, err
+	}
+
+	if 0 < len(tr.missing) {
+		names := make([]string, 0, len(tr.missing))
+		for name := range tr.missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return nil, fmt.Errorf("required params are not bound: %s (bind them with -p, -env-prefix environment variables, -bindings-file, or a -profile)", strings.Join(names, ", "))
+	}
+
+	if trps.Shuffle != nil && *trps.Shuffle {
+		tr.shuffle(ctx)
+	} else {
+		tr.sortByName()
+	}
+
+	if err := tr.orderByDependencies(); err != nil {
+		return nil, err
+	}
+
+	for _, tf := range tr.tfs {
+		tr.tracer.wrap(tf, tr.redactor)
+	}
+
 	return &tr, nil
 }
 
+// sortByName orders the tests by name, which is the default order
+// so that the same tests always execute in the same order.
+func (tr *TestRun) sortByName() {
+	sort.SliceStable(tr.tfs, func(i, j int) bool {
+		return tr.tfs[i].Name < tr.tfs[j].Name
+	})
+}
+
+// shuffle randomizes the order of the tests with the
+// TestRunParams.Seed (or, if that's zero, a new seed), which is
+// logged so that the order can be reproduced.
+func (tr *TestRun) shuffle(ctx *Ctx) {
+	seed := time.Now().UnixNano()
+	if tr.trps.Seed != nil && *tr.trps.Seed != 0 {
+		seed = *tr.trps.Seed
+	}
+	tr.seed = seed
+
+	ctx.Logf("Shuffling tests with -seed %d", seed)
+
+	rand.New(rand.NewSource(seed)).Shuffle(len(tr.tfs), func(i, j int) {
+		tr.tfs[i], tr.tfs[j] = tr.tfs[j], tr.tfs[i]
+	})
+}
+
+// ShuffleSeed returns the seed used to shuffle the tests, which is
+// zero if they weren't shuffled.
+func (tr *TestRun) ShuffleSeed() int64 {
+	return tr.seed
+}
+
 // Exec the TestRun
 func (tr *TestRun) Exec(ctx *Ctx) error {
+	if tr.trps.ValidateOnly != nil && *tr.trps.ValidateOnly {
+		ctx.Logf("%s is valid", *tr.trps.Filename)
+		return nil
+	}
+
+	emit := tr.trps.Emit == nil || *tr.trps.Emit
+
+	if tr.trps.List != nil && *tr.trps.List {
+		if !emit {
+			return nil
+		}
+		format := "text"
+		if tr.trps.EmitJSON != nil && *tr.trps.EmitJSON {
+			format = "JSON"
+		}
+		return tr.WriteList(os.Stdout, format)
+	}
+
+	if tr.trps.Preflight != nil && *tr.trps.Preflight {
+		return tr.Preflight(ctx, os.Stdout)
+	}
+
+	if tr.trps.DryRun != nil && *tr.trps.DryRun {
+		if !emit {
+			return nil
+		}
+		return tr.WritePlan(ctx, os.Stdout)
+	}
+
 	testReport := report.NewTestReport()
 	testReport.Name = tr.Name
 	testReport.Version = tr.Version
 
-	taskResults, err := async.Sequential(ctx, tr.tfs...)
-	if err != nil {
-		return fmt.Errorf("failed to execute tasks: %w", err)
-	}
-
-	for _, taskResult := range taskResults {
-		if ts, ok := taskResult.Result.(*junit.TestSuite); ok {
-			if ts != nil {
-				testReport.TestSuite = append(testReport.TestSuite, ts)
-				testReport.Total += ts.Total
-				testReport.Passed += ts.Passed
-				testReport.Skipped += ts.Skipped
-				testReport.Failures += ts.Failures
-				testReport.Errors += ts.Errors
+	var (
+		taskResults async.TaskResults
+		err         error
+		execErr     error
+		leakCheck   = tr.trps.LeakCheck != nil && *tr.trps.LeakCheck
+		goroutines  = runtime.NumGoroutine()
+	)
+
+	defer tr.deadline.start(ctx)()
+
+	// Each iteration executes the tests anew (with new channels
+	// and trace IDs), and its TestSuites are reported separately
+	// with a plax.iteration property.
+	repeat := tr.repeat()
+	tr.Iterations = nil
+	tr.tracer.start(fmt.Sprintf("%s-%s", tr.Name, tr.Version))
+	for i := 1; i <= repeat; i++ {
+		if 1 < repeat {
+			ctx.Logf("Iteration %d of %d", i, repeat)
+		}
+
+		tr.deps.reset()
+		tr.hooks.reset()
+		tr.groupDeadlines.reset()
+		tr.pools.reset()
+
+		var results async.TaskResults
+		if tr.trps.MaxConcurrency != nil && 1 < *tr.trps.MaxConcurrency {
+			results, err = async.Parallel(ctx, *tr.trps.MaxConcurrency, tr.tfs...)
+		} else {
+			results, err = async.Sequential(ctx, tr.tfs...)
+		}
+		tr.pools.close()
+		if err != nil {
+			if results == nil {
+				return fmt.Errorf("failed to execute tasks: %w", err)
 			}
+			// The context is done, but the report still
+			// has the tests that finished.
+			execErr = fmt.Errorf("failed to execute tasks: %w", err)
 		}
+		taskResults = append(taskResults, results...)
+
+		it := Iteration{Number: i}
+		for _, taskResult := range results {
+			if ts, ok := taskResult.Result.(*junit.TestSuite); ok {
+				if ts != nil {
+					if 1 < repeat {
+						ts.AddProperty("plax.iteration", strconv.Itoa(i))
+					}
+					it.add(ts)
+					testReport.TestSuite = append(testReport.TestSuite, ts)
+					testReport.Total += ts.Total
+					testReport.Passed += ts.Passed
+					testReport.Skipped += ts.Skipped
+					testReport.Failures += ts.Failures
+					testReport.Errors += ts.Errors
+				}
+			}
+		}
+		tr.Iterations = append(tr.Iterations, it)
+
+		if execErr != nil {
+			break
+		}
+	}
+
+	tr.markSlow(ctx, testReport)
+
+	if leakCheck {
+		tr.Leaked = tr.checkLeaks(ctx, goroutines)
+	}
+
+	if tr.excluded != nil {
+		testReport.Excluded = *tr.excluded
 	}
 
+	tr.addProperties(testReport)
+
+	tr.redactor.Report(testReport)
+
 	testReport.Finish()
 
-	err = tr.Reports.Generate(ctx.Ctx, tr.Params, tr.trps.Bindings, testReport, *tr.trps.EmitJSON)
+	tr.Report = testReport
+
+	if err = tr.tracer.finish(ctx, testReport); err != nil {
+		ctx.Warnf("%s", err)
+	}
+
+	stdoutType := "XML"
+	if tr.trps.EmitJSON != nil && *tr.trps.EmitJSON {
+		stdoutType = "JSON"
+	}
+	if tr.trps.EmitTAP != nil && *tr.trps.EmitTAP {
+		stdoutType = "TAP"
+	}
+	if tr.trps.EmitJSONFlat != nil && *tr.trps.EmitJSONFlat {
+		stdoutType = "JSON-FLAT"
+	}
+	if tr.trps.EmitJSONLines != nil && *tr.trps.EmitJSONLines {
+		stdoutType = "JSON-LINES"
+	}
+	if tr.trps.EmitHTML != nil && *tr.trps.EmitHTML {
+		stdoutType = "HTML"
+	}
+
+	if tr.trps.OutputFile != nil && *tr.trps.OutputFile != "" {
+		// The output file replaces the default stdout report.
+		if err = tr.WriteFile(*tr.trps.OutputFile, stdoutType); err != nil {
+			return err
+		}
+		stdoutType = ""
+	} else if !emit {
+		stdoutType = ""
+	} else if stdoutType != "XML" && stdoutType != "JSON" {
+		// TAP, flat JSON, and HTML replace the default stdout
+		// report.
+		if err = tr.Write(os.Stdout, stdoutType); err != nil {
+			return err
+		}
+		stdoutType = ""
+	}
+
+	if tr.trps.TimingsFile != nil && *tr.trps.TimingsFile != "" {
+		if err = tr.WriteTimingsFile(*tr.trps.TimingsFile); err != nil {
+			return err
+		}
+	}
+
+	if tr.trps.MetricsFile != nil && *tr.trps.MetricsFile != "" {
+		if err = tr.WriteMetricsFile(*tr.trps.MetricsFile); err != nil {
+			return err
+		}
+	}
+
+	if tr.trps.AllureDir != nil && *tr.trps.AllureDir != "" {
+		if err = tr.WriteAllure(*tr.trps.AllureDir); err != nil {
+			return err
+		}
+	}
+
+	if tr.trps.FlakyFile != nil && *tr.trps.FlakyFile != "" {
+		if err = tr.WriteFlakyFile(*tr.trps.FlakyFile); err != nil {
+			return err
+		}
+	}
+
+	err = tr.Reports.Generate(ctx.Ctx, tr.Params, tr.trps.Bindings, testReport, stdoutType)
 	if err != nil {
-		ctx.Logf(err.Error())
+		ctx.Logf("%s", err)
+	}
+
+	if tr.trps.GitHub != nil && *tr.trps.GitHub {
+		if err = tr.WriteGitHub(os.Stderr); err != nil {
+			return err
+		}
+	}
+
+	if tr.trps.Verbose != nil && *tr.trps.Verbose {
+		if err = tr.WriteSummary(os.Stderr); err != nil {
+			return err
+		}
+		if 0 < tr.flaky() {
+			if err = tr.Flakiness().Write(os.Stderr, "text"); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err = tr.deadline.err(); err != nil {
+		return err
+	}
+
+	if execErr != nil {
+		return execErr
 	}
 
 	if taskResults.HasError() {
 		ctx.Logdf("TaskResult Error: %s", taskResults.Error())
-		return fmt.Errorf(taskResults.Error())
+		return fmt.Errorf("%s", taskResults.Error())
+	}
+
+	return nil
+}
+
+// addProperties adds the run metadata as properties of each
+// TestSuite: the plax.version (if known), the plax.started time of
+// the test run, the plax.seed of shuffled tests, the plax.shard
+// ("INDEX/TOTAL") of sharded tests, and the
+// TestRunParams.Properties (sorted by name).
+func (tr *TestRun) addProperties(r *report.TestReport) {
+	names := make([]string, 0, len(tr.trps.Properties))
+	for name := range tr.trps.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	started := r.Started.Format(time.RFC3339Nano)
+
+	for _, ts := range r.TestSuite {
+		if tr.trps.PlaxVersion != nil && *tr.trps.PlaxVersion != "" {
+			ts.AddProperty("plax.version", *tr.trps.PlaxVersion)
+		}
+		ts.AddProperty("plax.started", started)
+		if tr.seed != 0 {
+			ts.AddProperty("plax.seed", strconv.FormatInt(tr.seed, 10))
+		}
+		if tr.sharding() {
+			ts.AddProperty("plax.shard", fmt.Sprintf("%d/%d", tr.shardIndex(), *tr.trps.ShardTotal))
+		}
+		for _, name := range names {
+			ts.AddProperty(name, tr.trps.Properties[name])
+		}
+	}
+}
+
+// WritePlan writes the names of the tests that Exec would execute, in
+// order, along with the bindings for each test.
+func (tr *TestRun) WritePlan(ctx *Ctx, w io.Writer) error {
+	var sb strings.Builder
+
+	if len(tr.tfs) == 0 {
+		sb.WriteString("No tests selected\n")
+	}
+
+	for _, tf := range tr.tfs {
+		sb.WriteString(tf.Name + "\n")
+
+		info, have := tr.infos[tf]
+		if have && info.skip != "" {
+			sb.WriteString("  (skip: " + info.skip + ")\n")
+		}
+		if !have || info.bs == nil {
+			continue
+		}
+
+		keys := make([]string, 0, len(*info.bs))
+		for k := range *info.bs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			v := (*info.bs)[k]
+			js, err := subst.JSONMarshal(&v)
+			if err != nil {
+				js = []byte(fmt.Sprintf("%#v", v))
+			}
+			sb.WriteString(ctx.Redactions.Redactf("  %s=%s\n", k, js))
+		}
+	}
+
+	_, err := io.WriteString(w, tr.redactor.String(sb.String()))
+
+	return err
+}
+
+// WriteSummary writes a one-line summary of the Report.
+func (tr *TestRun) WriteSummary(w io.Writer) error {
+	if tr.Report == nil {
+		return fmt.Errorf("test run %s has no report", tr.Name)
+	}
+
+	if err := tr.writeIterations(w); err != nil {
+		return err
+	}
+
+	r := tr.Report
+	_, err := fmt.Fprintf(w, "Total=%d Passed=%d Failed=%d Errors=%d Skipped=%d Duration=%s\n",
+		r.Total, r.Passed, r.Failures, r.Errors, r.Skipped, r.Time.Round(time.Millisecond))
+	if err != nil {
+		return fmt.Errorf("failed to write summary: %w", err)
+	}
+
+	return tr.writeSlowest(w)
+}
+
+// timingName is the name of a TestCase for Timings: the name of the
+// TestSuite, which is the name of the test in the test run, followed
+// by the name of the TestCase if the TestSuite has several.
+func timingName(ts *junit.TestSuite, tc junit.TestCase) string {
+	if len(ts.TestCase) == 1 {
+		return ts.Name
+	}
+	return ts.Name + "/" + tc.Name
+}
+
+// Timings returns the elapsed duration of each TestCase of the Report
+// by test name.  Skipped tests have no duration.
+func (tr *TestRun) Timings() map[string]time.Duration {
+	timings := make(map[string]time.Duration)
+	if tr.Report == nil {
+		return timings
+	}
+
+	for _, ts := range tr.Report.TestSuite {
+		for _, tc := range ts.TestCase {
+			var d time.Duration
+			if tc.Time != nil {
+				d = *tc.Time
+			}
+			timings[timingName(ts, tc)] = d
+		}
+	}
+
+	return timings
+}
+
+// WriteTimings writes a CSV of the name, duration (in seconds), and
+// status of each TestCase of the Report, in the order of execution.
+func (tr *TestRun) WriteTimings(w io.Writer) error {
+	if tr.Report == nil {
+		return fmt.Errorf("test run %s has no report", tr.Name)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"name", "duration", "status"})
+
+	for _, ts := range tr.Report.TestSuite {
+		for _, tc := range ts.TestCase {
+			var d time.Duration
+			if tc.Time != nil {
+				d = *tc.Time
+			}
+			cw.Write([]string{
+				timingName(ts, tc),
+				strconv.FormatFloat(d.Seconds(), 'f', 6, 64),
+				string(tc.Status),
+			})
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write timings: %w", err)
+	}
+
+	return nil
+}
+
+// WriteTimingsFile writes the WriteTimings CSV to the named file.
+//
+// Missing parent directories are created.
+func (tr *TestRun) WriteTimingsFile(filename string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("failed to make directory for timings: %w", err)
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create timings file: %w", err)
+	}
+
+	if err = tr.WriteTimings(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("failed to write timings: %w", err)
+	}
+
+	return nil
+}
+
+// ExitCode returns the process exit code for the Report according to
+// the TestRunParams.ExitCodePolicy (or the DefaultExitCodePolicy).
+//
+// A non-nil err, which is usually what Exec returned, always results
+// in a non-zero exit code.
+func (tr *TestRun) ExitCode(err error) int {
+	policy := DefaultExitCodePolicy()
+	if tr.trps != nil && tr.trps.ExitCodePolicy != nil {
+		policy = *tr.trps.ExitCodePolicy
+	}
+
+	if errors.Is(err, ErrRunTimeout) && policy.TimeoutCode != 0 {
+		return policy.TimeoutCode
+	}
+
+	code := policy.Code(tr.Report)
+	if code == 0 && err != nil {
+		code = 1
+	}
+
+	return code
+}
+
+// Write the Report in the given format ("XML", "JSON", "TAP",
+// "JSON-FLAT", "JSON-LINES", or "HTML").
+func (tr *TestRun) Write(w io.Writer, format string) error {
+	switch format {
+	case "TAP":
+		return tr.WriteTAP(w)
+	case "JSON-FLAT":
+		return tr.WriteJSONFlat(w, false)
+	case "JSON-LINES":
+		return tr.WriteJSONFlat(w, true)
+	case "HTML":
+		return tr.WriteHTML(w)
+	}
+
+	if tr.Report == nil {
+		return fmt.Errorf("test run %s has no report", tr.Name)
+	}
+
+	var (
+		bs  []byte
+		err error
+	)
+
+	switch format {
+	case "JSON":
+		bs, err = json.MarshalIndent(tr.Report, "", "  ")
+	case "XML":
+		bs, err = xml.MarshalIndent(tr.Report, "", "  ")
+	default:
+		return fmt.Errorf("unknown result format %s", format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal results: %w", err)
+	}
+
+	if _, err = fmt.Fprintf(w, "%s\n", bs); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+
+	return nil
+}
+
+// WriteFile writes the Report in the given format to the named file.
+//
+// Missing parent directories are created.
+func (tr *TestRun) WriteFile(filename string, format string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("failed to make directory for results: %w", err)
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create results file: %w", err)
+	}
+
+	if err = tr.Write(f, format); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
 	}
 
 	return nil
@@ -206,6 +1062,32 @@ func (idl *IncludeDirList) Set(value string) error {
 	return nil
 }
 
+// PropertyMap are the properties to add to each TestSuite.
+//
+// We make an explicit type to enable flag.Var to parse multiple
+// parameters.
+type PropertyMap map[string]string
+
+// String representation
+func (pm *PropertyMap) String() string {
+	return "name=value"
+}
+
+// Set the name=value property
+func (pm *PropertyMap) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("bad property: '%s'", value)
+	}
+
+	if *pm == nil {
+		*pm = make(PropertyMap)
+	}
+	(*pm)[parts[0]] = parts[1]
+
+	return nil
+}
+
 // TestRunParams used to exec a TestRun
 type TestRunParams struct {
 	Bindings        plaxDsl.Bindings
@@ -221,5 +1103,242 @@ type TestRunParams struct {
 	LogLevel        *string
 	Labels          *string
 	Priority        *int
+	DefaultPriority *int
 	Redact          *bool
+	MaxConcurrency  *int
+	GroupTimeout    *time.Duration
+	DefaultRetries  *int
+	EmitTAP         *bool
+	OutputFile      *string
+	DryRun          *bool
+	ValidateOnly    *bool
+
+	// EmitJSONFlat, when true, writes the results as a JSON array
+	// of FlatTestCases, and EmitJSONLines writes them as
+	// newline-delimited JSON.
+	EmitJSONFlat  *bool
+	EmitJSONLines *bool
+
+	// EmitHTML, when true, writes the results as a self-contained
+	// HTML page (see WriteHTML).
+	EmitHTML *bool
+
+	// List, when true, makes Exec write the Catalog (as JSON with
+	// EmitJSON) rather than executing anything.
+	List *bool
+
+	// LogFormat is "text" (the default) or "json".
+	LogFormat *string
+
+	// BindingsFile is a YAML (or JSON) map of bindings, which
+	// don't replace the Bindings.
+	BindingsFile *string
+
+	// EnvPrefix, when not empty, binds the environment variables
+	// with this prefix, which don't replace the Bindings.
+	EnvPrefix *string
+
+	// Profile, when not empty, is the name of the TestRun profile
+	// whose bindings don't replace the Bindings.
+	Profile *string
+
+	// CaptureLogs adds the (redacted) logs of each test to its
+	// test case.
+	CaptureLogs *bool
+
+	// CaptureBindings adds the final (redacted) bindings of each
+	// test to its test case as properties.
+	CaptureBindings *bool
+
+	// MsgHistory, when positive, is the number of messages
+	// received on each channel that are remembered, so that the
+	// failure of a recv that times out has the (redacted) messages
+	// that did arrive.
+	MsgHistory *int
+
+	// ConnectAttempts, when greater than one, is the default
+	// number of attempts to open each channel, with
+	// ConnectDelay before the first retry (doubling for every
+	// subsequent retry up to ConnectMaxDelay).  See
+	// plaxDsl.Backoff.
+	ConnectAttempts *int
+	ConnectDelay    *time.Duration
+	ConnectMaxDelay *time.Duration
+
+	// Emit, unless false, writes the test results to standard
+	// output.
+	Emit *bool
+
+	// Properties are added to each TestSuite of the Report.
+	Properties PropertyMap
+
+	// ExpandEnv replaces each ${NAME} in the test run file (after
+	// includes) with the binding or environment variable NAME.
+	// With ExpandEnvStrict, an undefined NAME is an error rather
+	// than empty.
+	ExpandEnv       *bool
+	ExpandEnvStrict *bool
+
+	// IncludeTimeout and IncludeHeader configure the fetching of
+	// remote (http(s)) includes.
+	IncludeTimeout *time.Duration
+	IncludeHeader  *string
+
+	// PlaxVersion, when not empty, is added to each TestSuite of
+	// the Report as the plax.version property.
+	PlaxVersion *string
+
+	// Shuffle randomizes the order of the tests with the Seed
+	// (unless it's zero).
+	Shuffle *bool
+	Seed    *int64
+
+	// ShardTotal, when positive, splits the tests into that many
+	// shards by the hash of their names, and only the tests in
+	// the ShardIndex shard (from 0) are executed.
+	ShardIndex *int
+	ShardTotal *int
+
+	// ProgressOut, when not nil, gets a line of JSON (a
+	// ProgressEvent) as each test starts and finishes.
+	ProgressOut io.Writer
+
+	// Stdin, when not nil, is the source of the test run
+	// specification when the Filename is StdinFilename.
+	//
+	// Defaults to os.Stdin.
+	Stdin io.Reader
+
+	// LeakCheck, when true, warns (with a goroutine dump) when
+	// more than LeakThreshold (or DefaultLeakThreshold)
+	// goroutines are still running after the tests.
+	LeakCheck     *bool
+	LeakThreshold *int
+
+	// RedactValues are secret values that are replaced with
+	// Redacted in the results (names, messages, properties, and
+	// captured logs).
+	RedactValues RedactValueList
+
+	// RedactPatterns are regular expressions whose matches (or
+	// captured groups, as with redactions) are masked (see
+	// plaxDsl.Mask) in the logs and the results.  A pattern that
+	// doesn't compile makes NewTestRun fail.
+	RedactPatterns RedactPatternList
+
+	// Preflight, when true, makes Exec check that the channels of
+	// the tests are reachable (each within PreflightTimeout or
+	// the dsl.DefaultPreflightTimeout) rather than executing the
+	// tests.
+	Preflight        *bool
+	PreflightTimeout *time.Duration
+
+	// RunTimeout, when positive, is the maximum duration of the
+	// execution of all of the tests.  At the deadline, the
+	// executing tests are canceled, the remaining tests are
+	// skipped, and Exec returns an ErrRunTimeout.
+	RunTimeout *time.Duration
+
+	// FailFast, when true, skips the remaining tests after the
+	// first test that fails or errors.
+	FailFast *bool
+
+	// MaxFailures, when positive, skips the remaining tests once
+	// that many test cases failed or errored.  FailFast takes
+	// precedence.
+	MaxFailures *int
+
+	// TimingsFile, when not empty, is the file for a CSV of the
+	// duration of each test.
+	TimingsFile *string
+
+	// MetricsFile, when not empty, is the file for the results as
+	// Prometheus metrics (see WriteMetrics).
+	MetricsFile *string
+
+	// AllureDir, when not empty, is the directory for the results
+	// as Allure result files (see WriteAllure).
+	AllureDir *string
+
+	// ExitCodePolicy, when not nil, replaces the
+	// DefaultExitCodePolicy for ExitCode.
+	ExitCodePolicy *ExitCodePolicy
+
+	// Repeat, when greater than one, is the number of times that
+	// Exec executes the selected tests (see TestRun.Iterations).
+	Repeat *int
+
+	// Flaky, when positive (and at least 2), is the number of
+	// times that Exec executes the selected tests in order to
+	// classify them (see TestRun.Flakiness) as stable-pass (with
+	// a pass ratio of at least FlakyPassRatio), stable-fail (at
+	// most FlakyFailRatio), or flaky.  FlakyFile, when not empty,
+	// is the file for the JSON FlakyReport.
+	Flaky          *int
+	FlakyPassRatio *float64
+	FlakyFailRatio *float64
+	FlakyFile      *string
+
+	// RerunFailed, when not empty, is a file of plaxrun JUnit XML
+	// results, and only the tests that failed or had errors in
+	// those results are executed.
+	RerunFailed *string
+
+	// ReuseConnections, when true, makes the tests of each
+	// selected group share their connections (see
+	// plaxDsl.ChanPool), which are opened by the first test that
+	// needs them and closed after the group's last test.
+	ReuseConnections *bool
+
+	// OTelEndpoint, when not empty, is the OTLP/HTTP endpoint
+	// (e.g., "http://localhost:4318") for the spans of the test
+	// run, its groups, and its tests.  Without it, the spans are
+	// exported if OTEL_EXPORTER_OTLP_ENDPOINT (or
+	// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT) is set.
+	OTelEndpoint *string
+
+	// SlowThreshold, when positive, is the duration after which a
+	// test is slow: its TestCase gets a "slow" property, a warning
+	// is logged, and the summary lists the Slowest tests (which
+	// defaults to DefaultSlowest).
+	SlowThreshold *time.Duration
+	Slowest       *int
+
+	// GitHub, when true, writes the GitHub Actions annotations of
+	// the failed, errored, and skipped tests (see WriteGitHub) to
+	// standard error.
+	GitHub *bool
+
+	// JUnitClassname and JUnitName, when not empty, are templates
+	// for the classname and name of each TestCase (see
+	// JUnitPlaceholders).
+	JUnitClassname *string
+	JUnitName      *string
+
+	// RunSkipped, when true, executes the tests with a TestDef.Skip
+	// instead of reporting them as skipped.
+	RunSkipped *bool
+
+	// ChangedSince, when not empty, is a git ref, and only the tests
+	// whose files (or includes) changed since the merge base of
+	// that ref and HEAD are executed.
+	ChangedSince *string
+}
+
+// connectBackoff returns the default plaxDsl.Backoff for opening
+// channels, which is nil unless ConnectAttempts is greater than one.
+func (trps *TestRunParams) connectBackoff() *plaxDsl.Backoff {
+	if trps.ConnectAttempts == nil || *trps.ConnectAttempts <= 1 {
+		return nil
+	}
+	b := &plaxDsl.Backoff{
+		Attempts: *trps.ConnectAttempts,
+	}
+	if trps.ConnectDelay != nil {
+		b.Delay = *trps.ConnectDelay
+	}
+	if trps.ConnectMaxDelay != nil {
+		b.MaxDelay = *trps.ConnectMaxDelay
+	}
+	return b
 }
@@ -0,0 +1,378 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	plaxDsl "github.com/Comcast/plax/dsl"
+)
+
+// RunOptions are the options for RunTests.
+//
+// RunOptions mirrors TestRunParams without the pointers.  Start with
+// DefaultRunOptions to get the same defaults as the plaxrun command.
+type RunOptions struct {
+	// Filename is the test run specification.
+	Filename string
+
+	// Dir is the directory containing the test files.
+	Dir string
+
+	// ReportPluginDir is the directory containing the report
+	// plugins.
+	ReportPluginDir string
+
+	// IncludeDirs are the YAML include directories.
+	IncludeDirs []string
+
+	// Bindings are the parameter bindings.
+	Bindings plaxDsl.Bindings
+
+	// BindingsFile is a YAML (or JSON) map of bindings, which
+	// don't replace the Bindings.
+	BindingsFile string
+
+	// EnvPrefix, when not empty, binds the environment variables
+	// with this prefix, which don't replace the Bindings.
+	EnvPrefix string
+
+	// Profile is the name of the test run profile (if any) whose
+	// bindings don't replace the Bindings.
+	Profile string
+
+	// Groups, Tests, and SuiteName are the test groups, tests,
+	// and test suite to execute.
+	Groups    []string
+	Tests     []string
+	SuiteName string
+
+	Verbose         bool
+	LogLevel        string
+	LogFormat       string
+	Redact          bool
+	Labels          string
+	Priority        int
+	DefaultPriority int
+	MaxConcurrency  int
+	GroupTimeout    time.Duration
+	DefaultRetries  int
+	DryRun          bool
+	ValidateOnly    bool
+	List            bool
+
+	// Shuffle randomizes the order of the tests with the Seed
+	// (unless it's zero).  See TestRun.ShuffleSeed.
+	Shuffle bool
+	Seed    int64
+
+	// ShardTotal, when positive, splits the tests into that many
+	// shards, and only the tests in the ShardIndex shard are
+	// executed.
+	ShardIndex int
+	ShardTotal int
+
+	// ProgressOut, when not nil, gets a line of JSON (a
+	// ProgressEvent) as each test starts and finishes.
+	ProgressOut io.Writer
+
+	// Stdin, when not nil, replaces os.Stdin as the source of the
+	// test run specification when the Filename is StdinFilename.
+	Stdin io.Reader
+
+	// LeakCheck warns (with a goroutine dump) when more than
+	// LeakThreshold (or DefaultLeakThreshold) goroutines are
+	// still running after the tests.
+	LeakCheck     bool
+	LeakThreshold int
+
+	// RunTimeout, when positive, is the maximum duration of the
+	// execution of all of the tests.
+	RunTimeout time.Duration
+
+	// FailFast skips the remaining tests after the first test
+	// that fails or errors.
+	FailFast bool
+
+	// MaxFailures, when positive, skips the remaining tests once
+	// that many test cases failed or errored.
+	MaxFailures int
+
+	// RedactValues are secret values that are replaced with
+	// Redacted in the results.
+	RedactValues []string
+
+	// RedactPatterns are regular expressions whose matches are
+	// masked in the logs and the results.
+	RedactPatterns []string
+
+	// CaptureLogs adds the (redacted) logs of each test to its
+	// test case, and CaptureBindings adds the final (redacted)
+	// bindings of each test as properties.
+	CaptureLogs     bool
+	CaptureBindings bool
+
+	// MsgHistory, when positive, is the number of messages
+	// received on each channel that are reported when a recv
+	// times out.
+	MsgHistory int
+
+	// ConnectAttempts, ConnectDelay, and ConnectMaxDelay specify
+	// the default backoff for opening channels.
+	ConnectAttempts int
+	ConnectDelay    time.Duration
+	ConnectMaxDelay time.Duration
+
+	// Preflight makes Exec check that the channels of the tests
+	// are reachable rather than executing the tests.
+	Preflight        bool
+	PreflightTimeout time.Duration
+
+	// Emit, when true, writes the test results to standard
+	// output (as JUnit XML unless EmitJSON, EmitTAP,
+	// EmitJSONFlat, EmitJSONLines, or EmitHTML).
+	Emit          bool
+	EmitJSON      bool
+	EmitTAP       bool
+	EmitJSONFlat  bool
+	EmitJSONLines bool
+	EmitHTML      bool
+
+	// OutputFile, when not empty, is the file for the test
+	// results, which are then not written to standard output.
+	OutputFile string
+
+	// Properties are added to each TestSuite of the Report.
+	Properties map[string]string
+
+	// PlaxVersion, when not empty, is added to each TestSuite of
+	// the Report as the plax.version property.
+	PlaxVersion string
+
+	// ExpandEnv replaces each ${NAME} in the test run file with
+	// the binding or environment variable NAME, which must be
+	// defined with ExpandEnvStrict.
+	ExpandEnv       bool
+	ExpandEnvStrict bool
+
+	// IncludeTimeout and IncludeHeader configure the fetching of
+	// remote (http(s)) includes.
+	IncludeTimeout time.Duration
+	IncludeHeader  string
+
+	// TimingsFile, when not empty, is the file for a CSV of the
+	// duration of each test.
+	TimingsFile string
+
+	// MetricsFile, when not empty, is the file for the results as
+	// Prometheus metrics.
+	MetricsFile string
+
+	// AllureDir, when not empty, is the directory for the results
+	// as Allure result files.
+	AllureDir string
+
+	// ExitCodePolicy determines TestRun.ExitCode.
+	ExitCodePolicy ExitCodePolicy
+
+	// Repeat, when greater than one, is the number of times to
+	// execute the tests.
+	Repeat int
+
+	// Flaky, when positive, is the number of times to execute the
+	// tests to classify them (see TestRun.Flakiness) with the
+	// FlakyPassRatio and FlakyFailRatio (which default to
+	// DefaultFlakyPassRatio and DefaultFlakyFailRatio).
+	// FlakyFile, when not empty, is the file for the JSON
+	// FlakyReport.
+	Flaky          int
+	FlakyPassRatio float64
+	FlakyFailRatio float64
+	FlakyFile      string
+
+	// RerunFailed, when not empty, is a file of JUnit XML results
+	// whose failed tests are the only ones executed.
+	RerunFailed string
+
+	// ReuseConnections makes the tests of each group share their
+	// connections.
+	ReuseConnections bool
+
+	// OTelEndpoint, when not empty, is the OTLP/HTTP endpoint for
+	// the spans of the test run.
+	OTelEndpoint string
+
+	// SlowThreshold, when positive, is the duration after which a
+	// test is slow, and the summary then lists the Slowest tests.
+	SlowThreshold time.Duration
+	Slowest       int
+
+	// GitHub writes GitHub Actions annotations for the failed,
+	// errored, and skipped tests to standard error.
+	GitHub bool
+
+	// JUnitClassname and JUnitName are templates for the classname
+	// and name of each TestCase (see JUnitPlaceholders).
+	JUnitClassname string
+	JUnitName      string
+
+	// RunSkipped executes the tests with a TestDef.Skip.
+	RunSkipped bool
+
+	// ChangedSince, when not empty, is a git ref, and only the tests
+	// that changed since then are executed.
+	ChangedSince string
+}
+
+// DefaultRunOptions returns the RunOptions with the defaults of the
+// plaxrun command except for Emit, which is false.
+func DefaultRunOptions() RunOptions {
+	return RunOptions{
+		Filename:        "spec.yaml",
+		Dir:             ".",
+		ReportPluginDir: "plugins/report",
+		Bindings:        make(plaxDsl.Bindings),
+		Verbose:         true,
+		LogLevel:        "info",
+		Priority:        -1,
+		MaxConcurrency:  1,
+		ExitCodePolicy:  DefaultExitCodePolicy(),
+		FlakyPassRatio:  DefaultFlakyPassRatio,
+		FlakyFailRatio:  DefaultFlakyFailRatio,
+		Slowest:         DefaultSlowest,
+	}
+}
+
+// params makes the TestRunParams for the RunOptions.
+func (opts RunOptions) params() *TestRunParams {
+	if opts.Dir == "" {
+		opts.Dir = "."
+	}
+	if opts.LogLevel == "" {
+		opts.LogLevel = "info"
+	}
+	if opts.ReportPluginDir == "" {
+		opts.ReportPluginDir = "plugins/report"
+	}
+	if opts.Bindings == nil {
+		opts.Bindings = make(plaxDsl.Bindings)
+	}
+
+	return &TestRunParams{
+		Bindings:         opts.Bindings,
+		BindingsFile:     &opts.BindingsFile,
+		EnvPrefix:        &opts.EnvPrefix,
+		Profile:          &opts.Profile,
+		Groups:           TestGroupList(opts.Groups),
+		Tests:            TestList(opts.Tests),
+		SuiteName:        &opts.SuiteName,
+		IncludeDirs:      IncludeDirList(opts.IncludeDirs),
+		Filename:         &opts.Filename,
+		Dir:              &opts.Dir,
+		ReportPluginDir:  &opts.ReportPluginDir,
+		EmitJSON:         &opts.EmitJSON,
+		Verbose:          &opts.Verbose,
+		LogLevel:         &opts.LogLevel,
+		LogFormat:        &opts.LogFormat,
+		Labels:           &opts.Labels,
+		Priority:         &opts.Priority,
+		DefaultPriority:  &opts.DefaultPriority,
+		Redact:           &opts.Redact,
+		MaxConcurrency:   &opts.MaxConcurrency,
+		GroupTimeout:     &opts.GroupTimeout,
+		DefaultRetries:   &opts.DefaultRetries,
+		EmitTAP:          &opts.EmitTAP,
+		EmitJSONFlat:     &opts.EmitJSONFlat,
+		EmitJSONLines:    &opts.EmitJSONLines,
+		EmitHTML:         &opts.EmitHTML,
+		OutputFile:       &opts.OutputFile,
+		DryRun:           &opts.DryRun,
+		ValidateOnly:     &opts.ValidateOnly,
+		List:             &opts.List,
+		Shuffle:          &opts.Shuffle,
+		Seed:             &opts.Seed,
+		ShardIndex:       &opts.ShardIndex,
+		ShardTotal:       &opts.ShardTotal,
+		ProgressOut:      opts.ProgressOut,
+		Stdin:            opts.Stdin,
+		LeakCheck:        &opts.LeakCheck,
+		LeakThreshold:    &opts.LeakThreshold,
+		RunTimeout:       &opts.RunTimeout,
+		FailFast:         &opts.FailFast,
+		MaxFailures:      &opts.MaxFailures,
+		RedactValues:     opts.RedactValues,
+		RedactPatterns:   opts.RedactPatterns,
+		CaptureLogs:      &opts.CaptureLogs,
+		CaptureBindings:  &opts.CaptureBindings,
+		MsgHistory:       &opts.MsgHistory,
+		ConnectAttempts:  &opts.ConnectAttempts,
+		ConnectDelay:     &opts.ConnectDelay,
+		ConnectMaxDelay:  &opts.ConnectMaxDelay,
+		Preflight:        &opts.Preflight,
+		PreflightTimeout: &opts.PreflightTimeout,
+		Emit:             &opts.Emit,
+		Properties:       PropertyMap(opts.Properties),
+		PlaxVersion:      &opts.PlaxVersion,
+		ExpandEnv:        &opts.ExpandEnv,
+		ExpandEnvStrict:  &opts.ExpandEnvStrict,
+		IncludeTimeout:   &opts.IncludeTimeout,
+		IncludeHeader:    &opts.IncludeHeader,
+		TimingsFile:      &opts.TimingsFile,
+		MetricsFile:      &opts.MetricsFile,
+		AllureDir:        &opts.AllureDir,
+		ExitCodePolicy:   &opts.ExitCodePolicy,
+		Repeat:           &opts.Repeat,
+		Flaky:            &opts.Flaky,
+		FlakyPassRatio:   &opts.FlakyPassRatio,
+		FlakyFailRatio:   &opts.FlakyFailRatio,
+		FlakyFile:        &opts.FlakyFile,
+		RerunFailed:      &opts.RerunFailed,
+		ReuseConnections: &opts.ReuseConnections,
+		OTelEndpoint:     &opts.OTelEndpoint,
+		SlowThreshold:    &opts.SlowThreshold,
+		Slowest:          &opts.Slowest,
+		GitHub:           &opts.GitHub,
+		JUnitClassname:   &opts.JUnitClassname,
+		JUnitName:        &opts.JUnitName,
+		RunSkipped:       &opts.RunSkipped,
+		ChangedSince:     &opts.ChangedSince,
+	}
+}
+
+// RunTests makes and executes a TestRun.
+//
+// The returned TestRun has the TestReport of the execution, and it's
+// returned even when the execution reports an error.
+func RunTests(ctx context.Context, opts RunOptions) (*TestRun, error) {
+	if opts.Filename == "" {
+		return nil, fmt.Errorf("RunOptions.Filename is empty")
+	}
+
+	c := NewCtx(ctx)
+
+	tr, err := NewTestRun(c, opts.params())
+	if err != nil {
+		return nil, err
+	}
+
+	return tr, tr.Exec(c)
+}
@@ -0,0 +1,467 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Comcast/plax/junit"
+)
+
+func TestRunTests(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec := `name: run
+version: 0.0.1
+tests:
+  pass:
+    path: pass.yaml
+    version: fake
+groups:
+  passes:
+    tests:
+      - name: pass
+      - name: pass
+`
+	filename := writeRunSpec(t, spec)
+
+	opts := runOptions(filename)
+	opts.Groups = []string{"passes"}
+	opts.Properties = map[string]string{"git.sha": "abc"}
+
+	tr, err := RunTests(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cwd, _ := os.Getwd(); cwd != wd {
+		t.Fatalf("working directory changed to %s", cwd)
+	}
+
+	if tr.Report == nil || tr.Report.Total != 2 || tr.Report.Passed != 2 {
+		t.Fatalf("unexpected report %#v", tr.Report)
+	}
+
+	props := tr.Report.TestSuite[0].Properties
+	if len(props) != 2 || props[0].Name != "plax.started" || props[1] != (junit.Property{Name: "git.sha", Value: "abc"}) {
+		t.Fatalf("unexpected properties %#v", props)
+	}
+
+	if _, err := RunTests(context.Background(), RunOptions{}); err == nil {
+		t.Fatal("expected an error without a filename")
+	}
+}
+
+func TestRunTestsFlagOptions(t *testing.T) {
+	spec := `name: run
+version: 0.0.1
+tests:
+  pass: {path: pass.yaml, version: fake}
+groups:
+  passes:
+    tests:
+      - name: pass
+`
+	filename := writeRunSpec(t, spec)
+
+	bindings := filepath.Join(filepath.Dir(filename), "bindings.yaml")
+	if err := ioutil.WriteFile(bindings, []byte("HOST: localhost\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("PLAXTEST_PORT", "1883")
+	defer os.Unsetenv("PLAXTEST_PORT")
+
+	opts := runOptions(filename)
+	opts.Groups = []string{"passes"}
+	opts.LogFormat = "json"
+	opts.CaptureLogs = true
+	opts.CaptureBindings = true
+	opts.BindingsFile = bindings
+	opts.EnvPrefix = "PLAXTEST_"
+	opts.PlaxVersion = "1.2.3"
+
+	tr, err := RunTests(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trps := tr.trps
+	if *trps.LogFormat != "json" || !*trps.CaptureLogs || !*trps.CaptureBindings {
+		t.Fatalf("unexpected params %#v", trps)
+	}
+
+	if trps.Bindings["HOST"] != "localhost" || trps.Bindings["port"] != float64(1883) {
+		t.Fatalf("unexpected bindings %#v", trps.Bindings)
+	}
+
+	var version string
+	for _, p := range tr.Report.TestSuite[0].Properties {
+		if p.Name == "plax.version" {
+			version = p.Value
+		}
+	}
+	if version != "1.2.3" {
+		t.Fatalf("unexpected plax.version %q", version)
+	}
+
+	opts.LogFormat = "xml"
+	if _, err := RunTests(context.Background(), opts); err == nil {
+		t.Fatal("expected an error for an unknown log format")
+	}
+}
+
+func TestRunTestsStdin(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := `pass:
+  path: pass.yaml
+  version: fake
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "tests.yaml"), []byte(tests), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "pass.yaml"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(spec string) (*TestRun, error) {
+		opts := DefaultRunOptions()
+		opts.Filename = StdinFilename
+		opts.Stdin = strings.NewReader(spec)
+		opts.Dir = dir
+		opts.LogLevel = "none"
+		opts.Groups = []string{"passes"}
+		return RunTests(context.Background(), opts)
+	}
+
+	tr, err := run(`name: run
+version: 0.0.1
+tests: "#include<tests.yaml>"
+groups:
+  passes:
+    tests:
+      - name: pass
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Report == nil || tr.Report.Total != 1 || tr.Report.Passed != 1 {
+		t.Fatalf("unexpected report %#v", tr.Report)
+	}
+
+	_, err = run(`name: run
+version: 0.0.1
+tests: "#include<nope.yaml>"
+`)
+	if err == nil || !strings.Contains(err.Error(), "from standard input, so relative to -dir "+dir) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestRunTestsRequiredParams(t *testing.T) {
+	spec := `name: run
+version: 0.0.1
+tests:
+  pass:
+    path: pass.yaml
+    version: fake
+    params:
+      - HOST
+      - PORT
+      - USER
+groups:
+  passes:
+    tests:
+      - name: pass
+params:
+  HOST:
+    required: true
+  PORT:
+    required: true
+  USER:
+    dependsOn:
+      - HOST
+    cmd: echo
+`
+	filename := writeRunSpec(t, spec)
+
+	opts := runOptions(filename)
+	opts.Groups = []string{"passes"}
+	opts.DryRun = true
+
+	_, err := RunTests(context.Background(), opts)
+	if err == nil || !strings.Contains(err.Error(), "required params are not bound: HOST, PORT ") {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	opts.Bindings["HOST"] = "localhost"
+	opts.Bindings["PORT"] = 1883
+	opts.Bindings["USER"] = "queso"
+
+	if _, err := RunTests(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunTestsShuffle(t *testing.T) {
+	spec := `name: run
+version: 0.0.1
+tests:
+  t1: {path: pass.yaml, version: fake}
+  t2: {path: pass.yaml, version: fake}
+  t3: {path: pass.yaml, version: fake}
+  t4: {path: pass.yaml, version: fake}
+  t5: {path: pass.yaml, version: fake}
+  t6: {path: pass.yaml, version: fake}
+groups:
+  many:
+    tests:
+      - name: t4
+      - name: t1
+      - name: t6
+      - name: t2
+      - name: t5
+      - name: t3
+`
+	filename := writeRunSpec(t, spec)
+
+	order := func(shuffle bool, seed int64) (string, int64) {
+		opts := runOptions(filename)
+		opts.Groups = []string{"many"}
+		opts.DryRun = true
+		opts.Shuffle = shuffle
+		opts.Seed = seed
+
+		tr, err := RunTests(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names := make([]string, 0, len(tr.tfs))
+		for _, tf := range tr.tfs {
+			names = append(names, tf.Name[strings.LastIndex(tf.Name, ":")+1:])
+		}
+		return strings.Join(names, ","), tr.ShuffleSeed()
+	}
+
+	// Without -shuffle, the tests are sorted by name.
+	if got, seed := order(false, 0); got != "t1,t2,t3,t4,t5,t6" || seed != 0 {
+		t.Fatalf("got %s (seed %d) without -shuffle", got, seed)
+	}
+
+	first, seed := order(true, 0)
+	if seed == 0 {
+		t.Fatal("no seed")
+	}
+
+	if again, _ := order(true, seed); again != first {
+		t.Fatalf("got %s, want %s with seed %d", again, first, seed)
+	}
+}
+
+func TestRunTestsRepeat(t *testing.T) {
+	spec := `name: run
+version: 0.0.1
+tests:
+  t1: {path: pass.yaml, version: fake}
+  t2: {path: pass.yaml, version: fake}
+groups:
+  both:
+    tests:
+      - name: t1
+      - name: t2
+`
+	filename := writeRunSpec(t, spec)
+
+	opts := runOptions(filename)
+	opts.Groups = []string{"both"}
+	opts.Repeat = 3
+
+	tr, err := RunTests(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tr.Report.Total != 6 || tr.Report.Passed != 6 || len(tr.Report.TestSuite) != 6 {
+		t.Fatalf("unexpected report %#v", tr.Report)
+	}
+
+	if len(tr.Iterations) != 3 || tr.Iterations[2] != (Iteration{Number: 3, Total: 2, Passed: 2}) {
+		t.Fatalf("unexpected iterations %#v", tr.Iterations)
+	}
+
+	if got := tr.Report.TestSuite[5].Properties[0]; got != (junit.Property{Name: "plax.iteration", Value: "3"}) {
+		t.Fatalf("unexpected property %#v", got)
+	}
+
+	var buf bytes.Buffer
+	if err := tr.WriteSummary(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) != 6 || lines[0] != "Iteration=1 Total=2 Passed=2 Failed=0 Errors=0 Skipped=0 PassRate=100.0%" || lines[3] != "Iterations=3 PassRate=100.0%" {
+		t.Fatalf("unexpected summary %q", buf.String())
+	}
+
+	opts.Repeat = -1
+	if _, err := RunTests(context.Background(), opts); err == nil {
+		t.Fatal("expected an error for a negative repeat")
+	}
+}
+
+func TestRunTestsShards(t *testing.T) {
+	spec := `name: run
+version: 0.0.1
+tests:
+  t1: {path: pass.yaml, version: fake}
+  t2: {path: pass.yaml, version: fake}
+  t3: {path: pass.yaml, version: fake}
+  t4: {path: pass.yaml, version: fake}
+  t5: {path: pass.yaml, version: fake}
+  t6: {path: pass.yaml, version: fake}
+groups:
+  many:
+    tests:
+      - name: t1
+      - name: t2
+      - name: t3
+      - name: t4
+      - name: t5
+      - name: t6
+  one:
+    tests:
+      - name: t1
+`
+	filename := writeRunSpec(t, spec)
+
+	run := func(index, total int, groups ...string) *TestRun {
+		opts := runOptions(filename)
+		opts.Groups = groups
+		opts.ShardIndex = index
+		opts.ShardTotal = total
+
+		tr, err := RunTests(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tr
+	}
+
+	const total = 3
+	var (
+		seen  = make(map[string]int)
+		empty = 0
+	)
+
+	for index := 0; index < total; index++ {
+		tr := run(index, total, "many", "one")
+		for _, tf := range tr.tfs {
+			seen[tf.Name]++
+		}
+		for _, ts := range tr.Report.TestSuite {
+			want := junit.Property{Name: "plax.shard", Value: fmt.Sprintf("%d/%d", index, total)}
+			if ts.Properties[len(ts.Properties)-1] != want {
+				t.Fatalf("unexpected properties %#v", ts.Properties)
+			}
+		}
+
+		// The "one" group is empty in all but one shard.
+		if len(run(index, total, "one").tfs) == 0 {
+			empty++
+		}
+	}
+
+	if len(seen) != 7 {
+		t.Fatalf("shards executed %v", seen)
+	}
+	for name, n := range seen {
+		if n != 1 {
+			t.Fatalf("%s executed by %d shards", name, n)
+		}
+	}
+	if empty != total-1 {
+		t.Fatalf("%d empty shards", empty)
+	}
+
+	opts := runOptions(filename)
+	opts.ShardIndex = total
+	opts.ShardTotal = total
+	if _, err := RunTests(context.Background(), opts); err == nil {
+		t.Fatal("expected an error for a bad shard index")
+	}
+}
+
+func TestRunTestsProgress(t *testing.T) {
+	spec := `name: run
+version: 0.0.1
+tests:
+  t1: {path: pass.yaml, version: fake}
+  t2: {path: pass.yaml, version: fake}
+groups:
+  passes:
+    tests:
+      - name: t1
+      - name: t2
+  never:
+    when: "false"
+    tests:
+      - name: t1
+`
+	filename := writeRunSpec(t, spec)
+
+	var out bytes.Buffer
+
+	opts := runOptions(filename)
+	opts.Groups = []string{"passes", "never"}
+	opts.Tests = []string{"t1"}
+	opts.MaxConcurrency = 2
+	opts.ProgressOut = &out
+
+	if _, err := RunTests(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(map[string]string)
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var ev ProgressEvent
+		if err := dec.Decode(&ev); err != nil {
+			t.Fatal(err)
+		}
+		events[ev.Name] += ev.Event + " "
+	}
+
+	// Skipped groups and -t tests have their own events too.
+	for _, name := range []string{"run-0.0.1:passes:t1", "run-0.0.1:passes:t2", "run-0.0.1:never", "run-0.0.1:t1"} {
+		if got := events[name]; got != "start pass " {
+			t.Fatalf("%s had events %q in %v", name, got, events)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Comcast/plax/cmd/plaxrun/async"
+	"github.com/Comcast/plax/cmd/plaxrun/plugins/report"
+	plaxDsl "github.com/Comcast/plax/dsl"
+	"github.com/Comcast/plax/junit"
+)
+
+func TestWriteFile(t *testing.T) {
+	ts := junit.NewTestSuite("suite")
+	tc := junit.NewTestCase("passes", "passes.yaml")
+	tc.Finish(junit.Passed)
+	ts.Add(*tc)
+
+	tr := &TestRun{
+		Name:   "file",
+		Report: report.NewTestReport(),
+	}
+	tr.Report.TestSuite = append(tr.Report.TestSuite, ts)
+	tr.Report.Total = ts.Total
+
+	filename := filepath.Join(t.TempDir(), "results", "file.xml")
+	if err := tr.WriteFile(filename, "XML"); err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got report.TestReport
+	if err := xml.Unmarshal(bs, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Total != 1 || len(got.TestSuite) != 1 {
+		t.Fatalf("unexpected results %s", bs)
+	}
+
+	if err := tr.WriteFile(filename, "CSV"); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+func TestWritePlan(t *testing.T) {
+	tr := &TestRun{
+		infos: make(map[*async.TaskFunc]*taskInfo),
+	}
+
+	tf := &async.TaskFunc{
+		Name: "waitrun:wait-prompt:wait",
+	}
+	tr.tfs = append(tr.tfs, tf)
+	tr.infos[tf] = &taskInfo{
+		bs: &plaxDsl.Bindings{
+			"WAIT":   600,
+			"MARGIN": "200",
+		},
+	}
+
+	var sb strings.Builder
+	if err := tr.WritePlan(NewCtx(context.Background()), &sb); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "waitrun:wait-prompt:wait\n  MARGIN=\"200\"\n  WAIT=600\n"
+	if got := sb.String(); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	tr := &TestRun{
+		Report: &report.TestReport{
+			Total:    4,
+			Passed:   1,
+			Failures: 1,
+			Errors:   1,
+			Skipped:  1,
+			Time:     1500 * time.Millisecond,
+		},
+	}
+
+	var sb strings.Builder
+	if err := tr.WriteSummary(&sb); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "Total=4 Passed=1 Failed=1 Errors=1 Skipped=1 Duration=1.5s\n"
+	if got := sb.String(); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestTimings(t *testing.T) {
+	elapsed := 1500 * time.Millisecond
+
+	single := junit.NewTestSuite("run:group:single")
+	single.Add(junit.TestCase{Name: "single", Status: junit.Passed, Time: &elapsed})
+
+	suite := junit.NewTestSuite("run:group:suite")
+	suite.Add(junit.TestCase{Name: "a", Status: junit.Failed, Time: &elapsed})
+	suite.Add(junit.TestCase{Name: "b,c", Status: junit.Skipped})
+
+	tr := &TestRun{
+		Report: report.NewTestReport(),
+	}
+	tr.Report.TestSuite = append(tr.Report.TestSuite, single, suite)
+
+	timings := tr.Timings()
+	if len(timings) != 3 || timings["run:group:single"] != elapsed || timings["run:group:suite/a"] != elapsed || timings["run:group:suite/b,c"] != 0 {
+		t.Fatalf("unexpected timings %v", timings)
+	}
+
+	var sb strings.Builder
+	if err := tr.WriteTimings(&sb); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "name,duration,status\n" +
+		"run:group:single,1.500000,passed\n" +
+		"run:group:suite/a,1.500000,failed\n" +
+		"\"run:group:suite/b,c\",0.000000,skipped\n"
+	if got := sb.String(); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+// stuckPlugin ignores its context and finishes when released.
+type stuckPlugin struct {
+	release chan struct{}
+}
+
+func (p *stuckPlugin) Invoke(ctx context.Context) (*junit.TestSuite, error) {
+	<-p.release
+	ts := junit.NewTestSuite("stuck")
+	ts.Finish()
+	return ts, nil
+}
+
+func TestExecCanceled(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	ThePluginRegistry.Register("stuck", func(def PluginDef) (Plugin, error) {
+		return &stuckPlugin{release: release}, nil
+	})
+
+	spec := `name: run
+version: 0.0.1
+tests:
+  first: {path: pass.yaml, version: fake}
+  hang: {path: pass.yaml, version: stuck}
+groups:
+  all:
+    tests:
+      - name: first
+      - name: hang
+`
+	opts := runOptions(writeRunSpec(t, spec))
+	opts.Groups = []string{"all"}
+	opts.MaxConcurrency = 2
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	tr, err := RunTests(ctx, opts)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v rather than %v", context.DeadlineExceeded, err)
+	}
+
+	// The report still has the test that finished.
+	r := tr.Report
+	if r == nil || len(r.TestSuite) != 1 || r.Passed != 1 {
+		t.Fatalf("unexpected report %#v", r)
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// isNamePattern reports whether the -t or -g selector is a pattern:
+// either "~REGEXP" or a shell glob.
+func isNamePattern(selector string) bool {
+	return strings.HasPrefix(selector, "~") || strings.ContainsAny(selector, "*?[")
+}
+
+// matchName reports whether the name matches the pattern selector.
+func matchName(selector, name string) (bool, error) {
+	if strings.HasPrefix(selector, "~") {
+		re, err := regexp.Compile(selector[1:])
+		if err != nil {
+			return false, fmt.Errorf("bad regular expression %q: %w", selector, err)
+		}
+		return re.MatchString(name), nil
+	}
+
+	matched, err := path.Match(selector, name)
+	if err != nil {
+		return false, fmt.Errorf("bad pattern %q: %w", selector, err)
+	}
+	return matched, nil
+}
+
+// selectNames replaces each pattern selector with the (sorted) names
+// that it matches.  Exact selectors are kept as they are (so they can
+// be reported if they are unknown), and a pattern doesn't select a
+// name that's already selected.
+//
+// Patterns that don't match anything are an error, which uses the
+// kind ("test" or "test group").
+func selectNames(selectors []string, names []string, kind string) ([]string, error) {
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+
+	var (
+		selected  = make([]string, 0, len(selectors))
+		have      = make(map[string]bool)
+		unmatched = make([]string, 0)
+	)
+
+	// Patterns don't select the names that are selected exactly.
+	for _, s := range selectors {
+		if !isNamePattern(s) {
+			have[s] = true
+		}
+	}
+
+	for _, s := range selectors {
+		if !isNamePattern(s) {
+			selected = append(selected, s)
+			continue
+		}
+		n := 0
+		for _, name := range sorted {
+			matched, err := matchName(s, name)
+			if err != nil {
+				return nil, err
+			}
+			if !matched {
+				continue
+			}
+			n++
+			if !have[name] {
+				selected = append(selected, name)
+				have[name] = true
+			}
+		}
+		if n == 0 {
+			unmatched = append(unmatched, s)
+		}
+	}
+
+	if 0 < len(unmatched) {
+		return nil, fmt.Errorf("no %s matches: %s", kind, strings.Join(unmatched, ", "))
+	}
+
+	return selected, nil
+}
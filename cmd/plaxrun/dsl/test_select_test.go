@@ -0,0 +1,61 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectNames(t *testing.T) {
+	names := []string{"mqtt/pub", "integration_b", "mqtt/sub", "integration_a", "mqtt/deep/pub", "basic"}
+
+	tests := []struct {
+		name      string
+		selectors []string
+		want      []string
+		err       string
+	}{
+		{"exact", []string{"basic", "nope"}, []string{"basic", "nope"}, ""},
+		{"glob", []string{"mqtt/*"}, []string{"mqtt/pub", "mqtt/sub"}, ""},
+		{"regexp", []string{"~^integration_"}, []string{"integration_a", "integration_b"}, ""},
+		{"union", []string{"mqtt/*", "mqtt/sub", "~pub$"}, []string{"mqtt/pub", "mqtt/sub", "mqtt/deep/pub"}, ""},
+		{"no match", []string{"basic", "kafka/*", "~^unit_"}, nil, "no test matches: kafka/*, ~^unit_"},
+		{"bad regexp", []string{"~("}, nil, "bad regular expression"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectNames(tt.selectors, names, "test")
+			if tt.err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
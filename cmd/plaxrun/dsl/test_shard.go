@@ -0,0 +1,96 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	plaxDsl "github.com/Comcast/plax/dsl"
+)
+
+// sharding reports whether -shard-total (and -shard-index) split the
+// tests into shards.
+func (tr TestRun) sharding() bool {
+	return tr.trps.ShardTotal != nil && 0 < *tr.trps.ShardTotal
+}
+
+// shardIndex returns the -shard-index.
+func (tr TestRun) shardIndex() int {
+	if tr.trps.ShardIndex == nil {
+		return 0
+	}
+	return *tr.trps.ShardIndex
+}
+
+// shardedCount returns the number of tests that aren't in the
+// -shard-index shard so far.
+func (tr TestRun) shardedCount() int {
+	if tr.sharded == nil {
+		return 0
+	}
+	return *tr.sharded
+}
+
+// checkShard validates the -shard-index and -shard-total.
+func checkShard(trps *TestRunParams) error {
+	if trps.ShardTotal == nil || *trps.ShardTotal == 0 {
+		if trps.ShardIndex != nil && *trps.ShardIndex != 0 {
+			return fmt.Errorf("shard index %d requires a shard total", *trps.ShardIndex)
+		}
+		return nil
+	}
+
+	index := 0
+	if trps.ShardIndex != nil {
+		index = *trps.ShardIndex
+	}
+
+	if *trps.ShardTotal < 0 || index < 0 || *trps.ShardTotal <= index {
+		return fmt.Errorf("shard index %d isn't in [0,%d)", index, *trps.ShardTotal)
+	}
+
+	return nil
+}
+
+// shardOf returns the shard of the test with the given name.
+func shardOf(name string, total int) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return int(h.Sum32() % uint32(total))
+}
+
+// inShard reports whether the named test is in the -shard-index
+// shard (counting the tests that aren't).
+func (tr TestRun) inShard(ctx *plaxDsl.Ctx, name string) bool {
+	if !tr.sharding() {
+		return true
+	}
+
+	if shardOf(name, *tr.trps.ShardTotal) == tr.shardIndex() {
+		return true
+	}
+
+	ctx.Logdf("shard excluded %s test", name)
+	if tr.sharded != nil {
+		*tr.sharded++
+	}
+
+	return false
+}
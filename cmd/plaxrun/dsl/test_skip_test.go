@@ -0,0 +1,74 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Comcast/plax/junit"
+)
+
+func TestSkip(t *testing.T) {
+	// The skipped test's missing file doesn't matter.
+	spec := `name: run
+version: 0.0.1
+tests:
+  pass: {path: pass.yaml, version: fake}
+  refund: {path: refund.yaml, version: fake, skip: "refunds are down"}
+groups:
+  all:
+    tests:
+      - name: pass
+      - name: refund
+`
+	filename := writeRunSpec(t, spec)
+
+	opts := runOptions(filename)
+	opts.Groups = []string{"all"}
+
+	tr, err := RunTests(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := tr.Report
+	if r.Total != 2 || r.Passed != 1 || r.Skipped != 1 {
+		t.Fatalf("unexpected report %#v", r)
+	}
+	tc := r.TestSuite[1].TestCase[0]
+	if tc.Status != junit.Skipped || tc.Message != "skip: refunds are down" {
+		t.Fatalf("unexpected test case %#v", tc)
+	}
+
+	var plan strings.Builder
+	if err := tr.WritePlan(NewCtx(context.Background()), &plan); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(plan.String(), "run-0.0.1:all:refund\n  (skip: refunds are down)\n") {
+		t.Fatalf("unexpected plan %q", plan.String())
+	}
+
+	// With -run-skipped, the test's missing file is an error.
+	opts.RunSkipped = true
+	if _, err = RunTests(context.Background(), opts); err == nil {
+		t.Fatal("expected an error with -run-skipped")
+	}
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/Comcast/plax/cmd/plaxrun/plugins/report"
+)
+
+var (
+	// DefaultSlowest is the number of the slowest tests that the
+	// summary lists when there's a TestRunParams.SlowThreshold.
+	DefaultSlowest = 5
+)
+
+// SlowTest is the duration of a test (see TestRun.Slowest).
+type SlowTest struct {
+	Name string
+	Time time.Duration
+
+	// Slow reports whether the test took longer than the
+	// TestRunParams.SlowThreshold.
+	Slow bool
+}
+
+// slowThreshold returns the TestRunParams.SlowThreshold (or zero).
+func (tr *TestRun) slowThreshold() time.Duration {
+	if tr.trps == nil || tr.trps.SlowThreshold == nil {
+		return 0
+	}
+	return *tr.trps.SlowThreshold
+}
+
+// markSlow adds a "slow" property to each TestCase that took longer
+// than the TestRunParams.SlowThreshold and warns about it.
+func (tr *TestRun) markSlow(ctx *Ctx, r *report.TestReport) {
+	threshold := tr.slowThreshold()
+	if threshold <= 0 {
+		return
+	}
+
+	for _, ts := range r.TestSuite {
+		for i := range ts.TestCase {
+			tc := &ts.TestCase[i]
+			if tc.Time == nil || *tc.Time <= threshold {
+				continue
+			}
+			tc.AddProperty("slow", "true")
+			ctx.Warnf("Slow test %s took %s (more than %s)", timingName(ts, *tc), tc.Time.Round(time.Millisecond), threshold)
+		}
+	}
+}
+
+// Slowest returns the (at most) n tests of the Report that took the
+// longest, slowest first.
+func (tr *TestRun) Slowest(n int) []SlowTest {
+	var (
+		threshold = tr.slowThreshold()
+		timings   = tr.Timings()
+		slowest   = make([]SlowTest, 0, len(timings))
+	)
+	for name, d := range timings {
+		if 0 < d {
+			slowest = append(slowest, SlowTest{
+				Name: name,
+				Time: d,
+				Slow: 0 < threshold && threshold < d,
+			})
+		}
+	}
+
+	sort.Slice(slowest, func(i, j int) bool {
+		if slowest[i].Time != slowest[j].Time {
+			return slowest[j].Time < slowest[i].Time
+		}
+		return slowest[i].Name < slowest[j].Name
+	})
+
+	if 0 <= n && n < len(slowest) {
+		slowest = slowest[:n]
+	}
+
+	return slowest
+}
+
+// writeSlowest writes the slowest tests for the summary when there's
+// a TestRunParams.SlowThreshold.
+func (tr *TestRun) writeSlowest(w io.Writer) error {
+	threshold := tr.slowThreshold()
+	if threshold <= 0 {
+		return nil
+	}
+
+	n := DefaultSlowest
+	if tr.trps.Slowest != nil {
+		n = *tr.trps.Slowest
+	}
+
+	var (
+		slowest = tr.Slowest(n)
+		slow    int
+	)
+	for _, st := range tr.Slowest(-1) {
+		if st.Slow {
+			slow++
+		}
+	}
+
+	if _, err := fmt.Fprintf(w, "Slow=%d SlowThreshold=%s\n", slow, threshold); err != nil {
+		return fmt.Errorf("failed to write summary: %w", err)
+	}
+
+	for _, st := range slowest {
+		mark := ""
+		if st.Slow {
+			mark = " slow"
+		}
+		if _, err := fmt.Fprintf(w, "  %s %s%s\n", st.Name, st.Time.Round(time.Millisecond), mark); err != nil {
+			return fmt.Errorf("failed to write summary: %w", err)
+		}
+	}
+
+	return nil
+}
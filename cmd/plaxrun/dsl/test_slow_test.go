@@ -0,0 +1,101 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Comcast/plax/junit"
+)
+
+// timedPlugin passes after (supposedly) taking its version's
+// duration.
+type timedPlugin struct {
+	name string
+	took time.Duration
+}
+
+func (p *timedPlugin) Invoke(ctx context.Context) (*junit.TestSuite, error) {
+	ts := junit.NewTestSuite(p.name)
+	tc := junit.NewTestCase(p.name, "")
+	tc.Finish(junit.Passed)
+	tc.Time = &p.took
+	ts.Add(*tc)
+	ts.Finish()
+	return ts, nil
+}
+
+func TestSlow(t *testing.T) {
+	for _, took := range []string{"1s", "3s"} {
+		d, _ := time.ParseDuration(took)
+		ThePluginRegistry.Register(PluginModule("timed-"+took), func(def PluginDef) (Plugin, error) {
+			name, _ := def.GetPluginDefName()
+			return &timedPlugin{name: name, took: d}, nil
+		})
+	}
+
+	spec := `name: run
+version: 0.0.1
+tests:
+  quick: {path: pass.yaml, version: fake}
+  medium: {path: pass.yaml, version: timed-1s}
+  long: {path: pass.yaml, version: timed-3s}
+groups:
+  all:
+    tests:
+      - name: quick
+      - name: medium
+      - name: long
+`
+	filename := writeRunSpec(t, spec)
+
+	opts := runOptions(filename)
+	opts.Groups = []string{"all"}
+	opts.SlowThreshold = 2 * time.Second
+	opts.Slowest = 2
+
+	tr, err := RunTests(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ts := range tr.Report.TestSuite {
+		slow := false
+		for _, p := range ts.TestCase[0].Properties {
+			slow = slow || p.Name == "slow"
+		}
+		if slow != strings.HasSuffix(ts.Name, ":long") {
+			t.Fatalf("%s has properties %v", ts.Name, ts.TestCase[0].Properties)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tr.WriteSummary(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "Slow=1 SlowThreshold=2s\n  run-0.0.1:all:long 3s slow\n  run-0.0.1:all:medium 1s\n"
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Fatalf("unexpected summary\n%s", got)
+	}
+}
@@ -34,6 +34,10 @@ type TestSuiteRef struct {
 
 // getTaskFuncs for the TestList
 func (ts *TestSuiteRef) getTaskFunc(ctx *plaxDsl.Ctx, tr TestRun) (*async.TaskFunc, error) {
+	if _, ok := tr.Tests[ts.name]; !ok {
+		return nil, fmt.Errorf("no such test suite: %s", ts.name)
+	}
+
 	bs, err := (&tr.trps.Bindings).Copy()
 	if err != nil {
 		return nil, fmt.Errorf("failed to copy bindings for test suite %s: %w", ts.name, err)
@@ -43,7 +47,6 @@ func (ts *TestSuiteRef) getTaskFunc(ctx *plaxDsl.Ctx, tr TestRun) (*async.TaskFu
 	tdr := TestDefRef{
 		TestConstraints: TestConstraints{
 			Priority: tr.trps.Priority,
-			Labels:   tr.trps.Labels,
 		},
 		Name:  ts.name,
 		tests: ts.tests,
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Comcast/plax/junit"
+)
+
+// WriteTAP writes the Report in the Test Anything Protocol (version
+// 13) format.
+//
+// Each TestCase of each TestSuite gets one test line, which is
+// described by the names of both (or just the TestSuite's when they
+// are the same, as for tests that plaxrun skipped).  A skipped
+// TestCase uses the SKIP directive, and the message of a failed or
+// errored TestCase is written as a YAML diagnostic block.
+func (tr *TestRun) WriteTAP(w io.Writer) error {
+	if tr.Report == nil {
+		return fmt.Errorf("test run %s has no report", tr.Name)
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("TAP version 13\n")
+	sb.WriteString(fmt.Sprintf("1..%d\n", tr.Report.Total))
+
+	n := 0
+	for _, ts := range tr.Report.TestSuite {
+		if ts == nil {
+			continue
+		}
+		for _, tc := range ts.TestCase {
+			n++
+			desc := ts.Name
+			if tc.Name != ts.Name {
+				desc = fmt.Sprintf("%s: %s", ts.Name, tc.Name)
+			}
+			desc = tapEscape(desc)
+
+			switch tc.Status {
+			case junit.Skipped:
+				sb.WriteString(fmt.Sprintf("ok %d - %s # SKIP %s\n", n, desc, tapEscape(oneLine(tc.Message))))
+			case junit.Failed, junit.Error:
+				sb.WriteString(fmt.Sprintf("not ok %d - %s\n", n, desc))
+				sb.WriteString("  ---\n")
+				sb.WriteString(fmt.Sprintf("  status: %s\n", tc.Status))
+				if tc.Message != "" {
+					js, err := json.Marshal(tc.Message)
+					if err != nil {
+						return err
+					}
+					sb.WriteString(fmt.Sprintf("  message: %s\n", js))
+				}
+				sb.WriteString("  ...\n")
+			default:
+				sb.WriteString(fmt.Sprintf("ok %d - %s\n", n, desc))
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+
+	return err
+}
+
+// tapEscape escapes the '#' characters that would otherwise start a
+// TAP directive.
+func tapEscape(s string) string {
+	return strings.ReplaceAll(s, "#", `\#`)
+}
+
+// oneLine replaces line breaks with spaces.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Comcast/plax/cmd/plaxrun/plugins/report"
+	"github.com/Comcast/plax/junit"
+)
+
+func TestWriteTAP(t *testing.T) {
+	ts := junit.NewTestSuite("suite")
+
+	for _, c := range []struct {
+		name    string
+		status  junit.TestCaseStatus
+		message string
+	}{
+		{"passes", junit.Passed, ""},
+		{"fails", junit.Failed, "expected\ntacos"},
+		{"breaks", junit.Error, "broken"},
+		{"skips #1", junit.Skipped, "priority"},
+		{"suite", junit.Skipped, "skip: broken upstream"},
+	} {
+		tc := junit.NewTestCase(c.name, c.name+".yaml")
+		tc.Finish(c.status, c.message)
+		ts.Add(*tc)
+	}
+
+	tr := &TestRun{
+		Name: "tap",
+		Report: &report.TestReport{
+			TestSuite: []*junit.TestSuite{ts},
+			Total:     ts.Total,
+		},
+	}
+
+	var sb strings.Builder
+	if err := tr.WriteTAP(&sb); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `TAP version 13
+1..5
+ok 1 - suite: passes
+not ok 2 - suite: fails
+  ---
+  status: failed
+  message: "expected\ntacos"
+  ...
+not ok 3 - suite: breaks
+  ---
+  status: error
+  message: "broken"
+  ...
+ok 4 - suite: skips \#1 # SKIP priority
+ok 5 - suite # SKIP skip: broken upstream
+`
+	if got := sb.String(); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteTAPWithoutReport(t *testing.T) {
+	tr := &TestRun{}
+
+	var sb strings.Builder
+	if err := tr.WriteTAP(&sb); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,548 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	jschema "github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+// testRunSchema is the JSON Schema for a TestRun (after includes
+// have been processed).
+const testRunSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "definitions": {
+    "names": {
+      "type": "array",
+      "items": { "type": "string" }
+    },
+    "duration": {
+      "type": ["string", "integer"]
+    },
+    "params": {
+      "type": "object",
+      "additionalProperties": { "type": ["string", "number", "boolean", "null"] }
+    },
+    "guard": {
+      "type": "object",
+      "properties": {
+        "dependsOn": { "$ref": "#/definitions/names" },
+        "libraries": { "$ref": "#/definitions/names" },
+        "src": { "type": "string" }
+      },
+      "additionalProperties": false
+    },
+    "iterate": {
+      "type": "object",
+      "properties": {
+        "dependsOn": { "$ref": "#/definitions/names" },
+        "param": { "type": "string" },
+        "params": { "type": "string" },
+        "guard": { "$ref": "#/definitions/guard" }
+      },
+      "additionalProperties": false
+    },
+    "testRef": {
+      "type": "object",
+      "required": ["name"],
+      "properties": {
+        "name": { "type": "string" },
+        "params": { "$ref": "#/definitions/params" },
+        "guard": { "$ref": "#/definitions/guard" },
+        "labels": { "type": "string" },
+        "priority": { "type": "integer" },
+        "retry": { "type": "integer" },
+        "seed": { "type": "integer" },
+        "iterate": { "$ref": "#/definitions/iterate" }
+      },
+      "additionalProperties": false
+    },
+    "testRefs": {
+      "type": "array",
+      "items": { "$ref": "#/definitions/testRef" }
+    },
+    "groupRef": {
+      "type": "object",
+      "required": ["name"],
+      "properties": {
+        "name": { "type": "string" },
+        "params": { "$ref": "#/definitions/params" },
+        "guard": { "$ref": "#/definitions/guard" }
+      },
+      "additionalProperties": false
+    }
+  },
+  "type": "object",
+  "properties": {
+    "name": { "type": "string" },
+    "version": { "type": ["string", "number"] },
+    "tests": {
+      "type": "object",
+      "additionalProperties": {
+        "type": "object",
+        "required": ["path"],
+        "properties": {
+          "path": { "type": "string" },
+          "version": { "type": "string" },
+          "params": { "$ref": "#/definitions/names" },
+          "dependsOn": { "$ref": "#/definitions/names" },
+          "retries": { "type": "integer", "minimum": 0 },
+          "retryDelay": { "$ref": "#/definitions/duration" },
+          "labels": { "$ref": "#/definitions/names" },
+          "priority": { "type": "integer", "minimum": 0 },
+          "concurrencyGroup": { "type": "string" },
+          "skip": { "type": "string" },
+          "expectFail": { "type": "boolean" }
+        },
+        "additionalProperties": false
+      }
+    },
+    "groups": {
+      "type": "object",
+      "additionalProperties": {
+        "type": "object",
+        "properties": {
+          "iterate": { "$ref": "#/definitions/iterate" },
+          "forEach": {
+            "type": "object",
+            "required": ["in", "as"],
+            "properties": {
+              "in": { "type": "string" },
+              "as": { "type": "string" }
+            },
+            "additionalProperties": false
+          },
+          "params": { "$ref": "#/definitions/params" },
+          "tests": { "$ref": "#/definitions/testRefs" },
+          "groups": {
+            "type": "array",
+            "items": { "$ref": "#/definitions/groupRef" }
+          },
+          "timeout": { "$ref": "#/definitions/duration" },
+          "labels": { "$ref": "#/definitions/names" },
+          "concurrencyGroup": { "type": "string" },
+          "when": { "type": "string" },
+          "setup": { "$ref": "#/definitions/testRefs" },
+          "teardown": { "$ref": "#/definitions/testRefs" }
+        },
+        "additionalProperties": false
+      }
+    },
+    "params": {
+      "type": "object",
+      "additionalProperties": {
+        "type": "object",
+        "properties": {
+          "dependsOn": { "$ref": "#/definitions/names" },
+          "cmd": { "type": "string" },
+          "args": { "type": "array" },
+          "envs": { "type": "object" },
+          "redact": { "type": "boolean" },
+          "secret": { "type": "boolean" },
+          "required": { "type": "boolean" },
+          "compute": { "type": "string" }
+        },
+        "additionalProperties": false
+      }
+    },
+    "reports": {
+      "type": "object",
+      "additionalProperties": {
+        "type": "object",
+        "properties": {
+          "dependsOn": { "$ref": "#/definitions/names" },
+          "config": {}
+        },
+        "additionalProperties": false
+      }
+    },
+    "profiles": {
+      "type": "object",
+      "additionalProperties": { "type": "object" }
+    },
+    "setup": { "$ref": "#/definitions/testRefs" },
+    "teardown": { "$ref": "#/definitions/testRefs" }
+  },
+  "additionalProperties": false
+}`
+
+// ValidationError is a problem with a TestRun.
+type ValidationError struct {
+	// Path is the location of the problem (e.g.,
+	// "groups.basic.tests.0.name").
+	Path string
+
+	// Line is the line number of the problem, or 0 if unknown.
+	Line int
+
+	// Message describes the problem.
+	Message string
+}
+
+func (ve ValidationError) Error() string {
+	if ve.Line <= 0 {
+		return fmt.Sprintf("%s: %s", ve.Path, ve.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", ve.Line, ve.Path, ve.Message)
+}
+
+// ValidationErrors is the error for a TestRun with problems.
+type ValidationErrors []ValidationError
+
+func (ves ValidationErrors) Error() string {
+	msgs := make([]string, len(ves))
+	for i, ve := range ves {
+		msgs[i] = ve.Error()
+	}
+	return fmt.Sprintf("invalid test run:\n  %s", strings.Join(msgs, "\n  "))
+}
+
+// ValidateTestRun checks the TestRun YAML against the TestRun JSON
+// Schema, checks that groups reference defined tests and groups, and
+// checks that parameter dependencies reference defined params and
+// that test dependencies reference defined tests, and checks that
+// neither have cycles.
+//
+// All of the problems are returned.  The error is only for YAML that
+// can't be parsed at all.
+func ValidateTestRun(bs []byte) ([]ValidationError, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(bs, &root); err != nil {
+		return nil, fmt.Errorf("test runner configuration parse error: %w", err)
+	}
+
+	if len(root.Content) == 0 {
+		return []ValidationError{{
+			Path:    "(root)",
+			Message: "empty test run",
+		}}, nil
+	}
+
+	doc := root.Content[0]
+
+	var x interface{}
+	if err := doc.Decode(&x); err != nil {
+		return nil, fmt.Errorf("test runner configuration parse error: %w", err)
+	}
+
+	js, err := json.Marshal(&x)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert test run to JSON: %w", err)
+	}
+
+	result, err := jschema.Validate(jschema.NewStringLoader(testRunSchema), jschema.NewBytesLoader(js))
+	if err != nil {
+		return nil, fmt.Errorf("schema validation error: %w", err)
+	}
+
+	var ves []ValidationError
+
+	for _, re := range result.Errors() {
+		path := re.Field()
+		ves = append(ves, ValidationError{
+			Path:    path,
+			Line:    lineAt(doc, path),
+			Message: re.Description(),
+		})
+	}
+
+	ves = append(ves, validateRefs(doc)...)
+	ves = append(ves, validateParamCycles(doc)...)
+	ves = append(ves, validateTestCycles(doc)...)
+
+	sort.SliceStable(ves, func(i, j int) bool {
+		return ves[i].Line < ves[j].Line
+	})
+
+	return ves, nil
+}
+
+// validateRefs finds references to undefined tests, groups, and
+// params.
+func validateRefs(doc *yaml.Node) []ValidationError {
+	var (
+		ves    []ValidationError
+		tests  = mappingValue(doc, "tests")
+		groups = mappingValue(doc, "groups")
+		params = mappingValue(doc, "params")
+	)
+
+	// check that each name in the sequences at the keys of n is
+	// defined by the mapping defs.
+	check := func(path string, n *yaml.Node, what string, defs *yaml.Node, keys ...string) {
+		for _, key := range keys {
+			seq := mappingValue(n, key)
+			if seq == nil || seq.Kind != yaml.SequenceNode {
+				continue
+			}
+			for i, item := range seq.Content {
+				p := fmt.Sprintf("%s.%d", key, i)
+				if path != "" {
+					p = path + "." + p
+				}
+				if item.Kind == yaml.MappingNode {
+					if item = mappingValue(item, "name"); item == nil {
+						continue
+					}
+					p += ".name"
+				}
+				if item.Kind != yaml.ScalarNode || mappingValue(defs, item.Value) != nil {
+					continue
+				}
+				ves = append(ves, ValidationError{
+					Path:    p,
+					Line:    item.Line,
+					Message: fmt.Sprintf("no such %s %q", what, item.Value),
+				})
+			}
+		}
+	}
+
+	// checkGuard checks the params of a (possibly missing) guard.
+	checkGuard := func(path string, n *yaml.Node) {
+		if g := mappingValue(n, "guard"); g != nil {
+			check(path+".guard", g, "param", params, "dependsOn")
+		}
+	}
+
+	eachMapping(tests, func(name string, n *yaml.Node) {
+		check("tests."+name, n, "param", params, "params")
+		check("tests."+name, n, "test", tests, "dependsOn")
+	})
+
+	eachMapping(params, func(name string, n *yaml.Node) {
+		check("params."+name, n, "param", params, "dependsOn")
+		if c := mappingValue(n, "compute"); c != nil && mappingValue(n, "cmd") != nil {
+			ves = append(ves, ValidationError{
+				Path:    "params." + name + ".compute",
+				Line:    c.Line,
+				Message: "compute can't be combined with cmd",
+			})
+		}
+	})
+
+	eachMapping(mappingValue(doc, "reports"), func(name string, n *yaml.Node) {
+		check("reports."+name, n, "param", params, "dependsOn")
+	})
+
+	eachMapping(groups, func(name string, n *yaml.Node) {
+		path := "groups." + name
+
+		check(path, n, "test", tests, "tests", "setup", "teardown")
+		check(path, n, "group", groups, "groups")
+
+		if it := mappingValue(n, "iterate"); it != nil {
+			check(path+".iterate", it, "param", params, "dependsOn")
+			checkGuard(path+".iterate", it)
+		}
+
+		for _, key := range []string{"tests", "groups", "setup", "teardown"} {
+			seq := mappingValue(n, key)
+			if seq == nil || seq.Kind != yaml.SequenceNode {
+				continue
+			}
+			for i, ref := range seq.Content {
+				checkGuard(fmt.Sprintf("%s.%s.%d", path, key, i), ref)
+			}
+		}
+	})
+
+	check("", doc, "test", tests, "setup", "teardown")
+	for _, key := range []string{"setup", "teardown"} {
+		if seq := mappingValue(doc, key); seq != nil && seq.Kind == yaml.SequenceNode {
+			for i, ref := range seq.Content {
+				checkGuard(fmt.Sprintf("%s.%d", key, i), ref)
+			}
+		}
+	}
+
+	return ves
+}
+
+// validateParamCycles finds cycles in the dependencies (including
+// the ones implied by compute expressions) of the params.
+func validateParamCycles(doc *yaml.Node) []ValidationError {
+	var (
+		ves    []ValidationError
+		params = mappingValue(doc, "params")
+		deps   = make(map[string][]string)
+		lines  = make(map[string]int)
+		order  []string
+	)
+
+	isParam := func(name string) bool {
+		return mappingValue(params, name) != nil
+	}
+
+	eachMapping(params, func(name string, n *yaml.Node) {
+		order = append(order, name)
+		lines[name] = n.Line
+		deps[name] = dependsOn(n, isParam)
+		if c := mappingValue(n, "compute"); c != nil && c.Kind == yaml.ScalarNode {
+			deps[name] = append(deps[name], computeRefs(c.Value, name, isParam)...)
+		}
+	})
+
+	for _, cycle := range findCycles(order, deps) {
+		ves = append(ves, ValidationError{
+			Path:    "params." + cycle[0],
+			Line:    lines[cycle[0]],
+			Message: "param dependency cycle: " + strings.Join(cycle, " -> "),
+		})
+	}
+
+	return ves
+}
+
+// validateTestCycles finds cycles in the dependencies of the tests.
+func validateTestCycles(doc *yaml.Node) []ValidationError {
+	var (
+		ves   []ValidationError
+		tests = mappingValue(doc, "tests")
+		deps  = make(map[string][]string)
+		lines = make(map[string]int)
+		order []string
+	)
+
+	isTest := func(name string) bool {
+		return mappingValue(tests, name) != nil
+	}
+
+	eachMapping(tests, func(name string, n *yaml.Node) {
+		order = append(order, name)
+		lines[name] = n.Line
+		deps[name] = dependsOn(n, isTest)
+	})
+
+	for _, cycle := range findCycles(order, deps) {
+		ves = append(ves, ValidationError{
+			Path:    "tests." + cycle[0],
+			Line:    lines[cycle[0]],
+			Message: "test dependency cycle: " + strings.Join(cycle, " -> "),
+		})
+	}
+
+	return ves
+}
+
+// dependsOn returns the defined names in the dependsOn sequence of
+// the mapping n.
+func dependsOn(n *yaml.Node, defined func(string) bool) []string {
+	var acc []string
+	if seq := mappingValue(n, "dependsOn"); seq != nil && seq.Kind == yaml.SequenceNode {
+		for _, item := range seq.Content {
+			if item.Kind == yaml.ScalarNode && defined(item.Value) {
+				acc = append(acc, item.Value)
+			}
+		}
+	}
+	return acc
+}
+
+// findCycles returns the cycles (like [a b a]) in the dependencies
+// of the names, which are explored in order.
+func findCycles(order []string, deps map[string][]string) [][]string {
+	// A name is done once all of its dependencies have been
+	// explored, so each cycle is found once.
+	var (
+		cycles [][]string
+		done   = make(map[string]bool)
+		path   []string
+		visit  func(name string)
+	)
+	visit = func(name string) {
+		for i, p := range path {
+			if p == name {
+				cycles = append(cycles, append(append([]string{}, path[i:]...), name))
+				return
+			}
+		}
+		if done[name] {
+			return
+		}
+		path = append(path, name)
+		for _, d := range deps[name] {
+			visit(d)
+		}
+		path = path[:len(path)-1]
+		done[name] = true
+	}
+
+	for _, name := range order {
+		visit(name)
+	}
+
+	return cycles
+}
+
+// mappingValue returns the value for the key in the mapping n.
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// eachMapping calls f with each key and value in the mapping n.
+func eachMapping(n *yaml.Node, f func(key string, value *yaml.Node)) {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		f(n.Content[i].Value, n.Content[i+1])
+	}
+}
+
+// lineAt returns the line of the node at the (dotted) JSON Schema
+// field path, or of the closest ancestor that exists.
+func lineAt(doc *yaml.Node, path string) int {
+	n := doc
+	if path == "" || path == "(root)" {
+		return n.Line
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		var next *yaml.Node
+		switch n.Kind {
+		case yaml.MappingNode:
+			next = mappingValue(n, key)
+		case yaml.SequenceNode:
+			if i, err := strconv.Atoi(key); err == nil && 0 <= i && i < len(n.Content) {
+				next = n.Content[i]
+			}
+		}
+		if next == nil {
+			break
+		}
+		n = next
+	}
+
+	return n.Line
+}
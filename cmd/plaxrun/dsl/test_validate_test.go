@@ -0,0 +1,108 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"testing"
+)
+
+func TestValidateTestRun(t *testing.T) {
+	bs := []byte(`name: validate
+version: 0.0.1
+tests:
+  wait:
+    path: test-wait.yaml
+    params:
+      - WAIT
+      - MARGIN
+groups:
+  wait:
+    tests:
+      - name: wiat
+    groups:
+      - name: nested
+  nested:
+    tmeout: 5s
+params:
+  WAIT:
+    cmd: echo
+`)
+
+	ves, err := ValidateTestRun(bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ValidationError{
+		{Path: "tests.wait.params.1", Line: 8, Message: `no such param "MARGIN"`},
+		{Path: "groups.wait.tests.0.name", Line: 12, Message: `no such test "wiat"`},
+		{Path: "groups.nested", Line: 16, Message: "Additional property tmeout is not allowed"},
+	}
+
+	if len(ves) != len(want) {
+		t.Fatalf("got %v, want %v", ves, want)
+	}
+
+	for i, ve := range ves {
+		if ve != want[i] {
+			t.Errorf("got %#v, want %#v", ve, want[i])
+		}
+	}
+}
+
+func TestValidateTestRunParamCycles(t *testing.T) {
+	bs := []byte(`params:
+  ENV:
+    required: true
+  TOPIC:
+    compute: ENV + "/" + bs["URL"]
+  URL:
+    dependsOn:
+      - TOPIC
+  SELF:
+    compute: SELF + 1
+    cmd: echo
+`)
+
+	ves, err := ValidateTestRun(bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ValidationError{
+		{Path: "params.TOPIC", Line: 5, Message: "param dependency cycle: TOPIC -> URL -> TOPIC"},
+		{Path: "params.SELF.compute", Line: 10, Message: "compute can't be combined with cmd"},
+	}
+
+	if len(ves) != len(want) {
+		t.Fatalf("got %v, want %v", ves, want)
+	}
+
+	for i, ve := range ves {
+		if ve != want[i] {
+			t.Errorf("got %#v, want %#v", ve, want[i])
+		}
+	}
+}
+
+func TestValidateTestRunParseError(t *testing.T) {
+	if _, err := ValidateTestRun([]byte("tests: [")); err == nil {
+		t.Fatal("expected a parse error")
+	}
+}
@@ -0,0 +1,193 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	plaxDsl "github.com/Comcast/plax/dsl"
+	"github.com/Comcast/plax/junit"
+)
+
+// WatchDebounce is how long Watch waits for more changes before
+// executing the tests again, since one save can change a file several
+// times.
+var WatchDebounce = 250 * time.Millisecond
+
+// Watch executes the tests given by the TestRunParams, and then it
+// executes them again each time the test run file, its includes, or
+// the test files change, until the ctx is done.
+//
+// A concise summary of each execution is written to w.  Problems with
+// the test run are also written to w (so that they can be fixed while
+// watching), and only problems with watching are returned.
+func Watch(ctx *Ctx, trps *TestRunParams, w io.Writer) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch files: %w", err)
+	}
+	defer watcher.Close()
+
+	if trps.Bindings == nil {
+		trps.Bindings = make(plaxDsl.Bindings)
+	}
+	bindings, err := (&trps.Bindings).Copy()
+	if err != nil {
+		return fmt.Errorf("failed to copy bindings: %w", err)
+	}
+
+	dirs := make(map[string]bool)
+
+	for {
+		// Each execution starts with the given bindings, and
+		// includes are read again.
+		bs, err := bindings.Copy()
+		if err != nil {
+			return fmt.Errorf("failed to copy bindings: %w", err)
+		}
+		trps.Bindings = *bs
+		ctx.IncludeCache = plaxDsl.NewIncludeCache()
+
+		paths := watchExec(ctx, trps, w)
+
+		// Directories are watched, since editors often replace
+		// files, and so that added files are seen.
+		watched := make(map[string]bool)
+		for _, path := range paths {
+			watched[filepath.Dir(path)] = true
+			if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+				watched[path] = true
+			}
+		}
+		for dir := range dirs {
+			if !watched[dir] {
+				watcher.Remove(dir)
+				delete(dirs, dir)
+			}
+		}
+		for dir := range watched {
+			if dirs[dir] {
+				continue
+			}
+			if err := watcher.Add(dir); err != nil {
+				fmt.Fprintf(w, "Can't watch %s: %s\n", dir, err)
+				continue
+			}
+			dirs[dir] = true
+		}
+
+		fmt.Fprintf(w, "Watching %d files for changes\n", len(paths))
+
+		if err := waitForChange(ctx, watcher, paths); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// watchExec executes the tests once for Watch, and it returns the
+// paths to watch.
+func watchExec(ctx *Ctx, trps *TestRunParams, w io.Writer) []string {
+	tr, err := NewTestRun(ctx, trps)
+	if err == nil {
+		if err = tr.Exec(ctx); tr.Report != nil {
+			if trps.Verbose == nil || !*trps.Verbose {
+				// Already written otherwise.
+				tr.WriteSummary(w)
+			}
+			for _, ts := range tr.Report.TestSuite {
+				for _, tc := range ts.TestCase {
+					if tc.Status == junit.Failed || tc.Status == junit.Error {
+						fmt.Fprintf(w, "FAIL %s: %s\n", ts.Name, tc.Message)
+					}
+				}
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(w, "%s\n", err)
+	}
+
+	paths := make([]string, 0)
+	if trps.Filename != nil && *trps.Filename != StdinFilename {
+		if path, err := filepath.Abs(*trps.Filename); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	paths = append(paths, ctx.IncludeCache.Paths()...)
+
+	if tr != nil {
+		for _, td := range tr.Tests {
+			path := td.Path
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(ctx.Dir, path)
+			}
+			paths = append(paths, path)
+		}
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+// waitForChange returns after one of the paths (or something in one
+// of them, if it's a directory) has changed and then no more changes
+// have happened for the WatchDebounce.
+func waitForChange(ctx *Ctx, watcher *fsnotify.Watcher, paths []string) error {
+	relevant := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		relevant[path] = true
+	}
+
+	var quiet <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return fmt.Errorf("watcher closed")
+			}
+			if ev.Op == fsnotify.Chmod {
+				continue
+			}
+			if relevant[ev.Name] || relevant[filepath.Dir(ev.Name)] {
+				quiet = time.After(WatchDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return fmt.Errorf("watcher closed")
+			}
+			ctx.Logf("Watch error: %s", err)
+		case <-quiet:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer that's safe for Watch to write while
+// the test reads.
+type syncBuffer struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.String()
+}
+
+func TestWatch(t *testing.T) {
+	spec := `name: run
+version: 0.0.1
+tests:
+  include: common.yaml
+groups:
+  passes:
+    tests:
+      - name: pass
+`
+	filename := writeRunSpec(t, spec)
+	common := filepath.Join(filepath.Dir(filename), "common.yaml")
+	if err := ioutil.WriteFile(common, []byte("pass: {path: pass.yaml, version: fake}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := runOptions(filename)
+	opts.Groups = []string{"passes"}
+
+	defer func(d time.Duration) { WatchDebounce = d }(WatchDebounce)
+	WatchDebounce = 10 * time.Millisecond
+
+	cctx, cancel := context.WithCancel(context.Background())
+	ctx := NewCtx(cctx)
+
+	var (
+		out  syncBuffer
+		done = make(chan error, 1)
+	)
+	go func() {
+		done <- Watch(ctx, opts.params(), &out)
+	}()
+
+	waitFor := func(n int) {
+		deadline := time.Now().Add(5 * time.Second)
+		for strings.Count(out.String(), "Watching") < n {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for execution %d: %s", n, out.String())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	waitFor(1)
+	if !strings.Contains(out.String(), "Total=1 Passed=1") {
+		t.Fatalf("unexpected output %s", out.String())
+	}
+
+	// Changing an include executes the tests again.
+	if err := ioutil.WriteFile(common, []byte("pass: {path: nope.yaml, version: fake}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(2)
+	if !strings.Contains(out.String(), "nope.yaml") {
+		t.Fatalf("expected an error for the missing test file: %s", out.String())
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
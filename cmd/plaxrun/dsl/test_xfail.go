@@ -0,0 +1,70 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+
+	"github.com/Comcast/plax/junit"
+)
+
+// expectFail wraps the function of a test with TestDef.ExpectFail so
+// that a failed (or errored) TestCase is reported as passed with an
+// "xfail" property and a passed TestCase is reported as failed with
+// an "xpass" property.
+//
+// An error without a TestSuite (like a test file that doesn't load)
+// is still an error.
+func expectFail(name string, td TestDef, f func() (*junit.TestSuite, error)) func() (*junit.TestSuite, error) {
+	if !td.ExpectFail {
+		return f
+	}
+
+	return func() (*junit.TestSuite, error) {
+		ts, err := f()
+		if ts == nil {
+			return ts, err
+		}
+
+		tcs := ts.TestCase
+		ts.TestCase = make([]junit.TestCase, 0, len(tcs))
+		ts.Total, ts.Passed, ts.Skipped, ts.Failures, ts.Errors = 0, 0, 0, 0, 0
+
+		xpassed := 0
+		for _, tc := range tcs {
+			switch tc.Status {
+			case junit.Failed, junit.Error:
+				tc.Status = junit.Passed
+				tc.Message = "expected failure: " + tc.Message
+				tc.AddProperty("xfail", "true")
+			case junit.Passed:
+				tc.Status = junit.Failed
+				tc.Message = "unexpected pass (expectFail)"
+				tc.AddProperty("xpass", "true")
+				xpassed++
+			}
+			ts.Add(tc)
+		}
+
+		if 0 < xpassed {
+			return ts, fmt.Errorf("%s: %d test(s) passed unexpectedly (expectFail)", name, xpassed)
+		}
+		return ts, nil
+	}
+}
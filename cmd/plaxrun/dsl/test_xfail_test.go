@@ -0,0 +1,70 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Comcast/plax/junit"
+)
+
+func TestExpectFail(t *testing.T) {
+	spec := `name: run
+version: 0.0.1
+tests:
+  known: {path: pass.yaml, version: failing, expectFail: true}
+  fixed: {path: pass.yaml, version: fake, expectFail: true}
+  after: {path: pass.yaml, version: fake, dependsOn: [known]}
+groups:
+  all:
+    tests:
+      - name: known
+      - name: fixed
+      - name: after
+`
+	filename := writeRunSpec(t, spec)
+
+	opts := runOptions(filename)
+	opts.Groups = []string{"all"}
+
+	// The unexpected pass is an error.
+	tr, err := RunTests(context.Background(), opts)
+	if err == nil {
+		t.Fatal("expected an error for the unexpected pass")
+	}
+
+	// An expected failure doesn't skip its dependents.
+	r := tr.Report
+	if r.Total != 3 || r.Passed != 2 || r.Failures != 1 {
+		t.Fatalf("unexpected report %#v", r)
+	}
+
+	known := r.TestSuite[1].TestCase[0]
+	if known.Status != junit.Passed || known.Message != "expected failure: no tacos" ||
+		len(known.Properties) != 1 || known.Properties[0].Name != "xfail" {
+		t.Fatalf("unexpected known test case %#v", known)
+	}
+
+	fixed := r.TestSuite[0].TestCase[0]
+	if fixed.Status != junit.Failed || fixed.Message != "unexpected pass (expectFail)" ||
+		len(fixed.Properties) != 1 || fixed.Properties[0].Name != "xpass" {
+		t.Fatalf("unexpected fixed test case %#v", fixed)
+	}
+}
@@ -58,27 +58,99 @@ func main() {
 
 	var (
 		trps = &dsl.TestRunParams{
-			Bindings:    make(plaxDsl.Bindings),
-			IncludeDirs: dsl.IncludeDirList{wd},
-			Filename:    flag.String("run", "spec.yaml", "Filename for test run specification"),
-			Dir:         flag.String("dir", ".", "Directory containing test files"),
-			ReportPluginDir: flag.String("reportPluginDir", "plugins/report", "Directory containing the report plugins"),
-			EmitJSON:    flag.Bool("json", false, "Emit JSON test output; instead of JUnit XML"),
-			Groups:      dsl.TestGroupList{},
-			Verbose:     flag.Bool("v", true, "Verbosity"),
-			LogLevel:    flag.String("log", "info", "Log level (info, debug, none)"),
-			Labels:      flag.String("labels", "", "Labels for tests to run"),
-			SuiteName:   flag.String("s", "", "Suite name to execute; -t options represent the tests in the suite to execute"),
-			Priority:    flag.Int("priority", -1, "Test priority"),
-			Redact:      flag.Bool("redact", false, "enable redactions when -log debug"),
+			Bindings:         make(plaxDsl.Bindings),
+			IncludeDirs:      dsl.IncludeDirList{wd},
+			Filename:         flag.String("run", "spec.yaml", `Filename for test run specification ("-" means standard input)`),
+			Dir:              flag.String("dir", ".", "Directory containing test files"),
+			ReportPluginDir:  flag.String("reportPluginDir", "plugins/report", "Directory containing the report plugins"),
+			EmitJSON:         flag.Bool("json", false, "Emit JSON test output; instead of JUnit XML"),
+			Groups:           dsl.TestGroupList{},
+			Properties:       dsl.PropertyMap{},
+			PlaxVersion:      &version,
+			Verbose:          flag.Bool("v", true, "Verbosity"),
+			LogLevel:         flag.String("log", "info", "Log level (info, debug, none)"),
+			LogFormat:        flag.String("log-format", "text", "Log format (text, json)"),
+			BindingsFile:     flag.String("bindings-file", "", "YAML or JSON file of parameter bindings; -p bindings take precedence"),
+			EnvPrefix:        flag.String("env-prefix", "", "Bind environment variables with this prefix (removed, and the rest lowercased); -p bindings take precedence"),
+			Profile:          flag.String("profile", "", "Name of the test run profile whose bindings to use; -p bindings take precedence"),
+			ConnectAttempts:  flag.Int("connect-attempts", 1, "Default maximum number of attempts to open each channel"),
+			ConnectDelay:     flag.Duration("connect-delay", plaxDsl.DefaultBackoffDelay, "Delay before the first retry to open a channel, which doubles for each subsequent retry"),
+			ConnectMaxDelay:  flag.Duration("connect-max-delay", 0, "Maximum delay between attempts to open a channel (0 means no maximum)"),
+			MsgHistory:       flag.Int("msg-history", 0, "Number of the last messages received on a channel to report when a recv times out"),
+			CaptureLogs:      flag.Bool("capture-logs", false, "Add the (redacted) logs of each test to its test case as system-out and system-err"),
+			CaptureBindings:  flag.Bool("capture-bindings", false, "Add the final (redacted) bindings of each test to its test case as properties"),
+			Labels:           flag.String("labels", "", `Labels expression for tests to run (e.g. "smoke && !slow")`),
+			SuiteName:        flag.String("s", "", "Suite name to execute; -t options represent the tests in the suite to execute"),
+			Priority:         flag.Int("priority", -1, "Test priority"),
+			DefaultPriority:  flag.Int("default-priority", 0, "Priority of tests that don't specify one"),
+			Redact:           flag.Bool("redact", false, "enable redactions when -log debug"),
+			MaxConcurrency:   flag.Int("concurrency", 1, "Maximum number of test groups and tests to execute concurrently"),
+			GroupTimeout:     flag.Duration("group-timeout", 0, "Default maximum duration of the tests of each test group without a timeout (0 means no timeout)"),
+			DefaultRetries:   flag.Int("retries", 0, "Default number of times to retry a failing test"),
+			EmitTAP:          flag.Bool("tap", false, "Emit TAP (Test Anything Protocol) test output; instead of JUnit XML"),
+			EmitJSONFlat:     flag.Bool("json-flat", false, "Emit a JSON array of the test cases; instead of JUnit XML"),
+			EmitHTML:         flag.Bool("html", false, "Emit an HTML page of the test results; instead of JUnit XML"),
+			EmitJSONLines:    flag.Bool("json-lines", false, "Emit newline-delimited JSON of the test cases; instead of JUnit XML"),
+			OutputFile:       flag.String("o", "", "Filename for test output; instead of standard output"),
+			DryRun:           flag.Bool("dry-run", false, "List the tests that would execute (with their parameters) without executing them"),
+			Preflight:        flag.Bool("preflight", false, "Check that the channels (brokers, databases, ...) of the tests are reachable without executing the tests"),
+			PreflightTimeout: flag.Duration("preflight-timeout", plaxDsl.DefaultPreflightTimeout, "Timeout for each -preflight channel check"),
+			AllureDir:        flag.String("allure-dir", "", "Directory for the test results as Allure result files"),
+			MetricsFile:      flag.String("metrics-file", "", "Filename for the test results as Prometheus metrics"),
+			TimingsFile:      flag.String("timings-csv", "", "Filename for a CSV of the name, duration (in seconds), and status of each test"),
+			IncludeTimeout:   flag.Duration("include-timeout", plaxDsl.DefaultIncludeTimeout, "Timeout for fetching each http(s) include"),
+			IncludeHeader:    flag.String("include-header", "", `Header ("Name: value") for fetching http(s) includes`),
+			ExpandEnv:        flag.Bool("expand-env", false, "Expand ${NAME} in the test run specification with the binding or environment variable NAME ($$ is a literal $)"),
+			ExpandEnvStrict:  flag.Bool("expand-env-strict", false, "With -expand-env, fail on undefined variables rather than expanding them to nothing"),
+			Shuffle:          flag.Bool("shuffle", false, "Execute the tests in a random order"),
+			Seed:             flag.Int64("seed", 0, "Seed for -shuffle to reproduce an order (0 means a new seed, which is logged)"),
+			ShardIndex:       flag.Int("shard-index", 0, "Index (from 0) of the shard of tests to execute with -shard-total"),
+			ShardTotal:       flag.Int("shard-total", 0, "Number of shards to split the tests into by the hash of their names (0 means no sharding)"),
+			LeakCheck:        flag.Bool("leak-check", false, "Warn (with a goroutine dump) when goroutines are still running after the tests"),
+			LeakThreshold:    flag.Int("leak-threshold", dsl.DefaultLeakThreshold, "Number of goroutines that -leak-check allows to still be running"),
+			FailFast:         flag.Bool("fail-fast", false, "Skip the remaining tests after the first test that fails or errors"),
+			MaxFailures:      flag.Int("max-failures", 0, "Skip the remaining tests once this many tests failed or errored (0 means no limit)"),
+			RunTimeout:       flag.Duration("timeout", 0, "Maximum duration of the execution of all of the tests, after which the remaining tests are skipped (0 means no timeout)"),
+			List:             flag.Bool("list", false, "List the tests, groups, and params of the test run specification (as JSON with -json) and then exit"),
+			ValidateOnly:     flag.Bool("validate-only", false, "Validate the test run specification and then exit"),
+			Repeat:           flag.Int("repeat", 1, "Number of times to execute the selected tests (reporting each iteration)"),
+			Flaky:            flag.Int("flaky", 0, "Number of times to execute each selected test to classify it as stable-pass, stable-fail, or flaky (0 means no classification)"),
+			FlakyPassRatio:   flag.Float64("flaky-pass-ratio", dsl.DefaultFlakyPassRatio, "Minimum pass ratio of a stable-pass test with -flaky"),
+			FlakyFailRatio:   flag.Float64("flaky-fail-ratio", dsl.DefaultFlakyFailRatio, "Maximum pass ratio of a stable-fail test with -flaky"),
+			FlakyFile:        flag.String("flaky-report", "", "Filename for the JSON report of the -flaky classifications"),
+			SlowThreshold:    flag.Duration("slow", 0, "Duration after which a test is slow, which is warned about, marked with a slow property, and listed in the summary (0 means no threshold)"),
+			Slowest:          flag.Int("slowest", dsl.DefaultSlowest, "Number of the slowest tests that the summary lists with -slow"),
+			GitHub:           flag.Bool("github", false, "Write GitHub Actions annotations (::error and ::warning lines) for failed and skipped tests to standard error"),
+			JUnitClassname:   flag.String("junit-classname-template", "", "Template for the classname of each JUnit test case (placeholders {suite}, {run}, {version}, {group}, {test}, {shard})"),
+			JUnitName:        flag.String("junit-name-template", "", "Template for the name of each JUnit test case (placeholders {suite}, {run}, {version}, {group}, {test}, {shard})"),
+			ChangedSince:     flag.String("changed-since", "", "Git ref (e.g. origin/main) whose changed test files (or their includes) select the tests to execute"),
+			RunSkipped:       flag.Bool("run-skipped", false, "Execute the tests that have a skip reason instead of reporting them as skipped"),
+			OTelEndpoint:     flag.String("otel-endpoint", "", `OTLP/HTTP endpoint (e.g. "http://localhost:4318") for the OpenTelemetry spans of the run, each group, and each test`),
+			ReuseConnections: flag.Bool("reuse-connections", false, "Share the connections (MQTT, ...) of the tests of each group, which are closed after the group's last test"),
+			RerunFailed:      flag.String("rerun-failed", "", "JUnit XML results file (from -o) whose failed or errored tests are the only ones to execute"),
 		}
-		vers = flag.Bool("version", false, "Print version and then exit")
+		vers        = flag.Bool("version", false, "Print version and then exit")
+		merge       = fileList{}
+		chanPlugins = fileList{}
+		watch       = flag.Bool("watch", false, "Execute the tests again whenever the test run specification, its includes, or the tests change")
+
+		progressFile = flag.String("progress", "", `Filename for a line of JSON as each test starts and finishes ("-" means standard error)`)
+
+		skippedOK   = flag.Bool("skipped-ok", true, "Exit with 0 (rather than the -failure-exit-code) when all tests were skipped")
+		failureCode = flag.Int("failure-exit-code", 1, "Exit code when a test failed")
+		errorCode   = flag.Int("error-exit-code", 1, "Exit code when a test had an error (takes precedence over -failure-exit-code)")
+		timeoutCode = flag.Int("timeout-exit-code", 124, "Exit code when -timeout cut the test run short (takes precedence over the other exit codes)")
 	)
 
 	flag.Var(&trps.Bindings, "p", fmt.Sprintf("Parameter Bindings: %s", trps.Bindings.String()))
 	flag.Var(&trps.IncludeDirs, "I", "YAML include directories")
 	flag.Var(&trps.Groups, "g", fmt.Sprintf("Groups to execute: %s", trps.Groups.String()))
 	flag.Var(&trps.Tests, "t", fmt.Sprintf("Tests to execute: %s", trps.Tests.String()))
+	flag.Var(&merge, "merge", "JUnit XML results file to merge (to -o or standard output) and then exit")
+	flag.Var(&chanPlugins, "chan-plugin", "Go plugin (.so) that registers channel types for the tests")
+	flag.Var(&trps.RedactValues, "redact-value", "Secret value to replace with REDACTED in the test results")
+	flag.Var(&trps.RedactPatterns, "redact-pattern", "Regular expression whose matches are masked in the logs and the test results")
+	flag.Var(&trps.Properties, "property", fmt.Sprintf("Property of each test suite in the results: %s", trps.Properties.String()))
 
 	flag.Parse()
 
@@ -87,16 +159,64 @@ func main() {
 		return
 	}
 
+	if 0 < len(merge) {
+		if err := mergeResults(merge, *trps.OutputFile); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	trps.ExitCodePolicy = &dsl.ExitCodePolicy{
+		SkippedIsSuccess: *skippedOK,
+		FailureCode:      *failureCode,
+		ErrorCode:        *errorCode,
+		TimeoutCode:      *timeoutCode,
+	}
+
+	ctx := dsl.NewCtx(context.Background())
+
+	if *trps.LogFormat == "json" {
+		// Also convert what's logged with the log package.
+		jl := plaxDsl.NewJSONLogger(os.Stderr)
+		ctx.Logger = jl
+		log.SetFlags(0)
+		log.SetOutput(jl)
+	}
+
 	switch *trps.LogLevel {
 	case "debug", "DEBUG":
 		log.Printf("plaxrun version %s %s %s\n", version, commit, date)
 	}
 
-	if len(trps.Groups) == 0 && len(trps.Tests) == 0 && trps.SuiteName == nil {
+	for _, filename := range chanPlugins {
+		if _, err := plaxDsl.LoadChanPlugin(ctx.Ctx, filename); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if len(trps.Groups) == 0 && len(trps.Tests) == 0 && trps.SuiteName == nil && !*trps.List && !*trps.ValidateOnly {
 		log.Fatal(fmt.Errorf("at least 1 test or test group or test suite must be specified"))
 	}
 
-	ctx := dsl.NewCtx(context.Background())
+	switch *progressFile {
+	case "":
+	case "-":
+		trps.ProgressOut = os.Stderr
+	default:
+		f, err := os.Create(*progressFile)
+		if err != nil {
+			log.Fatalf("failed to create progress file: %v", err)
+		}
+		defer f.Close()
+		trps.ProgressOut = f
+	}
+
+	if *watch {
+		if err := dsl.Watch(ctx, trps, os.Stderr); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
 	testRun, err := dsl.NewTestRun(ctx, trps)
 	if err != nil {
@@ -105,6 +225,44 @@ func main() {
 
 	err = testRun.Exec(ctx)
 	if err != nil {
-		log.Fatal(err)
+		log.Print(err)
+	}
+
+	if code := testRun.ExitCode(err); code != 0 {
+		os.Exit(code)
 	}
 }
+
+// fileList are filenames given by repeating a flag.
+type fileList []string
+
+// String representation
+func (fl *fileList) String() string {
+	return "Filename"
+}
+
+// Set adds the filename
+func (fl *fileList) Set(value string) error {
+	*fl = append(*fl, value)
+	return nil
+}
+
+// mergeResults merges the results files into the output file (or
+// standard output).
+func mergeResults(inputs []string, output string) error {
+	if output == "" {
+		return dsl.MergeJUnit(inputs, os.Stdout)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create results file: %w", err)
+	}
+
+	if err = dsl.MergeJUnit(inputs, f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
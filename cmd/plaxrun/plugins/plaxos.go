@@ -21,9 +21,11 @@ package plugins
 import (
 	"context"
 	"fmt"
+	"time"
 
 	// Import required to dynamically register channels
 	_ "github.com/Comcast/plax/chans"
+	plaxDsl "github.com/Comcast/plax/dsl"
 	plaxInvoke "github.com/Comcast/plax/invoke"
 	"github.com/Comcast/plax/junit"
 
@@ -106,6 +108,31 @@ func init() {
 				return nil, err
 			}
 
+			captureLogs, err := def.GetPluginDefCaptureLogs()
+			if err != nil {
+				return nil, err
+			}
+
+			captureBindings, err := def.GetPluginDefCaptureBindings()
+			if err != nil {
+				return nil, err
+			}
+
+			msgHistory, err := def.GetPluginDefMsgHistory()
+			if err != nil {
+				return nil, err
+			}
+
+			connectBackoff, err := def.GetPluginDefConnectBackoff()
+			if err != nil {
+				return nil, err
+			}
+
+			chanPool, err := def.GetPluginDefChanPool()
+			if err != nil {
+				return nil, err
+			}
+
 			i := plaxInvoke.Invocation{
 				SuiteName:          name,
 				Tests:              tests,
@@ -120,6 +147,11 @@ func init() {
 				ComplainOnAnyError: true,
 				Retry:              retry,
 				Redact:             redact,
+				CaptureLogs:        captureLogs,
+				CaptureBindings:    captureBindings,
+				MsgHistory:         msgHistory,
+				ConnectBackoff:     connectBackoff,
+				ChanPool:           chanPool,
 			}
 
 			i.Dir, err = def.GetPluginDefDir()
@@ -143,3 +175,8 @@ func init() {
 func (p *PlaxOSPlugin) Invoke(ctx context.Context) (*junit.TestSuite, error) {
 	return p.invocation.Exec(ctx)
 }
+
+// Preflight checks the channels of the tests without executing them
+func (p *PlaxOSPlugin) Preflight(ctx context.Context, timeout time.Duration) (map[string][]plaxDsl.ChanCheck, error) {
+	return p.invocation.Preflight(ctx, timeout)
+}
@@ -42,6 +42,7 @@ type TestReport struct {
 	Skipped   int                `xml:"skipped,attr" json:"skipped"`
 	Failures  int                `xml:"failures,attr" json:"failures"`
 	Errors    int                `xml:"errors,attr" json:"errors"`
+	Excluded  int                `xml:"excluded,attr,omitempty" json:"excluded,omitempty"`
 	Started   time.Time          `xml:"started,attr" json:"timestamp"`
 	Time      time.Duration      `xml:"time,attr" json:"time"`
 }
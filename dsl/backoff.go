@@ -0,0 +1,140 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+var (
+	// DefaultBackoffDelay is the delay before the first retry of
+	// a Backoff that doesn't specify a Delay.
+	DefaultBackoffDelay = 100 * time.Millisecond
+)
+
+// Backoff is a specification for retrying to open a channel (with
+// exponential backoff), which is useful when a broker might not be
+// ready yet.
+//
+// A channel made via a request to Mother uses the request's Backoff,
+// if any, and otherwise the test's ConnectBackoff.
+type Backoff struct {
+	// Attempts is the maximum number of attempts to open the
+	// channel.  Zero or one means no retries.
+	Attempts int `json:"attempts,omitempty" yaml:"attempts,omitempty"`
+
+	// Delay is the delay before the first retry, which doubles
+	// for every subsequent retry.
+	//
+	// Defaults to DefaultBackoffDelay.
+	Delay time.Duration `json:"delay,omitempty" yaml:"delay,omitempty"`
+
+	// MaxDelay, when not zero, is the maximum delay between
+	// attempts.
+	MaxDelay time.Duration `json:"maxDelay,omitempty" yaml:"maxDelay,omitempty"`
+}
+
+// UnmarshalJSON accepts durations as strings (like "250ms") or as
+// numbers of nanoseconds.
+func (b *Backoff) UnmarshalJSON(bs []byte) error {
+	var raw struct {
+		Attempts int
+		Delay    interface{}
+		MaxDelay interface{}
+	}
+	if err := json.Unmarshal(bs, &raw); err != nil {
+		return err
+	}
+
+	delay, err := backoffDuration("delay", raw.Delay)
+	if err != nil {
+		return err
+	}
+	maxDelay, err := backoffDuration("maxDelay", raw.MaxDelay)
+	if err != nil {
+		return err
+	}
+
+	*b = Backoff{
+		Attempts: raw.Attempts,
+		Delay:    delay,
+		MaxDelay: maxDelay,
+	}
+
+	return nil
+}
+
+func backoffDuration(name string, x interface{}) (time.Duration, error) {
+	switch vv := x.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return time.Duration(vv), nil
+	case string:
+		d, err := time.ParseDuration(vv)
+		if err != nil {
+			return 0, fmt.Errorf("bad backoff %s '%s': %w", name, vv, err)
+		}
+		return d, nil
+	default:
+		return 0, fmt.Errorf("bad backoff %s %#v (%T)", name, x, x)
+	}
+}
+
+// Open opens the channel, retrying with exponential backoff until the
+// channel opens, the attempts are exhausted, or the context is done.
+//
+// A nil Backoff makes a single attempt.
+func (b *Backoff) Open(ctx *Ctx, c Chan) error {
+	if b == nil || b.Attempts <= 1 {
+		return c.Open(ctx)
+	}
+
+	delay := b.Delay
+	if delay <= 0 {
+		delay = DefaultBackoffDelay
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = c.Open(ctx); err == nil {
+			return nil
+		}
+		if b.Attempts <= attempt {
+			return fmt.Errorf("failed to open %s channel after %d attempts: %w", c.Kind(), attempt, err)
+		}
+
+		if 0 < b.MaxDelay && b.MaxDelay < delay {
+			delay = b.MaxDelay
+		}
+		ctx.Logf("attempt %d of %d to open %s channel failed (%s); retrying in %s", attempt, b.Attempts, c.Kind(), err, delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("gave up opening %s channel after %d attempts: %w", c.Kind(), attempt, err)
+		case <-timer.C:
+		}
+
+		delay *= 2
+	}
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// unreadyChan fails to open until it has been asked failures times.
+type unreadyChan struct {
+	MockChan
+	failures int
+	opens    int
+}
+
+func (c *unreadyChan) Open(ctx *Ctx) error {
+	c.opens++
+	if c.opens <= c.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestBackoff(t *testing.T) {
+	ctx := NewCtx(nil)
+
+	t.Run("eventually", func(t *testing.T) {
+		c := &unreadyChan{failures: 2}
+		b := &Backoff{
+			Attempts: 3,
+			Delay:    time.Millisecond,
+		}
+		if err := b.Open(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+		if c.opens != 3 {
+			t.Fatal(c.opens)
+		}
+	})
+
+	t.Run("exhausted", func(t *testing.T) {
+		c := &unreadyChan{failures: 5}
+		b := &Backoff{
+			Attempts: 3,
+			Delay:    time.Millisecond,
+			MaxDelay: 2 * time.Millisecond,
+		}
+		err := b.Open(ctx, c)
+		if err == nil || !strings.Contains(err.Error(), "after 3 attempts") {
+			t.Fatal(err)
+		}
+		if c.opens != 3 {
+			t.Fatal(c.opens)
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		c := &unreadyChan{failures: 1}
+		var b *Backoff
+		if err := b.Open(ctx, c); err == nil {
+			t.Fatal("expected an error")
+		}
+		if c.opens != 1 {
+			t.Fatal(c.opens)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var req MotherMakeRequest
+		js := `{"name":"c","type":"mock","backoff":{"attempts":4,"delay":"250ms","maxDelay":2000000000}}`
+		if err := json.Unmarshal([]byte(js), &req); err != nil {
+			t.Fatal(err)
+		}
+		b := req.Backoff
+		if b == nil || b.Attempts != 4 || b.Delay != 250*time.Millisecond || b.MaxDelay != 2*time.Second {
+			t.Fatalf("%#v", b)
+		}
+		if err := json.Unmarshal([]byte(`{"delay":"soon"}`), b); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
@@ -55,6 +55,29 @@ func (r ChanRegistry) Register(ctx *Ctx, kind ChanKind, maker ChanMaker) {
 // Chan types.
 var TheChanRegistry = make(ChanRegistry)
 
+// RegisterChannel adds a Chan type to TheChanRegistry.
+//
+// The package that implements a Chan type calls RegisterChannel in
+// its init function, so the type is available to every test once the
+// package is linked into a build (with a _ import, like chans/std) or
+// loaded as a Go plugin (see LoadChanPlugin).  Registered types are
+// made by mother like the standard ones.
+//
+// RegisterChannel panics if the kind is empty, the maker is nil, or
+// the kind is already registered.
+func RegisterChannel(kind ChanKind, maker ChanMaker) {
+	if kind == "" {
+		panic("dsl: RegisterChannel with an empty kind")
+	}
+	if maker == nil {
+		panic("dsl: RegisterChannel with a nil maker for " + string(kind))
+	}
+	if _, have := TheChanRegistry[kind]; have {
+		panic("dsl: RegisterChannel called twice for " + string(kind))
+	}
+	TheChanRegistry.Register(NewCtx(nil), kind, maker)
+}
+
 // Chan can send and receive messages.
 type Chan interface {
 	// Open starts up the Chan.
@@ -89,3 +112,12 @@ type Chan interface {
 
 	DocSpec() *DocSpec
 }
+
+// Binder is implemented by a Chan that offers bindings (such as the
+// URL it's listening on) once it's open.
+//
+// When Mother makes a Binder, its bindings are added to the test's
+// bindings.
+type Binder interface {
+	Bindings(ctx *Ctx) map[string]interface{}
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+	"plugin"
+	"sort"
+)
+
+// LoadChanPlugin opens the Go plugin (built with "go build
+// -buildmode=plugin"), whose init functions register its Chan types
+// with RegisterChannel, and returns the kinds that it registered.
+//
+// The plugin must be built with the same version of Go and of this
+// module (and their dependencies) as the executable that loads it.
+// Go plugins are only supported on some platforms (Linux, FreeBSD, and
+// macOS).
+func LoadChanPlugin(ctx *Ctx, filename string) ([]ChanKind, error) {
+	before := make(map[ChanKind]bool, len(TheChanRegistry))
+	for kind := range TheChanRegistry {
+		before[kind] = true
+	}
+
+	if _, err := plugin.Open(filename); err != nil {
+		return nil, fmt.Errorf("failed to load channel plugin %s: %w", filename, err)
+	}
+
+	kinds := make([]ChanKind, 0, 1)
+	for kind := range TheChanRegistry {
+		if !before[kind] {
+			kinds = append(kinds, kind)
+		}
+	}
+	if len(kinds) == 0 {
+		return nil, fmt.Errorf("channel plugin %s didn't register any channel types", filename)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+
+	ctx.Logdf("Loaded channel types %v from %s", kinds, filename)
+
+	return kinds, nil
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRegisterChannel(t *testing.T) {
+	const kind = "registered-mock"
+
+	RegisterChannel(kind, NewMockChan)
+	defer delete(TheChanRegistry, kind)
+
+	if _, have := TheChanRegistry[kind]; !have {
+		t.Fatalf("%s isn't registered", kind)
+	}
+
+	panics := func(name string, kind ChanKind, maker ChanMaker) {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("%s didn't panic", name)
+			}
+		}()
+		RegisterChannel(kind, maker)
+	}
+
+	panics("twice", kind, NewMockChan)
+	panics("empty kind", "", NewMockChan)
+	panics("nil maker", "nil-maker", nil)
+}
+
+func TestLoadChanPluginMissing(t *testing.T) {
+	if _, err := LoadChanPlugin(NewCtx(nil), filepath.Join(t.TempDir(), "missing.so")); err == nil {
+		t.Fatal("expected an error for a missing plugin")
+	}
+}
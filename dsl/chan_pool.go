@@ -0,0 +1,402 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"strings"
+	"sync"
+)
+
+// PooledChanKinds are the kinds of Chans whose connections a
+// ChanPool shares.
+//
+// A shared connection's messages are routed to the tests by their
+// topics, so a kind should only be pooled when each message it
+// receives has the topic of the subscription that it matches.
+var PooledChanKinds = map[ChanKind]bool{}
+
+// ChanPool shares the connections of Chans (with PooledChanKinds)
+// among the tests that make Chans with the same kind and options.
+//
+// Each test gets its own Chan, which only receives the messages for
+// its own subscriptions, but those Chans share one underlying Chan
+// (connection) until the pool is closed.
+type ChanPool struct {
+	sync.Mutex
+
+	ctx   *Ctx
+	conns map[string]*pooledConn
+}
+
+// NewChanPool makes a ChanPool that opens and closes the underlying
+// Chans with the given Ctx.
+func NewChanPool(ctx *Ctx) *ChanPool {
+	return &ChanPool{
+		ctx:   ctx,
+		conns: make(map[string]*pooledConn),
+	}
+}
+
+// Len returns the number of underlying Chans.
+func (p *ChanPool) Len() int {
+	p.Lock()
+	defer p.Unlock()
+
+	return len(p.conns)
+}
+
+// Close closes all of the underlying Chans.
+//
+// The pool can be used again afterwards, and it will open new
+// underlying Chans.
+func (p *ChanPool) Close() error {
+	p.Lock()
+	conns := p.conns
+	p.conns = make(map[string]*pooledConn)
+	p.Unlock()
+
+	var first error
+	for _, c := range conns {
+		if err := c.shutdown(p.ctx); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// Chan returns a Chan of the given kind and (substituted) options,
+// using the maker only if the pool doesn't already have an
+// underlying Chan for them.
+func (p *ChanPool) Chan(ctx *Ctx, kind ChanKind, opts interface{}, maker ChanMaker) (Chan, error) {
+	key := string(kind) + " " + JSON(opts)
+
+	p.Lock()
+	defer p.Unlock()
+
+	c, have := p.conns[key]
+	if !have {
+		under, err := maker(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		c = &pooledConn{
+			pool:  p,
+			key:   key,
+			under: under,
+			subs:  make(map[string]bool),
+			views: make(map[*pooledChan]bool),
+		}
+		p.conns[key] = c
+	}
+
+	return &pooledChan{
+		pool: p,
+		key:  key,
+		kind: kind,
+		conn: c,
+		c:    make(chan Msg, DefaultChanBufferSize),
+	}, nil
+}
+
+// conn returns the underlying connection for the key, which is
+// replaced if it was killed or the pool was closed.
+func (p *ChanPool) conn(key string, old *pooledConn) *pooledConn {
+	p.Lock()
+	defer p.Unlock()
+
+	if c, have := p.conns[key]; have {
+		return c
+	}
+
+	c := &pooledConn{
+		pool:  p,
+		key:   key,
+		under: old.under,
+		subs:  make(map[string]bool),
+		views: make(map[*pooledChan]bool),
+	}
+	p.conns[key] = c
+
+	return c
+}
+
+// remove forgets the underlying connection.
+func (p *ChanPool) remove(c *pooledConn) {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.conns[c.key] == c {
+		delete(p.conns, c.key)
+	}
+}
+
+// pooledConn is an underlying Chan shared by pooledChans.
+type pooledConn struct {
+	sync.Mutex
+
+	pool  *ChanPool
+	key   string
+	under Chan
+
+	open bool
+	done chan struct{}
+
+	// subs are the topics that the underlying Chan has
+	// subscribed to.
+	subs map[string]bool
+
+	views map[*pooledChan]bool
+}
+
+// attach opens the underlying Chan (if necessary) for the view.
+func (c *pooledConn) attach(ctx *Ctx, v *pooledChan) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if !c.open {
+		if err := c.under.Open(c.pool.ctx); err != nil {
+			return err
+		}
+		c.open = true
+		c.done = make(chan struct{})
+		go c.route(c.under.Recv(c.pool.ctx), c.done)
+	} else {
+		ctx.Logf("reusing pooled %s connection", c.under.Kind())
+	}
+
+	c.views[v] = true
+
+	return nil
+}
+
+// detach stops routing messages to the view.
+func (c *pooledConn) detach(v *pooledChan) {
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.views, v)
+}
+
+// sub subscribes the underlying Chan to the topic (once).
+func (c *pooledConn) sub(ctx *Ctx, topic string) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.subs[topic] {
+		return nil
+	}
+	if err := c.under.Sub(ctx, topic); err != nil {
+		return err
+	}
+	c.subs[topic] = true
+
+	return nil
+}
+
+// route gives each message that the underlying Chan receives to the
+// views with a matching subscription.
+func (c *pooledConn) route(in chan Msg, done chan struct{}) {
+	ctx := c.pool.ctx
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		case m, ok := <-in:
+			if !ok {
+				return
+			}
+			c.Lock()
+			for v := range c.views {
+				if v.subscribed(m.Topic) {
+					v.deliver(ctx, m)
+				}
+			}
+			c.Unlock()
+		}
+	}
+}
+
+// shutdown closes the underlying Chan.
+func (c *pooledConn) shutdown(ctx *Ctx) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if !c.open {
+		return nil
+	}
+	c.open = false
+	c.subs = make(map[string]bool)
+	close(c.done)
+
+	return c.under.Close(ctx)
+}
+
+// pooledChan is a test's Chan that shares an underlying Chan.
+type pooledChan struct {
+	sync.Mutex
+
+	pool *ChanPool
+	key  string
+	kind ChanKind
+	conn *pooledConn
+
+	topics []string
+	c      chan Msg
+}
+
+func (v *pooledChan) DocSpec() *DocSpec {
+	return v.conn.under.DocSpec()
+}
+
+func (v *pooledChan) Kind() ChanKind {
+	return v.kind
+}
+
+func (v *pooledChan) Open(ctx *Ctx) error {
+	v.Lock()
+	v.conn = v.pool.conn(v.key, v.conn)
+	conn := v.conn
+	topics := v.topics
+	v.Unlock()
+
+	if err := conn.attach(ctx, v); err != nil {
+		return err
+	}
+
+	// After a Kill, the new connection needs the subscriptions.
+	for _, topic := range topics {
+		if err := conn.sub(ctx, topic); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close only stops this Chan from receiving messages.  The
+// underlying Chan stays open until the ChanPool is closed.
+func (v *pooledChan) Close(ctx *Ctx) error {
+	v.connection().detach(v)
+	return nil
+}
+
+// Kill kills the underlying Chan, which the other tests sharing it
+// will also notice.  The ChanPool makes a new underlying Chan for
+// the next Open.
+func (v *pooledChan) Kill(ctx *Ctx) error {
+	conn := v.connection()
+	v.pool.remove(conn)
+
+	conn.Lock()
+	conn.open = false
+	conn.subs = make(map[string]bool)
+	if conn.done != nil {
+		close(conn.done)
+		conn.done = nil
+	}
+	conn.Unlock()
+
+	return conn.under.Kill(ctx)
+}
+
+func (v *pooledChan) Sub(ctx *Ctx, topic string) error {
+	if err := v.connection().sub(ctx, topic); err != nil {
+		return err
+	}
+
+	v.Lock()
+	v.topics = append(v.topics, topic)
+	v.Unlock()
+
+	return nil
+}
+
+func (v *pooledChan) Recv(ctx *Ctx) chan Msg {
+	return v.c
+}
+
+func (v *pooledChan) Pub(ctx *Ctx, m Msg) error {
+	return v.connection().under.Pub(ctx, m)
+}
+
+func (v *pooledChan) To(ctx *Ctx, m Msg) error {
+	v.deliver(ctx, m)
+	return nil
+}
+
+func (v *pooledChan) connection() *pooledConn {
+	v.Lock()
+	defer v.Unlock()
+
+	return v.conn
+}
+
+// subscribed reports whether one of this Chan's subscriptions
+// matches the topic.
+func (v *pooledChan) subscribed(topic string) bool {
+	v.Lock()
+	defer v.Unlock()
+
+	for _, filter := range v.topics {
+		if TopicMatches(filter, topic) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deliver queues the message for Recv, dropping it if the queue is
+// full.
+func (v *pooledChan) deliver(ctx *Ctx, m Msg) {
+	select {
+	case v.c <- m:
+	default:
+		ctx.Warnf("pooled %s chan queue is full; dropping a message on %s", v.kind, m.Topic)
+	}
+}
+
+// TopicMatches reports whether the topic matches the filter, which
+// can have MQTT wildcards ("+" for one level and a final "#" for
+// any levels).
+func TopicMatches(filter, topic string) bool {
+	if filter == topic {
+		return true
+	}
+
+	var (
+		fs = strings.Split(filter, "/")
+		ts = strings.Split(topic, "/")
+	)
+	for i, f := range fs {
+		switch {
+		case f == "#" && i == len(fs)-1:
+			return true
+		case len(ts) <= i:
+			return false
+		case f != "+" && f != ts[i]:
+			return false
+		}
+	}
+
+	return len(fs) == len(ts)
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"testing"
+	"time"
+)
+
+// countingChan is a MockChan that counts its opens and closes.
+type countingChan struct {
+	*MockChan
+	opens, closes int
+}
+
+func (c *countingChan) Open(ctx *Ctx) error {
+	c.opens++
+	return nil
+}
+
+func (c *countingChan) Close(ctx *Ctx) error {
+	c.closes++
+	return nil
+}
+
+func TestChanPool(t *testing.T) {
+	ctx := NewCtx(nil)
+
+	var made []*countingChan
+	maker := func(ctx *Ctx, def interface{}) (Chan, error) {
+		c, err := NewMockChan(ctx, def)
+		if err != nil {
+			return nil, err
+		}
+		cc := &countingChan{MockChan: c.(*MockChan)}
+		made = append(made, cc)
+		return cc, nil
+	}
+
+	pool := NewChanPool(ctx)
+	opts := map[string]interface{}{"broker": "tcp://localhost:1883"}
+
+	open := func() Chan {
+		c, err := pool.Chan(ctx, "mock", opts, maker)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = c.Open(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	a, b := open(), open()
+	if len(made) != 1 || made[0].opens != 1 || pool.Len() != 1 {
+		t.Fatalf("made %d chans with %d opens", len(made), made[0].opens)
+	}
+
+	if err := a.Sub(ctx, "orders/+"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Sub(ctx, "alerts/#"); err != nil {
+		t.Fatal(err)
+	}
+
+	recv := func(c Chan) string {
+		select {
+		case m := <-c.Recv(ctx):
+			return m.Topic
+		case <-time.After(time.Second):
+			return ""
+		}
+	}
+
+	// Each test only receives the messages for its own
+	// subscriptions, even when another test published them.
+	if err := b.Pub(ctx, Msg{Topic: "orders/1", Payload: "{}"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Pub(ctx, Msg{Topic: "alerts/fire/big", Payload: "{}"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := recv(a); got != "orders/1" {
+		t.Fatalf("a received %q", got)
+	}
+	if got := recv(b); got != "alerts/fire/big" {
+		t.Fatalf("b received %q", got)
+	}
+
+	// Closing a test's Chan leaves the connection open.
+	if err := a.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if made[0].closes != 0 {
+		t.Fatal("closed the shared connection")
+	}
+
+	if err := pool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if made[0].closes != 1 || pool.Len() != 0 {
+		t.Fatalf("closed %d times", made[0].closes)
+	}
+
+	// The pool makes a new connection after it's closed.
+	open()
+	if len(made) != 2 {
+		t.Fatalf("made %d chans", len(made))
+	}
+	pool.Close()
+}
+
+func TestTopicMatches(t *testing.T) {
+	for _, c := range []struct {
+		filter, topic string
+		want          bool
+	}{
+		{"a/b", "a/b", true},
+		{"a/b", "a/c", false},
+		{"a/+", "a/b", true},
+		{"a/+", "a/b/c", false},
+		{"a/#", "a/b/c", true},
+		{"#", "a", true},
+		{"a/+/c", "a/b/c", true},
+		{"a/b/c", "a/b", false},
+	} {
+		if got := TopicMatches(c.filter, c.topic); got != c.want {
+			t.Errorf("TopicMatches(%q, %q) = %v", c.filter, c.topic, got)
+		}
+	}
+}
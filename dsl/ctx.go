@@ -48,6 +48,16 @@ type Ctx struct {
 	Dir         string
 	LogLevel    string
 
+	// Fetcher fetches (and caches) remote includes.
+	Fetcher *IncludeFetcher
+
+	// IncludeCache, when not nil, caches parsed includes.
+	IncludeCache *IncludeCache
+
+	// TraceIDs, when not nil, generates the trace IDs of tests
+	// (see NewTraceID).
+	TraceIDs func() string
+
 	*Redactions
 }
 
@@ -59,19 +69,37 @@ func NewCtx(ctx context.Context) *Ctx {
 
 	// Make default redactions
 	redactions := NewRedactions()
-
-	// If the context was a dsl.Ctx then use the redactions from the original context
+	logger := DefaultLogger
+	fetcher := NewIncludeFetcher(DefaultIncludeTimeout, "")
+	var (
+		cache    *IncludeCache
+		traceIDs func() string
+	)
+
+	// If the context was a dsl.Ctx then use the redactions,
+	// logger, fetcher, and include cache from the original context
 	if dslCtx, ok := ctx.(*Ctx); ok {
 		redactions = dslCtx.Redactions
+		if dslCtx.Logger != nil {
+			logger = dslCtx.Logger
+		}
+		if dslCtx.Fetcher != nil {
+			fetcher = dslCtx.Fetcher
+		}
+		cache = dslCtx.IncludeCache
+		traceIDs = dslCtx.TraceIDs
 	}
 
 	return &Ctx{
-		Context:     ctx,
-		Logger:      DefaultLogger,
-		LogLevel:    DefaultLogLevel,
-		IncludeDirs: make([]string, 0, 1),
-		Dir:         ".",
-		Redactions:  redactions,
+		Context:      ctx,
+		Logger:       logger,
+		LogLevel:     DefaultLogLevel,
+		IncludeDirs:  make([]string, 0, 1),
+		Dir:          ".",
+		Fetcher:      fetcher,
+		IncludeCache: cache,
+		TraceIDs:     traceIDs,
+		Redactions:   redactions,
 	}
 }
 
@@ -79,12 +107,15 @@ func NewCtx(ctx context.Context) *Ctx {
 func (c *Ctx) WithCancel() (*Ctx, func()) {
 	ctx, cancel := context.WithCancel(c.Context)
 	return &Ctx{
-		Context:     ctx,
-		Logger:      DefaultLogger,
-		LogLevel:    c.LogLevel,
-		IncludeDirs: c.IncludeDirs,
-		Dir:         c.Dir,
-		Redactions:  c.Redactions, // not copying
+		Context:      ctx,
+		Logger:       c.Logger,
+		LogLevel:     c.LogLevel,
+		IncludeDirs:  c.IncludeDirs,
+		Dir:          c.Dir,
+		Fetcher:      c.Fetcher,
+		IncludeCache: c.IncludeCache,
+		TraceIDs:     c.TraceIDs,
+		Redactions:   c.Redactions, // not copying
 	}, cancel
 }
 
@@ -92,12 +123,15 @@ func (c *Ctx) WithCancel() (*Ctx, func()) {
 func (c *Ctx) WithTimeout(d time.Duration) (*Ctx, func()) {
 	ctx, cancel := context.WithTimeout(c.Context, d)
 	return &Ctx{
-		Context:     ctx,
-		Logger:      DefaultLogger,
-		LogLevel:    c.LogLevel,
-		IncludeDirs: c.IncludeDirs,
-		Dir:         c.Dir,
-		Redactions:  c.Redactions, // not copying
+		Context:      ctx,
+		Logger:       c.Logger,
+		LogLevel:     c.LogLevel,
+		IncludeDirs:  c.IncludeDirs,
+		Dir:          c.Dir,
+		Fetcher:      c.Fetcher,
+		IncludeCache: c.IncludeCache,
+		TraceIDs:     c.TraceIDs,
+		Redactions:   c.Redactions, // not copying
 	}, cancel
 }
 
@@ -145,7 +179,7 @@ func (c *Ctx) Indf(format string, args ...interface{}) {
 	switch c.LogLevel {
 	case "none", "NONE":
 	default:
-		c.Redactf("| "+format, args...)
+		c.levelf("info", "| ", format, args...)
 	}
 }
 
@@ -155,13 +189,13 @@ func (c *Ctx) Indf(format string, args ...interface{}) {
 func (c *Ctx) Inddf(format string, args ...interface{}) {
 	switch c.LogLevel {
 	case "debug", "DEBUG":
-		c.Redactf("| "+format, args...)
+		c.levelf("debug", "| ", format, args...)
 	}
 }
 
 // Warnf emits a log  with a '!' prefix.
 func (c *Ctx) Warnf(format string, args ...interface{}) {
-	c.Redactf("! "+format, args...)
+	c.levelf("warn", "! ", format, args...)
 }
 
 // Logf emits a log line starting with a '>' when ctx.LogLevel isn't 'none'.
@@ -169,7 +203,7 @@ func (c *Ctx) Logf(format string, args ...interface{}) {
 	switch c.LogLevel {
 	case "none", "NONE":
 	default:
-		c.Redactf("> "+format, args...)
+		c.levelf("info", "> ", format, args...)
 	}
 }
 
@@ -179,8 +213,17 @@ func (c *Ctx) Logf(format string, args ...interface{}) {
 func (c *Ctx) Logdf(format string, args ...interface{}) {
 	switch c.LogLevel {
 	case "debug", "DEBUG":
-		c.Redactf("> "+format, args...)
+		c.levelf("debug", "> ", format, args...)
+	}
+}
+
+// levelf logs the redacted message with the prefix.
+func (c *Ctx) levelf(level string, prefix string, format string, args ...interface{}) {
+	if ll, is := c.Logger.(LevelLogger); is {
+		ll.Log(level, prefix, c.Redactions.Redactf(format, args...))
+		return
 	}
+	c.Redactf(prefix+format, args...)
 }
 
 // Logger is an interface that allows for pluggable loggers.
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// MsgHistory remembers the last messages received on each channel
+// of a test, so that a recv that fails can report the messages that
+// did arrive (the near misses).
+type MsgHistory struct {
+	sync.Mutex
+
+	// N is the number of messages to remember per channel.
+	N int
+
+	msgs map[Chan][]Msg
+
+	// recving is the channel of the last recv.
+	recving Chan
+}
+
+// NewMsgHistory makes a MsgHistory that remembers the last n messages
+// received on each channel.
+func NewMsgHistory(n int) *MsgHistory {
+	return &MsgHistory{
+		N:    n,
+		msgs: make(map[Chan][]Msg),
+	}
+}
+
+// recv notes that a recv is receiving from the channel.
+func (h *MsgHistory) recv(c Chan) {
+	if h == nil {
+		return
+	}
+	h.Lock()
+	h.recving = c
+	h.Unlock()
+}
+
+// add remembers a message received on the channel, forgetting the
+// oldest message if there are already N.
+func (h *MsgHistory) add(c Chan, m Msg) {
+	if h == nil || h.N <= 0 {
+		return
+	}
+	h.Lock()
+	msgs := append(h.msgs[c], m)
+	if h.N < len(msgs) {
+		msgs = msgs[len(msgs)-h.N:]
+	}
+	h.msgs[c] = msgs
+	h.Unlock()
+}
+
+// Last returns the remembered messages (oldest first) of the
+// channel.
+func (h *MsgHistory) Last(c Chan) []Msg {
+	if h == nil {
+		return nil
+	}
+	h.Lock()
+	defer h.Unlock()
+	return append([]Msg{}, h.msgs[c]...)
+}
+
+// report describes the (redacted) messages received on the channel
+// of the last recv.
+func (h *MsgHistory) report(ctx *Ctx) string {
+	if h == nil || h.N <= 0 {
+		return ""
+	}
+
+	h.Lock()
+	c := h.recving
+	h.Unlock()
+
+	msgs := h.Last(c)
+	if len(msgs) == 0 {
+		return "; no messages were received on the channel"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("; the last %d messages received on the channel:", len(msgs)))
+	for i, m := range msgs {
+		line := fmt.Sprintf("\n  %d. topic '%s': %s", i+1, m.Topic, m.Payload)
+		if ctx.Redactions != nil {
+			line = ctx.Redactions.RedactAll(line)
+		}
+		sb.WriteString(line)
+	}
+
+	return sb.String()
+}
@@ -19,61 +19,223 @@
 package dsl
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
 // FindInclude searches the include directories for the file
+//
+// A filename that's an http(s) URL is fetched with the ctx.Fetcher
+// instead.
+//
+// When the file can't be found, the error is an *IncludeError with
+// the paths that were tried.
 func FindInclude(ctx *Ctx, filename string) ([]byte, error) {
+	bs, _, err := findInclude(ctx, filename)
+	return bs, err
+}
+
+// findInclude is FindInclude that also returns the paths that were
+// tried (with the path that was read last).
+func findInclude(ctx *Ctx, filename string) ([]byte, []IncludeCandidate, error) {
+	if IsRemoteInclude(filename) {
+		bs, err := fetchInclude(ctx, filename)
+		if err != nil {
+			return nil, nil, &IncludeError{
+				Filename: filename,
+				Err:      err,
+			}
+		}
+		return bs, nil, nil
+	}
+
+	path, _, tried, err := resolveInclude(ctx, filename)
+	if err != nil {
+		return nil, tried, err
+	}
+
+	bs, err := readInclude(filename, path, tried)
+	if err != nil {
+		return nil, tried, err
+	}
+
+	if path != filename {
+		ctx.Logf("YAML including %s", path) // ToDo: Logdf
+	}
+
+	return bs, tried, nil
+}
+
+// readInclude reads the path that resolveInclude found for the
+// filename.
+func readInclude(filename, path string, tried []IncludeCandidate) ([]byte, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, candidateFailed(filename, tried, fmt.Errorf("failed to read: %w", err))
+	}
+	return bs, nil
+}
+
+// candidateFailed makes an IncludeError for a filename whose last
+// tried path exists but couldn't be included.  A remote include has
+// no tried paths.
+func candidateFailed(filename string, tried []IncludeCandidate, err error) error {
+	if 0 < len(tried) {
+		tried[len(tried)-1].Err = err
+	}
+	return &IncludeError{
+		Filename:   filename,
+		Candidates: tried,
+		Err:        err,
+	}
+}
+
+// fetchInclude fetches the URL with the ctx.Fetcher.
+func fetchInclude(ctx *Ctx, url string) ([]byte, error) {
+	if ctx.Fetcher == nil {
+		ctx.Fetcher = NewIncludeFetcher(DefaultIncludeTimeout, "")
+	}
+	bs, err := ctx.Fetcher.Fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	ctx.Logf("YAML including %s", url)
+	return bs, nil
+}
+
+// resolveInclude finds the path of the file in the include
+// directories.
+//
+// The paths that were tried are returned in order, and the last one
+// is the path that was found.
+func resolveInclude(ctx *Ctx, filename string) (string, os.FileInfo, []IncludeCandidate, error) {
 	dirs := ctx.IncludeDirs
 	if len(dirs) == 0 {
 		// ToDo: To dangerous?
 		dirs = []string{"."}
 	}
 
+	var paths []string
 	if strings.HasPrefix(filename, "/") {
-		bs, err := ioutil.ReadFile(filename)
-		return bs, err
+		paths = []string{filename}
+	} else {
+		for _, dir := range dirs {
+			paths = append(paths, dir+"/"+filename)
+		}
 	}
 
-	for _, dir := range dirs {
-		path := dir + "/" + filename
-		bs, err := ioutil.ReadFile(path)
+	tried := make([]IncludeCandidate, 0, len(paths))
+	for _, path := range paths {
+		fi, err := os.Stat(path)
 		if err != nil {
-			if err == os.ErrNotExist {
-				continue
-			}
-			if _, is := err.(*os.PathError); is {
-				continue
+			if _, is := err.(*os.PathError); !is {
+				return "", nil, tried, err
 			}
-			return nil, err
+			tried = append(tried, IncludeCandidate{
+				Path: path,
+			})
+			continue
+		}
+		tried = append(tried, IncludeCandidate{
+			Path:   path,
+			Exists: true,
+		})
+		if fi.IsDir() {
+			tried[len(tried)-1].Err = errors.New("is a directory")
+			continue
 		}
 
-		ctx.Logf("YAML including %s", path) // ToDo: Logdf
-		return bs, nil
+		return path, fi, tried, nil
 	}
 
-	return nil, &os.PathError{
-		Op:   "find",
-		Path: filename,
-		Err:  fmt.Errorf("%s: %v", os.ErrNotExist, dirs),
+	return "", nil, tried, &IncludeError{
+		Filename:   filename,
+		Candidates: tried,
+		Err:        os.ErrNotExist,
 	}
 }
 
 // ReadIncluded is a utility function that's convenient for Include().
+//
+// With a ctx.IncludeCache, each file is only read and parsed once
+// (unless it's modified).
 func ReadIncluded(ctx *Ctx, filename string) (interface{}, error) {
+	if ctx.IncludeCache == nil {
+		return readIncluded(ctx, filename)
+	}
+
+	if IsRemoteInclude(filename) {
+		key := includeKey{
+			path: filename,
+		}
+		if x, have := ctx.IncludeCache.get(key); have {
+			ctx.Logdf("YAML including %s (cached)", key.path)
+			return x, nil
+		}
+
+		x, err := readIncluded(ctx, filename)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx.IncludeCache.put(key, x)
+
+		return x, nil
+	}
+
+	path, fi, tried, err := resolveInclude(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	key := includeKey{
+		modTime: fi.ModTime(),
+	}
+	if key.path, err = filepath.Abs(path); err != nil {
+		return nil, err
+	}
+
+	if x, have := ctx.IncludeCache.get(key); have {
+		ctx.Logdf("YAML including %s (cached)", key.path)
+		return x, nil
+	}
+
+	bs, err := readInclude(filename, key.path, tried)
+	if err != nil {
+		return nil, err
+	}
+	ctx.Logf("YAML including %s", key.path)
+
+	x, err := parseIncluded(filename, bs, tried)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.IncludeCache.put(key, x)
+
+	return x, nil
+}
+
+func readIncluded(ctx *Ctx, filename string) (interface{}, error) {
 	// ToDo: Reconsider the following line.
-	bs, err := FindInclude(ctx, filename)
+	bs, tried, err := findInclude(ctx, filename)
 	if err != nil {
 		return nil, err
 	}
+	return parseIncluded(filename, bs, tried)
+}
+
+// parseIncluded parses the YAML that was read for the filename.
+func parseIncluded(filename string, bs []byte, tried []IncludeCandidate) (interface{}, error) {
 	var x interface{}
 	if err := yaml.Unmarshal(bs, &x); err != nil {
-		return nil, err
+		return nil, candidateFailed(filename, tried, fmt.Errorf("failed to parse: %w", withAnchorHint(err)))
 	}
 	return x, nil
 }
@@ -90,12 +252,12 @@ func IncludeMap(ctx *Ctx, k string, v interface{}, at []string) (map[string]inte
 
 	y, err := ReadIncluded(ctx, filename)
 	if err != nil {
-		return nil, err
+		return nil, withDirective(err, k+": "+filename)
 	}
 
 	z, err := Include(ctx, y, append(at, k))
 	if err != nil {
-		return nil, err
+		return nil, includedBy(err, filename)
 	}
 
 	m0, ok := z.(map[string]interface{})
@@ -126,9 +288,13 @@ func Include(ctx *Ctx, x interface{}, at []string) (interface{}, error) {
 			ctx.Logf("including value %s at %v", filename, at)
 			y, err := ReadIncluded(ctx, filename)
 			if err != nil {
-				return nil, err
+				return nil, withDirective(err, s)
+			}
+			z, err := Include(ctx, y, at)
+			if err != nil {
+				return nil, includedBy(err, filename)
 			}
-			return Include(ctx, y, at)
+			return z, nil
 		}
 		return x, nil
 	case map[string]interface{}:
@@ -138,7 +304,7 @@ func Include(ctx *Ctx, x interface{}, at []string) (interface{}, error) {
 			case "include":
 				m0, err := IncludeMap(ctx, k, v, at)
 				if err != nil {
-					return nil, fmt.Errorf("failed to include map: %w", err)
+					return nil, includeMapFailed(err, "failed to include map")
 				}
 				for k0, v0 := range m0 {
 					m[k0] = v0
@@ -151,7 +317,7 @@ func Include(ctx *Ctx, x interface{}, at []string) (interface{}, error) {
 				for _, v := range vl {
 					m0, err := IncludeMap(ctx, k, v, at)
 					if err != nil {
-						return nil, fmt.Errorf("failed to include as map: %w", err)
+						return nil, includeMapFailed(err, "failed to include as map")
 					}
 					for k0, v0 := range m0 {
 						m[k0] = v0
@@ -177,6 +343,11 @@ func Include(ctx *Ctx, x interface{}, at []string) (interface{}, error) {
 			}
 			z, err := Include(ctx, y, at)
 			if err != nil {
+				var ie *IncludeError
+				if splicing && errors.As(err, &ie) && len(ie.Chain) == 0 {
+					// Report the directive as written.
+					ie.Directive = s
+				}
 				return nil, err
 			}
 			if !splicing {
@@ -197,13 +368,23 @@ func Include(ctx *Ctx, x interface{}, at []string) (interface{}, error) {
 	return x, nil
 }
 
+// includeMapFailed wraps the error from IncludeMap unless it's an
+// *IncludeError, which already says what failed (and which gets its
+// Chain as the error is returned).
+func includeMapFailed(err error, msg string) error {
+	if _, is := err.(*IncludeError); is {
+		return err
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
 // IncludeYAML surrounds Include() with YAML (un)marshaling.
 //
 // Intended to be used right after reading bytes that represent YAML.
 func IncludeYAML(ctx *Ctx, bs []byte) ([]byte, error) {
 	var x interface{}
 	if err := yaml.Unmarshal(bs, &x); err != nil {
-		return nil, err
+		return nil, withAnchorHint(err)
 	}
 	y, err := Include(ctx, x, []string{})
 	if err != nil {
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// IncludeCache holds the parsed YAML of included files, so that a
+// fragment that's included by many files is only read and parsed
+// once.
+//
+// Files are identified by their absolute path and modification time,
+// so the same include filename that resolves to different files (via
+// different IncludeDirs) isn't confused, and a modified file is read
+// again.  Remote includes are identified by their URL.
+type IncludeCache struct {
+	sync.Mutex
+	entries map[includeKey]interface{}
+}
+
+type includeKey struct {
+	path    string
+	modTime time.Time
+}
+
+// NewIncludeCache makes an empty IncludeCache.
+func NewIncludeCache() *IncludeCache {
+	return &IncludeCache{
+		entries: make(map[includeKey]interface{}),
+	}
+}
+
+func (c *IncludeCache) get(key includeKey) (interface{}, bool) {
+	c.Lock()
+	defer c.Unlock()
+	x, have := c.entries[key]
+	return x, have
+}
+
+func (c *IncludeCache) put(key includeKey, x interface{}) {
+	c.Lock()
+	defer c.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[includeKey]interface{})
+	}
+	c.entries[key] = x
+}
+
+// Len returns the number of cached includes.
+func (c *IncludeCache) Len() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.entries)
+}
+
+// Paths returns the (sorted) absolute paths of the cached includes
+// that are files.
+func (c *IncludeCache) Paths() []string {
+	c.Lock()
+	defer c.Unlock()
+
+	have := make(map[string]bool)
+	paths := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		if IsRemoteInclude(key.path) || have[key.path] {
+			continue
+		}
+		have[key.path] = true
+		paths = append(paths, key.path)
+	}
+	sort.Strings(paths)
+
+	return paths
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIncludeCache(t *testing.T) {
+	var (
+		cache = NewIncludeCache()
+		dir1  = t.TempDir()
+		dir2  = t.TempDir()
+		then  = time.Now().Add(-time.Hour).Truncate(time.Second)
+	)
+
+	write := func(dir, content string, modTime time.Time) {
+		filename := filepath.Join(dir, "common.yaml")
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filename, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	read := func(dirs ...string) interface{} {
+		ctx := NewCtx(nil)
+		ctx.IncludeCache = cache
+		ctx.IncludeDirs = dirs
+		x, err := ReadIncluded(ctx, "common.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return x.(map[string]interface{})["like"]
+	}
+
+	write(dir1, "like: tacos\n", then)
+	write(dir2, "like: queso\n", then)
+
+	if got := read(dir1); got != "tacos" {
+		t.Fatalf("got %v", got)
+	}
+
+	// Same path and modification time, so the cached value.
+	write(dir1, "like: chips\n", then)
+	if got := read(dir1); got != "tacos" {
+		t.Fatalf("got %v rather than the cached value", got)
+	}
+
+	// Another file with the same include filename.
+	if got := read(dir2, dir1); got != "queso" {
+		t.Fatalf("got %v from the wrong file", got)
+	}
+
+	// A modified file is read again.
+	write(dir1, "like: chips\n", then.Add(time.Minute))
+	if got := read(dir1); got != "chips" {
+		t.Fatalf("got %v rather than the modified value", got)
+	}
+
+	if n := cache.Len(); n != 3 {
+		t.Fatalf("%d cached includes", n)
+	}
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// IncludeCandidate is a path that was tried for an include.
+type IncludeCandidate struct {
+	Path string
+
+	// Exists reports whether there was something at the Path.
+	Exists bool
+
+	// Err, when not nil, is why the existing Path couldn't be
+	// included (for example because it failed to parse).
+	Err error
+}
+
+func (c IncludeCandidate) String() string {
+	switch {
+	case !c.Exists:
+		return c.Path + " (not found)"
+	case c.Err != nil:
+		return fmt.Sprintf("%s (exists but %s)", c.Path, c.Err)
+	default:
+		return c.Path
+	}
+}
+
+// IncludeError reports an include that failed along with the paths
+// that were tried.
+type IncludeError struct {
+	// Directive is the include directive (for example
+	// "include: common.yaml" or "#include<common.yaml>").
+	Directive string
+
+	// Filename is the file that was to be included.
+	Filename string
+
+	// Candidates are the paths that were tried, in order.
+	Candidates []IncludeCandidate
+
+	// Chain are the included files (outermost first) that led to
+	// the Directive.
+	Chain []string
+
+	Err error
+}
+
+func (e *IncludeError) Error() string {
+	var sb strings.Builder
+
+	sb.WriteString("failed to include ")
+	if e.Directive != "" {
+		sb.WriteString(fmt.Sprintf("%q", e.Directive))
+	} else {
+		sb.WriteString(e.Filename)
+	}
+
+	if 0 < len(e.Chain) {
+		sb.WriteString(" via ")
+		sb.WriteString(strings.Join(e.Chain, " -> "))
+	}
+
+	if len(e.Candidates) == 0 {
+		sb.WriteString(": ")
+		sb.WriteString(e.Err.Error())
+		return sb.String()
+	}
+
+	sb.WriteString("; tried ")
+	for i, c := range e.Candidates {
+		if 0 < i {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(c.String())
+	}
+
+	return sb.String()
+}
+
+func (e *IncludeError) Unwrap() error {
+	return e.Err
+}
+
+// withDirective sets the Directive of an IncludeError (if it doesn't
+// have one yet).
+func withDirective(err error, directive string) error {
+	var ie *IncludeError
+	if errors.As(err, &ie) && ie.Directive == "" {
+		ie.Directive = directive
+	}
+	return err
+}
+
+// includedBy notes that the failed include was in the given included
+// file.
+func includedBy(err error, filename string) error {
+	var ie *IncludeError
+	if errors.As(err, &ie) {
+		ie.Chain = append([]string{filename}, ie.Chain...)
+	}
+	return err
+}
+
+// unknownAnchor matches the YAML error for an alias whose anchor
+// isn't defined in the same document.
+var unknownAnchor = regexp.MustCompile(`unknown anchor '([^']*)' referenced`)
+
+// withAnchorHint explains an unknown anchor error.
+//
+// Each file is parsed on its own before includes are processed, so an
+// alias (including a '<<: *x' merge key) can't refer to an anchor in
+// another file.
+func withAnchorHint(err error) error {
+	m := unknownAnchor.FindStringSubmatch(err.Error())
+	if m == nil {
+		return err
+	}
+	return fmt.Errorf("%w (anchors don't cross includes, so define '&%s' in the same file or share the map with 'include:')", err, m[1])
+}
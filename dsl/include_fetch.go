@@ -0,0 +1,131 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultIncludeTimeout is the default timeout for fetching a remote
+// include.
+var DefaultIncludeTimeout = 30 * time.Second
+
+// IncludeFetcher fetches includes given as http(s) URLs.
+//
+// Fetched content is cached, so each URL is only fetched once by the
+// IncludeFetcher.
+type IncludeFetcher struct {
+	// Timeout is the maximum duration of each fetch.
+	Timeout time.Duration
+
+	// Header, when not empty, is a "Name: value" header (often
+	// an "Authorization" header) to add to each request.
+	Header string
+
+	// Client, when not nil, replaces http.DefaultClient.
+	Client *http.Client
+
+	sync.Mutex
+	cache map[string][]byte
+}
+
+// NewIncludeFetcher makes an IncludeFetcher with the given timeout
+// and header, which are described with the IncludeFetcher fields.
+func NewIncludeFetcher(timeout time.Duration, header string) *IncludeFetcher {
+	return &IncludeFetcher{
+		Timeout: timeout,
+		Header:  header,
+		cache:   make(map[string][]byte),
+	}
+}
+
+// IsRemoteInclude reports whether the include filename is an http(s)
+// URL.
+func IsRemoteInclude(filename string) bool {
+	return strings.HasPrefix(filename, "http://") || strings.HasPrefix(filename, "https://")
+}
+
+// Fetch returns the (possibly cached) content at the URL.
+func (f *IncludeFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	f.Lock()
+	bs, have := f.cache[url]
+	f.Unlock()
+	if have {
+		return bs, nil
+	}
+
+	bs, err := f.fetch(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch include %s: %w", url, err)
+	}
+
+	f.Lock()
+	if f.cache == nil {
+		f.cache = make(map[string][]byte)
+	}
+	f.cache[url] = bs
+	f.Unlock()
+
+	return bs, nil
+}
+
+func (f *IncludeFetcher) fetch(ctx context.Context, url string) ([]byte, error) {
+	timeout := f.Timeout
+	if timeout <= 0 {
+		timeout = DefaultIncludeTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.Header != "" {
+		parts := strings.SplitN(f.Header, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("bad include header (not 'Name: value')")
+		}
+		req.Header.Set(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	}
+
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %s", resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
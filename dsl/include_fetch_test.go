@@ -0,0 +1,81 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestIncludeFetcher(t *testing.T) {
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tacos" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/common.yaml":
+			fetches++
+			w.Write([]byte("like: tacos\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := NewCtx(nil)
+	ctx.Fetcher = NewIncludeFetcher(time.Second, "Authorization: Bearer tacos")
+
+	for i := 0; i < 2; i++ {
+		bs, err := IncludeYAML(ctx, []byte("include: "+srv.URL+"/common.yaml\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var x map[string]interface{}
+		if err := yaml.Unmarshal(bs, &x); err != nil {
+			t.Fatal(err)
+		}
+		if x["like"] != "tacos" {
+			t.Fatalf("unexpected %s", bs)
+		}
+	}
+
+	if fetches != 1 {
+		t.Fatalf("fetched %d times", fetches)
+	}
+
+	missing := srv.URL + "/missing.yaml"
+	if _, err := IncludeYAML(ctx, []byte("include: "+missing+"\n")); err == nil {
+		t.Fatal("expected an error")
+	} else if !strings.Contains(err.Error(), missing) || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ctx.Fetcher = NewIncludeFetcher(time.Second, "")
+	if _, err := IncludeYAML(ctx, []byte("include: "+srv.URL+"/common.yaml\n")); err == nil {
+		t.Fatal("expected an error without the header")
+	}
+}
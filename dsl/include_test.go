@@ -19,8 +19,11 @@
 package dsl
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"gopkg.in/yaml.v3"
@@ -74,3 +77,84 @@ func TestInclude(t *testing.T) {
 		t.Fatal("receive empty")
 	}
 }
+
+func TestIncludeErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"outer.yaml": "include: inner.yaml\n",
+		"inner.yaml": "x: '#include<bad.yaml>'\n",
+		"bad.yaml":   "a: [\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, cache := range []bool{false, true} {
+		ctx := NewCtx(nil)
+		ctx.IncludeDirs = []string{filepath.Join(dir, "missing"), dir}
+		if cache {
+			ctx.IncludeCache = NewIncludeCache()
+		}
+
+		_, err := IncludeYAML(ctx, []byte("include: outer.yaml\n"))
+		var ie *IncludeError
+		if !errors.As(err, &ie) {
+			t.Fatalf("expected an IncludeError rather than %v", err)
+		}
+		if ie.Directive != "#include<bad.yaml>" || strings.Join(ie.Chain, ",") != "outer.yaml,inner.yaml" {
+			t.Fatalf("unexpected directive %q or chain %v", ie.Directive, ie.Chain)
+		}
+		if len(ie.Candidates) != 2 || ie.Candidates[0].Exists || !ie.Candidates[1].Exists || ie.Candidates[1].Err == nil {
+			t.Fatalf("unexpected candidates %v", ie.Candidates)
+		}
+		if msg := err.Error(); !strings.Contains(msg, "via outer.yaml -> inner.yaml") || !strings.Contains(msg, "missing/bad.yaml (not found)") || !strings.Contains(msg, "exists but failed to parse") {
+			t.Fatal(msg)
+		}
+
+		_, err = IncludeYAML(ctx, []byte("include: nope.yaml\n"))
+		if !errors.As(err, &ie) || !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("expected a missing include rather than %v", err)
+		}
+		if ie.Directive != "include: nope.yaml" || len(ie.Chain) != 0 || len(ie.Candidates) != 2 || ie.Candidates[1].Exists {
+			t.Fatalf("unexpected error %#v", ie)
+		}
+	}
+}
+
+func TestIncludeAnchors(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"defaults.yaml": "defaults: &defaults\n  qos: 1\n",
+		"uses.yaml":     "pub:\n  <<: *defaults\n  topic: a\n",
+		"local.yaml":    "d: &d {qos: 1}\npub:\n  <<: *d\n  topic: a\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := NewCtx(nil)
+	ctx.IncludeDirs = []string{dir}
+
+	bs, err := IncludeYAML(ctx, []byte("x: '#include<local.yaml>'\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(bs), "qos: 1") {
+		t.Fatalf("merge key wasn't applied in %s", bs)
+	}
+
+	for _, src := range []string{
+		"includes: [defaults.yaml, uses.yaml]\n",
+		"include: defaults.yaml\npub:\n  <<: *defaults\n",
+	} {
+		_, err := IncludeYAML(ctx, []byte(src))
+		if err == nil {
+			t.Fatalf("expected an error for %q", src)
+		}
+		if msg := err.Error(); !strings.Contains(msg, "unknown anchor 'defaults'") || !strings.Contains(msg, "anchors don't cross includes") {
+			t.Fatalf("unexpected error for %q: %s", src, msg)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LevelLogger is a Logger that also wants the level ("debug",
+// "info", or "warn") of each message.
+//
+// The Ctx logging methods use Log rather than Printf when the Ctx's
+// Logger is a LevelLogger.  The prefix ("> ", "| ", or "! ") is what
+// the methods would otherwise write before the message.
+type LevelLogger interface {
+	Logger
+	Log(level string, prefix string, msg string)
+}
+
+// JSONLogger logs each message as a one-line JSON object with the
+// level, timestamp, test name (if any), and message.
+//
+// A JSONLogger is also an io.Writer, so it can be given to
+// log.SetOutput (along with log.SetFlags(0)) to convert lines logged
+// by the log package, which are logged at the "info" level.
+type JSONLogger struct {
+	// Out is where the JSON is written.  Defaults to os.Stderr.
+	Out io.Writer
+
+	// Test is the name of the test, if any, being logged.
+	Test string
+
+	mu *sync.Mutex
+}
+
+// NewJSONLogger makes a JSONLogger that writes to out.
+func NewJSONLogger(out io.Writer) *JSONLogger {
+	return &JSONLogger{
+		Out: out,
+		mu:  &sync.Mutex{},
+	}
+}
+
+// WithTest returns a JSONLogger, writing to the same Out, for the
+// given test.
+func (l *JSONLogger) WithTest(name string) *JSONLogger {
+	return &JSONLogger{
+		Out:  l.Out,
+		Test: name,
+		mu:   l.mu,
+	}
+}
+
+// jsonLogLine is what a JSONLogger writes.
+type jsonLogLine struct {
+	Level     string    `json:"level"`
+	Timestamp time.Time `json:"timestamp"`
+	Test      string    `json:"test,omitempty"`
+	Msg       string    `json:"msg"`
+}
+
+// Log writes the message (without the prefix) as JSON.
+func (l *JSONLogger) Log(level string, prefix string, msg string) {
+	js, err := json.Marshal(jsonLogLine{
+		Level:     level,
+		Timestamp: time.Now().UTC(),
+		Test:      l.Test,
+		Msg:       strings.TrimRight(msg, "\n"),
+	})
+	if err != nil {
+		// Shouldn't happen with just strings.
+		js = []byte(fmt.Sprintf(`{"level":"error","msg":%q}`, err.Error()))
+	}
+
+	out := l.Out
+	if out == nil {
+		out = os.Stderr
+	}
+
+	if l.mu != nil {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+	}
+
+	out.Write(append(js, '\n'))
+}
+
+// Printf logs at the "info" level.
+func (l *JSONLogger) Printf(format string, args ...interface{}) {
+	l.Log("info", "", fmt.Sprintf(format, args...))
+}
+
+// Write logs each line of p at the "info" level.
+func (l *JSONLogger) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		l.Log("info", "", line)
+	}
+	return len(p), nil
+}
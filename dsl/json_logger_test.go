@@ -0,0 +1,79 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONLogger(t *testing.T) {
+	var (
+		buf bytes.Buffer
+		ctx = NewCtx(context.Background())
+	)
+
+	ctx.Logger = NewJSONLogger(&buf).WithTest("queso")
+	ctx.LogLevel = "debug"
+	ctx.Redact = true
+	if err := ctx.AddRedaction("secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.Logf("the %s is out", "secret")
+
+	// A derived Ctx should have the same logger.
+	tctx, cancel := NewCtx(ctx).WithCancel()
+	defer cancel()
+	tctx.LogLevel = "debug"
+	tctx.Logdf("debugging")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines: %q", buf.String())
+	}
+
+	var got jsonLogLine
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Level != "info" || got.Test != "queso" || got.Msg != "the <redacted> is out" || got.Timestamp.IsZero() {
+		t.Fatalf("unexpected %s", lines[0])
+	}
+
+	if err := json.Unmarshal([]byte(lines[1]), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Level != "debug" || got.Msg != "debugging" {
+		t.Fatalf("unexpected %s", lines[1])
+	}
+}
+
+func TestJSONLoggerWrite(t *testing.T) {
+	var buf bytes.Buffer
+
+	NewJSONLogger(&buf).Write([]byte("one\ntwo\n"))
+
+	if n := strings.Count(buf.String(), `"level":"info"`); n != 2 {
+		t.Fatalf("expected 2 lines: %q", buf.String())
+	}
+}
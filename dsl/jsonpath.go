@@ -0,0 +1,252 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ohler55/ojg/jp"
+)
+
+// JSONPath is a (parsed) JSONPath expression.
+//
+// The syntax is the one supported by github.com/ohler55/ojg/jp,
+// which includes filters (like '[?(@.n > 1)]'), unions, and slices
+// in addition to the usual child, wildcard, and recursive descent
+// steps.  The expression must start with '$'.
+type JSONPath struct {
+	src  string
+	expr jp.Expr
+}
+
+func (p *JSONPath) String() string {
+	return p.src
+}
+
+// ParseJSONPath parses the JSONPath expression.
+func ParseJSONPath(s string) (*JSONPath, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "$") {
+		return nil, fmt.Errorf("bad JSONPath '%s': should start with '$'", s)
+	}
+	x, err := jp.ParseString(s)
+	if err != nil {
+		return nil, fmt.Errorf("bad JSONPath '%s': %w", s, err)
+	}
+	return &JSONPath{
+		src:  s,
+		expr: x,
+	}, nil
+}
+
+// splitJSONPath returns the JSONPath at the start of the string and
+// the rest of the string, which starts at the first space or
+// operator character that isn't in brackets, parentheses, or quotes.
+func splitJSONPath(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	var (
+		depth int
+		quote byte
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case depth == 0 && strings.IndexByte(" \t\n=!<>~", c) >= 0:
+			return s[:i], s[i:]
+		}
+	}
+	return s, ""
+}
+
+// Eval returns the values that the JSONPath finds in the
+// (deserialized JSON) value.
+func (p *JSONPath) Eval(x interface{}) []interface{} {
+	return p.expr.Get(x)
+}
+
+// JSONPathAssertion is an assertion like
+//
+//	$.order.status == "shipped"
+//
+// The operators are ==, !=, <, <=, >, >=, and =~ (which matches a
+// string with a regular expression).  The right-hand side is a JSON
+// value (or a bare string).  An assertion without an operator checks
+// that the JSONPath finds something.
+//
+// When the JSONPath finds more than one value, the assertion holds
+// if any value satisfies it (except for !=, which holds if no value
+// is equal).  An assertion (even with !=) fails when the JSONPath
+// finds nothing.
+type JSONPathAssertion struct {
+	src  string
+	path *JSONPath
+	op   string
+	want interface{}
+	re   *regexp.Regexp
+}
+
+func (a *JSONPathAssertion) String() string {
+	return a.src
+}
+
+// ParseJSONPathAssertion parses an assertion.
+func ParseJSONPathAssertion(s string) (*JSONPathAssertion, error) {
+	src, rest := splitJSONPath(s)
+	p, err := ParseJSONPath(src)
+	if err != nil {
+		return nil, err
+	}
+	a := &JSONPathAssertion{
+		src:  strings.TrimSpace(s),
+		path: p,
+	}
+
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		return a, nil
+	}
+
+	for _, op := range []string{"==", "!=", "<=", ">=", "=~", "<", ">"} {
+		if strings.HasPrefix(rest, op) {
+			a.op = op
+			rest = strings.TrimSpace(rest[len(op):])
+			break
+		}
+	}
+	if a.op == "" {
+		return nil, fmt.Errorf("bad JSONPath assertion '%s': unknown operator at '%s'", s, rest)
+	}
+	if rest == "" {
+		return nil, fmt.Errorf("bad JSONPath assertion '%s': missing value", s)
+	}
+
+	if err := json.Unmarshal([]byte(rest), &a.want); err != nil {
+		a.want = rest
+	}
+
+	if a.op == "=~" {
+		pat, is := a.want.(string)
+		if !is {
+			return nil, fmt.Errorf("bad JSONPath assertion '%s': =~ needs a string", s)
+		}
+		if a.re, err = regexp.Compile(pat); err != nil {
+			return nil, fmt.Errorf("bad JSONPath assertion '%s': %w", s, err)
+		}
+	}
+
+	return a, nil
+}
+
+// holds checks the operator for one value.
+func (a *JSONPathAssertion) holds(x interface{}) bool {
+	switch a.op {
+	case "==", "!=":
+		return JSON(x) == JSON(a.want)
+	case "=~":
+		s, is := x.(string)
+		return is && a.re.MatchString(s)
+	}
+
+	var c int
+	switch vv := x.(type) {
+	case float64:
+		w, is := a.want.(float64)
+		if !is {
+			return false
+		}
+		switch {
+		case vv < w:
+			c = -1
+		case w < vv:
+			c = 1
+		}
+	case string:
+		w, is := a.want.(string)
+		if !is {
+			return false
+		}
+		c = strings.Compare(vv, w)
+	default:
+		return false
+	}
+
+	switch a.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return 0 < c
+	default: // ">="
+		return 0 <= c
+	}
+}
+
+// Check returns an error that reports the value found if the
+// assertion doesn't hold for the (deserialized JSON) value.
+func (a *JSONPathAssertion) Check(x interface{}) error {
+	xs := a.path.Eval(x)
+
+	found := func() string {
+		if len(xs) == 1 {
+			return JSON(xs[0])
+		}
+		return JSON(xs)
+	}
+
+	// Every assertion (including !=) needs something to check.
+	if len(xs) == 0 {
+		return fmt.Errorf("jsonpath %s found nothing", a.src)
+	}
+
+	if a.op == "" {
+		return nil
+	}
+
+	if a.op == "!=" {
+		for _, x := range xs {
+			if a.holds(x) {
+				return fmt.Errorf("jsonpath %s failed: found %s", a.src, found())
+			}
+		}
+		return nil
+	}
+
+	for _, x := range xs {
+		if a.holds(x) {
+			return nil
+		}
+	}
+	return fmt.Errorf("jsonpath %s failed: found %s", a.src, found())
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	doc := dejson(`{"order":{"id":7,"status":"shipped","items":[{"sku":"taco","n":2},{"sku":"queso","n":1}],"a.b":true}}`)
+
+	for src, want := range map[string]string{
+		`$`:                             JSON(doc),
+		`$.order.id`:                    `[7]`,
+		`$['order']["a.b"]`:             `[true]`,
+		`$.order.items[1].sku`:          `["queso"]`,
+		`$.order.items[-1].n`:           `[1]`,
+		`$.order.items[*].sku`:          `["taco","queso"]`,
+		`$..sku`:                        `["taco","queso"]`,
+		`$..[0].sku`:                    `["taco"]`,
+		`$.order.items[?(@.n > 1)].sku`: `["taco"]`,
+		`$.order.items[0:1].sku`:        `["taco"]`,
+		`$.order.nope`:                  `null`,
+		`$.order.items[9]`:              `null`,
+	} {
+		p, err := ParseJSONPath(src)
+		if err != nil {
+			t.Fatalf("%s: %s", src, err)
+		}
+		got := JSON(p.Eval(doc))
+		if src == "$" {
+			got = JSON(p.Eval(doc)[0])
+		}
+		if got != want {
+			t.Fatalf("%s: got %s (wanted %s)", src, got, want)
+		}
+	}
+
+	for _, src := range []string{`order`, `$.`, `$[1`, `$[x]`, `$.[1]`} {
+		if _, err := ParseJSONPath(src); err == nil {
+			t.Fatalf("%s: no error", src)
+		}
+	}
+}
+
+func TestJSONPathAssertion(t *testing.T) {
+	doc := dejson(`{"order":{"id":7,"status":"shipped","items":[{"sku":"taco"},{"sku":"queso"}]}}`)
+
+	for src, want := range map[string]string{
+		`$.order.status == "shipped"`:                   "",
+		`$.order.status == shipped`:                     "",
+		`$.order.status != "pending"`:                   "",
+		`$.order.id >= 7`:                               "",
+		`$.order.id < 7`:                                `found 7`,
+		`$.order.status =~ "^ship"`:                     "",
+		`$.order.items[*].sku == "queso"`:               "",
+		`$.order.items[*].sku != "queso"`:               `found ["taco","queso"]`,
+		`$.order.items[?(@.sku == 'taco')].sku == taco`: "",
+		`$.order.items[?(@.sku != 'taco')].sku`:         "",
+		`$.order.status == "delivered"`:                 `jsonpath $.order.status == "delivered" failed: found "shipped"`,
+		`$.order.id`:                                    "",
+		`$.order.nope`:                                  `found nothing`,
+		`$.order.nope == 1`:                             `found nothing`,
+		`$.order.nope != "x"`:                           `jsonpath $.order.nope != "x" found nothing`,
+	} {
+		a, err := ParseJSONPathAssertion(src)
+		if err != nil {
+			t.Fatalf("%s: %s", src, err)
+		}
+		err = a.Check(doc)
+		switch {
+		case want == "" && err != nil:
+			t.Fatalf("%s: %s", src, err)
+		case want != "" && (err == nil || !strings.Contains(err.Error(), want)):
+			t.Fatalf("%s: got %v (wanted %s)", src, err, want)
+		}
+	}
+
+	for _, src := range []string{`$.x ~ 1`, `$.x ==`, `$.x =~ 3`, `$.x =~ "("`, `$.x[?(@.y ==`} {
+		if _, err := ParseJSONPathAssertion(src); err == nil {
+			t.Fatalf("%s: no error", src)
+		}
+	}
+}
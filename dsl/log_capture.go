@@ -0,0 +1,95 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// LogCapture is a LevelLogger that remembers what's logged while
+// passing it along to another Logger.
+//
+// Everything remembered is redacted, even when redactions are
+// disabled for the log itself.
+type LogCapture struct {
+	// Logger gets everything that's logged.
+	Logger Logger
+
+	redactions *Redactions
+
+	sync.Mutex
+	stdout strings.Builder
+	stderr strings.Builder
+}
+
+// NewLogCapture makes a LogCapture that passes what's logged along
+// to the given Logger.
+func NewLogCapture(logger Logger, redactions *Redactions) *LogCapture {
+	return &LogCapture{
+		Logger:     logger,
+		redactions: redactions,
+	}
+}
+
+// Log remembers and then logs the message.
+//
+// Warnings are remembered as Stderr and everything else as Stdout.
+func (c *LogCapture) Log(level string, prefix string, msg string) {
+	line := strings.TrimRight(prefix+msg, "\n") + "\n"
+	if c.redactions != nil {
+		line = c.redactions.RedactAll(line)
+	}
+
+	c.Lock()
+	if level == "warn" {
+		c.stderr.WriteString(line)
+	} else {
+		c.stdout.WriteString(line)
+	}
+	c.Unlock()
+
+	switch l := c.Logger.(type) {
+	case nil:
+	case LevelLogger:
+		l.Log(level, prefix, msg)
+	default:
+		l.Printf("%s", prefix+msg)
+	}
+}
+
+// Printf remembers and then logs the message as Stdout.
+func (c *LogCapture) Printf(format string, args ...interface{}) {
+	c.Log("info", "", fmt.Sprintf(format, args...))
+}
+
+// Stdout returns what has been remembered as Stdout.
+func (c *LogCapture) Stdout() string {
+	c.Lock()
+	defer c.Unlock()
+	return c.stdout.String()
+}
+
+// Stderr returns what has been remembered as Stderr.
+func (c *LogCapture) Stderr() string {
+	c.Lock()
+	defer c.Unlock()
+	return c.stderr.String()
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestLogCapture(t *testing.T) {
+	var (
+		buf bytes.Buffer
+		ctx = NewCtx(context.Background())
+	)
+
+	if err := ctx.AddRedaction("secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	capture := NewLogCapture(NewJSONLogger(&buf), ctx.Redactions)
+	ctx.Logger = capture
+
+	ctx.Logf("the %s is out", "secret")
+	ctx.Warnf("careful")
+
+	if got, want := capture.Stdout(), "> the <redacted> is out\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	if got, want := capture.Stderr(), "! careful\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// Redactions are disabled, so the log itself isn't redacted.
+	if !strings.Contains(buf.String(), `"msg":"the secret is out"`) {
+		t.Fatalf("log is missing the message: %s", buf.String())
+	}
+}
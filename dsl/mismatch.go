@@ -0,0 +1,79 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Comcast/sheens/match"
+)
+
+// mismatch explains why a pattern didn't match a target by listing
+// the (top-most) properties that didn't match.  For example, an
+// HTTP response with the wrong status code gives "statuscode:
+// expected 200 got 503".
+//
+// Each property is considered by itself, so mismatch can return nil
+// even though the pattern doesn't match (due to inconsistent
+// bindings, say).
+func mismatch(pattern, target interface{}) error {
+	var fs failures
+	explain(&fs, "", Canon(pattern), target)
+	return fs.err()
+}
+
+func explain(fs *failures, path string, pattern, target interface{}) {
+	pm, isMap := pattern.(map[string]interface{})
+	tm, targetIsMap := target.(map[string]interface{})
+	if !isMap || !targetIsMap {
+		*fs = append(*fs, fmt.Errorf("%sexpected %s got %s", prefix(path), JSON(pattern), JSON(target)))
+		return
+	}
+
+	ps := make([]string, 0, len(pm))
+	for p := range pm {
+		ps = append(ps, p)
+	}
+	sort.Strings(ps)
+
+	for _, p := range ps {
+		bss, err := match.Match(map[string]interface{}{p: pm[p]}, tm, match.NewBindings())
+		if err != nil || 0 < len(bss) {
+			continue
+		}
+		at := p
+		if path != "" {
+			at = path + "." + p
+		}
+		x, have := tm[p]
+		if !have {
+			*fs = append(*fs, fmt.Errorf("%s: missing", at))
+			continue
+		}
+		explain(fs, at, pm[p], x)
+	}
+}
+
+func prefix(path string) string {
+	if path == "" {
+		return ""
+	}
+	return path + ": "
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMismatch(t *testing.T) {
+	response := `{"statuscode":503,"headers":{"Content-Type":["text/plain"]},"body":{"error":"busy"}}`
+	for _, c := range []struct {
+		pattern string
+		want    string
+	}{
+		{`{"statuscode":200}`, "statuscode: expected 200 got 503"},
+		{`{"statuscode":503,"headers":{"Content-Type":["application/json"]}}`, `headers.Content-Type: expected ["application/json"] got ["text/plain"]`},
+		{`{"statuscode":200,"body":{"error":"?e","retry":"?r"}}`, "body.retry: missing; statuscode: expected 200 got 503"},
+		{`{"statuscode":"?s","body":"?b"}`, ""},
+		{`"tacos"`, `expected "tacos" got {"body":{"error":"busy"},"headers":{"Content-Type":["text/plain"]},"statuscode":503}`},
+	} {
+		t.Run(c.pattern, func(t *testing.T) {
+			err := mismatch(dejson(c.pattern), dejson(response))
+			if c.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || err.Error() != c.want {
+				t.Fatalf("%v rather than %s", err, c.want)
+			}
+		})
+	}
+}
+
+func TestRecvMismatch(t *testing.T) {
+	ctx, s, tst := newTest(t)
+
+	p := &Phase{}
+	s.Phases["phase1"] = p
+
+	addMock(t, ctx, p)
+
+	p.AddStep(ctx, &Step{
+		Pub: &Pub{
+			Payload: `{"statuscode":503,"body":"busy"}`,
+		},
+	})
+
+	p.AddStep(ctx, &Step{
+		Recv: &Recv{
+			Pattern: dejson(`{"statuscode":200,"body":"?body"}`),
+			Timeout: 100 * time.Millisecond,
+		},
+	})
+
+	if err := tst.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := tst.Run(ctx)
+	if errs == nil {
+		t.Fatal("expected an error")
+	}
+	if msg, want := errs.Err.Error(), "; last statuscode: expected 200 got 503"; !strings.Contains(msg, want) {
+		t.Fatalf("%q doesn't contain %q", msg, want)
+	}
+}
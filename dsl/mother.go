@@ -45,6 +45,11 @@ type MotherMakeRequest struct {
 
 	// Config is the configuration (if any) for the requested channel.
 	Config interface{} `json:"config,omitempty"`
+
+	// Backoff, when not nil, is the specification for retrying
+	// to open the channel.  Defaults to the test's
+	// ConnectBackoff.
+	Backoff *Backoff `json:"backoff,omitempty"`
 }
 
 // MotherResponse is the structure of the generic response to a
@@ -156,10 +161,25 @@ func (c *Mother) Pub(ctx *Ctx, m Msg) error {
 		return punt(err)
 	}
 
-	if err := ch.Open(ctx); err != nil {
+	backoff := req.Make.Backoff
+	if backoff == nil {
+		backoff = c.t.ConnectBackoff
+	}
+
+	if err := backoff.Open(ctx, ch); err != nil {
 		return punt(err)
 	}
 
+	if b, is := ch.(Binder); is {
+		if c.t.Bindings == nil {
+			c.t.Bindings = make(Bindings)
+		}
+		for p, v := range b.Bindings(ctx) {
+			ctx.Indf("    Binding %s from chan '%s'", p, req.Make.Name)
+			c.t.Bindings[p] = v
+		}
+	}
+
 	resp.Success = true
 	c.t.Chans[req.Make.Name] = ch
 
@@ -55,3 +55,57 @@ func TestDocsMother(t *testing.T) {
 	}
 
 }
+
+// binderMock is a MockChan that's also a Binder.
+type binderMock struct {
+	*MockChan
+}
+
+func (c *binderMock) Bindings(ctx *Ctx) map[string]interface{} {
+	return map[string]interface{}{
+		"?!url": "http://localhost:40404",
+	}
+}
+
+func TestMotherBinder(t *testing.T) {
+	ctx, s, tst := newTest(t)
+
+	tst.Registry = ChanRegistry{
+		"binder": func(ctx *Ctx, opts interface{}) (Chan, error) {
+			c, err := NewMockChan(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return &binderMock{c.(*MockChan)}, nil
+		},
+	}
+
+	p := &Phase{}
+	s.Phases["phase1"] = p
+
+	p.AddStep(ctx, &Step{
+		Pub: &Pub{
+			Payload: dejson(`{"make":{"name":"b","type":"binder"}}`),
+		},
+	})
+	p.AddStep(ctx, &Step{
+		Recv: &Recv{
+			Chan:    "mother",
+			Pattern: dejson(`{"success":true}`),
+		},
+	})
+	p.AddStep(ctx, &Step{
+		Pub: &Pub{
+			Chan:    "b",
+			Payload: `{"hook":"{?!url}/hook"}`,
+		},
+	})
+	p.AddStep(ctx, &Step{
+		Recv: &Recv{
+			Chan:    "b",
+			Pattern: dejson(`{"hook":"http://localhost:40404/hook"}`),
+		},
+	})
+
+	run(t, ctx, tst)
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DefaultPreflightTimeout is the maximum duration of each channel
+// check by Test.Preflight.
+var DefaultPreflightTimeout = 10 * time.Second
+
+// Pinger is implemented by a Chan that can check that its endpoint
+// (a broker, a database, ...) is reachable without sending or
+// receiving any messages.
+//
+// The Chan isn't opened before Ping, and Ping should leave nothing
+// open.
+type Pinger interface {
+	Ping(ctx *Ctx) error
+}
+
+// ChanCheck is the result of checking a channel that a test makes.
+type ChanCheck struct {
+	// Name is the name of the channel in the test.
+	Name string `json:"name"`
+
+	// Kind is the channel type.
+	Kind ChanKind `json:"kind"`
+
+	// Checked is false when the Chan isn't a Pinger.
+	Checked bool `json:"checked"`
+
+	// Latency is the duration of the check.
+	Latency time.Duration `json:"latency,omitempty"`
+
+	// Err is the reason the endpoint is unreachable (or the Chan
+	// couldn't be made).
+	Err error `json:"-"`
+}
+
+// Preflight checks that the endpoints of the channels that the test
+// asks Mother to make are reachable.  No steps are executed.
+//
+// Requires the test's Bindings, which are used to substitute the
+// channels' configurations.
+func (t *Test) Preflight(ctx *Ctx, timeout time.Duration) []ChanCheck {
+	if t.Spec == nil {
+		return nil
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultPreflightTimeout
+	}
+
+	names := make([]string, 0, len(t.Spec.Phases))
+	for name := range t.Spec.Phases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var checks []ChanCheck
+
+	for _, name := range names {
+		for _, s := range t.Spec.Phases[name].Steps {
+			if s.Pub == nil || s.Pub.Chan != "mother" {
+				continue
+			}
+
+			req, err := motherRequest(s.Pub.Payload)
+			if err != nil || req.Make == nil {
+				continue
+			}
+
+			checks = append(checks, t.checkChan(ctx, timeout, req.Make))
+		}
+	}
+
+	return checks
+}
+
+// motherRequest parses the payload of a pub to Mother.
+func motherRequest(payload interface{}) (*MotherRequest, error) {
+	js, is := payload.(string)
+	if !is {
+		bs, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		js = string(bs)
+	}
+
+	var req MotherRequest
+	if err := json.Unmarshal([]byte(js), &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// checkChan makes the requested Chan and pings it.
+func (t *Test) checkChan(ctx *Ctx, timeout time.Duration, req *MotherMakeRequest) ChanCheck {
+	check := ChanCheck{
+		Name: req.Name,
+		Kind: req.Type,
+	}
+
+	c, err := t.makeChan(ctx, req.Type, req.Config)
+	if err != nil {
+		check.Err = fmt.Errorf("failed to make %s channel: %w", req.Type, err)
+		return check
+	}
+
+	p, is := c.(Pinger)
+	if !is {
+		return check
+	}
+
+	pctx, cancel := ctx.WithTimeout(timeout)
+	defer cancel()
+
+	started := time.Now()
+	check.Checked = true
+	check.Err = p.Ping(pctx)
+	check.Latency = time.Since(started)
+
+	return check
+}
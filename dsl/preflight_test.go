@@ -0,0 +1,108 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// pingChan is a MockChan that can be pinged.
+type pingChan struct {
+	MockChan
+	err error
+}
+
+func (c *pingChan) Ping(ctx *Ctx) error {
+	return c.err
+}
+
+func TestPreflight(t *testing.T) {
+	ctx := NewCtx(context.Background())
+
+	src := `
+spec:
+  phases:
+    phase1:
+      steps:
+        - pub:
+            chan: mother
+            payload:
+              make:
+                name: up
+                type: ping
+                config: {host: '?!HOST'}
+        - goto: phase2
+    phase2:
+      steps:
+        - pub:
+            chan: mother
+            payload: '{"make":{"name":"down","type":"ping","config":{"host":"down"}}}'
+        - pub:
+            chan: mother
+            payload:
+              make:
+                name: plain
+                type: mock
+        - pub:
+            chan: up
+            payload: hello
+`
+	tst := NewTest(ctx, "preflight", nil)
+	if err := yaml.Unmarshal([]byte(src), &tst); err != nil {
+		t.Fatal(err)
+	}
+	tst.Bindings["?!HOST"] = "up"
+
+	hosts := make([]interface{}, 0)
+	tst.Registry = ChanRegistry{
+		"mock": NewMockChan,
+		"ping": func(ctx *Ctx, def interface{}) (Chan, error) {
+			host := def.(map[string]interface{})["host"]
+			hosts = append(hosts, host)
+			c := &pingChan{}
+			if host != "up" {
+				c.err = fmt.Errorf("no route to %v", host)
+			}
+			return c, nil
+		},
+	}
+
+	checks := tst.Preflight(ctx, 0)
+	if len(checks) != 3 {
+		t.Fatalf("unexpected checks %#v", checks)
+	}
+
+	if c := checks[0]; c.Name != "up" || c.Kind != "ping" || !c.Checked || c.Err != nil {
+		t.Fatalf("unexpected check %#v", c)
+	}
+	if c := checks[1]; c.Name != "down" || !c.Checked || c.Err == nil {
+		t.Fatalf("unexpected check %#v", c)
+	}
+	if c := checks[2]; c.Name != "plain" || c.Checked || c.Err != nil {
+		t.Fatalf("unexpected check %#v", c)
+	}
+
+	if fmt.Sprint(hosts) != "[up down]" {
+		t.Fatalf("unexpected hosts %v", hosts)
+	}
+}
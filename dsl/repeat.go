@@ -0,0 +1,168 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+var (
+	// DefaultRepeatMax is the maximum number of iterations of a
+	// Repeat that specifies neither Max nor Timeout.
+	DefaultRepeatMax = 100
+
+	// RepeatIterationsBinding is the binding for the number of
+	// iterations the last Repeat performed.
+	RepeatIterationsBinding = "?!iterations"
+)
+
+// Repeat executes a sequence of Steps repeatedly until a condition
+// holds, which is useful for polling.
+type Repeat struct {
+	// Steps is the sequence of Steps for each iteration.
+	//
+	// These Steps can't goto or branch.
+	Steps []*Step
+
+	// Until is Javascript code that should return a boolean.
+	// The code is evaluated after each iteration, and true ends
+	// the Repeat.  Parameters and bindings substitution applies.
+	Until string
+
+	// Max, when positive, is the maximum number of iterations.
+	//
+	// Defaults to DefaultRepeatMax unless Timeout is given.
+	Max int `yaml:",omitempty"`
+
+	// Timeout, when positive, is the maximum duration of the
+	// Repeat.
+	Timeout time.Duration `yaml:",omitempty"`
+
+	// Delay, when positive, is the time to wait between
+	// iterations.
+	Delay time.Duration `yaml:",omitempty"`
+}
+
+// Exec performs iterations until Until returns true, which binds
+// RepeatIterationsBinding to the number of iterations.  If Max or
+// Timeout is reached first, the error reports the last value that
+// Until returned.
+func (r *Repeat) Exec(ctx *Ctx, t *Test) error {
+	if len(r.Steps) == 0 {
+		return Brokenf("repeat has no steps")
+	}
+	if r.Until == "" {
+		return Brokenf("repeat has no until")
+	}
+
+	max := r.Max
+	if max <= 0 && r.Timeout <= 0 {
+		max = DefaultRepeatMax
+	}
+
+	if 0 < r.Timeout {
+		rctx, cancel := ctx.WithTimeout(r.Timeout)
+		defer cancel()
+		ctx = rctx
+	}
+
+	var last interface{}
+	for i := 1; ; i++ {
+		ctx.Indf("    Repeat iteration %d", i)
+
+		for j, s := range r.Steps {
+			next, err := s.exec(ctx, t)
+			if err != nil {
+				if ctx.Err() == context.DeadlineExceeded {
+					return r.timedOut(i-1, last)
+				}
+				_, broke := IsBroken(err)
+				err = fmt.Errorf("iteration %d step %d (%s): %w", i, j, s.Kind(), err)
+				if broke {
+					return NewBroken(err)
+				}
+				return err
+			}
+			if next != "" {
+				return Brokenf("repeat step %d (%s) can't goto or branch", j, s.Kind())
+			}
+		}
+
+		done, x, err := r.until(ctx, t)
+		if err != nil {
+			return err
+		}
+		last = x
+
+		if done {
+			ctx.Indf("    Repeat performed %d iteration(s)", i)
+			t.Bindings[RepeatIterationsBinding] = i
+			return nil
+		}
+
+		if ctx.Err() == context.DeadlineExceeded {
+			return r.timedOut(i, last)
+		}
+
+		if 0 < max && max <= i {
+			return fmt.Errorf("repeat reached %d iteration(s); until last returned %s", i, JSON(last))
+		}
+
+		if 0 < r.Delay {
+			if err := Wait(ctx, r.Delay.String()); err != nil {
+				return err
+			}
+			if ctx.Err() == context.DeadlineExceeded {
+				return r.timedOut(i, last)
+			}
+		}
+	}
+}
+
+func (r *Repeat) timedOut(iterations int, last interface{}) error {
+	return fmt.Errorf("repeat timed out after %s and %d iteration(s); until last returned %s", r.Timeout, iterations, JSON(last))
+}
+
+// until evaluates Until, which should return a boolean.
+func (r *Repeat) until(ctx *Ctx, t *Test) (bool, interface{}, error) {
+	src, err := t.Bindings.StringSub(ctx, r.Until)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if src, err = t.prepareSource(ctx, src); err != nil {
+		return false, nil, err
+	}
+
+	x, err := JSExec(ctx, src, t.jsEnv(ctx))
+	if err != nil {
+		return false, nil, err
+	}
+
+	done, is := x.(bool)
+	if !is {
+		return false, x, Brokenf("repeat until Javascript returned a %T (%#v) and not a %T", x, x, done)
+	}
+
+	ctx.Indf("    Repeat until returned %v", done)
+
+	return done, x, nil
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// failures accumulates the errors of assertions that shouldn't stop
+// at the first failure.
+type failures []error
+
+// err returns nil, the only failure, or an error that lists all of
+// the failures.
+func (fs failures) err() error {
+	switch len(fs) {
+	case 0:
+		return nil
+	case 1:
+		return fs[0]
+	}
+	return fs
+}
+
+func (fs failures) Error() string {
+	acc := make([]string, len(fs))
+	for i, err := range fs {
+		acc[i] = err.Error()
+	}
+	return strings.Join(acc, "; ")
+}
+
+// with adds the failures (if any) to the given error (which can be
+// nil).
+//
+// A Broken error remains Broken.
+func (fs failures) with(err error) error {
+	if len(fs) == 0 {
+		return err
+	}
+	if err == nil {
+		return fmt.Errorf("%d soft failure(s): %v", len(fs), fs)
+	}
+	if b, is := IsBroken(err); is {
+		return NewBroken(fmt.Errorf("%w; %d soft failure(s): %v", b.Err, len(fs), fs))
+	}
+	return fmt.Errorf("%w; %d soft failure(s): %v", err, len(fs), fs)
+}
+
+// softFail records a failure that Soft says shouldn't stop the test.
+func (t *Test) softFail(ctx *Ctx, err error) {
+	ctx.Indf("    Soft failure: %s", err)
+	if fs, is := err.(failures); is {
+		t.softFailures = append(t.softFailures, fs...)
+		return
+	}
+	t.softFailures = append(t.softFailures, err)
+}
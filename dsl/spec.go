@@ -19,8 +19,11 @@
 package dsl
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -31,6 +34,10 @@ import (
 
 var DefaultInitialPhase = "phase1"
 
+// ErrStepTimeout is (wrapped by) the error for a Step that didn't
+// finish within its Timeout.
+var ErrStepTimeout = errors.New("timed out")
+
 // Spec represents a set of named test Phases.
 type Spec struct {
 	// InitialPhase is the starting phase, which defaults to
@@ -85,7 +92,7 @@ func (p *Phase) Exec(ctx *Ctx, t *Test) (string, error) {
 
 		if next, err = s.exec(ctx, t); err != nil {
 			_, broke := IsBroken(err)
-			err := fmt.Errorf("step %d: %w", i, err)
+			err := fmt.Errorf("step %d (%s): %w", i, s.Kind(), err)
 			if broke {
 				return "", NewBroken(err)
 			} else {
@@ -114,6 +121,12 @@ type Step struct {
 	// Skip will make the test execution skip this step.
 	Skip bool `yaml:",omitempty"`
 
+	// Timeout, when positive, is the maximum duration of this
+	// step, which overrides the timeouts of the test and its
+	// group.  At the timeout, the step is canceled (and a recv
+	// or wait stops waiting), and the step fails.
+	Timeout time.Duration `yaml:",omitempty"`
+
 	Pub       *Pub       `yaml:",omitempty"`
 	Sub       *Sub       `yaml:",omitempty"`
 	Recv      *Recv      `yaml:",omitempty"`
@@ -130,11 +143,74 @@ type Step struct {
 	Branch string `yaml:",omitempty"`
 
 	Ingest *Ingest `yaml:",omitempty"`
+
+	Repeat *Repeat `yaml:",omitempty"`
 }
 
-// exec calls exe() and then handles Fails (if any).
+// Kind returns the type of the Step (such as "recv"), which is used
+// in error messages.
+func (s *Step) Kind() string {
+	switch {
+	case s.Pub != nil:
+		return "pub"
+	case s.Sub != nil:
+		return "sub"
+	case s.Recv != nil:
+		return "recv"
+	case s.Reconnect != nil:
+		return "reconnect"
+	case s.Close != nil:
+		return "close"
+	case s.Ingest != nil:
+		return "ingest"
+	case s.Kill != nil:
+		return "kill"
+	case s.Branch != "":
+		return "branch"
+	case s.Run != "":
+		return "run"
+	case s.Wait != "":
+		return "wait"
+	case s.Goto != "":
+		return "goto"
+	case s.Repeat != nil:
+		return "repeat"
+	default:
+		return "step"
+	}
+}
+
+// exec calls exe() (subject to the Timeout, if any) and then handles
+// Fails (if any).
 func (s *Step) exec(ctx *Ctx, t *Test) (string, error) {
-	next, err := s.exe(ctx, t)
+	var (
+		next string
+		err  error
+	)
+	if 0 < s.Timeout {
+		sctx, cancel := ctx.WithTimeout(s.Timeout)
+		next, err = s.exe(sctx, t)
+		if sctx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+			ctx.Indf("    Step timeout (%v)", s.Timeout)
+			switch {
+			case err == nil && s.Recv != nil && s.Recv.None:
+				// Nothing matched before the deadline,
+				// which is what the Recv wanted.
+				ctx.Indf("    Recv none satisfied")
+			case err == nil:
+				var history string
+				if s.Recv != nil {
+					history = t.History.report(ctx)
+				}
+				err = fmt.Errorf("%w after %s%s", ErrStepTimeout, s.Timeout, history)
+			default:
+				err = fmt.Errorf("%w after %s: %v", ErrStepTimeout, s.Timeout, err)
+			}
+		}
+		cancel()
+	} else {
+		next, err = s.exe(ctx, t)
+	}
 	if err != nil {
 		if _, is := IsBroken(err); is {
 			return "", err
@@ -275,6 +351,14 @@ func (s *Step) exe(ctx *Ctx, t *Test) (string, error) {
 		}
 	}
 
+	if s.Repeat != nil {
+		ctx.Indf("    Repeat")
+
+		if err := s.Repeat.Exec(ctx, t); err != nil {
+			return "", err
+		}
+	}
+
 	if s.Branch != "" {
 		ctx.Indf("    Branch %s", short(s.Branch))
 
@@ -318,6 +402,11 @@ func (s *Step) exe(ctx *Ctx, t *Test) (string, error) {
 
 		ctx.Inddf("    Bindings: %s", JSON(t.Bindings))
 
+		if f, is := IsFailure(err); is && t.Soft {
+			t.softFail(ctx, f)
+			return "", nil
+		}
+
 		return "", err
 	}
 
@@ -346,7 +435,13 @@ func Wait(ctx *Ctx, durationString string) error {
 		return Brokenf("error parsing Wait '%s'", durationString)
 	}
 
-	time.Sleep(d)
+	tm := time.NewTimer(d)
+	defer tm.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-tm.C:
+	}
 
 	return nil
 }
@@ -548,14 +643,46 @@ type Recv struct {
 
 	Run string `json:",omitempty" yaml:",omitempty"`
 
-	// Schema is an optional URI for a JSON Schema that's used to
-	// validate incoming messages before other processing.
-	Schema string `json:",omitempty" yaml:",omitempty"`
+	// Schema is an optional JSON Schema that's used to validate
+	// incoming messages before other processing.  The Schema is
+	// either a URI for the schema or the schema itself (inline or
+	// included).  A message that doesn't conform fails the step
+	// with the list of violations.
+	Schema interface{} `json:",omitempty" yaml:",omitempty"`
 
 	// Max attempts to receive a message; optionally for a specific topic
 	Attempts int `json:",omitempty" yaml:",omitempty`
 
+	// JSONPath gives optional assertions (like `$.order.status
+	// == "shipped"`) that a message must also satisfy.  See
+	// JSONPathAssertion.
+	JSONPath []string `json:",omitempty" yaml:",omitempty"`
+
+	// Extract optionally binds variables to the values that
+	// JSONPaths find (like {"?status":"$.order.status"}).
+	Extract map[string]string `json:",omitempty" yaml:",omitempty"`
+
+	// XPath gives optional assertions (like `/order/status ==
+	// shipped`) that an XML payload must satisfy.  See
+	// XPathAssertion.
+	XPath []string `json:",omitempty" yaml:",omitempty"`
+
+	// XPathExtract optionally binds variables to the values of
+	// the nodes that XPaths find (like {"?id":"/order/@id"}).
+	XPathExtract map[string]string `json:",omitempty" yaml:",omitempty"`
+
+	// None inverts the Recv: the step succeeds only if no
+	// matching message arrives before the (required) Timeout,
+	// and it fails with the first message that matches.
+	None bool `json:",omitempty" yaml:",omitempty"`
+
 	ch Chan
+
+	assertions []*JSONPathAssertion
+	extracts   map[string]*JSONPath
+
+	xassertions []*XPathAssertion
+	xextracts   map[string]*XPath
 }
 
 // Substitute bindings for the receiver
@@ -623,27 +750,221 @@ func (r *Recv) Substitute(ctx *Ctx, t *Test) (*Recv, error) {
 		return nil, err
 	}
 
+	assertions := make([]*JSONPathAssertion, len(r.JSONPath))
+	for i, src := range r.JSONPath {
+		if src, err = t.Bindings.StringSub(ctx, src); err != nil {
+			return nil, err
+		}
+		if assertions[i], err = ParseJSONPathAssertion(src); err != nil {
+			return nil, NewBroken(err)
+		}
+		ctx.Inddf("    Effective jsonpath: %s", src)
+	}
+
+	extracts := make(map[string]*JSONPath, len(r.Extract))
+	for v, src := range r.Extract {
+		if src, err = t.Bindings.StringSub(ctx, src); err != nil {
+			return nil, err
+		}
+		if extracts[v], err = ParseJSONPath(src); err != nil {
+			return nil, NewBroken(err)
+		}
+	}
+
+	xassertions := make([]*XPathAssertion, len(r.XPath))
+	for i, src := range r.XPath {
+		if src, err = t.Bindings.StringSub(ctx, src); err != nil {
+			return nil, err
+		}
+		if xassertions[i], err = ParseXPathAssertion(src); err != nil {
+			return nil, NewBroken(err)
+		}
+		ctx.Inddf("    Effective xpath: %s", src)
+	}
+
+	xextracts := make(map[string]*XPath, len(r.XPathExtract))
+	for v, src := range r.XPathExtract {
+		if src, err = t.Bindings.StringSub(ctx, src); err != nil {
+			return nil, err
+		}
+		if xextracts[v], err = ParseXPath(src); err != nil {
+			return nil, NewBroken(err)
+		}
+	}
+
+	if r.None && r.Timeout <= 0 {
+		return nil, Brokenf("recv with none needs a timeout")
+	}
+
+	if 0 < len(xassertions) || 0 < len(xextracts) {
+		switch {
+		case r.Pattern != nil:
+			return nil, Brokenf("can't have both Pattern and XPath")
+		case 0 < len(assertions) || 0 < len(extracts):
+			return nil, Brokenf("can't have both JSONPath and XPath")
+		case r.Schema != nil:
+			return nil, Brokenf("can't have both Schema and XPath")
+		case r.Target != "payload":
+			return nil, Brokenf("can only use XPath with the payload (not also topic)")
+		}
+	}
+
 	return &Recv{
-		Chan:     r.Chan,
-		Topic:    topic,
-		Pattern:  pat,
-		Regexp:   reg,
-		Timeout:  r.Timeout,
-		Target:   r.Target,
-		Guard:    guard,
-		Run:      run,
-		Schema:   r.Schema,
-		Attempts: r.Attempts,
-		ch:       r.ch,
+		Chan:         r.Chan,
+		Topic:        topic,
+		Pattern:      pat,
+		Regexp:       reg,
+		Timeout:      r.Timeout,
+		Target:       r.Target,
+		Guard:        guard,
+		Run:          run,
+		Schema:       r.Schema,
+		Attempts:     r.Attempts,
+		JSONPath:     r.JSONPath,
+		Extract:      r.Extract,
+		XPath:        r.XPath,
+		XPathExtract: r.XPathExtract,
+		None:         r.None,
+		ch:           r.ch,
+		assertions:   assertions,
+		extracts:     extracts,
+		xassertions:  xassertions,
+		xextracts:    xextracts,
 	}, nil
 }
 
-func validateSchema(ctx *Ctx, schemaURI string, payload string) error {
+// usesJSONPath reports whether the Recv has JSONPath assertions or
+// extractions.
+func (r *Recv) usesJSONPath() bool {
+	return 0 < len(r.assertions) || 0 < len(r.extracts)
+}
+
+// checkJSONPath checks the JSONPath assertions against the
+// (deserialized) message and adds the extracted values to the
+// bindings.
+//
+// When all is true, checkJSONPath reports every failure rather than
+// only the first one.
+func (r *Recv) checkJSONPath(doc interface{}, bs match.Bindings, all bool) error {
+	var fs failures
+	for _, a := range r.assertions {
+		if err := a.Check(doc); err != nil {
+			if !all {
+				return err
+			}
+			fs = append(fs, err)
+		}
+	}
+
+	vs := make([]string, 0, len(r.extracts))
+	for v := range r.extracts {
+		vs = append(vs, v)
+	}
+	sort.Strings(vs)
+
+	for _, v := range vs {
+		p := r.extracts[v]
+		switch xs := p.Eval(doc); len(xs) {
+		case 0:
+			err := fmt.Errorf("jsonpath %s for %s found nothing", p, v)
+			if !all {
+				return err
+			}
+			fs = append(fs, err)
+		case 1:
+			bs[v] = xs[0]
+		default:
+			bs[v] = xs
+		}
+	}
+
+	return fs.err()
+}
+
+// usesXPath reports whether the Recv has XPath assertions or
+// extractions.
+func (r *Recv) usesXPath() bool {
+	return 0 < len(r.xassertions) || 0 < len(r.xextracts)
+}
+
+// checkXPath checks the XPath assertions against the (parsed XML)
+// message and adds the extracted values to the bindings.
+//
+// See checkJSONPath for the meaning of all.
+func (r *Recv) checkXPath(doc *XMLNode, bs match.Bindings, all bool) error {
+	var fs failures
+	for _, a := range r.xassertions {
+		if err := a.Check(doc); err != nil {
+			if !all {
+				return err
+			}
+			fs = append(fs, err)
+		}
+	}
+
+	vs := make([]string, 0, len(r.xextracts))
+	for v := range r.xextracts {
+		vs = append(vs, v)
+	}
+	sort.Strings(vs)
+
+	for _, v := range vs {
+		p := r.xextracts[v]
+		switch xs := p.Eval(doc); len(xs) {
+		case 0:
+			err := fmt.Errorf("xpath %s for %s found nothing", p, v)
+			if !all {
+				return err
+			}
+			fs = append(fs, err)
+		case 1:
+			bs[v] = xs[0]
+		default:
+			bs[v] = xs
+		}
+	}
+
+	return fs.err()
+}
+
+// expectation describes what the Recv is waiting for.
+func (r *Recv) expectation() string {
+	if r.Pattern == nil && r.Regexp == "" {
+		if 0 < len(r.JSONPath) {
+			return strings.Join(r.JSONPath, " and ")
+		}
+		if 0 < len(r.XPath) {
+			return strings.Join(r.XPath, " and ")
+		}
+		if r.usesXPath() {
+			return "an XML message"
+		}
+		if r.Schema != nil {
+			return "a message that conforms to the schema"
+		}
+	}
+	return fmt.Sprintf("%s", r.Pattern)
+}
+
+// schemaLoader returns a loader for the schema, which is either a
+// URI or the schema itself (as a string of JSON or as a value), and
+// a name for the schema.
+func schemaLoader(schema interface{}) (jschema.JSONLoader, string) {
+	switch vv := schema.(type) {
+	case string:
+		if strings.HasPrefix(strings.TrimSpace(vv), "{") {
+			return jschema.NewStringLoader(vv), "inline"
+		}
+		return jschema.NewReferenceLoader(vv), vv
+	default:
+		return jschema.NewGoLoader(Canon(vv)), "inline"
+	}
+}
+
+func validateSchema(ctx *Ctx, schemaSrc interface{}, payload string) error {
+	schema, schemaURI := schemaLoader(schemaSrc)
 	ctx.Indf("      schema: %s", schemaURI)
-	var (
-		doc    = jschema.NewStringLoader(payload)
-		schema = jschema.NewReferenceLoader(schemaURI)
-	)
+	doc := jschema.NewStringLoader(payload)
 
 	v, err := jschema.Validate(schema, doc)
 	if err != nil {
@@ -671,14 +992,42 @@ func (r *Recv) Exec(ctx *Ctx, t *Test) error {
 		timeout  = r.Timeout
 		in       = r.ch.Recv(ctx)
 		attempts = 0
+
+		// failure is the last JSONPath or XPath failure (if
+		// any).
+		failure error
+
+		// received counts the messages that arrived during
+		// this recv, and closest is the one (if any) that came
+		// closest to matching, with misses mismatched
+		// properties.
+		received int
+		closest  *Msg
+		misses   int
 	)
 
+	// near notes a message that didn't match.
+	near := func(m Msg, n int) {
+		if closest == nil || n <= misses {
+			closest, misses = &m, n
+		}
+	}
+
+	lastFailure := func() string {
+		if failure == nil {
+			return ""
+		}
+		return "; last " + failure.Error()
+	}
+
 	if timeout == 0 {
 		timeout = time.Second * 60 * 20 * 24
 	}
 
 	tm := time.NewTimer(timeout)
 
+	t.History.recv(r.ch)
+
 	if r.Regexp != "" {
 		ctx.Inddf("    Recv regexp %s", r.Regexp)
 	} else {
@@ -693,8 +1042,25 @@ func (r *Recv) Exec(ctx *Ctx, t *Test) error {
 			return nil
 		case <-tm.C:
 			ctx.Indf("    Recv timeout (%v)", timeout)
-			return fmt.Errorf("timeout after %s waiting for %s", timeout, r.Pattern)
+			if r.None {
+				ctx.Indf("    Recv none satisfied")
+				return nil
+			}
+			if received == 0 {
+				var history string
+				if 0 < len(t.History.Last(r.ch)) {
+					history = t.History.report(ctx)
+				}
+				return fmt.Errorf("timeout after %s waiting for %s: no messages received%s", timeout, r.expectation(), history)
+			}
+			nearest := fmt.Sprintf("topic '%s': %s", closest.Topic, closest.Payload)
+			if ctx.Redactions != nil {
+				nearest = ctx.Redactions.RedactAll(nearest)
+			}
+			return fmt.Errorf("timeout after %s waiting for %s: %d message(s) received, none matched; closest %s%s%s", timeout, r.expectation(), received, nearest, lastFailure(), t.History.report(ctx))
 		case m := <-in:
+			t.History.add(r.ch, m)
+			received++
 
 			ctx.Indf("    Recv dequeuing topic '%s' (vs '%s')", m.Topic, r.Topic)
 			ctx.Inddf("                   %s", m.Payload)
@@ -702,6 +1068,19 @@ func (r *Recv) Exec(ctx *Ctx, t *Test) error {
 			var (
 				err error
 				bss []match.Bindings
+
+				// doc is the deserialized target
+				// for JSONPaths (if haveDoc).
+				doc     interface{}
+				haveDoc bool
+
+				// xdoc is the parsed XML payload for
+				// XPaths (if any).
+				xdoc *XMLNode
+
+				// n is the number of properties that
+				// didn't match the pattern (if known).
+				n = 1 << 20
 			)
 
 			// Verify that either no Recv topic was
@@ -716,6 +1095,28 @@ func (r *Recv) Exec(ctx *Ctx, t *Test) error {
 						return Brokenf("can only regexp-match against payload (not also topic)")
 					}
 					bss, err = RegexpMatch(r.Regexp, m.Payload)
+					if err == nil && 0 < len(bss) && r.usesJSONPath() {
+						if err := json.Unmarshal([]byte(m.Payload), &doc); err != nil {
+							failure = fmt.Errorf("jsonpath needs a JSON payload: %w", err)
+							bss = nil
+						}
+						haveDoc = true
+					}
+					if err == nil && 0 < len(bss) && r.usesXPath() {
+						if xdoc, err = ParseXML(m.Payload); err != nil {
+							failure = fmt.Errorf("xpath needs an XML payload: %w", err)
+							bss, err = nil, nil
+						}
+					}
+				} else if r.usesXPath() {
+					ctx.Inddf("      xpath: %s", strings.Join(r.XPath, " and "))
+					var perr error
+					if xdoc, perr = ParseXML(m.Payload); perr != nil {
+						failure = fmt.Errorf("xpath needs an XML payload: %w", perr)
+					} else {
+						t.Bindings.Clean(ctx, r.ClearBindings)
+						bss = []match.Bindings{match.NewBindings()}
+					}
 				} else {
 					ctx.Inddf("      pattern:       %s", JSON(r.Pattern))
 
@@ -742,25 +1143,69 @@ func (r *Recv) Exec(ctx *Ctx, t *Test) error {
 
 					ctx.Inddf("      match target:  %s", JSON(target))
 
-					if r.Schema != "" {
+					if r.Schema != nil {
 						if err := validateSchema(ctx, r.Schema, m.Payload); err != nil {
-							return err
+							if _, broke := IsBroken(err); broke || !t.Soft {
+								return err
+							}
+							t.softFail(ctx, err)
 						}
 					}
 
 					target = Canon(target)
+					doc, haveDoc = target, true
 					t.Bindings.Clean(ctx, r.ClearBindings)
-					pattern, err := t.Bindings.Bind(ctx, r.Pattern)
-					if err != nil {
-						return err
+					if r.Pattern == nil && (r.usesJSONPath() || r.Schema != nil) {
+						// Just the JSONPaths (or the
+						// schema).
+						bss = []match.Bindings{match.NewBindings()}
+					} else {
+						pattern, err := t.Bindings.Bind(ctx, r.Pattern)
+						if err != nil {
+							return err
+						}
+						ctx.Inddf("      bound pattern: %s", JSON(pattern))
+						bss, err = match.Match(pattern, target, match.NewBindings())
+						if err == nil && len(bss) == 0 {
+							if f := mismatch(pattern, target); f != nil {
+								ctx.Indf("      %s", f)
+								failure = f
+								n = 1
+								if fs, is := f.(failures); is {
+									n = len(fs)
+								}
+							}
+						}
 					}
-					ctx.Inddf("      bound pattern: %s", JSON(pattern))
-					bss, err = match.Match(pattern, target, match.NewBindings())
 				}
 
 				if err != nil {
 					return err
 				}
+
+				if 0 < len(bss) && haveDoc && r.usesJSONPath() {
+					if err := r.checkJSONPath(doc, bss[0], t.Soft); err != nil {
+						if t.Soft {
+							t.softFail(ctx, err)
+						} else {
+							ctx.Indf("      %s", err)
+							failure = err
+							bss = nil
+						}
+					}
+				}
+
+				if 0 < len(bss) && xdoc != nil {
+					if err := r.checkXPath(xdoc, bss[0], t.Soft); err != nil {
+						if t.Soft {
+							t.softFail(ctx, err)
+						} else {
+							ctx.Indf("      %s", err)
+							failure = err
+							bss = nil
+						}
+					}
+				}
 				ctx.Indf("      result: %v", 0 < len(bss))
 				ctx.Inddf("      bss: %s", JSON(bss))
 
@@ -845,6 +1290,7 @@ func (r *Recv) Exec(ctx *Ctx, t *Test) error {
 						case bool:
 							if !vv {
 								ctx.Indf("    Recv guard not pleased")
+								near(m, 0)
 								continue
 							}
 							ctx.Indf("    Recv guard satisfied")
@@ -853,6 +1299,15 @@ func (r *Recv) Exec(ctx *Ctx, t *Test) error {
 						}
 					}
 
+					if r.None {
+						ctx.Indf("    Recv none violated")
+						violation := fmt.Sprintf("topic '%s' within %s: %s", m.Topic, timeout, m.Payload)
+						if ctx.Redactions != nil {
+							violation = ctx.Redactions.RedactAll(violation)
+						}
+						return fmt.Errorf("received a message matching %s on %s", r.expectation(), violation)
+					}
+
 					ctx.Indf("    Recv satisfied")
 					ctx.Inddf("      t.Bindings: %s", JSON(t.Bindings))
 
@@ -879,21 +1334,30 @@ func (r *Recv) Exec(ctx *Ctx, t *Test) error {
 
 				// Only increment the number of attempts given a topic match.
 				attempts++
+				near(m, n)
+			} else {
+				// A message on another topic is farther
+				// than any message on the topic.
+				near(m, 1<<30)
 			}
 
 			// Verify the receiver attempts was specified (not 0) and that
 			// the actual number of attempts has been reached
 			if r.Attempts != 0 && attempts >= r.Attempts {
 				ctx.Inddf("      attempts: %d of %d", attempts, r.Attempts)
+				if r.None {
+					ctx.Indf("    Recv none satisfied")
+					return nil
+				}
 				ctx.Inddf("      topic: %s", r.Topic)
 				match := fmt.Sprintf("pattern: %s", r.Pattern)
 				if r.Regexp != "" {
 					match = fmt.Sprintf("regexp: %s", r.Regexp)
 				}
 				if r.Topic != "" {
-					return fmt.Errorf("%d attempt(s) reached; expected maximum of %d attempt(s) to match %s on topic %s", attempts, r.Attempts, match, r.Topic)
+					return fmt.Errorf("%d attempt(s) reached; expected maximum of %d attempt(s) to match %s on topic %s%s", attempts, r.Attempts, match, r.Topic, lastFailure())
 				}
-				return fmt.Errorf("%d attempt(s) reached; expected maximum of %d attempt(s) to match %s", attempts, r.Attempts, match)
+				return fmt.Errorf("%d attempt(s) reached; expected maximum of %d attempt(s) to match %s%s", attempts, r.Attempts, match, lastFailure())
 			}
 		}
 	}
@@ -930,7 +1394,7 @@ func (p *Reconnect) Substitute(ctx *Ctx, t *Test) (*Reconnect, error) {
 func (p *Reconnect) Exec(ctx *Ctx, t *Test) error {
 	ctx.Indf("    Reconnect %s", JSON(p))
 
-	return p.ch.Open(ctx)
+	return t.ConnectBackoff.Open(ctx, p.ch)
 }
 
 type Close struct {
@@ -20,7 +20,9 @@ package dsl
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 )
@@ -180,6 +182,342 @@ func TestFails(t *testing.T) {
 
 }
 
+func TestStepTimeout(t *testing.T) {
+
+	ctx, s, tst := newTest(t)
+
+	{
+		p := &Phase{}
+
+		s.Phases["phase1"] = p
+
+		addMock(t, ctx, p)
+
+		p.AddStep(ctx, &Step{
+			Recv: &Recv{
+				Pattern: `{"want":"?*x"}`,
+				Timeout: time.Minute,
+			},
+			Timeout: 50 * time.Millisecond,
+		})
+	}
+
+	if err := tst.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	then := time.Now()
+	errs := tst.Run(ctx)
+	if errs == nil {
+		t.Fatal("expected a timeout")
+	}
+	err := errs.Err
+	if elapsed := time.Since(then); time.Second < elapsed {
+		t.Fatalf("step took %v", elapsed)
+	}
+	if !errors.Is(err, ErrStepTimeout) {
+		t.Fatalf("expected a step timeout rather than %v", err)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "step 2 (recv): timed out after 50ms") {
+		t.Fatal(msg)
+	}
+}
+
+func TestStepTimeoutNone(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		pattern string
+		fails   string
+	}{
+		{"satisfied", `{"want":"chips"}`, ""},
+		{"violated", `{"want":"tacos"}`, "received a message matching"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			ctx, s, tst := newTest(t)
+
+			p := &Phase{}
+			s.Phases["phase1"] = p
+
+			addMock(t, ctx, p)
+
+			p.AddStep(ctx, &Step{
+				Pub: &Pub{
+					Payload: `{"want":"tacos"}`,
+				},
+			})
+
+			// The step's deadline comes before the Recv's.
+			p.AddStep(ctx, &Step{
+				Recv: &Recv{
+					Pattern: dejson(c.pattern),
+					Timeout: time.Minute,
+					None:    true,
+				},
+				Timeout: 50 * time.Millisecond,
+			})
+
+			if err := tst.Init(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			then := time.Now()
+			errs := tst.Run(ctx)
+			if elapsed := time.Since(then); time.Second < elapsed {
+				t.Fatalf("step took %v", elapsed)
+			}
+			if c.fails == "" {
+				if errs != nil {
+					t.Fatal(errs)
+				}
+				return
+			}
+			if errs == nil {
+				t.Fatal("expected an error")
+			}
+			if msg := errs.Err.Error(); !strings.Contains(msg, c.fails) {
+				t.Fatalf("%q doesn't contain %q", msg, c.fails)
+			}
+		})
+	}
+}
+
+func TestRecvHistory(t *testing.T) {
+
+	ctx, s, tst := newTest(t)
+	tst.History = NewMsgHistory(2)
+	if err := ctx.AddSecret("tacos"); err != nil {
+		t.Fatal(err)
+	}
+
+	{
+		p := &Phase{}
+
+		s.Phases["phase1"] = p
+
+		addMock(t, ctx, p)
+
+		for _, want := range []string{"chips", "queso", "tacos"} {
+			p.AddStep(ctx, &Step{
+				Pub: &Pub{
+					Payload: `{"want":"` + want + `"}`,
+				},
+			})
+		}
+
+		p.AddStep(ctx, &Step{
+			Recv: &Recv{
+				Pattern: `{"need":"?x"}`,
+				Timeout: 100 * time.Millisecond,
+			},
+		})
+	}
+
+	if err := tst.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := tst.Run(ctx)
+	if errs == nil {
+		t.Fatal("expected a timeout")
+	}
+
+	msg := errs.Err.Error()
+	if !strings.Contains(msg, "the last 2 messages received on the channel:") ||
+		strings.Contains(msg, "chips") ||
+		!strings.Contains(msg, `2. topic '': {"want":"<redacted>"}`) {
+		t.Fatal(msg)
+	}
+}
+
+func TestRecvTimeoutReceived(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		pubs []string
+		want string
+	}{
+		{"none", nil, "timeout after 100ms waiting for map[size:large want:tacos]: no messages received"},
+		{"some", []string{`{"want":"chips","size":"small"}`, `{"want":"tacos","size":"small"}`, `{"want":"queso"}`},
+			`: 3 message(s) received, none matched; closest topic '': {"size":"small","want":"tacos"}; last size: missing; want: expected "tacos" got "queso"`},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			ctx, s, tst := newTest(t)
+
+			p := &Phase{}
+			s.Phases["phase1"] = p
+
+			addMock(t, ctx, p)
+
+			for _, pub := range c.pubs {
+				p.AddStep(ctx, &Step{
+					Pub: &Pub{
+						Payload: pub,
+					},
+				})
+			}
+
+			p.AddStep(ctx, &Step{
+				Recv: &Recv{
+					Pattern: dejson(`{"want":"tacos","size":"large"}`),
+					Timeout: 100 * time.Millisecond,
+				},
+			})
+
+			if err := tst.Init(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			errs := tst.Run(ctx)
+			if errs == nil {
+				t.Fatal("expected a timeout")
+			}
+			if msg := errs.Err.Error(); !strings.Contains(msg, c.want) {
+				t.Fatalf("%q doesn't contain %q", msg, c.want)
+			}
+		})
+	}
+}
+
+func TestRecvJSONPath(t *testing.T) {
+
+	ctx, s, tst := newTest(t)
+
+	{
+		p := &Phase{}
+
+		s.Phases["phase1"] = p
+
+		addMock(t, ctx, p)
+
+		for _, payload := range []string{
+			`{"order":{"id":1,"status":"pending"}}`,
+			`{"order":{"id":2,"status":"shipped","items":[{"sku":"taco"},{"sku":"queso"}]}}`,
+		} {
+			p.AddStep(ctx, &Step{
+				Pub: &Pub{
+					Payload: payload,
+				},
+			})
+		}
+
+		p.AddStep(ctx, &Step{
+			Recv: &Recv{
+				JSONPath: []string{`$.order.status == "shipped"`},
+				Extract: map[string]string{
+					"?id":   "$.order.id",
+					"?skus": "$..sku",
+				},
+				Timeout: time.Second,
+			},
+		})
+
+		p.AddStep(ctx, &Step{
+			Pub: &Pub{
+				Payload: `{"order":{"id":3,"status":"pending"}}`,
+			},
+		})
+
+		p.AddStep(ctx, &Step{
+			Recv: &Recv{
+				Pattern:  `{"order":{"id":3}}`,
+				JSONPath: []string{`$.order.status == "delivered"`},
+				Timeout:  100 * time.Millisecond,
+			},
+		})
+	}
+
+	if err := tst.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := tst.Run(ctx)
+	if errs == nil {
+		t.Fatal("expected a timeout")
+	}
+
+	if id := tst.Bindings["?id"]; id != 2.0 {
+		t.Fatal(JSON(tst.Bindings))
+	}
+	if skus := JSON(tst.Bindings["?skus"]); skus != `["taco","queso"]` {
+		t.Fatal(skus)
+	}
+
+	msg := errs.Err.Error()
+	if !strings.Contains(msg, `last jsonpath $.order.status == "delivered" failed: found "pending"`) {
+		t.Fatal(msg)
+	}
+}
+
+func TestRecvXPath(t *testing.T) {
+
+	ctx, s, tst := newTest(t)
+
+	{
+		p := &Phase{}
+
+		s.Phases["phase1"] = p
+
+		addMock(t, ctx, p)
+
+		for _, payload := range []string{
+			`{"not":"xml"}`,
+			`<order id="1"><status>pending</status></order>`,
+			`<order id="2"><status>shipped</status><item sku="taco"/><item sku="queso"/></order>`,
+		} {
+			p.AddStep(ctx, &Step{
+				Pub: &Pub{
+					Payload: payload,
+				},
+			})
+		}
+
+		p.AddStep(ctx, &Step{
+			Recv: &Recv{
+				XPath: []string{`/order/status == shipped`},
+				XPathExtract: map[string]string{
+					"?id":   "/order/@id",
+					"?skus": "//item/@sku",
+				},
+				Timeout: time.Second,
+			},
+		})
+
+		p.AddStep(ctx, &Step{
+			Pub: &Pub{
+				Payload: `<order id="3"><status>pending</status></order>`,
+			},
+		})
+
+		p.AddStep(ctx, &Step{
+			Recv: &Recv{
+				Regexp:  `id="3"`,
+				XPath:   []string{`/order/status == "delivered"`},
+				Timeout: 100 * time.Millisecond,
+			},
+		})
+	}
+
+	if err := tst.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := tst.Run(ctx)
+	if errs == nil {
+		t.Fatal("expected a timeout")
+	}
+
+	if id := tst.Bindings["?id"]; id != "2" {
+		t.Fatal(JSON(tst.Bindings))
+	}
+	if skus := JSON(tst.Bindings["?skus"]); skus != `["taco","queso"]` {
+		t.Fatal(skus)
+	}
+
+	msg := errs.Err.Error()
+	if !strings.Contains(msg, `last xpath /order/status == "delivered" failed: found "pending"`) {
+		t.Fatal(msg)
+	}
+}
+
 func TestValidateSchema(t *testing.T) {
 	ctx := NewCtx(nil)
 	schema := "file://../demos/order.json"
@@ -195,4 +533,349 @@ func TestValidateSchema(t *testing.T) {
 			t.Fatal("'want' was required")
 		}
 	})
+	t.Run("inline", func(t *testing.T) {
+		for _, schema := range []interface{}{
+			`{"type":"object","required":["want"]}`,
+			dejson(`{"type":"object","required":["want"]}`),
+		} {
+			if err := validateSchema(ctx, schema, `{"want":"chips"}`); err != nil {
+				t.Fatal(err)
+			}
+			if err := validateSchema(ctx, schema, `{"need":"queso"}`); err == nil {
+				t.Fatal("'want' was required")
+			}
+		}
+	})
+}
+
+func TestRecvSchema(t *testing.T) {
+
+	ctx, s, tst := newTest(t)
+
+	schema := dejson(`{
+	  "type": "object",
+	  "properties": {
+	    "status": {"enum": ["pending", "shipped"]},
+	    "n": {"type": "integer"}
+	  },
+	  "required": ["status", "n"]
+	}`)
+
+	{
+		p := &Phase{}
+
+		s.Phases["phase1"] = p
+
+		addMock(t, ctx, p)
+
+		for _, payload := range []string{
+			`{"status":"shipped","n":2}`,
+			`{"status":"lost","n":"two"}`,
+		} {
+			p.AddStep(ctx, &Step{
+				Pub: &Pub{
+					Payload: payload,
+				},
+			})
+			p.AddStep(ctx, &Step{
+				Recv: &Recv{
+					Schema:  schema,
+					Timeout: time.Second,
+				},
+			})
+		}
+	}
+
+	if err := tst.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := tst.Run(ctx)
+	if errs == nil {
+		t.Fatal("expected a schema violation")
+	}
+
+	msg := errs.Err.Error()
+	if !strings.Contains(msg, "schema (inline) validation errors:") ||
+		!strings.Contains(msg, "status: status must be one of the following") ||
+		!strings.Contains(msg, "n: Invalid type.") {
+		t.Fatal(msg)
+	}
+}
+
+func TestRecvNone(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		pattern string
+		timeout time.Duration
+		secret  string
+		fails   string
+		broken  bool
+	}{
+		{"nothing", `{"want":"chips"}`, 100 * time.Millisecond, "", "", false},
+		{"something", `{"want":"?want"}`, 100 * time.Millisecond, "", `received a message matching map[want:?want] on topic '' within 100ms: {"want":"tacos"}`, false},
+		{"redacted", `{"want":"?want"}`, 100 * time.Millisecond, "tacos", `received a message matching map[want:?want] on topic '' within 100ms: {"want":"<redacted>"}`, false},
+		{"forever", `{"want":"chips"}`, 0, "", "needs a timeout", true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			ctx, s, tst := newTest(t)
+			if c.secret != "" {
+				if err := ctx.AddSecret(c.secret); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			p := &Phase{}
+			s.Phases["phase1"] = p
+
+			addMock(t, ctx, p)
+
+			p.AddStep(ctx, &Step{
+				Pub: &Pub{
+					Payload: `{"want":"tacos"}`,
+				},
+			})
+
+			p.AddStep(ctx, &Step{
+				Recv: &Recv{
+					Pattern: dejson(c.pattern),
+					Timeout: c.timeout,
+					None:    true,
+				},
+			})
+
+			if err := tst.Init(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			errs := tst.Run(ctx)
+			if c.fails == "" {
+				if errs != nil {
+					t.Fatal(errs)
+				}
+				return
+			}
+			if errs == nil {
+				t.Fatal("expected an error")
+			}
+			if _, broke := errs.IsBroken(); broke != c.broken {
+				t.Fatalf("broken %v for %v", broke, errs)
+			}
+			if msg := errs.Err.Error(); !strings.Contains(msg, c.fails) {
+				t.Fatalf("%q doesn't contain %q", msg, c.fails)
+			}
+		})
+	}
+}
+
+func TestRecvSoft(t *testing.T) {
+
+	ctx, s, tst := newTest(t)
+	tst.Soft = true
+
+	{
+		p := &Phase{}
+
+		s.Phases["phase1"] = p
+
+		addMock(t, ctx, p)
+
+		p.AddStep(ctx, &Step{
+			Pub: &Pub{
+				Payload: `{"status":"lost","n":"two"}`,
+			},
+		})
+
+		p.AddStep(ctx, &Step{
+			Recv: &Recv{
+				Schema: dejson(`{"required": ["status", "n", "id"]}`),
+				JSONPath: []string{
+					`$.status == "lost"`,
+					`$.status == "shipped"`,
+					`$.n == 2`,
+				},
+				Extract: map[string]string{
+					"?status": "$.status",
+				},
+				Timeout: time.Second,
+			},
+		})
+
+		p.AddStep(ctx, &Step{
+			Run: `throw Failure("no tacos");`,
+		})
+
+		p.AddStep(ctx, &Step{
+			Run: `test.State.done = true;`,
+		})
+	}
+
+	if err := tst.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := tst.Run(ctx)
+	if errs == nil {
+		t.Fatal("expected soft failures")
+	}
+	if _, broke := errs.IsBroken(); broke {
+		t.Fatal(errs)
+	}
+
+	if done, _ := tst.State["done"].(bool); !done {
+		t.Fatal("test didn't finish")
+	}
+	if status := tst.Bindings["?status"]; status != "lost" {
+		t.Fatal(JSON(tst.Bindings))
+	}
+
+	msg := errs.Err.Error()
+	for _, want := range []string{
+		"4 soft failure(s):",
+		"id is required",
+		`$.status == "shipped" failed`,
+		"$.n == 2 failed",
+		"no tacos",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("%q doesn't contain %q", msg, want)
+		}
+	}
+}
+
+func TestRecvSoftTimeout(t *testing.T) {
+
+	ctx, s, tst := newTest(t)
+	tst.Soft = true
+
+	{
+		p := &Phase{}
+
+		s.Phases["phase1"] = p
+
+		addMock(t, ctx, p)
+
+		p.AddStep(ctx, &Step{
+			Run: `throw Failure("no tacos");`,
+		})
+
+		p.AddStep(ctx, &Step{
+			Pub: &Pub{
+				Payload: `{"status":"lost"}`,
+			},
+		})
+
+		// A timeout (with a closest message) isn't soft.
+		p.AddStep(ctx, &Step{
+			Recv: &Recv{
+				Pattern: dejson(`{"status":"shipped"}`),
+				Timeout: 50 * time.Millisecond,
+			},
+		})
+
+		p.AddStep(ctx, &Step{
+			Run: `test.State.done = true;`,
+		})
+	}
+
+	if err := tst.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := tst.Run(ctx)
+	if errs == nil {
+		t.Fatal("expected a timeout")
+	}
+
+	if done, _ := tst.State["done"].(bool); done {
+		t.Fatal("test continued after the timeout")
+	}
+
+	msg := errs.Err.Error()
+	for _, want := range []string{
+		"timeout after 50ms",
+		`closest topic '': {"status":"lost"}`,
+		"1 soft failure(s): failure: no tacos",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("%q doesn't contain %q", msg, want)
+		}
+	}
+}
+
+func TestRepeat(t *testing.T) {
+
+	run := func(t *testing.T, r *Repeat) (*Test, error) {
+		ctx, s, tst := newTest(t)
+		p := &Phase{}
+		s.Phases["phase1"] = p
+		p.AddStep(ctx, &Step{
+			Repeat: r,
+		})
+		if err := tst.Init(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if errs := tst.Run(ctx); errs != nil {
+			return tst, errs.Err
+		}
+		return tst, nil
+	}
+
+	count := []*Step{
+		{
+			Run: `test.State.n = (test.State.n || 0) + 1;`,
+		},
+	}
+
+	t.Run("until", func(t *testing.T) {
+		tst, err := run(t, &Repeat{
+			Steps: count,
+			Until: `return 3 <= test.State.n;`,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := tst.Bindings[RepeatIterationsBinding]; n != 3 {
+			t.Fatal(n)
+		}
+	})
+
+	t.Run("max", func(t *testing.T) {
+		_, err := run(t, &Repeat{
+			Steps: count,
+			Until: `return 10 <= test.State.n;`,
+			Max:   2,
+		})
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if msg := err.Error(); !strings.Contains(msg, "repeat reached 2 iteration(s); until last returned false") {
+			t.Fatal(msg)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		_, err := run(t, &Repeat{
+			Steps:   count,
+			Until:   `return false;`,
+			Timeout: 50 * time.Millisecond,
+			Delay:   10 * time.Millisecond,
+		})
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if msg := err.Error(); !strings.Contains(msg, "repeat timed out after 50ms") || !strings.Contains(msg, "until last returned false") {
+			t.Fatal(msg)
+		}
+	})
+
+	t.Run("bad", func(t *testing.T) {
+		_, err := run(t, &Repeat{
+			Steps: count,
+			Until: `return "yes";`,
+		})
+		if _, is := IsBroken(err); !is {
+			t.Fatal(err)
+		}
+	})
 }
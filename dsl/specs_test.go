@@ -91,6 +91,9 @@ func testTest(t *testing.T, tst *Test) {
 		ctx          = NewCtx(ctx0)
 	)
 
+	// As with plax, the test's directory is an include directory.
+	ctx.IncludeDirs = []string{tst.Dir}
+
 	if err := tst.Init(ctx); err != nil {
 		t.Fatal(err)
 	}
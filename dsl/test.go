@@ -91,6 +91,16 @@ type Test struct {
 	// should be interpreted as a success.
 	Negative bool
 
+	// Soft, when true, makes a failed schema validation, JSONPath
+	// or XPath assertion in a recv (or a Failure thrown by a run)
+	// not stop the test.  Instead, the test continues, and Run
+	// reports all of these failures at the end.  A recv timeout
+	// still stops the test.
+	Soft bool `json:",omitempty" yaml:",omitempty"`
+
+	// softFailures accumulates the failures that Soft collects.
+	softFailures failures
+
 	// elapsed is duration between the most recent steps.
 	elapsed time.Duration
 
@@ -106,10 +116,23 @@ type Test struct {
 	// by invoke.Run().
 	Retries *Retries
 
+	// ConnectBackoff, when not nil, is the default Backoff for
+	// opening the channels that the test makes.
+	ConnectBackoff *Backoff `json:",omitempty" yaml:",omitempty"`
+
+	// History, when not nil, remembers the last messages received
+	// on each channel, which a recv that times out reports.
+	History *MsgHistory `json:"-" yaml:"-"`
+
 	// Registry is the channel (type) registry for this test.
 	//
 	// Defaults to TheChanRegistry.
 	Registry ChanRegistry
+
+	// ChanPool, when not nil, shares the connections of the
+	// channels (with PooledChanKinds) that the test makes with
+	// other tests.
+	ChanPool *ChanPool `json:"-" yaml:"-"`
 }
 
 // NewTest create a initialized NewTest from the id and Spec
@@ -274,6 +297,8 @@ func (t *Test) Run(ctx *Ctx) *Errors {
 
 	errs := NewErrors()
 
+	t.softFailures = nil
+
 	if err := t.InitChans(ctx); err != nil {
 		errs.InitErr = err
 		return errs
@@ -296,6 +321,8 @@ func (t *Test) Run(ctx *Ctx) *Errors {
 		}
 	}
 
+	errs.Err = t.softFailures.with(errs.Err)
+
 	if !errs.IsFine() {
 		return errs
 	}
@@ -361,6 +388,10 @@ func (t *Test) makeChan(ctx *Ctx, kind ChanKind, opts interface{}) (Chan, error)
 		return nil, err
 	}
 
+	if t.ChanPool != nil && PooledChanKinds[kind] {
+		return t.ChanPool.Chan(ctx, kind, x, maker)
+	}
+
 	return maker(ctx, x)
 }
 
@@ -410,6 +441,9 @@ func (t *Test) Validate(ctx *Ctx) []error {
 			if s.Doc != "" {
 				ops++
 			}
+			if s.Repeat != nil {
+				ops++
+			}
 			if ops != 1 {
 				errs = append(errs,
 					fmt.Errorf("Step %d of phase %s does not have exactly one ops (%d)",
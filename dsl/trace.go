@@ -0,0 +1,41 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"github.com/google/uuid"
+)
+
+// TraceIDBinding is the binding for the trace ID of a test, which is
+// unique for each test and stable for the duration of the test (even
+// across retries).  Messages can include the trace ID (as in
+// "{?!traceId}") to correlate them with downstream logs.
+//
+// Since the variable starts with "?!", "clearbindings" doesn't remove
+// it.
+const TraceIDBinding = "?!traceId"
+
+// NewTraceID returns a new trace ID from c.TraceIDs or else a random
+// UUID.
+func (c *Ctx) NewTraceID() string {
+	if c.TraceIDs != nil {
+		return c.TraceIDs()
+	}
+	return uuid.New().String()
+}
@@ -0,0 +1,275 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+)
+
+// XMLNode is a (parsed) XML document.
+type XMLNode struct {
+	node *xmlquery.Node
+}
+
+// ParseXML parses the XML document.
+func ParseXML(s string) (*XMLNode, error) {
+	doc, err := xmlquery.Parse(strings.NewReader(s))
+	if err != nil {
+		return nil, fmt.Errorf("bad XML: %w", err)
+	}
+	if doc.SelectElement("*") == nil {
+		return nil, fmt.Errorf("bad XML: no root element")
+	}
+	return &XMLNode{
+		node: doc,
+	}, nil
+}
+
+// XPath is a (parsed) XPath 1.0 expression.
+//
+// The syntax and functions are the ones supported by
+// github.com/antchfx/xpath.  Names with namespace prefixes (like
+// 'soap:Body') match the prefixes used in the document.
+type XPath struct {
+	src  string
+	expr *xpath.Expr
+}
+
+func (p *XPath) String() string {
+	return p.src
+}
+
+// ParseXPath parses the XPath expression.
+func ParseXPath(s string) (*XPath, error) {
+	s = strings.TrimSpace(s)
+	x, err := xpath.Compile(s)
+	if err != nil {
+		return nil, fmt.Errorf("bad XPath '%s': %w", s, err)
+	}
+	return &XPath{
+		src:  s,
+		expr: x,
+	}, nil
+}
+
+// splitXPath returns the XPath at the start of the string and the
+// rest of the string, which starts at the first assertion operator
+// that isn't in brackets, parentheses, or quotes.
+func splitXPath(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	var (
+		depth int
+		quote byte
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case depth == 0 && strings.IndexByte("=!<>", c) >= 0:
+			if c == '=' && !strings.HasPrefix(s[i:], "==") && !strings.HasPrefix(s[i:], "=~") {
+				// XPath's own '='.
+				continue
+			}
+			return s[:i], s[i:]
+		}
+	}
+	return s, ""
+}
+
+// Eval returns the values that the XPath finds in the (parsed XML)
+// document: the string value (with surrounding space trimmed) of
+// each node that it selects or the value of an expression (like
+// 'count(//item)') that isn't a node-set.
+func (p *XPath) Eval(doc *XMLNode) []string {
+	switch vv := p.expr.Evaluate(xmlquery.CreateXPathNavigator(doc.node)).(type) {
+	case *xpath.NodeIterator:
+		var acc []string
+		for vv.MoveNext() {
+			acc = append(acc, strings.TrimSpace(vv.Current().Value()))
+		}
+		return acc
+	case float64:
+		return []string{strconv.FormatFloat(vv, 'f', -1, 64)}
+	default:
+		return []string{fmt.Sprint(vv)}
+	}
+}
+
+// XPathAssertion is an assertion like
+//
+//	/order/status == "shipped"
+//
+// The operators are ==, !=, <, <=, >, >=, and =~ (which matches the
+// value with a regular expression).  The right-hand side is a
+// (quoted or bare) string.  Values are compared as numbers when both
+// sides are numbers.  An assertion without an operator checks that
+// the XPath finds something.
+//
+// When the XPath finds more than one node, the assertion holds if
+// any value satisfies it (except for !=, which holds if no value is
+// equal).  An assertion (even with !=) fails when the XPath finds
+// nothing.
+type XPathAssertion struct {
+	src  string
+	path *XPath
+	op   string
+	want string
+	re   *regexp.Regexp
+}
+
+func (a *XPathAssertion) String() string {
+	return a.src
+}
+
+// ParseXPathAssertion parses an assertion.
+func ParseXPathAssertion(s string) (*XPathAssertion, error) {
+	src, rest := splitXPath(s)
+	p, err := ParseXPath(src)
+	if err != nil {
+		return nil, err
+	}
+	a := &XPathAssertion{
+		src:  strings.TrimSpace(s),
+		path: p,
+	}
+
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		return a, nil
+	}
+
+	for _, op := range []string{"==", "!=", "<=", ">=", "=~", "<", ">"} {
+		if strings.HasPrefix(rest, op) {
+			a.op = op
+			rest = strings.TrimSpace(rest[len(op):])
+			break
+		}
+	}
+	if a.op == "" {
+		return nil, fmt.Errorf("bad XPath assertion '%s': unknown operator at '%s'", s, rest)
+	}
+	if rest == "" {
+		return nil, fmt.Errorf("bad XPath assertion '%s': missing value", s)
+	}
+
+	a.want = rest
+	if rest[0] == '"' {
+		if err := json.Unmarshal([]byte(rest), &a.want); err != nil {
+			return nil, fmt.Errorf("bad XPath assertion '%s': %w", s, err)
+		}
+	} else if q := rest[0]; q == '\'' && 2 <= len(rest) && rest[len(rest)-1] == q {
+		a.want = rest[1 : len(rest)-1]
+	}
+
+	if a.op == "=~" {
+		if a.re, err = regexp.Compile(a.want); err != nil {
+			return nil, fmt.Errorf("bad XPath assertion '%s': %w", s, err)
+		}
+	}
+
+	return a, nil
+}
+
+// holds checks the operator for one value.
+func (a *XPathAssertion) holds(x string) bool {
+	if a.op == "=~" {
+		return a.re.MatchString(x)
+	}
+
+	var c int
+	f, err0 := strconv.ParseFloat(x, 64)
+	w, err1 := strconv.ParseFloat(a.want, 64)
+	if err0 == nil && err1 == nil {
+		switch {
+		case f < w:
+			c = -1
+		case w < f:
+			c = 1
+		}
+	} else {
+		c = strings.Compare(x, a.want)
+	}
+
+	switch a.op {
+	case "==", "!=":
+		return c == 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return 0 < c
+	default: // ">="
+		return 0 <= c
+	}
+}
+
+// Check returns an error that reports the content found if the
+// assertion doesn't hold for the (parsed XML) document.
+func (a *XPathAssertion) Check(doc *XMLNode) error {
+	xs := a.path.Eval(doc)
+
+	found := func() string {
+		if len(xs) == 1 {
+			return JSON(xs[0])
+		}
+		return JSON(xs)
+	}
+
+	// Every assertion (including !=) needs something to check.
+	if len(xs) == 0 {
+		return fmt.Errorf("xpath %s found nothing", a.src)
+	}
+
+	if a.op == "" {
+		return nil
+	}
+
+	if a.op == "!=" {
+		for _, x := range xs {
+			if a.holds(x) {
+				return fmt.Errorf("xpath %s failed: found %s", a.src, found())
+			}
+		}
+		return nil
+	}
+
+	for _, x := range xs {
+		if a.holds(x) {
+			return nil
+		}
+	}
+	return fmt.Errorf("xpath %s failed: found %s", a.src, found())
+}
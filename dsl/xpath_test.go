@@ -0,0 +1,127 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dsl
+
+import (
+	"strings"
+	"testing"
+)
+
+const testXML = `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <order id="7" status="shipped">
+      <item sku="taco"><n>2</n></item>
+      <item sku="queso"><n>1</n></item>
+      <note>Extra
+        <b>hot</b></note>
+    </order>
+  </soap:Body>
+</soap:Envelope>`
+
+func TestXPath(t *testing.T) {
+	doc, err := ParseXML(testXML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for src, want := range map[string]string{
+		`/Envelope/Body/order/@id`:           `null`,
+		`/soap:Envelope/soap:Body/order/@id`: `["7"]`,
+		`//item/@sku`:                        `["taco","queso"]`,
+		`//item[2]/@sku`:                     `["queso"]`,
+		`//item[last()]/n`:                   `["1"]`,
+		`//item[@sku='taco']/n`:              `["2"]`,
+		`//item[n="1"]/@sku`:                 `["queso"]`,
+		`//order/*/@sku`:                     `["taco","queso"]`,
+		`//order/@*`:                         `["7","shipped"]`,
+		`//note`:                             `["Extra\n        hot"]`,
+		`//note/text()`:                      `["Extra"]`,
+		`//n/../@sku`:                        `["taco","queso"]`,
+		`//order/nope`:                       `null`,
+		`//item[3]`:                          `null`,
+		`//item[n > 1]/@sku`:                 `["taco"]`,
+		`count(//item)`:                      `["2"]`,
+		`//*[local-name()='Body']/order/@id`: `["7"]`,
+	} {
+		p, err := ParseXPath(src)
+		if err != nil {
+			t.Fatalf("%s: %s", src, err)
+		}
+		if got := JSON(p.Eval(doc)); got != want {
+			t.Fatalf("%s: got %s (wanted %s)", src, got, want)
+		}
+	}
+
+	for _, src := range []string{``, `/a[1`, `/a[@b=]`, `/a/@`, `count(`} {
+		if _, err := ParseXPath(src); err == nil {
+			t.Fatalf("%s: no error", src)
+		}
+	}
+
+	for _, src := range []string{``, `<a>`, `not xml`} {
+		if _, err := ParseXML(src); err == nil {
+			t.Fatalf("%s: no error", src)
+		}
+	}
+}
+
+func TestXPathAssertion(t *testing.T) {
+	doc, err := ParseXML(testXML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for src, want := range map[string]string{
+		`//order/@status == "shipped"`:       "",
+		`//order/@status == shipped`:         "",
+		`//order/@status != 'pending'`:       "",
+		`//order/@id >= 7`:                   "",
+		`//order/@id < 10`:                   "",
+		`//order/@id < 7`:                    `found "7"`,
+		`//order/@status =~ ^ship`:           "",
+		`//item/@sku == queso`:               "",
+		`//item/@sku != queso`:               `found ["taco","queso"]`,
+		`//order/@status == delivered`:       `xpath //order/@status == delivered failed: found "shipped"`,
+		`//item`:                             "",
+		`//nope`:                             `xpath //nope found nothing`,
+		`//nope == 1`:                        `found nothing`,
+		`//nope != x`:                        `xpath //nope != x found nothing`,
+		`count(//item[@sku != 'taco']) == 1`: "",
+		`//item[n > 1]/@sku == taco`:         "",
+	} {
+		a, err := ParseXPathAssertion(src)
+		if err != nil {
+			t.Fatalf("%s: %s", src, err)
+		}
+		err = a.Check(doc)
+		switch {
+		case want == "" && err != nil:
+			t.Fatalf("%s: %s", src, err)
+		case want != "" && (err == nil || !strings.Contains(err.Error(), want)):
+			t.Fatalf("%s: got %v (wanted %s)", src, err, want)
+		}
+	}
+
+	for _, src := range []string{`/x ~ 1`, `/x ==`, `/x =~ (`, `/x == "a`} {
+		if _, err := ParseXPathAssertion(src); err == nil {
+			t.Fatalf("%s: no error", src)
+		}
+	}
+}
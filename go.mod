@@ -5,24 +5,44 @@ go 1.16
 require (
 	github.com/Comcast/sheens v0.9.1-0.20210115175817-a1a65cee59ac
 	github.com/alecthomas/jsonschema v0.0.0-20210526225647-edb03dcab7bc
+	github.com/alicebob/miniredis/v2 v2.23.0
+	github.com/antchfx/xmlquery v1.3.18
+	github.com/antchfx/xpath v1.2.5
 	github.com/avarabyeu/goRP/v5 v5.0.1 // indirect
 	github.com/aws/aws-sdk-go v1.40.4
 	github.com/dop251/goja v0.0.0-20210720190508-a7a3a1366b2e
+	github.com/eclipse/paho.golang v0.12.0
 	github.com/eclipse/paho.mqtt.golang v1.3.5
+	github.com/fsnotify/fsnotify v1.4.9
+	github.com/go-redis/redis/v8 v8.11.4
 	github.com/go-resty/resty/v2 v2.7.0 // indirect
 	github.com/go-sql-driver/mysql v1.6.0
-	github.com/google/uuid v1.3.0 // indirect
+	github.com/golang/protobuf v1.5.2
+	github.com/google/uuid v1.3.0
+	github.com/gorilla/websocket v1.5.0
 	github.com/harlow/kinesis-consumer v0.3.4
 	github.com/hashicorp/go-plugin v1.4.3
 	github.com/iancoleman/orderedmap v0.2.0 // indirect
 	github.com/itchyny/gojq v0.12.4
+	github.com/jhump/protoreflect v1.6.0
+	github.com/linkedin/goavro/v2 v2.15.0
+	github.com/nats-io/jwt/v2 v2.2.0
+	github.com/nats-io/nats-server/v2 v2.6.6
+	github.com/nats-io/nats.go v1.16.0
+	github.com/nats-io/nkeys v0.3.0
+	github.com/ohler55/ojg v1.12.4
+	github.com/rabbitmq/amqp091-go v1.5.0
 	github.com/xeipuuv/gojsonpointer v0.0.0-20190905194746-02993c407bfb // indirect
 	github.com/xeipuuv/gojsonschema v1.2.0
-	golang.org/x/net v0.0.0-20211029224645-99673261e6eb // indirect
-	golang.org/x/sys v0.0.0-20210630005230-0f9fa26af87c // indirect
-	golang.org/x/tools v0.1.5 // indirect
+	go.opentelemetry.io/otel v1.7.0
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp v1.7.0
+	go.opentelemetry.io/otel/sdk v1.7.0
+	go.opentelemetry.io/otel/trace v1.7.0
+	go.opentelemetry.io/proto/otlp v0.16.0
+	google.golang.org/grpc v1.46.0
+	google.golang.org/protobuf v1.28.0
 	gopkg.in/yaml.v1 v1.0.0-20140924161607-9f9df34309c0
-	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
+	gopkg.in/yaml.v3 v3.0.1
 	modernc.org/ccgo/v3 v3.9.6 // indirect
 	modernc.org/memory v1.0.5 // indirect
 	modernc.org/sqlite v1.11.2
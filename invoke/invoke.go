@@ -31,6 +31,7 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -72,7 +73,40 @@ type Invocation struct {
 	// React will set dsl.Ctx.Redact to enable log redactions.
 	Redact bool
 
+	// CaptureLogs will add each test's (redacted) log output to
+	// its TestCase as SystemOut (and warnings as SystemErr).
+	CaptureLogs bool
+
+	// CaptureBindings will add each test's final (redacted)
+	// bindings to its TestCase as "binding.VAR" properties.
+	CaptureBindings bool
+
+	// MsgHistory, when positive, is the number of messages
+	// received on each channel that each test remembers, so that
+	// a recv that times out can report them.  See dsl.MsgHistory.
+	MsgHistory int
+
+	// ConnectBackoff, when not nil, is the default
+	// dsl.Test.ConnectBackoff for tests that don't specify their
+	// own.
+	ConnectBackoff *dsl.Backoff
+
+	// IncludeTimeout and IncludeHeader, when not zero, configure
+	// the fetching of remote (http(s)) includes.  See
+	// dsl.IncludeFetcher.
+	IncludeTimeout time.Duration
+	IncludeHeader  string
+
+	// ChanPool, when not nil, is the dsl.Test.ChanPool of each
+	// test, which then share their connections.
+	ChanPool *dsl.ChanPool
+
 	retries *dsl.Retries
+
+	// fileDir reports whether Dir was set to the directory of the
+	// Filename (rather than given), so that the Invocation can be
+	// executed again.
+	fileDir bool
 }
 
 const (
@@ -87,30 +121,10 @@ const (
 //
 // This method calls Run(t) for each test t in the Invocation.
 func (inv *Invocation) Exec(ctx context.Context) (*junit.TestSuite, error) {
-	dslCtx := dsl.NewCtx(ctx)
-	dslCtx.Redact = inv.Redact
-
-	if len(inv.LogLevel) > 0 {
-		if err := dslCtx.SetLogLevel(inv.LogLevel); err != nil {
-			log.Fatal(err)
-		}
-	}
+	dslCtx := inv.newCtx(ctx)
 
 	inv.retries = dsl.NewRetries()
 
-	wd, err := os.Getwd()
-	if err != nil {
-		panic(err)
-	}
-
-	// Add invocation includeDirs to the dslCtx
-	if inv.IncludeDirs != nil {
-		dslCtx.IncludeDirs = inv.IncludeDirs
-	}
-
-	// Add current working directory to includeDirs
-	dslCtx.IncludeDirs = append(dslCtx.IncludeDirs, wd)
-
 	if inv.Retry != "" {
 		if n, err := strconv.Atoi(inv.Retry); err == nil {
 			inv.retries.N = n
@@ -125,60 +139,15 @@ func (inv *Invocation) Exec(ctx context.Context) (*junit.TestSuite, error) {
 	var (
 		suiteName = strings.ReplaceAll(inv.SuiteName, "{TS}", time.Now().UTC().Format(time.RFC3339Nano))
 		ts        = junit.NewTestSuite(suiteName)
-		filenames = make([]string, 0, 8)
 	)
 
-	// Populate filenames.
-	if inv.Dir != "" {
-		dir, err := filepath.Abs(inv.Dir)
-		if err != nil {
-			log.Fatal(err)
-		}
-		inv.Dir = dir
-
-		if suiteName == "" {
-			ts.Name = inv.Dir
-		}
-
-		// Set the context directory
-		dslCtx.Dir = dir
-
-		// Add the test spec's directory to the end of the includeDirs.
-		dslCtx.IncludeDirs = append(dslCtx.IncludeDirs, inv.Dir)
-
-		fs, err := ioutil.ReadDir(inv.Dir)
-		if err != nil {
-			log.Fatal(err)
-		}
-		for _, f := range fs {
-			if !strings.HasSuffix(f.Name(), ".yaml") {
-				continue
-			}
-			pathname := inv.Dir + "/" + f.Name()
-			filenames = append(filenames, pathname)
-		}
-	} else {
-		dir, err := filepath.Abs(filepath.Dir(inv.Filename))
-		if err != nil {
-			log.Fatal(err)
-		}
-		inv.Dir = dir
-
-		if suiteName == "" {
-			ts.Name = inv.Dir
-		}
-
-		// Set the context directory
-		dslCtx.Dir = dir
-
-		// Add the test spec's directory to the end of the includeDirs.
-		dslCtx.IncludeDirs = append(dslCtx.IncludeDirs, inv.Dir)
+	filenames, err := inv.filenames(dslCtx)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-		filename, err := filepath.Abs(inv.Filename)
-		if err != nil {
-			log.Fatal(err)
-		}
-		filenames = append(filenames, filename)
+	if suiteName == "" {
+		ts.Name = inv.Dir
 	}
 
 	var (
@@ -214,7 +183,25 @@ func (inv *Invocation) Exec(ctx context.Context) (*junit.TestSuite, error) {
 
 		log.Printf("Running test %s", filename)
 
-		if err := inv.Run(dslCtx, t); err != nil {
+		// The trace ID is stable for the test (even across
+		// retries) unless it's bound explicitly.
+		traceID, given := inv.Bindings[dsl.TraceIDBinding]
+		if !given {
+			traceID = dslCtx.NewTraceID()
+			t.Bindings[dsl.TraceIDBinding] = traceID
+		}
+		tc.AddProperty("traceId", fmt.Sprintf("%v", traceID))
+
+		tctx := dslCtx
+		var capture *dsl.LogCapture
+		if inv.CaptureLogs {
+			capture = dsl.NewLogCapture(dslCtx.Logger, dslCtx.Redactions)
+			c := *dslCtx
+			c.Logger = capture
+			tctx = &c
+		}
+
+		if err := inv.Run(tctx, t); err != nil {
 			if b, is := dsl.IsBroken(err); is {
 				// Any broken test is a failure (even
 				// for a 'negative' test).
@@ -245,6 +232,15 @@ func (inv *Invocation) Exec(ctx context.Context) (*junit.TestSuite, error) {
 			}
 		}
 
+		if capture != nil {
+			tc.SystemOut = capture.Stdout()
+			tc.SystemErr = capture.Stderr()
+		}
+
+		if inv.CaptureBindings {
+			addBindings(dslCtx, tc, t.Bindings)
+		}
+
 		ts.Add(*tc)
 	}
 
@@ -268,6 +264,158 @@ func (inv *Invocation) Exec(ctx context.Context) (*junit.TestSuite, error) {
 	return ts, nil
 }
 
+// addBindings adds the bindings (sorted by variable) to the TestCase
+// as properties.  Values of variables that want redaction (see
+// dsl.WantsRedaction) are replaced, and known redactions are always
+// applied to the other values.
+func addBindings(ctx *dsl.Ctx, tc *junit.TestCase, bs dsl.Bindings) {
+	ps := make([]string, 0, len(bs))
+	for p := range bs {
+		ps = append(ps, p)
+	}
+	sort.Strings(ps)
+
+	for _, p := range ps {
+		s, is := bs[p].(string)
+		if !is {
+			s = dsl.JSON(bs[p])
+		}
+		if dsl.WantsRedaction(p) {
+			s = "<redacted>"
+		} else {
+			s = ctx.Redactions.RedactAll(s)
+		}
+		tc.AddProperty("binding."+p, s)
+	}
+}
+
+// newCtx makes the dsl.Ctx for the Invocation.
+func (inv *Invocation) newCtx(ctx context.Context) *dsl.Ctx {
+	dslCtx := dsl.NewCtx(ctx)
+
+	// A parent dsl.Ctx shares its Redactions with the invocations,
+	// which can execute concurrently (plaxrun -concurrency), so a
+	// different setting goes in a copy.
+	if dslCtx.Redact != inv.Redact {
+		if _, shared := ctx.(*dsl.Ctx); shared {
+			dslCtx.Redactions = dslCtx.Redactions.Copy()
+		}
+		dslCtx.Redact = inv.Redact
+	}
+
+	if inv.IncludeTimeout != 0 || inv.IncludeHeader != "" {
+		dslCtx.Fetcher = dsl.NewIncludeFetcher(inv.IncludeTimeout, inv.IncludeHeader)
+	}
+
+	if len(inv.LogLevel) > 0 {
+		if err := dslCtx.SetLogLevel(inv.LogLevel); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		panic(err)
+	}
+
+	// Add invocation includeDirs to the dslCtx
+	if inv.IncludeDirs != nil {
+		dslCtx.IncludeDirs = inv.IncludeDirs
+	}
+
+	// Add current working directory to includeDirs
+	dslCtx.IncludeDirs = append(dslCtx.IncludeDirs, wd)
+
+	return dslCtx
+}
+
+// filenames returns the test files of the Invocation (the YAML files
+// in Dir or else the Filename), and it sets up the dslCtx to resolve
+// their includes.
+func (inv *Invocation) filenames(dslCtx *dsl.Ctx) ([]string, error) {
+	filenames := make([]string, 0, 8)
+
+	if inv.Dir != "" && !inv.fileDir {
+		dir, err := filepath.Abs(inv.Dir)
+		if err != nil {
+			return nil, err
+		}
+		inv.Dir = dir
+
+		// Set the context directory
+		dslCtx.Dir = dir
+
+		// Add the test spec's directory to the end of the includeDirs.
+		dslCtx.IncludeDirs = append(dslCtx.IncludeDirs, inv.Dir)
+
+		fs, err := ioutil.ReadDir(inv.Dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fs {
+			if !strings.HasSuffix(f.Name(), ".yaml") {
+				continue
+			}
+			pathname := inv.Dir + "/" + f.Name()
+			filenames = append(filenames, pathname)
+		}
+	} else {
+		dir, err := filepath.Abs(filepath.Dir(inv.Filename))
+		if err != nil {
+			return nil, err
+		}
+		inv.Dir = dir
+		inv.fileDir = true
+
+		// Set the context directory
+		dslCtx.Dir = dir
+
+		// Add the test spec's directory to the end of the includeDirs.
+		dslCtx.IncludeDirs = append(dslCtx.IncludeDirs, inv.Dir)
+
+		filename, err := filepath.Abs(inv.Filename)
+		if err != nil {
+			return nil, err
+		}
+		filenames = append(filenames, filename)
+	}
+
+	return filenames, nil
+}
+
+// Preflight checks the channels of the wanted tests (see
+// dsl.Test.Preflight) without executing them.  The checks are keyed
+// by test name.
+func (inv *Invocation) Preflight(ctx context.Context, timeout time.Duration) (map[string][]dsl.ChanCheck, error) {
+	dslCtx := inv.newCtx(ctx)
+
+	filenames, err := inv.filenames(dslCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := make(map[string][]dsl.ChanCheck)
+
+	for _, filename := range filenames {
+		t, err := inv.Load(dslCtx, filename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", filename, err)
+		}
+
+		if !t.Wanted(dslCtx, inv.Priority, strings.Split(inv.Labels, ","), inv.Tests) {
+			continue
+		}
+
+		for p, v := range inv.Bindings {
+			t.Bindings[p] = v
+		}
+
+		checks[t.Name] = t.Preflight(dslCtx, timeout)
+	}
+
+	return checks, nil
+}
+
 // Load a test
 func (inv *Invocation) Load(ctx *dsl.Ctx, filename string) (*dsl.Test, error) {
 	bs, err := ioutil.ReadFile(filename)
@@ -367,9 +515,14 @@ func (inv *Invocation) RunOnce(ctx *dsl.Ctx, t *dsl.Test) error {
 		return dsl.Brokenf("test is nil")
 	}
 
-	if t.Seed != 0 {
-		log.Printf("Setting pseudo-random number generator seed: %v", t.Seed)
-		rand.Seed(t.Seed)
+	// A -seed overrides the test's seed.
+	seed := t.Seed
+	if inv.Seed != 0 {
+		seed = inv.Seed
+	}
+	if seed != 0 {
+		log.Printf("Setting pseudo-random number generator seed: %v", seed)
+		rand.Seed(seed)
 	}
 
 	for p, v := range inv.Bindings {
@@ -379,6 +532,16 @@ func (inv *Invocation) RunOnce(ctx *dsl.Ctx, t *dsl.Test) error {
 		t.Bindings[p] = v
 	}
 
+	if t.ConnectBackoff == nil {
+		t.ConnectBackoff = inv.ConnectBackoff
+	}
+
+	t.ChanPool = inv.ChanPool
+
+	if 0 < inv.MsgHistory {
+		t.History = dsl.NewMsgHistory(inv.MsgHistory)
+	}
+
 	if err := t.Init(ctx); err != nil {
 		return err
 	}
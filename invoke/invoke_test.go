@@ -20,6 +20,8 @@ package invoke
 
 import (
 	"context"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 
 	"github.com/Comcast/plax/dsl"
@@ -59,3 +61,137 @@ func TestInvocationBasic(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestInvocationCaptureLogs(t *testing.T) {
+	i := &Invocation{
+		SuiteName:   "test:mock",
+		Filename:    "../demos/mock.yaml",
+		Seed:        42,
+		CaptureLogs: true,
+	}
+
+	ctx := dsl.NewCtx(context.Background())
+	ts, err := i.Exec(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ts.TestCase) != 1 || ts.TestCase[0].SystemOut == "" {
+		t.Fatalf("expected captured logs: %#v", ts.TestCase)
+	}
+}
+
+func TestInvocationTraceID(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "trace.yaml")
+	spec := `spec:
+  phases:
+    phase1:
+      steps:
+        - "$include<include/mock.yaml>"
+        - sub:
+            pattern: test
+        - pub:
+            topic: test
+            payload: '{"trace":"{?!traceId}"}'
+        - recv:
+            pattern: '{"trace":"trace-1"}'
+            timeout: 2s
+`
+	if err := ioutil.WriteFile(filename, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	i := &Invocation{
+		SuiteName:          "test:trace",
+		Filename:           filename,
+		IncludeDirs:        []string{"../demos"},
+		ComplainOnAnyError: true,
+	}
+
+	ctx := dsl.NewCtx(context.Background())
+	ctx.TraceIDs = func() string {
+		return "trace-1"
+	}
+
+	ts, err := i.Exec(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ps := ts.TestCase[0].Properties
+	if len(ps) != 1 || ps[0].Name != "traceId" || ps[0].Value != "trace-1" {
+		t.Fatalf("unexpected properties %#v", ps)
+	}
+}
+
+func TestInvocationCaptureBindings(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "capture.yaml")
+	spec := `spec:
+  phases:
+    phase1:
+      steps:
+        - "$include<include/mock.yaml>"
+        - pub:
+            payload: '{"order":{"id":42},"token":"{X_TOKEN}"}'
+        - recv:
+            pattern: '{"order":"?order","token":"?token"}'
+            timeout: 2s
+`
+	if err := ioutil.WriteFile(filename, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	i := &Invocation{
+		SuiteName:   "test:capture",
+		Filename:    filename,
+		IncludeDirs: []string{"../demos"},
+		Bindings: map[string]interface{}{
+			"X_TOKEN": "tacos",
+		},
+		ComplainOnAnyError: true,
+		CaptureBindings:    true,
+	}
+
+	ctx := dsl.NewCtx(context.Background())
+	ctx.TraceIDs = func() string {
+		return "trace-1"
+	}
+
+	ts, err := i.Exec(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string)
+	for _, p := range ts.TestCase[0].Properties {
+		got[p.Name] = p.Value
+	}
+	for name, want := range map[string]string{
+		"binding.?!traceId": "trace-1",
+		"binding.?order":    `{"id":42}`,
+		"binding.?token":    "<redacted>",
+		"binding.X_TOKEN":   "<redacted>",
+	} {
+		if got[name] != want {
+			t.Fatalf("%s is %q rather than %q in %v", name, got[name], want, got)
+		}
+	}
+}
+
+func TestInvocationExecAgain(t *testing.T) {
+	i := &Invocation{
+		SuiteName: "test:mock",
+		Filename:  "../demos/mock.yaml",
+	}
+
+	ctx := dsl.NewCtx(context.Background())
+	for attempt := 1; attempt <= 2; attempt++ {
+		ts, err := i.Exec(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ts.TestCase) != 1 {
+			t.Fatalf("attempt %d executed %d tests", attempt, len(ts.TestCase))
+		}
+	}
+}
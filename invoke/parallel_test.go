@@ -0,0 +1,70 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package invoke
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Comcast/plax/cmd/plaxrun/async"
+	"github.com/Comcast/plax/dsl"
+	"github.com/Comcast/plax/junit"
+)
+
+// TestInvocationParallel executes real specs concurrently with a
+// shared parent dsl.Ctx like plaxrun -concurrency does.  Use
+// "go test -race" to check that the invocations don't race on the
+// shared state (like the Redactions).
+func TestInvocationParallel(t *testing.T) {
+	ctx := dsl.NewCtx(context.Background())
+	ctx.Redact = true
+
+	tfs := make([]*async.TaskFunc, 0, 8)
+	for i := 0; i < 8; i++ {
+		i := i
+		tfs = append(tfs, &async.TaskFunc{
+			Name: fmt.Sprintf("mock-%d", i),
+			Func: func() (*junit.TestSuite, error) {
+				inv := &Invocation{
+					SuiteName: fmt.Sprintf("test:mock-%d", i),
+					Filename:  "../demos/mock.yaml",
+					Seed:      int64(i),
+					Redact:    i%2 == 0,
+					LogLevel:  "none",
+				}
+				return inv.Exec(ctx)
+			},
+		})
+	}
+
+	results, err := async.Parallel(ctx, 4, tfs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range results {
+		if r.Error != nil {
+			t.Fatalf("%s: %s", r.Name, r.Error)
+		}
+		if ts, _ := r.Result.(*junit.TestSuite); ts == nil || ts.Passed != ts.Total {
+			t.Fatalf("%s: unexpected result %#v", r.Name, r.Result)
+		}
+	}
+}
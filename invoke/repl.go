@@ -0,0 +1,254 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package invoke
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Comcast/plax/dsl"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// REPLHistory is the number of messages per channel that the
+	// REPL shows for each step.
+	REPLHistory = 20
+
+	// REPLTimeout is the default timeout of a recv entered in the
+	// REPL.
+	REPLTimeout = 10 * time.Second
+)
+
+// replHelp describes the REPL's commands.
+const replHelp = `Enter a step (or a list of steps) in YAML followed by an empty line.
+A recv without a timeout gives up after %s.  For example:
+
+  pub:
+    chan: mock
+    payload: '{"want":"tacos"}'
+
+Commands:
+
+  :phases          list the phases of the test
+  :run PHASE       execute the steps of the phase
+  :bindings        show the current bindings
+  :chans           list the channels
+  :help            show this help
+  :quit            exit
+`
+
+// REPL executes steps that it reads from in, and it writes what
+// happened (including the messages that the steps received) to out.
+//
+// When the Invocation has a Filename, the REPL loads that test, whose
+// phases are then available via ":run PHASE".  Steps execute with the
+// same machinery (and the same test) as a regular test run.
+func (inv *Invocation) REPL(ctx context.Context, in io.Reader, out io.Writer) error {
+	dslCtx := inv.newCtx(ctx)
+
+	var t *dsl.Test
+	if inv.Filename != "" {
+		filenames, err := inv.filenames(dslCtx)
+		if err != nil {
+			return err
+		}
+		if t, err = inv.Load(dslCtx, filenames[0]); err != nil {
+			return err
+		}
+		if t.Spec == nil {
+			t.Spec = dsl.NewSpec()
+		}
+	} else {
+		t = dsl.NewTest(dslCtx, "repl", dsl.NewSpec())
+		t.Name = "repl"
+	}
+
+	for p, v := range inv.Bindings {
+		t.Bindings[p] = v
+	}
+	if t.ConnectBackoff == nil {
+		t.ConnectBackoff = inv.ConnectBackoff
+	}
+
+	if err := t.Init(dslCtx); err != nil {
+		return err
+	}
+	if err := t.InitChans(dslCtx); err != nil {
+		return err
+	}
+	defer t.Close(dslCtx)
+
+	fmt.Fprintf(out, "Test %s. Type :help for help.\n", t.Name)
+
+	var (
+		lines = bufio.NewScanner(in)
+		acc   []string
+	)
+
+	prompt := func() {
+		if len(acc) == 0 {
+			fmt.Fprint(out, "plax> ")
+		} else {
+			fmt.Fprint(out, "  ... ")
+		}
+	}
+
+	for prompt(); lines.Scan(); prompt() {
+		line := lines.Text()
+
+		if len(acc) == 0 && strings.HasPrefix(strings.TrimSpace(line), ":") {
+			if quit := replCommand(dslCtx, t, strings.Fields(line), out); quit {
+				return nil
+			}
+			continue
+		}
+
+		if strings.TrimSpace(line) != "" {
+			acc = append(acc, line)
+			continue
+		}
+		if len(acc) == 0 {
+			continue
+		}
+
+		src := strings.Join(acc, "\n")
+		acc = nil
+
+		steps, err := replSteps(dslCtx, src)
+		if err != nil {
+			fmt.Fprintf(out, "error: %s\n", err)
+			continue
+		}
+		replExec(dslCtx, t, &dsl.Phase{Steps: steps}, out)
+	}
+
+	return lines.Err()
+}
+
+// replSteps parses the YAML for one step or a list of steps.
+func replSteps(ctx *dsl.Ctx, src string) ([]*dsl.Step, error) {
+	bs, err := dsl.IncludeYAML(ctx, []byte(src))
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []*dsl.Step
+	if err := yaml.Unmarshal(bs, &steps); err != nil {
+		var s dsl.Step
+		if err := yaml.Unmarshal(bs, &s); err != nil {
+			return nil, err
+		}
+		steps = []*dsl.Step{&s}
+	}
+
+	for _, s := range steps {
+		if s == nil || s.Kind() == "" {
+			return nil, fmt.Errorf("not a step: %s", src)
+		}
+		if s.Recv != nil && s.Recv.Timeout == 0 {
+			s.Recv.Timeout = REPLTimeout
+		}
+	}
+
+	return steps, nil
+}
+
+// replExec executes the phase and then writes the messages its steps
+// received, the outcome, and any changed bindings.
+func replExec(ctx *dsl.Ctx, t *dsl.Test, p *dsl.Phase, out io.Writer) {
+	before := dsl.JSON(t.Bindings)
+
+	t.History = dsl.NewMsgHistory(REPLHistory)
+	next, err := p.Exec(ctx, t)
+
+	names := make([]string, 0, len(t.Chans))
+	for name := range t.Chans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, m := range t.History.Last(t.Chans[name]) {
+			fmt.Fprintf(out, "received on %s: topic '%s': %s\n", name, m.Topic, m.Payload)
+		}
+	}
+
+	switch _, broke := dsl.IsBroken(err); {
+	case broke:
+		fmt.Fprintf(out, "broken: %s\n", err)
+	case err != nil:
+		fmt.Fprintf(out, "failed: %s\n", err)
+	default:
+		fmt.Fprintf(out, "ok\n")
+	}
+
+	if after := dsl.JSON(t.Bindings); after != before {
+		fmt.Fprintf(out, "bindings: %s\n", after)
+	}
+	if next != "" {
+		fmt.Fprintf(out, "next phase: %s\n", next)
+	}
+}
+
+// replCommand executes a REPL command, and it reports whether that
+// command was ":quit".
+func replCommand(ctx *dsl.Ctx, t *dsl.Test, args []string, out io.Writer) bool {
+	switch args[0] {
+	case ":quit", ":q", ":exit":
+		return true
+	case ":help", ":h":
+		fmt.Fprintf(out, replHelp, REPLTimeout)
+	case ":bindings", ":b":
+		fmt.Fprintf(out, "%s\n", dsl.JSON(t.Bindings))
+	case ":chans":
+		names := make([]string, 0, len(t.Chans))
+		for name, c := range t.Chans {
+			names = append(names, fmt.Sprintf("%s (%s)", name, c.Kind()))
+		}
+		sort.Strings(names)
+		fmt.Fprintf(out, "%s\n", strings.Join(names, "\n"))
+	case ":phases":
+		names := make([]string, 0, len(t.Spec.Phases))
+		for name := range t.Spec.Phases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(out, "%s\n", strings.Join(names, "\n"))
+	case ":run":
+		if len(args) != 2 {
+			fmt.Fprintf(out, "usage: :run PHASE\n")
+			break
+		}
+		p, have := t.Spec.Phases[args[1]]
+		if !have {
+			fmt.Fprintf(out, "no phase '%s'\n", args[1])
+			break
+		}
+		replExec(ctx, t, p, out)
+	default:
+		fmt.Fprintf(out, "unknown command %s (try :help)\n", args[0])
+	}
+	return false
+}
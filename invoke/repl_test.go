@@ -0,0 +1,109 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package invoke
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestREPL(t *testing.T) {
+	in := `
+- "$include<include/mock.yaml>"
+- sub:
+    pattern: test
+
+pub:
+  topic: test
+  payload: '{"want":"tacos","n":2}'
+
+recv: {pattern: '{"want":"?want"}', timeout: 1s}
+
+:bindings
+recv: {pattern: '{"want":"chips"}', timeout: 100ms}
+
+pub: {payload: 1}
+  nope: 2
+
+:run nope
+:run phase1
+:phases
+:nope
+:quit
+pub: {payload: "never"}
+
+`
+	filename := filepath.Join(t.TempDir(), "repl.yaml")
+	spec := `name: queso
+spec:
+  phases:
+    phase1:
+      steps:
+        - pub:
+            topic: test
+            payload: '{"want":"queso"}'
+        - recv:
+            pattern: '{"want":"queso"}'
+            timeout: 1s
+        - goto: phase2
+    phase2:
+      steps: []
+`
+	if err := ioutil.WriteFile(filename, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		out bytes.Buffer
+		inv = &Invocation{
+			Filename:    filename,
+			IncludeDirs: []string{"../demos"},
+		}
+	)
+
+	if err := inv.REPL(context.Background(), strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"Test queso.",
+		`"success":true}`,
+		`received on mock: topic 'test': {"n":2,"want":"tacos"}`,
+		`bindings: {"?want":"tacos"}`,
+		"failed: step 0 (recv): timeout after 100ms",
+		"error: yaml:",
+		"no phase 'nope'",
+		`received on mock: topic 'test': {"want":"queso"}`,
+		"next phase: phase2",
+		"phase1\nphase2\n",
+		"unknown command :nope",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("%q doesn't contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "never") {
+		t.Fatalf("%q continued after :quit", got)
+	}
+}
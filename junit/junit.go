@@ -36,14 +36,24 @@ const (
 	Skipped TestCaseStatus = "skipped"
 )
 
+// Property is a name/value pair that annotates a TestCase
+type Property struct {
+	Name  string `xml:"name,attr" json:"name"`
+	Value string `xml:"value,attr" json:"value"`
+}
+
 // TestCase information
 type TestCase struct {
-	Name    string         `xml:"name,attr" json:"name"`
-	File    string         `xml:"file,attr" json:"file"`
-	Status  TestCaseStatus `xml:"status,attr" json:"status"`
-	Time    *time.Duration `xml:"time,attr,omitempty" json:"time,omitempty"`
-	Started *time.Time     `xml:"started,attr,omitempty" json:"started,omitempty"`
-	Message string         `xml:"message,omitempty" json:"message,omitempty"`
+	Name       string         `xml:"name,attr" json:"name"`
+	Classname  string         `xml:"classname,attr,omitempty" json:"classname,omitempty"`
+	File       string         `xml:"file,attr" json:"file"`
+	Status     TestCaseStatus `xml:"status,attr" json:"status"`
+	Time       *time.Duration `xml:"time,attr,omitempty" json:"time,omitempty"`
+	Started    *time.Time     `xml:"started,attr,omitempty" json:"started,omitempty"`
+	Properties []Property     `xml:"properties>property,omitempty" json:"properties,omitempty"`
+	Message    string         `xml:"message,omitempty" json:"message,omitempty"`
+	SystemOut  string         `xml:"system-out,omitempty" json:"systemOut,omitempty"`
+	SystemErr  string         `xml:"system-err,omitempty" json:"systemErr,omitempty"`
 }
 
 // NewTestCase creates a new TestCase
@@ -74,18 +84,27 @@ func (tc *TestCase) Finish(status TestCaseStatus, message ...string) {
 	}
 }
 
+// AddProperty adds a Property to the TestCase
+func (tc *TestCase) AddProperty(name string, value string) {
+	tc.Properties = append(tc.Properties, Property{
+		Name:  name,
+		Value: value,
+	})
+}
+
 // TestSuite information
 type TestSuite struct {
-	Name     string        `xml:"name,attr" json:"name"`
-	Total    int           `xml:"tests,attr" json:"tests"`
-	Passed   int           `xml:"passed,attr" json:"passed"`
-	Skipped  int           `xml:"skipped,attr" json:"skipped"`
-	Failures int           `xml:"failures,attr" json:"failures"`
-	Errors   int           `xml:"errors,attr" json:"errors"`
-	TestCase []TestCase    `xml:"testcase" json:"testcase"`
-	Started  time.Time     `xml:"started,attr" json:"timestamp"`
-	Time     time.Duration `xml:"time,attr" json:"time"`
-	Message  string        `xml:"message,omitempty" json:"message,omitempty"`
+	Name       string        `xml:"name,attr" json:"name"`
+	Total      int           `xml:"tests,attr" json:"tests"`
+	Passed     int           `xml:"passed,attr" json:"passed"`
+	Skipped    int           `xml:"skipped,attr" json:"skipped"`
+	Failures   int           `xml:"failures,attr" json:"failures"`
+	Errors     int           `xml:"errors,attr" json:"errors"`
+	Properties []Property    `xml:"properties>property,omitempty" json:"properties,omitempty"`
+	TestCase   []TestCase    `xml:"testcase" json:"testcase"`
+	Started    time.Time     `xml:"started,attr" json:"timestamp"`
+	Time       time.Duration `xml:"time,attr" json:"time"`
+	Message    string        `xml:"message,omitempty" json:"message,omitempty"`
 }
 
 // NewTestSuite creates a new TestSuite
@@ -114,6 +133,14 @@ func (ts *TestSuite) Add(tc TestCase) {
 	}
 }
 
+// AddProperty adds a Property to the TestSuite
+func (ts *TestSuite) AddProperty(name string, value string) {
+	ts.Properties = append(ts.Properties, Property{
+		Name:  name,
+		Value: value,
+	})
+}
+
 // Finish the TestSuite
 func (ts *TestSuite) Finish(message ...string) {
 	now := time.Now().UTC()
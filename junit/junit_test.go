@@ -50,3 +50,44 @@ func TestJUnit(t *testing.T) {
 	}
 	fmt.Printf("%s\n", bs)
 }
+
+func TestJUnitProperties(t *testing.T) {
+	tc := NewTestCase("queso", "queso.yaml")
+	tc.AddProperty("attempts", "2")
+	tc.AddProperty("flaky", "true")
+	tc.Finish(Passed)
+
+	bs, err := xml.Marshal(tc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got TestCase
+	if err := xml.Unmarshal(bs, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Properties) != 2 || got.Properties[1].Name != "flaky" || got.Properties[1].Value != "true" {
+		t.Fatalf("unexpected properties %#v in %s", got.Properties, bs)
+	}
+}
+
+func TestJUnitSuiteProperties(t *testing.T) {
+	ts := NewTestSuite("Test")
+	ts.AddProperty("git.sha", "abc")
+	ts.AddProperty("note", `<"fish" & 'chips'>`)
+
+	bs, err := xml.Marshal(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got TestSuite
+	if err := xml.Unmarshal(bs, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Properties) != 2 || got.Properties[1].Value != `<"fish" & 'chips'>` {
+		t.Fatalf("unexpected properties %#v in %s", got.Properties, bs)
+	}
+}
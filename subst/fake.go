@@ -0,0 +1,113 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package subst
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// Word lists for the fake* functions.
+var (
+	fakeFirstNames = []string{
+		"Alice", "Ana", "Bob", "Carlos", "Chen", "Dana", "David", "Elena",
+		"Fatima", "Grace", "Hiro", "Ivan", "James", "Kofi", "Laura", "Maria",
+		"Mohammed", "Nina", "Omar", "Priya", "Quinn", "Rosa", "Sam", "Tara",
+		"Uma", "Victor", "Wei", "Yusuf", "Zoe",
+	}
+
+	fakeLastNames = []string{
+		"Anderson", "Brown", "Chen", "Davis", "Evans", "Garcia", "Hernandez",
+		"Ito", "Johnson", "Kim", "Lee", "Martin", "Miller", "Nguyen", "Okafor",
+		"Patel", "Rossi", "Singh", "Smith", "Taylor", "Wilson", "Young",
+	}
+
+	fakeStreets = []string{
+		"Main", "Oak", "Maple", "Cedar", "Elm", "Pine", "Market", "Chestnut",
+		"Walnut", "Park", "Lake", "Hill", "Washington", "Broad", "Spring",
+	}
+
+	fakeStreetSuffixes = []string{"St", "Ave", "Rd", "Blvd", "Ln", "Dr", "Ct", "Way"}
+
+	fakeCities = []string{
+		"Springfield", "Riverside", "Franklin", "Greenville", "Bristol",
+		"Clinton", "Fairview", "Salem", "Madison", "Georgetown", "Arlington",
+		"Ashland", "Dover", "Oxford", "Jackson",
+	}
+
+	// fakeDomains are reserved (RFC 2606), so fake emails never reach
+	// anyone.
+	fakeDomains = []string{"example.com", "example.org", "example.net"}
+)
+
+func pick(xs []string) string {
+	return xs[rand.Intn(len(xs))]
+}
+
+// fakeFunc makes a Func (without arguments) from a generator.
+func fakeFunc(name string, gen func() string) Func {
+	return func(args []string) (interface{}, error) {
+		if err := argCount(name, args, 0, 0); err != nil {
+			return nil, err
+		}
+		return gen(), nil
+	}
+}
+
+func fakeFirstName() string {
+	return pick(fakeFirstNames)
+}
+
+func fakeLastName() string {
+	return pick(fakeLastNames)
+}
+
+func fakeName() string {
+	return fakeFirstName() + " " + fakeLastName()
+}
+
+// fakeEmail makes an address at a reserved domain.
+func fakeEmail() string {
+	return fmt.Sprintf("%s.%s%d@%s",
+		strings.ToLower(fakeFirstName()), strings.ToLower(fakeLastName()),
+		rand.Intn(100), pick(fakeDomains))
+}
+
+// fakePhone makes a North American number in the 555-0100 through
+// 555-0199 range, which is reserved for fictional use.
+func fakePhone() string {
+	return fmt.Sprintf("+1-%d-555-01%02d", 200+rand.Intn(800), rand.Intn(100))
+}
+
+func fakeStreet() string {
+	return fmt.Sprintf("%d %s %s", 1+rand.Intn(9999), pick(fakeStreets), pick(fakeStreetSuffixes))
+}
+
+func fakeCity() string {
+	return pick(fakeCities)
+}
+
+func fakeZip() string {
+	return fmt.Sprintf("%05d", rand.Intn(100000))
+}
+
+func fakeAddress() string {
+	return fmt.Sprintf("%s, %s %s", fakeStreet(), fakeCity(), fakeZip())
+}
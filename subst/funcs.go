@@ -0,0 +1,276 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package subst
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Func is a function that can be called (with string arguments)
+// like '{{randInt 1 100}}' in a string that's subject to
+// substitution.  The Func's result replaces the call.
+//
+// Like a '{VAR}' substitution, a call that's surrounded by double
+// quotes is replaced by the result in the Subber's
+// DefaultSerialization (so '"{{randInt 1 100}}"' becomes a number
+// in JSON).
+type Func func(args []string) (interface{}, error)
+
+// Funcs is the registry of Funcs by name.
+//
+// The random Funcs use math/rand's default source, so they're
+// deterministic when that source is seeded (say via 'plax -seed').
+var Funcs = map[string]Func{
+	"uuid":       uuidFunc,
+	"randInt":    randIntFunc,
+	"randString": randStringFunc,
+	"now":        nowFunc,
+
+	"fakeFirstName": fakeFunc("fakeFirstName", fakeFirstName),
+	"fakeLastName":  fakeFunc("fakeLastName", fakeLastName),
+	"fakeName":      fakeFunc("fakeName", fakeName),
+	"fakeEmail":     fakeFunc("fakeEmail", fakeEmail),
+	"fakePhone":     fakeFunc("fakePhone", fakePhone),
+	"fakeStreet":    fakeFunc("fakeStreet", fakeStreet),
+	"fakeCity":      fakeFunc("fakeCity", fakeCity),
+	"fakeZip":       fakeFunc("fakeZip", fakeZip),
+	"fakeAddress":   fakeFunc("fakeAddress", fakeAddress),
+}
+
+// ctxFuncs are the Funcs that need the Ctx.
+var ctxFuncs = map[string]func(ctx *Ctx, args []string) (interface{}, error){
+	"readFile":       readFileFunc,
+	"readFileBase64": readFileBase64Func,
+}
+
+// funcArg is the syntax for one argument of a Func call: a
+// double-quoted string (perhaps with its quotes escaped within a
+// JSON string) or a word.  An argument can't contain a '{', so a
+// call with an argument like '{?n}' is made after that
+// substitution.
+const funcArg = `\\"[^"\\{]*\\"|"(?:[^"\\{]|\\.)*"|[^ "{}\\]+`
+
+// funcPattern matches a Func call like '{{now "RFC3339"}}' (with
+// optional surrounding double quotes).
+var funcPattern = regexp.MustCompile(`("?)\{\{ *([a-zA-Z][a-zA-Z0-9_]*)((?: +(?:` + funcArg + `))*) *\}\}("?)`)
+
+// funcArgPattern matches one argument of a Func call.
+var funcArgPattern = regexp.MustCompile(funcArg)
+
+// funcSub replaces the Func calls in the string with their results.
+func (b *Subber) funcSub(ctx *Ctx, s string) (string, error) {
+	var e error
+	y := funcPattern.ReplaceAllStringFunc(s, func(call string) string {
+		var (
+			ss                    = funcPattern.FindStringSubmatch(call)
+			leftQuote, rightQuote = ss[1], ss[4]
+			name                  = ss[2]
+		)
+
+		f, have := Funcs[name]
+		if !have {
+			cf, have := ctxFuncs[name]
+			if !have {
+				e = fmt.Errorf("unknown function '%s' in '%s'", name, call)
+				return call
+			}
+			f = func(args []string) (interface{}, error) {
+				return cf(ctx, args)
+			}
+		}
+
+		args := funcArgPattern.FindAllString(ss[3], -1)
+		for i, arg := range args {
+			switch {
+			case strings.HasPrefix(arg, `\"`):
+				args[i] = arg[2 : len(arg)-2]
+			case strings.HasPrefix(arg, `"`):
+				unquoted, err := strconv.Unquote(arg)
+				if err != nil {
+					e = fmt.Errorf("bad argument %s in '%s': %w", arg, call, err)
+					return call
+				}
+				args[i] = unquoted
+			}
+		}
+
+		ctx.trf("funcSub %s %q", name, args)
+		x, err := f(args)
+		if err != nil {
+			e = fmt.Errorf("%s in '%s'", err, call)
+			return call
+		}
+
+		serialization := "text"
+		quoted := leftQuote == `"` && rightQuote == `"`
+		if quoted {
+			serialization = b.DefaultSerialization
+		}
+		got, err := serial(x, serialization, "")
+		if err != nil {
+			e = err
+			return call
+		}
+		if !quoted {
+			got = leftQuote + got + rightQuote
+		}
+		return got
+	})
+	if e != nil {
+		return "", e
+	}
+	return y, nil
+}
+
+// argCount checks the number of arguments.
+func argCount(name string, args []string, min, max int) error {
+	if len(args) < min || max < len(args) {
+		if min == max {
+			return fmt.Errorf("%s needs %d arguments (not %d)", name, min, len(args))
+		}
+		return fmt.Errorf("%s needs %d to %d arguments (not %d)", name, min, max, len(args))
+	}
+	return nil
+}
+
+// uuidFunc returns a (pseudo-random) version 4 UUID.
+func uuidFunc(args []string) (interface{}, error) {
+	if err := argCount("uuid", args, 0, 0); err != nil {
+		return nil, err
+	}
+	var bs [16]byte
+	rand.Read(bs[:])
+	bs[6] = bs[6]&0x0f | 0x40
+	bs[8] = bs[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", bs[0:4], bs[4:6], bs[6:8], bs[8:10], bs[10:16]), nil
+}
+
+// randIntFunc returns a pseudo-random integer from MIN to MAX
+// (inclusive).
+func randIntFunc(args []string) (interface{}, error) {
+	if err := argCount("randInt", args, 2, 2); err != nil {
+		return nil, err
+	}
+	min, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("randInt bad MIN '%s'", args[0])
+	}
+	max, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("randInt bad MAX '%s'", args[1])
+	}
+	if max < min {
+		return nil, fmt.Errorf("randInt MAX %d is less than MIN %d", max, min)
+	}
+	return min + rand.Int63n(max-min+1), nil
+}
+
+const randStringRunes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// randStringFunc returns a pseudo-random alphanumeric string of the
+// given length.
+func randStringFunc(args []string) (interface{}, error) {
+	if err := argCount("randString", args, 1, 1); err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("randString bad length '%s'", args[0])
+	}
+	acc := make([]byte, n)
+	for i := range acc {
+		acc[i] = randStringRunes[rand.Intn(len(randStringRunes))]
+	}
+	return string(acc), nil
+}
+
+// timeLayouts are the named layouts for nowFunc.
+var timeLayouts = map[string]string{
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC822":      time.RFC822,
+	"Kitchen":     time.Kitchen,
+	"DateTime":    "2006-01-02 15:04:05",
+	"DateOnly":    "2006-01-02",
+	"TimeOnly":    "15:04:05",
+}
+
+// nowFunc returns the current (UTC) time in the given layout, which
+// is a name like "RFC3339" (the default), "Unix" (seconds),
+// "UnixMilli", or a Go time layout.
+func nowFunc(args []string) (interface{}, error) {
+	if err := argCount("now", args, 0, 1); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	layout := "RFC3339"
+	if 0 < len(args) {
+		layout = args[0]
+	}
+	switch layout {
+	case "Unix":
+		return now.Unix(), nil
+	case "UnixMilli":
+		return now.UnixNano() / int64(time.Millisecond), nil
+	}
+	if named, have := timeLayouts[layout]; have {
+		layout = named
+	}
+	return now.Format(layout), nil
+}
+
+// readFileFunc returns the contents of the file, which is found in
+// the Ctx's IncludeDirs unless the path is absolute.
+func readFileFunc(ctx *Ctx, args []string) (interface{}, error) {
+	bs, err := readFileArg(ctx, "readFile", args)
+	if err != nil {
+		return nil, err
+	}
+	return string(bs), nil
+}
+
+// readFileBase64Func is readFileFunc with the contents in (standard)
+// base64.
+func readFileBase64Func(ctx *Ctx, args []string) (interface{}, error) {
+	bs, err := readFileArg(ctx, "readFileBase64", args)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.EncodeToString(bs), nil
+}
+
+func readFileArg(ctx *Ctx, name string, args []string) ([]byte, error) {
+	if err := argCount(name, args, 1, 1); err != nil {
+		return nil, err
+	}
+	if filepath.IsAbs(args[0]) {
+		return ioutil.ReadFile(args[0])
+	}
+	return readFile(ctx, args[0])
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright 2021 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package subst
+
+import (
+	"context"
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFuncs(t *testing.T) {
+	var (
+		ctx = NewCtx(context.Background(), []string{"."})
+		bs  = NewBindings()
+	)
+
+	b, err := NewSubber("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for src, want := range map[string]string{
+		`{"id":"{{uuid}}"}`:                  `^\{"id":"[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}"\}$`,
+		`{"n":{{randInt 1 3}}}`:              `^\{"n":[123]\}$`,
+		`{"n":"{{randInt 1 3}}"}`:            `^\{"n":[123]\}$`,
+		`{"s":"n={{randInt 1 3}}"}`:          `^\{"s":"n=[123]"\}$`,
+		`{"y":"{{now \"2006\"}}"}`:           `^\{"y":"20[0-9]{2}"\}$`,
+		`{"n":{{ randInt -2 -2 }}}`:          `^\{"n":-2\}$`,
+		`key-{{randString 8}}`:               `^key-[a-zA-Z0-9]{8}$`,
+		`{{now "2006"}}`:                     `^20[0-9]{2}$`,
+		`{{now "DateOnly"}} at {{now Unix}}`: `^[0-9]{4}-[0-9]{2}-[0-9]{2} at [0-9]+$`,
+		`{{randString {?n}}}`:                `^[a-zA-Z0-9]{4}$`,
+		`{{readFile foo.txt}}`:               `^quesadilla\n\n$`,
+		`{"f":"{{readFile "foo.txt"}}"}`:     `^\{"f":"quesadilla\\n\\n"\}$`,
+		`{{readFileBase64 foo.txt}}`:         `^cXVlc2FkaWxsYQoK$`,
+	} {
+		bs["?n"] = 4
+		got, err := b.Sub(ctx, bs, src)
+		if err != nil {
+			t.Fatalf("%s: %s", src, err)
+		}
+		if !regexp.MustCompile(want).MatchString(got) {
+			t.Fatalf("%s: got %s", src, got)
+		}
+	}
+
+	if got, err := b.Sub(ctx, bs, `{{now}}`); err != nil {
+		t.Fatal(err)
+	} else if _, err := time.Parse(time.RFC3339, got); err != nil {
+		t.Fatal(err)
+	}
+
+	for src, want := range map[string]string{
+		`{{nope}}`:          `unknown function 'nope'`,
+		`{{uuid 1}}`:        `uuid needs 0 arguments (not 1)`,
+		`{{randInt 1}}`:     `randInt needs 2 arguments (not 1)`,
+		`{{randInt 3 1}}`:   `randInt MAX 1 is less than MIN 3`,
+		`{{randString x}}`:  `randString bad length 'x'`,
+		`{{now "a" "b"}}`:   `now needs 0 to 1 arguments (not 2)`,
+		`{{readFile}}`:      `readFile needs 1 arguments (not 0)`,
+		`{{readFile nope}}`: `file 'nope' not found in include paths [.]`,
+	} {
+		_, err := b.Sub(ctx, bs, src)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: got %v (wanted %s)", src, err, want)
+		}
+	}
+}
+
+func TestFake(t *testing.T) {
+	var (
+		ctx = NewCtx(context.Background(), []string{"."})
+		bs  = NewBindings()
+	)
+
+	b, err := NewSubber("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for src, want := range map[string]string{
+		`{"name":"{{fakeName}}"}`:    `^\{"name":"[A-Z][a-z]+ [A-Z][a-z]+"\}$`,
+		`{{fakeFirstName}}`:          `^[A-Z][a-z]+$`,
+		`{{fakeLastName}}`:           `^[A-Z][a-z]+$`,
+		`{"email":"{{fakeEmail}}"}`:  `^\{"email":"[a-z]+\.[a-z]+[0-9]*@example\.(com|org|net)"\}$`,
+		`{{fakePhone}}`:              `^\+1-[2-9][0-9]{2}-555-01[0-9]{2}$`,
+		`{{fakeZip}}`:                `^[0-9]{5}$`,
+		`{"addr":"{{fakeAddress}}"}`: `^\{"addr":"[0-9]+ [A-Z][a-z]+ [A-Z][a-z]+, [A-Z][a-z]+ [0-9]{5}"\}$`,
+	} {
+		got, err := b.Sub(ctx, bs, src)
+		if err != nil {
+			t.Fatalf("%s: %s", src, err)
+		}
+		if !regexp.MustCompile(want).MatchString(got) {
+			t.Fatalf("%s: got %s", src, got)
+		}
+	}
+
+	if _, err := b.Sub(ctx, bs, `{{fakeName 1}}`); err == nil || !strings.Contains(err.Error(), "fakeName needs 0 arguments") {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestFuncsSeed(t *testing.T) {
+	var (
+		ctx = NewCtx(context.Background(), []string{"."})
+		src = `{{uuid}} {{randInt 1 1000000}} {{randString 12}} {{fakeName}} {{fakeAddress}}`
+	)
+
+	b, err := NewSubber("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub := func() string {
+		rand.Seed(42)
+		s, err := b.Sub(ctx, NewBindings(), src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	defer rand.Seed(time.Now().UnixNano())
+
+	if s0, s1 := sub(), sub(); s0 != s1 {
+		t.Fatalf("%s != %s", s0, s1)
+	}
+}
@@ -579,6 +579,10 @@ func (b *Subber) Sub(ctx *Ctx, bs Bindings, s string) (string, error) {
 	for i := 0; i < b.Limit; i++ {
 		ctx.trf("Subber.Sub at %s", s)
 		var err error
+		if s, err = b.funcSub(ctx, s); err != nil {
+			ctx.trf("Subber.Sub func error at %s", s)
+			return "", err
+		}
 		s, err = b.pipeSub(ctx, bs, s)
 		if err != nil {
 			ctx.trf("Subber.Sub error at %s", s)